@@ -0,0 +1,114 @@
+// Package main provides the A–B loop for Personal Musician.
+// Pressing 'A' marks the start of a section of the playing track and 'B'
+// its end; the section then repeats until 'B' is pressed again or another
+// track starts, which helps when practising a solo or transcribing lyrics.
+// The audio thread jumps back to A as it reaches B, so the loop is seamless,
+// and the progress bar marks both points.
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// minLoopLength is the shortest section that can be looped.
+const minLoopLength = time.Second
+
+// ABLoop is a section of the playing track that repeats.
+type ABLoop struct {
+	A, B   time.Duration
+	Marked bool // Point A is set
+	Active bool // Point B is set too, and the section repeats
+}
+
+// MarkLoopA marks the current position as the start of the loop, dropping
+// any previous loop. Returns the position.
+func (p *Player) MarkLoopA() (time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.source == nil {
+		return 0, fmt.Errorf("nothing is playing")
+	}
+
+	speaker.Lock()
+	pos := p.streamer.Position()
+	p.source.loopA, p.source.loopB = pos, 0
+	speaker.Unlock()
+
+	p.loop = ABLoop{A: p.format.SampleRate.D(pos), Marked: true}
+	return p.loop.A, nil
+}
+
+// MarkLoopB marks the current position as the end of the loop and starts
+// repeating the section from A.
+func (p *Player) MarkLoopB() (ABLoop, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.source == nil {
+		return ABLoop{}, fmt.Errorf("nothing is playing")
+	}
+	if !p.loop.Marked {
+		return ABLoop{}, fmt.Errorf("mark the start of the loop first")
+	}
+
+	speaker.Lock()
+	pos := p.streamer.Position()
+	b := p.format.SampleRate.D(pos)
+	if b-p.loop.A < minLoopLength {
+		speaker.Unlock()
+		return ABLoop{}, fmt.Errorf("the end of the loop must be at least %s after its start", minLoopLength)
+	}
+	p.source.loopB = pos
+	speaker.Unlock()
+
+	p.loop.B, p.loop.Active = b, true
+	return p.loop, nil
+}
+
+// ClearLoop stops repeating and forgets both points.
+func (p *Player) ClearLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.source != nil {
+		speaker.Lock()
+		p.source.loopB = 0
+		speaker.Unlock()
+	}
+	p.loop = ABLoop{}
+}
+
+// loopChunk limits samples to the part of the track before point B, after
+// jumping back to A once B is reached. Without a loop it returns samples
+// unchanged. Called on the audio thread.
+func (s *gaplessSource) loopChunk(samples [][2]float64) [][2]float64 {
+	if s.loopB == 0 {
+		return samples
+	}
+	pos := s.track.streamer.Position()
+	if pos >= s.loopB {
+		if err := s.track.streamer.Seek(s.loopA); err != nil {
+			return samples
+		}
+		pos = s.loopA
+	}
+	left := s.rate.N(s.track.format.SampleRate.D(s.loopB - pos))
+	return samples[:max(1, min(len(samples), left))]
+}
+
+// markLoop draws the loop points over the cells of the progress bar.
+func markLoop(cells []string, state PlaybackState) {
+	if !state.Loop.Marked || state.Duration <= 0 {
+		return
+	}
+	column := func(d time.Duration) int {
+		return max(0, min(int(float64(d)/float64(state.Duration)*float64(len(cells))), len(cells)-1))
+	}
+	marker := lipgloss.NewStyle().Foreground(accentColor).Bold(true)
+	cells[column(state.Loop.A)] = marker.Render("[")
+	if state.Loop.Active {
+		cells[column(state.Loop.B)] = marker.Render("]")
+	}
+}
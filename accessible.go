@@ -0,0 +1,189 @@
+// Package main provides the screen-reader friendly mode for Personal Musician.
+// In accessible mode the alternate screen and mouse are not used, the view is
+// a single plain-text line for the focused item, and state changes such as a
+// new track or a finished download are printed as lines of their own so a
+// screen reader announces them.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// accessibleMode reports whether the screen-reader friendly mode is on.
+var accessibleMode bool
+
+// SetAccessibleMode switches the screen-reader friendly mode on or off.
+// Accessible mode implies ASCII glyphs so icons are not read out.
+func SetAccessibleMode(on bool) {
+	accessibleMode = on
+	if on {
+		SetASCIIMode(true)
+	}
+}
+
+// DetectAccessibleMode reads PM_ACCESSIBLE=1/0 from the environment,
+// falling back to the accessible setting of the config file.
+func DetectAccessibleMode() bool {
+	if on, err := strconv.ParseBool(os.Getenv("PM_ACCESSIBLE")); err == nil {
+		return on
+	}
+	return config.UI.Accessible != nil && *config.UI.Accessible
+}
+
+// programOptions returns the Bubble Tea options for the current mode and flags.
+func programOptions(f Flags) []tea.ProgramOption {
+	if accessibleMode {
+		return nil // Inline output that screen readers can follow
+	}
+	var options []tea.ProgramOption
+	if !f.NoAltScreen {
+		options = append(options, tea.WithAltScreen()) // Use alternate screen buffer
+	}
+	if !f.NoMouse {
+		options = append(options, tea.WithMouseCellMotion()) // Enable mouse support
+	}
+	return options
+}
+
+// announce returns a command that prints a line above the view in
+// accessible mode, and nil otherwise.
+func announce(line string) tea.Cmd {
+	if !accessibleMode || line == "" {
+		return nil
+	}
+	return tea.Println(Glyphs(line))
+}
+
+// announceChanges announces a newly started track and finished downloads.
+// It is called on every refresh tick.
+func (m Model) announceChanges() (Model, tea.Cmd) {
+	if !accessibleMode {
+		return m, nil
+	}
+
+	var cmds []tea.Cmd
+
+	state := m.player.GetState()
+	if state.CurrentFile != m.announcedTrack {
+		m.announcedTrack = state.CurrentFile
+		if state.CurrentFile != "" {
+			cmds = append(cmds, announce(Tf("Now playing: %s", m.currentSongName(state))))
+		}
+	}
+
+	for _, item := range m.downloader.Downloads() {
+		if m.announcedDownloads[item.ID] {
+			continue
+		}
+		switch item.State {
+		case DownloadDone:
+			cmds = append(cmds, announce(Tf("Download finished: %s", item.Title)))
+		case DownloadFailed:
+			cmds = append(cmds, announce(Tf("Download failed: %s", item.Title)))
+		default:
+			continue
+		}
+		m.announcedDownloads[item.ID] = true
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// renderAccessible renders the view as one plain-text line describing the
+// focused item, so the screen only changes when the focus does.
+func (m Model) renderAccessible() string {
+	if m.dialog != nil {
+		if m.dialog.prompt {
+			return fmt.Sprintf("%s: %s %s", m.dialog.Title, m.dialog.Message, m.dialog.input.Value())
+		}
+		return fmt.Sprintf("%s: %s %s", m.dialog.Title, m.dialog.Message, T("y/enter: yes • n/esc: no"))
+	}
+	if m.contextMenu != nil {
+		return accessibleItem(m.contextMenu.Title, itemLabel(m.contextMenu.Items, m.contextMenu.cursor, func(it menuItem) string { return it.Label }), m.contextMenu.cursor, len(m.contextMenu.Items))
+	}
+	if m.finderOpen {
+		matches := m.finderMatches()
+		return accessibleItem(T("Find")+" "+m.finderInput.Value(), itemLabel(matches, m.finderCursor, func(fm finderMatch) string { return fm.item.Label }), m.finderCursor, len(matches))
+	}
+
+	view := T(m.currentView.String())
+	switch m.currentView {
+	case ViewSearch:
+		return fmt.Sprintf("%s: %s", view, m.searchInput.Value())
+	case ViewLibrary:
+		files := m.visibleLibrary()
+		return accessibleItem(view, itemLabel(files, m.libraryCursor, MusicFile.DisplayName), m.libraryCursor, len(files))
+	case ViewQueue:
+		entry := m.player.QueueRange(m.queueCursor, m.queueCursor+1)
+		return accessibleItem(view, itemLabel(entry, 0, MusicFile.DisplayName), m.queueCursor, m.player.GetState().QueueLength)
+	case ViewResults:
+		return accessibleItem(view, itemLabel(m.youtubeResults, m.resultsCursor, func(r SearchResult) string { return r.Title }), m.resultsCursor, len(m.youtubeResults))
+	case ViewDownloads:
+		items := m.downloader.Downloads()
+		label := itemLabel(items, m.downloadsCursor, func(d DownloadItem) string {
+			return fmt.Sprintf("%s, %s", d.Title, d.State)
+		})
+		return accessibleItem(view, label, m.downloadsCursor, len(items))
+	case ViewPlaylists:
+		return accessibleItem(view, itemLabel(m.playlistNames, m.playlistsCursor, func(s string) string { return s }), m.playlistsCursor, len(m.playlistNames))
+	case ViewJellyfin:
+		if page := m.jellyfinPage(); page != nil {
+			return accessibleItem(page.parent.Name, itemLabel(page.items, page.cursor, JellyfinItem.Title), page.cursor, len(page.items))
+		}
+	case ViewBrowse:
+		total := m.browseCount(m.browseLevel)
+		cursor := m.browseCursor[m.browseLevel]
+		var label string
+		switch {
+		case cursor >= total:
+		case m.browseLevel == browseArtists:
+			label = m.browse[cursor].name
+		case m.browseLevel == browseAlbums:
+			artist, _ := m.browseSelectedArtist()
+			label = artist.albums[cursor].name
+		default:
+			tracks, start, _ := m.browseSelection()
+			label = tracks[start].DisplayName()
+		}
+		return accessibleItem(view, label, cursor, total)
+	case ViewOrganize:
+		return accessibleItem(view, itemLabel(m.organize, m.organizeCursor, func(move organizeMove) string {
+			label := fmt.Sprintf("%s to %s", relToMusicDir(move.file.Path), relToMusicDir(move.to))
+			if move.skip {
+				label += ", left out"
+			}
+			return label
+		}), m.organizeCursor, len(m.organize))
+	case ViewDuplicates:
+		return accessibleItem(view, itemLabel(m.duplicates, m.duplicatesCursor, func(r duplicateRow) string {
+			return fmt.Sprintf("%s, %s, copy of group %d", r.file.DisplayName(), r.file.FileName, r.group+1)
+		}), m.duplicatesCursor, len(m.duplicates))
+	case ViewLog:
+		entries := appLog.Entries()
+		return accessibleItem(view, itemLabel(entries, m.logCursor, LogEntry.String), m.logCursor, len(entries))
+	case ViewPlaylistEditor:
+		tracks := m.editing.Tracks
+		return accessibleItem(m.editing.Name, itemLabel(tracks, m.editorCursor, func(f MusicFile) string { return f.Name }), m.editorCursor, len(tracks))
+	}
+	return view
+}
+
+// accessibleItem formats the focused item of a list as "View: item (2 of 9)".
+func accessibleItem(view, label string, cursor, total int) string {
+	if total == 0 {
+		return fmt.Sprintf("%s: %s", view, T("empty"))
+	}
+	return fmt.Sprintf("%s: %s %s", view, label, Tf("(%d of %d)", cursor+1, total))
+}
+
+// itemLabel returns the label of items[cursor], or "" when out of range.
+func itemLabel[E any](items []E, cursor int, label func(E) string) string {
+	if cursor < 0 || cursor >= len(items) {
+		return ""
+	}
+	return label(items[cursor])
+}
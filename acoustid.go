@@ -0,0 +1,123 @@
+// Package main provides audio fingerprinting for Personal Musician.
+// Files named "audio.mp3" or "track01.mp3" carry nothing to search by, so
+// they are identified by their sound instead: Chromaprint's fpcalc computes
+// a fingerprint of the audio, AcoustID finds the recordings it belongs to,
+// and MusicBrainz supplies their tags. AcoustID needs a free API key, set
+// as acoustid_api_key under [providers].
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strconv"
+)
+
+// acoustIDAPI is the AcoustID lookup endpoint.
+const acoustIDAPI = "https://api.acoustid.org/v2/lookup"
+
+// audioFingerprint is the output of fpcalc -json.
+type audioFingerprint struct {
+	Duration    float64 `json:"duration"` // Seconds
+	Fingerprint string  `json:"fingerprint"`
+}
+
+// acoustIDResponse is the part of a lookup response that is used.
+type acoustIDResponse struct {
+	Status string `json:"status"`
+	Error  struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Results []struct {
+		Score      float64 `json:"score"` // 0 to 1
+		Recordings []struct {
+			ID string `json:"id"`
+		} `json:"recordings"`
+	} `json:"results"`
+}
+
+// checkIdentifyTools reports why tracks can't be identified by their
+// sound, if they can't.
+func checkIdentifyTools() error {
+	if config.Providers.AcoustIDKey == "" {
+		return fmt.Errorf("no AcoustID API key set (set acoustid_api_key under [providers])")
+	}
+	if _, ok := findTool("fpcalc"); !ok {
+		return fmt.Errorf("fpcalc not found; install Chromaprint (e.g. \"apt install libchromaprint-tools\" or \"brew install chromaprint\")")
+	}
+	return nil
+}
+
+// fingerprintAudio computes the Chromaprint fingerprint of a file.
+func fingerprintAudio(ctx context.Context, path string) (audioFingerprint, error) {
+	fpcalc, ok := findTool("fpcalc")
+	if !ok {
+		return audioFingerprint{}, checkIdentifyTools()
+	}
+	out, err := exec.CommandContext(ctx, fpcalc, "-json", path).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return audioFingerprint{}, fmt.Errorf("failed to fingerprint %s: %s", path, lastLine(string(exitErr.Stderr), err.Error()))
+		}
+		return audioFingerprint{}, fmt.Errorf("failed to fingerprint %s: %w", path, err)
+	}
+
+	var fp audioFingerprint
+	if err := json.Unmarshal(out, &fp); err != nil || fp.Fingerprint == "" {
+		return audioFingerprint{}, fmt.Errorf("failed to fingerprint %s: unexpected fpcalc output", path)
+	}
+	return fp, nil
+}
+
+// IdentifyTrack identifies a file by its sound and returns the tags of the
+// best matching recording, scored 0-100, or nil if it is not known.
+func IdentifyTrack(ctx context.Context, path string) (*MusicBrainzMatch, error) {
+	if err := checkIdentifyTools(); err != nil {
+		return nil, err
+	}
+	fp, err := fingerprintAudio(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"client":      {config.Providers.AcoustIDKey},
+		"meta":        {"recordingids"},
+		"duration":    {strconv.Itoa(int(fp.Duration))},
+		"fingerprint": {fp.Fingerprint},
+	}
+	body, err := httpGet(ctx, "acoustid", acoustIDAPI+"?"+params.Encode(), nil)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		body, err = statusErr.Body, nil // The body says what was wrong
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up fingerprint: %w", err)
+	}
+
+	var resp acoustIDResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse AcoustID response: %w", err)
+	}
+	if resp.Status != "ok" {
+		return nil, fmt.Errorf("failed to look up fingerprint: %s", resp.Error.Message)
+	}
+
+	// Results come best first; take the first that names a recording
+	for _, result := range resp.Results {
+		if len(result.Recordings) == 0 {
+			continue
+		}
+		match, err := LookupMusicBrainzRecording(ctx, result.Recordings[0].ID)
+		if err != nil {
+			return nil, err
+		}
+		match.Score = int(result.Score * 100)
+		return &match, nil
+	}
+	return nil, nil
+}
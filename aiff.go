@@ -0,0 +1,194 @@
+// Package main provides AIFF decoding for Personal Musician.
+// beep has no AIFF decoder, so the uncompressed PCM of AIFF and AIFF-C
+// files (big-endian, or little-endian "sowt" as written by many DAWs) is
+// read here directly, at 8 to 32 bits per sample. Compressed AIFF-C
+// variants are rejected.
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// aiffDecoder streams the sample frames of an AIFF file.
+type aiffDecoder struct {
+	f            *os.File
+	format       beep.Format
+	littleEndian bool  // AIFF-C "sowt"
+	frameSize    int   // Bytes per sample frame, all channels
+	dataOffset   int64 // File offset of the first frame
+	frames       int   // Sample frames in the file
+	pos          int   // Next frame to stream
+	buf          []byte
+	err          error
+}
+
+// decodeAIFF reads the header of an AIFF file and returns a streamer for
+// its audio. Closing the streamer closes f.
+func decodeAIFF(f *os.File) (beep.StreamSeekCloser, beep.Format, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(header[:4]) != "FORM" || (string(header[8:]) != "AIFF" && string(header[8:]) != "AIFC") {
+		return nil, beep.Format{}, fmt.Errorf("not an AIFF file")
+	}
+	aifc := string(header[8:]) == "AIFC"
+
+	d := &aiffDecoder{f: f}
+	var bits int
+	var haveComm, haveData bool
+	for !haveComm || !haveData {
+		chunk := make([]byte, 8)
+		if _, err := io.ReadFull(f, chunk); err != nil {
+			return nil, beep.Format{}, fmt.Errorf("missing COMM or SSND chunk")
+		}
+		size := int64(binary.BigEndian.Uint32(chunk[4:]))
+		start, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, beep.Format{}, fmt.Errorf("failed to read chunk: %w", err)
+		}
+
+		switch string(chunk[:4]) {
+		case "COMM":
+			comm := make([]byte, min(size, 22))
+			if _, err := io.ReadFull(f, comm); err != nil || len(comm) < 18 {
+				return nil, beep.Format{}, fmt.Errorf("failed to read COMM chunk")
+			}
+			d.format.NumChannels = int(binary.BigEndian.Uint16(comm[0:]))
+			d.frames = int(binary.BigEndian.Uint32(comm[2:]))
+			bits = int(binary.BigEndian.Uint16(comm[6:]))
+			d.format.SampleRate = beep.SampleRate(extendedFloat(comm[8:18]))
+			if aifc && len(comm) >= 22 {
+				switch string(comm[18:22]) {
+				case "NONE", "twos":
+				case "sowt":
+					d.littleEndian = true
+				default:
+					return nil, beep.Format{}, fmt.Errorf("unsupported AIFF-C compression %q", comm[18:22])
+				}
+			}
+			haveComm = true
+		case "SSND":
+			ssnd := make([]byte, 8)
+			if _, err := io.ReadFull(f, ssnd); err != nil {
+				return nil, beep.Format{}, fmt.Errorf("failed to read SSND chunk")
+			}
+			d.dataOffset = start + 8 + int64(binary.BigEndian.Uint32(ssnd))
+			haveData = true
+		}
+
+		// Chunks are padded to an even size
+		if _, err := f.Seek(start+size+size%2, io.SeekStart); err != nil {
+			return nil, beep.Format{}, fmt.Errorf("failed to read chunk: %w", err)
+		}
+	}
+
+	if d.format.NumChannels < 1 || bits < 1 || bits > 32 || d.format.SampleRate <= 0 {
+		return nil, beep.Format{}, fmt.Errorf("unsupported AIFF format: %d channels, %d bits", d.format.NumChannels, bits)
+	}
+	d.format.Precision = (bits + 7) / 8
+	d.frameSize = d.format.NumChannels * d.format.Precision
+	if err := d.Seek(0); err != nil {
+		return nil, beep.Format{}, err
+	}
+	return d, d.format, nil
+}
+
+// extendedFloat converts an 80-bit IEEE 754 extended precision number, as
+// AIFF stores the sample rate.
+func extendedFloat(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if b[0]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// Stream decodes frames into samples. Mono files play on both channels.
+func (d *aiffDecoder) Stream(samples [][2]float64) (n int, ok bool) {
+	if d.err != nil || d.pos >= d.frames {
+		return 0, false
+	}
+	want := min(len(samples), d.frames-d.pos)
+	if len(d.buf) < want*d.frameSize {
+		d.buf = make([]byte, want*d.frameSize)
+	}
+	read, err := io.ReadFull(d.f, d.buf[:want*d.frameSize])
+	n = read / d.frameSize
+	if err != nil {
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			d.err = err
+		}
+		d.frames = d.pos + n // Truncated file; end here
+	}
+
+	width := d.format.Precision
+	for i := range n {
+		frame := d.buf[i*d.frameSize:]
+		left := d.sample(frame[:width])
+		right := left
+		if d.format.NumChannels > 1 {
+			right = d.sample(frame[width : 2*width])
+		}
+		samples[i] = [2]float64{left, right}
+	}
+	d.pos += n
+	return n, n > 0
+}
+
+// sample converts one signed PCM sample to the range -1 to 1.
+func (d *aiffDecoder) sample(b []byte) float64 {
+	var v int32
+	for i := range b {
+		shift := 8 * (3 - i) // Left-justify in 32 bits
+		if d.littleEndian {
+			shift = 8 * (3 - (len(b) - 1 - i))
+		}
+		v |= int32(uint32(b[i]) << shift)
+	}
+	return float64(v) / (1 << 31)
+}
+
+// Err returns the error that stopped streaming, if any.
+func (d *aiffDecoder) Err() error {
+	return d.err
+}
+
+// Len returns the number of sample frames.
+func (d *aiffDecoder) Len() int {
+	return d.frames
+}
+
+// Position returns the next frame to be streamed.
+func (d *aiffDecoder) Position() int {
+	return d.pos
+}
+
+// Seek moves to frame p.
+func (d *aiffDecoder) Seek(p int) error {
+	if p < 0 || p > d.frames {
+		return fmt.Errorf("aiff: seek position %d out of range [0, %d]", p, d.frames)
+	}
+	if _, err := d.f.Seek(d.dataOffset+int64(p)*int64(d.frameSize), io.SeekStart); err != nil {
+		return fmt.Errorf("aiff: failed to seek: %w", err)
+	}
+	d.pos = p
+	return nil
+}
+
+// Close closes the file.
+func (d *aiffDecoder) Close() error {
+	return d.f.Close()
+}
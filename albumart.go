@@ -0,0 +1,218 @@
+// Package main provides album-art-derived colors for Personal Musician.
+// When the playing track has embedded album art, its dominant colors replace
+// the theme's primary and accent colors until the track changes, giving each
+// album its own look.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// albumColorsEnabled reports whether album art colors are applied.
+var albumColorsEnabled = true
+
+// DetectAlbumColors reads PM_ALBUM_COLORS=1/0, falling back to the
+// album_colors setting of the config file; album colors are on by default.
+func DetectAlbumColors() bool {
+	if on, err := strconv.ParseBool(os.Getenv("PM_ALBUM_COLORS")); err == nil {
+		return on
+	}
+	if config.UI.AlbumColors != nil {
+		return *config.UI.AlbumColors
+	}
+	return true
+}
+
+// SetAlbumColorsEnabled turns album art colors on or off.
+func SetAlbumColorsEnabled(on bool) {
+	albumColorsEnabled = on
+}
+
+// AlbumColors are the theme colors taken from a track's album art.
+type AlbumColors struct {
+	Primary string // Hex color for borders, headers and the played waveform
+	Accent  string // Hex color for the now-playing line and playhead
+}
+
+// albumColorsMsg carries the colors for a track; nil colors restore the theme.
+type albumColorsMsg struct {
+	path   string
+	colors *AlbumColors
+}
+
+// ExtractAlbumColors reads the album art of a file and derives its colors.
+// Files without usable art yield nil and no error.
+func ExtractAlbumColors(path string) (*AlbumColors, error) {
+	picture, err := ReadPicture(path)
+	if err != nil || picture == nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(picture))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode album art: %w", err)
+	}
+	return dominantColors(img), nil
+}
+
+// loadAlbumColors returns a command that extracts the album colors of a track.
+func loadAlbumColors(path string) tea.Cmd {
+	return func() tea.Msg {
+		colors, err := ExtractAlbumColors(path)
+		if err != nil {
+			appLog.Add("album art", err.Error())
+		}
+		return albumColorsMsg{path: path, colors: colors}
+	}
+}
+
+// updateAlbumColors starts extracting album colors when a new track starts.
+func (m Model) updateAlbumColors() (Model, tea.Cmd) {
+	if !albumColorsEnabled {
+		return m, nil
+	}
+	current := m.player.GetState().CurrentFile
+	if current == "" || current == m.albumColorsPath {
+		return m, nil
+	}
+	m.albumColorsPath = current
+	return m, loadAlbumColors(current)
+}
+
+// applyAlbumColors switches the palette to a track's album colors, or back to
+// the plain theme when colors is nil.
+func (m Model) applyAlbumColors(msg albumColorsMsg) Model {
+	if msg.path != m.albumColorsPath {
+		return m // A newer track has started since
+	}
+	SetAlbumColors(msg.colors)
+	m.downloadSpinner.Style = lipgloss.NewStyle().Foreground(primaryColor)
+	return m
+}
+
+// colorBucket accumulates the pixels that fall into one quantized color.
+type colorBucket struct {
+	r, g, b, n int
+}
+
+// dominantColors picks the most common vivid color as primary and the most
+// common one with a clearly different hue as accent. Art that is almost
+// entirely gray yields nil.
+func dominantColors(img image.Image) *AlbumColors {
+	bounds := img.Bounds()
+	step := max(1, max(bounds.Dx(), bounds.Dy())/64) // Sample about 64x64 pixels
+
+	buckets := make(map[int]*colorBucket)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r, g, b := int(cr>>8), int(cg>>8), int(cb>>8)
+			if _, s, l := rgbToHSL(r, g, b); s < 0.25 || l < 0.15 || l > 0.9 {
+				continue // Skip grays, near-black and near-white
+			}
+			key := r>>5<<6 | g>>5<<3 | b>>5
+			bucket := buckets[key]
+			if bucket == nil {
+				bucket = &colorBucket{}
+				buckets[key] = bucket
+			}
+			bucket.r += r
+			bucket.g += g
+			bucket.b += b
+			bucket.n++
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	sorted := make([]*colorBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		sorted = append(sorted, bucket)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].n > sorted[j].n })
+
+	primary := sorted[0]
+	primaryHue, _, _ := rgbToHSL(primary.r/primary.n, primary.g/primary.n, primary.b/primary.n)
+	accent := primary
+	for _, bucket := range sorted[1:] {
+		hue, _, _ := rgbToHSL(bucket.r/bucket.n, bucket.g/bucket.n, bucket.b/bucket.n)
+		if diff := math.Abs(hue - primaryHue); math.Min(diff, 360-diff) >= 40 {
+			accent = bucket
+			break
+		}
+	}
+
+	return &AlbumColors{
+		Primary: readableHex(primary),
+		Accent:  readableHex(accent),
+	}
+}
+
+// readableHex returns a bucket's mean color with its lightness clamped so it
+// stays legible as text on dark and light backgrounds.
+func readableHex(bucket *colorBucket) string {
+	h, s, l := rgbToHSL(bucket.r/bucket.n, bucket.g/bucket.n, bucket.b/bucket.n)
+	l = math.Max(0.45, math.Min(l, 0.7))
+	r, g, b := hslToRGB(h, s, l)
+	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+}
+
+// rgbToHSL converts 8-bit RGB to hue (degrees), saturation and lightness.
+func rgbToHSL(r, g, b int) (h, s, l float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	hi := math.Max(rf, math.Max(gf, bf))
+	lo := math.Min(rf, math.Min(gf, bf))
+	l = (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, l
+	}
+
+	d := hi - lo
+	if l > 0.5 {
+		s = d / (2 - hi - lo)
+	} else {
+		s = d / (hi + lo)
+	}
+	switch hi {
+	case rf:
+		h = math.Mod((gf-bf)/d+6, 6)
+	case gf:
+		h = (bf-rf)/d + 2
+	default:
+		h = (rf-gf)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// hslToRGB converts hue (degrees), saturation and lightness to 8-bit RGB.
+func hslToRGB(h, s, l float64) (r, g, b int) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf, bf = c, x, 0
+	case h < 120:
+		rf, gf, bf = x, c, 0
+	case h < 180:
+		rf, gf, bf = 0, c, x
+	case h < 240:
+		rf, gf, bf = 0, x, c
+	case h < 300:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+	return int(math.Round((rf + m) * 255)), int(math.Round((gf + m) * 255)), int(math.Round((bf + m) * 255))
+}
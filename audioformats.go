@@ -0,0 +1,142 @@
+// Package main provides the audio formats Personal Musician can play.
+// Each format is known by its file extension and brings its decoder, a way
+// to read the duration without decoding and its MIME type. Library scans,
+// playback, waveforms and casting all look formats up here, so supporting
+// a new one means adding a single entry.
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/flac"
+	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// audioFormat describes a file format the player can play.
+type audioFormat struct {
+	name     string
+	mime     string
+	decode   func(f *os.File) (beep.StreamSeekCloser, beep.Format, error)
+	duration func(path string) (time.Duration, error) // Without decoding the audio; nil reads the decoder's header
+}
+
+// audioFormats maps lowercase file extensions to their formats.
+var audioFormats = map[string]audioFormat{
+	".mp3": {
+		name:     "MP3",
+		mime:     "audio/mpeg",
+		decode:   func(f *os.File) (beep.StreamSeekCloser, beep.Format, error) { return mp3.Decode(f) },
+		duration: mp3Duration,
+	},
+	".flac": {
+		name:     "FLAC",
+		mime:     "audio/flac",
+		decode:   func(f *os.File) (beep.StreamSeekCloser, beep.Format, error) { return flac.Decode(f) },
+		duration: flacDuration,
+	},
+	".wav": {
+		name:   "WAV",
+		mime:   "audio/wav",
+		decode: func(f *os.File) (beep.StreamSeekCloser, beep.Format, error) { return wav.Decode(f) },
+	},
+	".aiff": aiffFormat,
+	".aif":  aiffFormat,
+}
+
+// aiffFormat is AIFF, which has two common extensions.
+var aiffFormat = audioFormat{
+	name:   "AIFF",
+	mime:   "audio/aiff",
+	decode: decodeAIFF,
+}
+
+// audioFormatOf returns the format of a file by its extension.
+func audioFormatOf(path string) (audioFormat, bool) {
+	format, ok := audioFormats[strings.ToLower(filepath.Ext(path))]
+	return format, ok
+}
+
+// isAudioFile reports whether path has the extension of a playable format.
+func isAudioFile(path string) bool {
+	_, ok := audioFormatOf(path)
+	return ok
+}
+
+// decodeAudioFile opens and decodes an audio file. Closing the returned
+// streamer closes the file.
+func decodeAudioFile(path string) (beep.StreamSeekCloser, beep.Format, error) {
+	format, ok := audioFormatOf(path)
+	if !ok {
+		return nil, beep.Format{}, fmt.Errorf("unsupported audio format %q", filepath.Ext(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, beep.Format{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	streamer, sampleFormat, err := format.decode(f)
+	if err != nil {
+		f.Close()
+		return nil, beep.Format{}, fmt.Errorf("failed to decode %s: %w", format.name, err)
+	}
+	return streamer, sampleFormat, nil
+}
+
+// audioDuration returns the duration of an audio file without decoding it.
+func audioDuration(path string) (time.Duration, error) {
+	format, ok := audioFormatOf(path)
+	if !ok {
+		return 0, fmt.Errorf("unsupported audio format %q", filepath.Ext(path))
+	}
+	if format.duration == nil {
+		return headerDuration(path)
+	}
+	return format.duration(path)
+}
+
+// headerDuration returns the duration of a file whose decoder only reads
+// the header before streaming, as for uncompressed formats.
+func headerDuration(path string) (time.Duration, error) {
+	streamer, format, err := decodeAudioFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer streamer.Close()
+	return format.SampleRate.D(streamer.Len()), nil
+}
+
+// flacDuration reads the duration of a FLAC file from its STREAMINFO
+// block, which always comes first.
+func flacDuration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	// "fLaC", the block header, then STREAMINFO
+	header := make([]byte, 4+4+34)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, fmt.Errorf("failed to read FLAC header: %w", err)
+	}
+	if string(header[:4]) != "fLaC" || header[4]&0x7f != 0 {
+		return 0, fmt.Errorf("no FLAC stream info found")
+	}
+
+	// Sample rate (20 bits), channels (3), bits per sample (5) and total
+	// samples (36) are packed from byte 10 of STREAMINFO
+	packed := binary.BigEndian.Uint64(header[8+10 : 8+18])
+	rate := packed >> 44
+	samples := packed & (1<<36 - 1)
+	if rate == 0 || samples == 0 {
+		return 0, fmt.Errorf("FLAC stream info has no length")
+	}
+	return time.Duration(float64(samples) / float64(rate) * float64(time.Second)), nil
+}
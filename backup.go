@@ -0,0 +1,426 @@
+// Package main provides library backups for Personal Musician.
+// With a remote set under [backup], the Music directory and the library
+// data (playlists, listening stats and the saved session) are mirrored to
+// it with rclone, so any of its backends works: S3, WebDAV, Google Drive,
+// an SFTP server and so on. Backups run from the finder, on a schedule
+// while the player runs, or from cron with "personal-musician backup", and
+// their progress shows in the Downloads view.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// backupCheckInterval is how often the schedule is checked for a due backup.
+const backupCheckInterval = time.Minute
+
+// BackupFile records when the last backup finished.
+var BackupFile = dataPath("backup.json")
+
+// backupDataFiles are the library data backed up from the data directory.
+// Caches, tools and the instance socket are left out.
+func backupDataFiles() []string {
+	return []string{PlaylistDir, StatsFile, HistoryFile, StateFile, LayoutFile, EpisodesFile}
+}
+
+// BackupProgress is the state of a backup.
+type BackupProgress struct {
+	Running        bool
+	Phase          string    // What is being backed up: "music" or "library data"
+	Bytes          int64     // Bytes transferred so far
+	TotalBytes     int64     // Bytes to transfer in this phase
+	Speed          float64   // Bytes per second
+	Transfers      int       // Files transferred so far
+	TotalTransfers int       // Files to transfer in this phase
+	Finished       time.Time // When the last backup succeeded
+	Error          string    // Why the last backup failed
+}
+
+// Percent returns how much of the current phase is done.
+func (p BackupProgress) Percent() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / float64(p.TotalBytes) * 100
+}
+
+// Backup mirrors the library to an rclone remote, one backup at a time.
+type Backup struct {
+	cfg BackupConfig
+
+	mu       sync.Mutex
+	progress BackupProgress
+	cancel   context.CancelFunc
+	done     chan struct{} // Closed when the running backup ends
+}
+
+// backupRecord is the content of BackupFile.
+type backupRecord struct {
+	Last time.Time `json:"last"`
+}
+
+// NewBackup creates a backup to the configured remote.
+func NewBackup(cfg BackupConfig) *Backup {
+	b := &Backup{cfg: cfg}
+	if data, err := os.ReadFile(BackupFile); err == nil {
+		var record backupRecord
+		if json.Unmarshal(data, &record) == nil {
+			b.progress.Finished = record.Last
+		}
+	}
+	return b
+}
+
+// Remote returns the rclone remote backed up to.
+func (b *Backup) Remote() string {
+	return b.cfg.Remote
+}
+
+// Available reports an error if rclone cannot be found.
+func (b *Backup) Available() error {
+	_, err := b.rclone()
+	return err
+}
+
+// rclone returns the path of the rclone binary.
+func (b *Backup) rclone() (string, error) {
+	name := b.cfg.Rclone
+	if name == "" {
+		name = "rclone"
+	}
+	path, ok := findTool(name)
+	if !ok {
+		return "", fmt.Errorf("rclone not found (install it from https://rclone.org/install/ or set rclone under [backup])")
+	}
+	return path, nil
+}
+
+// Start starts a backup in the background unless one is running.
+func (b *Backup) Start() error {
+	rclone, err := b.rclone()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.progress.Running {
+		return fmt.Errorf("a backup is already running")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	b.progress = BackupProgress{Running: true, Phase: "music", Finished: b.progress.Finished}
+	go b.run(ctx, rclone, b.done)
+	return nil
+}
+
+// Cancel stops the running backup.
+func (b *Backup) Cancel() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.progress.Running {
+		return fmt.Errorf("no backup is running")
+	}
+	b.cancel()
+	return nil
+}
+
+// Close cancels the running backup, if any, and waits for rclone to exit.
+func (b *Backup) Close() {
+	b.Cancel()
+	b.Wait()
+}
+
+// Wait blocks until the running backup ends and returns its error.
+func (b *Backup) Wait() error {
+	b.mu.Lock()
+	done := b.done
+	b.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.progress.Error != "" {
+		return errors.New(b.progress.Error)
+	}
+	return nil
+}
+
+// Progress returns a snapshot of the backup's state.
+func (b *Backup) Progress() BackupProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.progress
+}
+
+// Running reports whether a backup is in progress.
+func (b *Backup) Running() bool {
+	return b.Progress().Running
+}
+
+// Schedule starts a backup whenever the last one is older than interval,
+// until ctx ends. The time of the last backup survives restarts, so a
+// daily backup runs even if the player is never open for a whole day. A
+// failed backup is retried after another interval.
+func (b *Backup) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(backupCheckInterval)
+	defer ticker.Stop()
+	var attempted time.Time
+	for {
+		p := b.Progress()
+		if !p.Running && time.Since(p.Finished) >= interval && time.Since(attempted) >= interval {
+			attempted = time.Now()
+			if err := b.Start(); err != nil {
+				slog.Warn("scheduled backup not started", "err", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run backs up the music, then the library data.
+func (b *Backup) run(ctx context.Context, rclone string, done chan struct{}) {
+	defer close(done)
+	defer b.cancel()
+	slog.Info("backup started", "remote", b.cfg.Remote)
+
+	err := b.sync(ctx, rclone, "music", MusicDir, remotePath(b.cfg.Remote, "Music"), nil)
+	if err == nil {
+		var filters []string
+		for _, path := range backupDataFiles() {
+			name := "/" + filepath.Base(path)
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				name += "/**"
+			}
+			filters = append(filters, "--include", name)
+		}
+		err = b.sync(ctx, rclone, "library data", DataDir(), remotePath(b.cfg.Remote, "Data"), filters)
+	}
+	if ctx.Err() != nil {
+		err = fmt.Errorf("backup cancelled")
+	}
+
+	b.mu.Lock()
+	b.progress.Running = false
+	b.progress.Speed = 0
+	if err != nil {
+		b.progress.Error = err.Error()
+	} else {
+		b.progress.Finished = time.Now()
+	}
+	finished := b.progress.Finished
+	b.mu.Unlock()
+
+	if err != nil {
+		slog.Error("backup failed", "remote", b.cfg.Remote, "err", err)
+		appLog.Add("error", fmt.Sprintf("backup to %s failed: %v", b.cfg.Remote, err))
+		return
+	}
+	slog.Info("backup finished", "remote", b.cfg.Remote)
+	data, _ := json.Marshal(backupRecord{Last: finished})
+	if err := writeFileAtomic(BackupFile, data); err != nil {
+		slog.Warn("failed to record backup", "err", err)
+	}
+}
+
+// rcloneLine is a line of rclone's JSON log.
+type rcloneLine struct {
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+	Stats *struct {
+		Bytes          int64   `json:"bytes"`
+		TotalBytes     int64   `json:"totalBytes"`
+		Speed          float64 `json:"speed"`
+		Transfers      int     `json:"transfers"`
+		TotalTransfers int     `json:"totalTransfers"`
+	} `json:"stats"`
+}
+
+// sync mirrors one directory to the remote with "rclone sync", which also
+// deletes remote files that were deleted locally.
+func (b *Backup) sync(ctx context.Context, rclone, phase, src, dst string, filters []string) error {
+	b.mu.Lock()
+	b.progress = BackupProgress{Running: true, Phase: phase, Finished: b.progress.Finished}
+	b.mu.Unlock()
+
+	args := append([]string{
+		"sync", src, dst,
+		"--use-json-log", // Machine-readable log lines
+		"--stats", "1s",  // Report progress every second
+		"--stats-log-level", "NOTICE", // Log progress without verbose output
+	}, filters...)
+	cmd := exec.CommandContext(ctx, rclone, args...)
+	slog.Info("rclone", "args", cmd.Args)
+
+	// rclone logs to stderr
+	stderr, err := cmd.StderrPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start rclone: %w", err)
+	}
+	lastError := b.readStats(stderr)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to back up %s: %s", phase, lastLine(lastError, err.Error()))
+	}
+	return nil
+}
+
+// readStats parses rclone's log, updating the progress from its stats
+// lines and keeping the rest in the in-app log. It returns the last error
+// rclone logged.
+func (b *Backup) readStats(r io.Reader) string {
+	var lastError string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var line rcloneLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			appLog.Add("rclone", scanner.Text())
+			continue
+		}
+		if line.Stats == nil {
+			appLog.Add("rclone", line.Msg)
+			if line.Level == "error" || line.Level == "critical" {
+				lastError = line.Msg
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		b.progress.Bytes = line.Stats.Bytes
+		b.progress.TotalBytes = line.Stats.TotalBytes
+		b.progress.Speed = line.Stats.Speed
+		b.progress.Transfers = line.Stats.Transfers
+		b.progress.TotalTransfers = line.Stats.TotalTransfers
+		b.mu.Unlock()
+	}
+	return lastError
+}
+
+// remotePath joins a directory name to an rclone remote such as "s3:bucket"
+// or "dav:".
+func remotePath(remote, name string) string {
+	remote = strings.TrimSuffix(remote, "/")
+	if strings.HasSuffix(remote, ":") {
+		return remote + name
+	}
+	return remote + "/" + name
+}
+
+// runBackup backs up the library once, printing progress, for cron jobs
+// and scripts. Ctrl+C, SIGTERM or SIGHUP cancels the backup.
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	remote := fs.String("remote", config.Backup.Remote, "back up to rclone `remote` instead of [backup] remote")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("usage: personal-musician backup [--remote remote]")
+	}
+	if *remote == "" {
+		return fmt.Errorf("no backup remote set (set remote under [backup], PM_BACKUP_REMOTE or --remote)")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
+
+	cfg := config.Backup
+	cfg.Remote = *remote
+	backup := NewBackup(cfg)
+	if err := backup.Start(); err != nil {
+		return err
+	}
+	fmt.Println(Glyphs("☁ Backing up to " + cfg.Remote))
+
+	done := make(chan error, 1)
+	go func() { done <- backup.Wait() }()
+
+	terminal := isTerminal(os.Stdout)
+	ticker := time.NewTicker(headlessProgressInterval)
+	defer ticker.Stop()
+	interrupted := ctx.Done()
+	for {
+		select {
+		case err := <-done:
+			if terminal {
+				fmt.Println()
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println(Glyphs("✓ Backed up to " + cfg.Remote))
+			return nil
+		case <-interrupted:
+			backup.Cancel()
+			interrupted = nil
+		case <-ticker.C:
+			if p := backup.Progress(); terminal && p.Running {
+				fmt.Printf("\r%-12s %5.1f%%  %d/%d files  %-12s", p.Phase, p.Percent(), p.Transfers, p.TotalTransfers, formatSpeed(p.Speed))
+			}
+		}
+	}
+}
+
+// startBackup starts a backup from the TUI.
+func (m Model) startBackup() (tea.Model, tea.Cmd) {
+	if m.backup == nil {
+		return m, func() tea.Msg { return errorMsg(T("No backup remote set (set remote under [backup])")) }
+	}
+	if err := m.backup.Start(); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	m.currentView = ViewDownloads
+	m, tick := m.retick()
+	return m, tea.Batch(tick, func() tea.Msg { return statusMsg(Tf("Backing up to %s", m.backup.Remote())) })
+}
+
+// renderBackupStatus renders the state of the backup for the Downloads
+// view's header, e.g. "☁ Backing up music  ████░░ 42% 12/30 1.2 MB/s".
+func (m Model) renderBackupStatus() string {
+	if m.backup == nil {
+		return ""
+	}
+	p := m.backup.Progress()
+	switch {
+	case p.Running:
+		filled := int(p.Percent() / 100 * progressBarWidth)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+		info := fmt.Sprintf("%s %3.0f%% %d/%d", bar, p.Percent(), p.Transfers, p.TotalTransfers)
+		if p.Speed > 0 {
+			info += " " + formatSpeed(p.Speed)
+		}
+		return nowPlayingStyle.Render("☁ "+Tf("Backing up %s", T(p.Phase))) + "  " + mutedStyle.Render(info)
+	case p.Error != "":
+		return normalStyle.Render("✗ "+T("Backup failed")) + "  " + mutedStyle.Render(p.Error)
+	case !p.Finished.IsZero():
+		return mutedStyle.Render("☁ " + Tf("Last backup %s", p.Finished.Format("Jan 2 15:04")))
+	default:
+		return mutedStyle.Render("☁ " + T("Not backed up yet"))
+	}
+}
@@ -0,0 +1,358 @@
+// Package main provides the artist and album browser of Personal Musician.
+// The Artists view groups the library by the artist and album tags read
+// from the files, drilling down from artists to their albums to the tracks
+// in album order. Enter opens an entry or plays a track followed by the
+// rest of its album; p plays and a queues the selected artist, album or
+// track. Backspace goes back up.
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Levels of the artist and album browser.
+const (
+	browseArtists = iota
+	browseAlbums
+	browseTracks
+)
+
+// browseArtist is an artist and their albums.
+type browseArtist struct {
+	name   string
+	albums []browseAlbum // Oldest first
+	songs  int
+}
+
+// browseAlbum is an album and its tracks.
+type browseAlbum struct {
+	name   string
+	year   string
+	tracks []MusicFile // In album order
+}
+
+// tracks returns every track of the artist, album by album.
+func (a browseArtist) tracks() []MusicFile {
+	var tracks []MusicFile
+	for _, album := range a.albums {
+		tracks = append(tracks, album.tracks...)
+	}
+	return tracks
+}
+
+// groupByArtist groups files by their artist and album tags, ignoring
+// case. Files without them are grouped under an unknown artist or album,
+// listed last.
+func groupByArtist(files []MusicFile) []browseArtist {
+	unknownArtist, unknownAlbum := T("Unknown artist"), T("Unknown album")
+	albums := make(map[string]map[string]*browseAlbum) // By artist and album, in lower case
+	artistNames := make(map[string]string)             // The first spelling of each artist
+	for _, file := range files {
+		artist := strings.TrimSpace(file.Tags.Artist)
+		if artist == "" {
+			artist = unknownArtist
+		}
+		name := strings.TrimSpace(file.Tags.Album)
+		if name == "" {
+			name = unknownAlbum
+		}
+		artistKey, albumKey := strings.ToLower(artist), strings.ToLower(name)
+		if albums[artistKey] == nil {
+			albums[artistKey] = make(map[string]*browseAlbum)
+			artistNames[artistKey] = artist
+		}
+		album := albums[artistKey][albumKey]
+		if album == nil {
+			album = &browseAlbum{name: name}
+			albums[artistKey][albumKey] = album
+		}
+		album.tracks = append(album.tracks, file)
+		if album.year == "" {
+			album.year = file.Tags.Year
+		}
+	}
+
+	// Unknown entries go last; the rest sort by name, ignoring case
+	byName := func(unknown string) func(a, b string) int {
+		return func(a, b string) int {
+			if (a == unknown) != (b == unknown) {
+				if a == unknown {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+		}
+	}
+
+	var artists []browseArtist
+	for key, byAlbum := range albums {
+		artist := browseArtist{name: artistNames[key]}
+		for _, album := range byAlbum {
+			slices.SortStableFunc(album.tracks, func(a, b MusicFile) int {
+				if c := cmp.Compare(a.Tags.TrackNumber(), b.Tags.TrackNumber()); c != 0 {
+					return c
+				}
+				return cmp.Compare(strings.ToLower(a.DisplayName()), strings.ToLower(b.DisplayName()))
+			})
+			artist.albums = append(artist.albums, *album)
+			artist.songs += len(album.tracks)
+		}
+		slices.SortFunc(artist.albums, func(a, b browseAlbum) int {
+			if (a.name == unknownAlbum) != (b.name == unknownAlbum) {
+				return byName(unknownAlbum)(a.name, b.name)
+			}
+			if c := cmp.Compare(a.year, b.year); c != 0 {
+				return c
+			}
+			return byName(unknownAlbum)(a.name, b.name)
+		})
+		artists = append(artists, artist)
+	}
+	slices.SortFunc(artists, func(a, b browseArtist) int { return byName(unknownArtist)(a.name, b.name) })
+	return artists
+}
+
+// openBrowse switches to the Artists view.
+func (m Model) openBrowse() (tea.Model, tea.Cmd) {
+	m.currentView = ViewBrowse
+	return m.refreshBrowse(), nil
+}
+
+// refreshBrowse groups the library again if it changed since it was last
+// grouped, keeping the selected artist and album where they still exist.
+func (m Model) refreshBrowse() Model {
+	if m.browseGen == m.libraryGen && m.browse != nil {
+		return m
+	}
+	var artist, album string
+	if a, ok := m.browseSelectedArtist(); ok {
+		artist = a.name
+		if al, ok := m.browseSelectedAlbum(); ok {
+			album = al.name
+		}
+	}
+
+	m.browse = groupByArtist(m.libraryFiles)
+	m.browseGen = m.libraryGen
+
+	i := slices.IndexFunc(m.browse, func(a browseArtist) bool { return a.name == artist })
+	if i < 0 { // The artist is gone
+		m.browseLevel = browseArtists
+		m.browseCursor[browseArtists] = max(min(m.browseCursor[browseArtists], len(m.browse)-1), 0)
+		return m
+	}
+	m.browseCursor[browseArtists] = i
+	i = slices.IndexFunc(m.browse[i].albums, func(al browseAlbum) bool { return al.name == album })
+	if i < 0 { // The album is gone
+		m.browseLevel = min(m.browseLevel, browseAlbums)
+		m.browseCursor[browseAlbums] = 0
+		return m
+	}
+	m.browseCursor[browseAlbums] = i
+	m.browseCursor[browseTracks] = max(min(m.browseCursor[browseTracks], m.browseCount(browseTracks)-1), 0)
+	return m
+}
+
+// browseSelectedArtist returns the artist under the cursor of the artist
+// list.
+func (m Model) browseSelectedArtist() (browseArtist, bool) {
+	if i := m.browseCursor[browseArtists]; i < len(m.browse) {
+		return m.browse[i], true
+	}
+	return browseArtist{}, false
+}
+
+// browseSelectedAlbum returns the album under the cursor of the album list.
+func (m Model) browseSelectedAlbum() (browseAlbum, bool) {
+	artist, ok := m.browseSelectedArtist()
+	if i := m.browseCursor[browseAlbums]; ok && i < len(artist.albums) {
+		return artist.albums[i], true
+	}
+	return browseAlbum{}, false
+}
+
+// browseCount returns the number of entries at a level.
+func (m Model) browseCount(level int) int {
+	switch level {
+	case browseArtists:
+		return len(m.browse)
+	case browseAlbums:
+		artist, _ := m.browseSelectedArtist()
+		return len(artist.albums)
+	default:
+		album, _ := m.browseSelectedAlbum()
+		return len(album.tracks)
+	}
+}
+
+// browseSelection returns the tracks of the entry under the cursor, the
+// index of the selected track among them, and the entry's name.
+func (m Model) browseSelection() (tracks []MusicFile, start int, name string) {
+	switch m.browseLevel {
+	case browseArtists:
+		artist, _ := m.browseSelectedArtist()
+		return artist.tracks(), 0, artist.name
+	case browseAlbums:
+		album, _ := m.browseSelectedAlbum()
+		return album.tracks, 0, album.name
+	default:
+		album, _ := m.browseSelectedAlbum()
+		start = m.browseCursor[browseTracks]
+		if start >= len(album.tracks) {
+			return nil, 0, ""
+		}
+		return album.tracks, start, album.name
+	}
+}
+
+// playTracks plays the track at start and queues the ones after it,
+// replacing the current queue.
+func (m Model) playTracks(tracks []MusicFile, start int, name string) (tea.Model, tea.Cmd) {
+	if start >= len(tracks) {
+		return m, nil
+	}
+	if err := m.player.PlayTrack(tracks[start]); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	m.player.ClearQueue()
+	for _, t := range tracks[start+1:] {
+		m.player.Enqueue(t)
+	}
+	return m, func() tea.Msg { return statusMsg(Tf("Now playing: %s", name)) }
+}
+
+// handleBrowseKeys handles keys in the Artists view.
+func (m Model) handleBrowseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m = m.refreshBrowse()
+	if cursor, ok := m.navigateList(msg.String(), m.browseCursor[m.browseLevel], m.browseCount(m.browseLevel)); ok {
+		m.browseCursor[m.browseLevel] = cursor
+		return m, nil
+	}
+	if m.browseCount(m.browseLevel) == 0 {
+		if msg.String() == "backspace" && m.browseLevel > browseArtists {
+			m.browseLevel--
+		}
+		return m, nil
+	}
+
+	tracks, start, name := m.browseSelection()
+	switch msg.String() {
+	case "enter": // Open, or play the track and the rest of its album
+		if m.browseLevel < browseTracks {
+			m.browseLevel++
+			m.browseCursor[m.browseLevel] = 0
+			return m, nil
+		}
+		return m.playTracks(tracks, start, tracks[start].DisplayName())
+	case "p": // Play the selected artist, album or track
+		if m.browseLevel == browseTracks {
+			return m.playTracks(tracks, start, tracks[start].DisplayName())
+		}
+		return m.playTracks(tracks, 0, name)
+	case "a": // Queue the selected artist, album or track
+		if m.browseLevel == browseTracks {
+			m.player.Enqueue(tracks[start])
+			return m, func() tea.Msg { return statusMsg(Tf("Queued: %s", tracks[start].DisplayName())) }
+		}
+		for _, t := range tracks {
+			m.player.Enqueue(t)
+		}
+		return m, func() tea.Msg { return statusMsg(Tf("Queued %d songs", len(tracks))) }
+	case "m": // Context menu
+		return m.openContextMenu()
+	case "i": // Track details
+		if m.browseLevel == browseTracks {
+			return m, m.showTrackInfo(tracks[start])
+		}
+	case "backspace":
+		if m.browseLevel > browseArtists {
+			m.browseLevel--
+		}
+	}
+	return m, nil
+}
+
+// renderBrowseView renders the level being browsed.
+func (m Model) renderBrowseView() string {
+	var b strings.Builder
+
+	crumbs := []string{T("Artists")}
+	if artist, ok := m.browseSelectedArtist(); ok && m.browseLevel > browseArtists {
+		crumbs = append(crumbs, artist.name)
+		if album, ok := m.browseSelectedAlbum(); ok && m.browseLevel > browseAlbums {
+			crumbs = append(crumbs, album.name)
+		}
+	}
+	b.WriteString(headerStyle.Render(" 📚 "+truncate(strings.Join(crumbs, " › "), 60)+" ") + "\n\n")
+
+	total := m.browseCount(m.browseLevel)
+	if total == 0 {
+		b.WriteString(mutedStyle.Render(T("Nothing here") + "\n"))
+		return b.String()
+	}
+
+	width := m.width - 4
+	if m.isWide() {
+		width = m.leftPaneWidth() - 6
+	}
+
+	artist, _ := m.browseSelectedArtist()
+	album, _ := m.browseSelectedAlbum()
+	cursor := m.browseCursor[m.browseLevel]
+	b.WriteString(m.browseScroll.render(total, 1, m.maxVisible(), func(i int) string {
+		var label, detail string
+		switch m.browseLevel {
+		case browseArtists:
+			a := m.browse[i]
+			label = "› " + a.name
+			detail = Tf("%d albums · %d songs", len(a.albums), a.songs)
+		case browseAlbums:
+			al := artist.albums[i]
+			label = "● " + al.name
+			var length time.Duration
+			for _, t := range al.tracks {
+				length += t.Duration
+			}
+			details := []string{Tf("%d songs", len(al.tracks))}
+			if al.year != "" {
+				details = append([]string{al.year}, details...)
+			}
+			if length > 0 {
+				details = append(details, FormatDuration(length))
+			}
+			detail = strings.Join(details, " · ")
+		default:
+			t := album.tracks[i]
+			title := t.Tags.Title
+			if title == "" {
+				title = t.Name
+			}
+			label = "♪ " + title
+			if n := t.Tags.TrackNumber(); n > 0 {
+				label = fmt.Sprintf("♪ %2d. %s", n, title)
+			}
+			if t.Duration > 0 {
+				detail = FormatDuration(t.Duration)
+			}
+		}
+
+		label = truncate(label, max(width-24, 10))
+		suffix := ""
+		if detail != "" {
+			suffix = " " + mutedStyle.Render(detail)
+		}
+		if i == cursor {
+			return selectedStyle.Render("> "+label) + suffix
+		}
+		return normalStyle.Render("  "+label) + suffix
+	}))
+
+	return b.String()
+}
@@ -0,0 +1,539 @@
+// Package main provides Chromecast output for Personal Musician.
+// Cast devices (Chromecast, Google/Nest speakers) are discovered with mDNS.
+// Casting launches the Default Media Receiver on the device over the Cast
+// v2 protocol, serves the track being cast from a small local HTTP server
+// and relays load, play, pause and seek requests to the device.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/mdns"
+)
+
+// Cast protocol constants.
+const (
+	castService        = "_googlecast._tcp"
+	castDefaultApp     = "CC1AD845" // Default Media Receiver
+	castSender         = "sender-0"
+	castReceiver       = "receiver-0"
+	castNSConnection   = "urn:x-cast:com.google.cast.tp.connection"
+	castNSHeartbeat    = "urn:x-cast:com.google.cast.tp.heartbeat"
+	castNSReceiver     = "urn:x-cast:com.google.cast.receiver"
+	castNSMedia        = "urn:x-cast:com.google.cast.media"
+	castDialTimeout    = 5 * time.Second
+	castLaunchTimeout  = 20 * time.Second
+	castPingInterval   = 5 * time.Second
+	castMaxMessageSize = 64 << 10
+)
+
+// CastDevice is a cast device found on the local network.
+type CastDevice struct {
+	Name  string // Friendly name, e.g. "Living Room speaker"
+	Model string // Model name, e.g. "Google Nest Mini"
+	Addr  string // host:port of the Cast service
+}
+
+// DiscoverCastDevices looks for cast devices on the local network for the
+// given time and returns them sorted by name.
+func DiscoverCastDevices(timeout time.Duration) ([]CastDevice, error) {
+	entries := make(chan *mdns.ServiceEntry, 16)
+	params := mdns.DefaultParams(castService)
+	params.Entries = entries
+	params.Timeout = timeout
+	params.DisableIPv6 = true
+
+	var queryErr error
+	go func() {
+		queryErr = mdns.Query(params)
+		close(entries)
+	}()
+
+	seen := make(map[string]bool)
+	var devices []CastDevice
+	for entry := range entries {
+		if entry.AddrV4 == nil {
+			continue
+		}
+		addr := net.JoinHostPort(entry.AddrV4.String(), strconv.Itoa(entry.Port))
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		device := CastDevice{Name: entry.Host, Addr: addr}
+		for _, field := range entry.InfoFields {
+			if v, ok := strings.CutPrefix(field, "fn="); ok {
+				device.Name = v
+			} else if v, ok := strings.CutPrefix(field, "md="); ok {
+				device.Model = v
+			}
+		}
+		devices = append(devices, device)
+	}
+	if queryErr != nil && len(devices) == 0 {
+		return nil, fmt.Errorf("failed to search for cast devices: %w", queryErr)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return strings.ToLower(devices[i].Name) < strings.ToLower(devices[j].Name)
+	})
+	return devices, nil
+}
+
+// castMessage is a Cast v2 CastMessage with a JSON payload.
+type castMessage struct {
+	Source      string
+	Destination string
+	Namespace   string
+	Payload     string
+}
+
+// encode serializes the message as protobuf: protocol_version (1),
+// source_id (2), destination_id (3), namespace (4), payload_type (5) and
+// payload_utf8 (6).
+func (msg castMessage) encode() []byte {
+	var b []byte
+	b = append(b, 0x08, 0x00) // CASTV2_1_0
+	b = appendProtoString(b, 2, msg.Source)
+	b = appendProtoString(b, 3, msg.Destination)
+	b = appendProtoString(b, 4, msg.Namespace)
+	b = append(b, 0x28, 0x00) // STRING payload
+	b = appendProtoString(b, 6, msg.Payload)
+	return b
+}
+
+// appendProtoString appends a length-delimited protobuf field.
+func appendProtoString(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|2))
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// decodeCastMessage parses a protobuf CastMessage, keeping the fields the
+// player uses.
+func decodeCastMessage(b []byte) (castMessage, error) {
+	var msg castMessage
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return msg, errors.New("malformed cast message")
+		}
+		b = b[n:]
+		field, wireType := key>>3, key&7
+
+		switch wireType {
+		case 0: // Varint
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return msg, errors.New("malformed cast message")
+			}
+			b = b[n:]
+		case 2: // Length-delimited
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return msg, errors.New("malformed cast message")
+			}
+			value := string(b[n : n+int(length)])
+			b = b[n+int(length):]
+			switch field {
+			case 2:
+				msg.Source = value
+			case 3:
+				msg.Destination = value
+			case 4:
+				msg.Namespace = value
+			case 6:
+				msg.Payload = value
+			}
+		default:
+			return msg, fmt.Errorf("unsupported wire type %d in cast message", wireType)
+		}
+	}
+	return msg, nil
+}
+
+// castRequest is a message waiting to be sent to the device.
+type castRequest struct {
+	namespace string
+	payload   map[string]any
+}
+
+// CastSession is a connection to a cast device running the media receiver.
+type CastSession struct {
+	Device CastDevice
+
+	conn    *tls.Conn
+	writeMu sync.Mutex // Serializes writes to conn
+	files   *castFileServer
+	queue   chan castRequest // Requests for the writer goroutine
+	done    chan struct{}
+	once    sync.Once
+
+	mu             sync.Mutex
+	requestID      int
+	transportID    string // Receiver app connection, set once launched
+	mediaSessionID int    // Media being played, set by MEDIA_STATUS
+	err            error  // Why the session ended
+}
+
+// StartCast connects to a device and launches the media receiver on it.
+func StartCast(ctx context.Context, device CastDevice) (*CastSession, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: castDialTimeout},
+		// Cast devices present self-signed certificates
+		Config: &tls.Config{InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", device.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", device.Name, err)
+	}
+
+	// Serve files on the interface the device reached us through
+	local := conn.LocalAddr().(*net.TCPAddr).IP
+	files, err := startCastFileServer(local)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s := &CastSession{
+		Device: device,
+		conn:   conn.(*tls.Conn),
+		files:  files,
+		queue:  make(chan castRequest, 16),
+		done:   make(chan struct{}),
+	}
+	launched := make(chan struct{})
+	go s.readLoop(launched)
+
+	s.write(castReceiver, castNSConnection, map[string]any{"type": "CONNECT"})
+	s.write(castReceiver, castNSReceiver, map[string]any{"type": "LAUNCH", "appId": castDefaultApp, "requestId": s.nextRequestID()})
+
+	select {
+	case <-launched:
+	case <-s.done:
+		return nil, s.Err()
+	case <-ctx.Done():
+		s.Close()
+		return nil, ctx.Err()
+	case <-time.After(castLaunchTimeout):
+		s.Close()
+		return nil, fmt.Errorf("%s did not start the media receiver", device.Name)
+	}
+	go s.writeLoop()
+	slog.Info("casting", "device", device.Name, "addr", device.Addr)
+	return s, nil
+}
+
+// nextRequestID returns a new request ID.
+func (s *CastSession) nextRequestID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requestID++
+	return s.requestID
+}
+
+// write sends a message to the device.
+func (s *CastSession) write(destination, namespace string, payload map[string]any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode cast message: %w", err)
+	}
+	msg := castMessage{Source: castSender, Destination: destination, Namespace: namespace, Payload: string(data)}.encode()
+
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(msg)))
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(castDialTimeout))
+	if _, err := s.conn.Write(append(frame, msg...)); err != nil {
+		s.fail(fmt.Errorf("lost connection to %s: %w", s.Device.Name, err))
+		return err
+	}
+	return nil
+}
+
+// writeLoop sends queued requests to the receiver app and keeps the
+// connection alive.
+func (s *CastSession) writeLoop() {
+	ping := time.NewTicker(castPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case req := <-s.queue:
+			s.mu.Lock()
+			destination := s.transportID
+			if req.namespace == castNSMedia && req.payload["type"] != "LOAD" {
+				req.payload["mediaSessionId"] = s.mediaSessionID
+			}
+			s.mu.Unlock()
+			req.payload["requestId"] = s.nextRequestID()
+			s.write(destination, req.namespace, req.payload)
+		case <-ping.C:
+			s.write(castReceiver, castNSHeartbeat, map[string]any{"type": "PING"})
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// readLoop handles messages from the device until the connection closes.
+// launched is closed once the media receiver is running.
+func (s *CastSession) readLoop(launched chan struct{}) {
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(s.conn, header[:]); err != nil {
+			s.fail(fmt.Errorf("lost connection to %s: %w", s.Device.Name, err))
+			return
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > castMaxMessageSize {
+			s.fail(fmt.Errorf("oversized message from %s", s.Device.Name))
+			return
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(s.conn, buf); err != nil {
+			s.fail(fmt.Errorf("lost connection to %s: %w", s.Device.Name, err))
+			return
+		}
+		msg, err := decodeCastMessage(buf)
+		if err != nil {
+			slog.Debug("cast message ignored", "err", err)
+			continue
+		}
+		s.handle(msg, launched)
+	}
+}
+
+// castPayload holds the fields of device messages the player reads.
+type castPayload struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	Status struct {
+		Applications []struct {
+			AppID       string `json:"appId"`
+			TransportID string `json:"transportId"`
+		} `json:"applications"`
+	} `json:"status"`
+}
+
+// handle processes one message from the device.
+func (s *CastSession) handle(msg castMessage, launched chan struct{}) {
+	var payload castPayload
+	if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+		return
+	}
+
+	switch payload.Type {
+	case "PING":
+		s.write(msg.Source, castNSHeartbeat, map[string]any{"type": "PONG"})
+
+	case "RECEIVER_STATUS":
+		for _, app := range payload.Status.Applications {
+			if app.AppID != castDefaultApp {
+				continue
+			}
+			s.mu.Lock()
+			first := s.transportID == ""
+			s.transportID = app.TransportID
+			s.mu.Unlock()
+			if first {
+				s.write(app.TransportID, castNSConnection, map[string]any{"type": "CONNECT"})
+				close(launched)
+			}
+		}
+
+	case "MEDIA_STATUS":
+		var media struct {
+			Status []struct {
+				MediaSessionID int `json:"mediaSessionId"`
+			} `json:"status"`
+		}
+		if json.Unmarshal([]byte(msg.Payload), &media) == nil && len(media.Status) > 0 {
+			s.mu.Lock()
+			s.mediaSessionID = media.Status[0].MediaSessionID
+			s.mu.Unlock()
+		}
+
+	case "LAUNCH_ERROR", "LOAD_FAILED", "INVALID_REQUEST":
+		slog.Error("cast error", "device", s.Device.Name, "type", payload.Type, "reason", payload.Reason)
+		if payload.Type == "LAUNCH_ERROR" {
+			s.fail(fmt.Errorf("%s could not start the media receiver: %s", s.Device.Name, payload.Reason))
+		}
+
+	case "CLOSE":
+		s.mu.Lock()
+		closed := msg.Source == s.transportID
+		s.mu.Unlock()
+		if closed {
+			s.fail(fmt.Errorf("%s stopped casting", s.Device.Name))
+		}
+	}
+}
+
+// enqueue queues a request for the receiver app. Requests are dropped if
+// the device stops responding.
+func (s *CastSession) enqueue(namespace string, payload map[string]any) {
+	select {
+	case s.queue <- castRequest{namespace: namespace, payload: payload}:
+	case <-s.done:
+	default:
+		slog.Warn("cast request dropped", "type", payload["type"])
+	}
+}
+
+// Load starts playing a file on the device at the given position.
+func (s *CastSession) Load(path, title string, position time.Duration, autoplay bool) {
+	s.enqueue(castNSMedia, map[string]any{
+		"type": "LOAD",
+		"media": map[string]any{
+			"contentId":   s.files.serve(path),
+			"contentType": audioContentType(path),
+			"streamType":  "BUFFERED",
+			"metadata":    map[string]any{"metadataType": 3, "title": title},
+		},
+		"autoplay":    autoplay,
+		"currentTime": position.Seconds(),
+	})
+}
+
+// Play resumes playback on the device.
+func (s *CastSession) Play() {
+	s.enqueue(castNSMedia, map[string]any{"type": "PLAY"})
+}
+
+// Pause pauses playback on the device.
+func (s *CastSession) Pause() {
+	s.enqueue(castNSMedia, map[string]any{"type": "PAUSE"})
+}
+
+// Seek moves playback on the device to the given position.
+func (s *CastSession) Seek(position time.Duration) {
+	s.enqueue(castNSMedia, map[string]any{"type": "SEEK", "currentTime": position.Seconds()})
+}
+
+// Stop stops playback on the device.
+func (s *CastSession) Stop() {
+	s.enqueue(castNSMedia, map[string]any{"type": "STOP"})
+}
+
+// Name returns the name of the device.
+func (s *CastSession) Name() string {
+	return s.Device.Name
+}
+
+// Err returns why the session ended, or nil while it is running.
+func (s *CastSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// fail ends the session with an error.
+func (s *CastSession) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.shutdown()
+}
+
+// Close stops the receiver app and disconnects from the device.
+func (s *CastSession) Close() {
+	s.mu.Lock()
+	transportID := s.transportID
+	s.mu.Unlock()
+	if transportID != "" && s.Err() == nil {
+		s.write(castReceiver, castNSReceiver, map[string]any{"type": "STOP", "requestId": s.nextRequestID()})
+	}
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = errors.New("session closed")
+	}
+	s.mu.Unlock()
+	s.shutdown()
+}
+
+// shutdown closes the connection and the file server once.
+func (s *CastSession) shutdown() {
+	s.once.Do(func() {
+		close(s.done)
+		s.conn.Close()
+		s.files.Close()
+	})
+}
+
+// castFileServer serves the files being cast to the device. Each file gets
+// an unguessable URL, so nothing else in the library is exposed.
+type castFileServer struct {
+	server *http.Server
+	base   string // URL prefix, e.g. "http://192.168.1.10:41234"
+
+	mu    sync.Mutex
+	files map[string]string // Token to path
+}
+
+// startCastFileServer starts serving on a free port of the given address.
+func startCastFileServer(ip net.IP) (*castFileServer, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start cast file server: %w", err)
+	}
+	fs := &castFileServer{
+		base:  "http://" + listener.Addr().String(),
+		files: make(map[string]string),
+	}
+	fs.server = &http.Server{Handler: fs, ReadHeaderTimeout: 10 * time.Second}
+	go fs.server.Serve(listener)
+	return fs, nil
+}
+
+// serve registers a file and returns its URL.
+func (fs *castFileServer) serve(path string) string {
+	var b [16]byte
+	rand.Read(b[:])
+	token := hex.EncodeToString(b[:])
+
+	fs.mu.Lock()
+	fs.files[token] = path
+	fs.mu.Unlock()
+	return fs.base + "/cast/" + token + strings.ToLower(filepath.Ext(path))
+}
+
+// ServeHTTP serves a registered file, with range requests for seeking.
+func (fs *castFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, "/cast/")
+	token := strings.TrimSuffix(name, filepath.Ext(name))
+	fs.mu.Lock()
+	path, found := fs.files[token]
+	fs.mu.Unlock()
+	if !ok || !found {
+		http.NotFound(w, r)
+		return
+	}
+	// Chromecasts fetch media with CORS
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	http.ServeFile(w, r, path)
+}
+
+// Close stops the file server.
+func (fs *castFileServer) Close() error {
+	return fs.server.Close()
+}
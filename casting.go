@@ -0,0 +1,206 @@
+// Package main provides the casting controls of the Personal Musician TUI.
+// Chromecasts and DLNA renderers are found together and offered in one menu.
+// While casting, the local player keeps running muted and acts as the clock:
+// after every update its track, pause state and position are compared with
+// what was last sent to the cast device, and changes are relayed to it. All
+// the usual keys therefore control the device.
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// castDiscoveryTime is how long to listen for cast devices.
+const castDiscoveryTime = 3 * time.Second
+
+// castSeekThreshold is the position jump that is relayed as a seek.
+const castSeekThreshold = 3 * time.Second
+
+// castTarget is a remote output the player can mirror, such as a
+// Chromecast or a DLNA renderer. Requests are sent in the background.
+type castTarget interface {
+	Name() string
+	Load(path, title string, position time.Duration, autoplay bool)
+	Play()
+	Pause()
+	Seek(position time.Duration)
+	Stop()
+	Err() error // Why the session ended, or nil while it is running
+	Close()
+}
+
+// castDevicesMsg is sent when the search for cast devices finishes.
+type castDevicesMsg struct {
+	devices   []CastDevice
+	renderers []DLNARenderer
+	err       error
+}
+
+// castStartedMsg is sent when a cast session has started or failed to.
+type castStartedMsg struct {
+	session castTarget
+	err     error
+}
+
+// castMirror is the playback state last sent to the cast device.
+type castMirror struct {
+	session  castTarget
+	file     string        // Track loaded on the device
+	paused   bool          // Whether the device was paused
+	position time.Duration // Local position at the last sync
+	synced   time.Time     // Time of the last sync
+	wasMuted bool          // Local mute state before casting started
+}
+
+// discoverCastDevices searches the local network for cast devices.
+func (m Model) discoverCastDevices() (tea.Model, tea.Cmd) {
+	return m, tea.Batch(
+		func() tea.Msg { return statusMsg(T("Searching for cast devices...")) },
+		func() tea.Msg {
+			var msg castDevicesMsg
+			var castErr, dlnaErr error
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				msg.devices, castErr = DiscoverCastDevices(castDiscoveryTime)
+			}()
+			go func() {
+				defer wg.Done()
+				msg.renderers, dlnaErr = DiscoverDLNARenderers(castDiscoveryTime)
+			}()
+			wg.Wait()
+
+			// Either kind of device is enough
+			if castErr != nil && dlnaErr != nil {
+				msg.err = castErr
+			}
+			return msg
+		},
+	)
+}
+
+// showCastDevices opens a menu of the devices found.
+func (m Model) showCastDevices(msg castDevicesMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	if len(msg.devices) == 0 && len(msg.renderers) == 0 {
+		return m, func() tea.Msg { return statusMsg(T("No cast devices found")) }
+	}
+
+	menu := &ContextMenu{Title: T("Cast to")}
+	for _, d := range msg.devices {
+		device := d
+		label := device.Name
+		if device.Model != "" {
+			label += " (" + device.Model + ")"
+		}
+		menu.Items = append(menu.Items, menuItem{label, func(m Model) (tea.Model, tea.Cmd) {
+			return m, m.startCast(device.Name, func(ctx context.Context) (castTarget, error) {
+				return StartCast(ctx, device)
+			})
+		}})
+	}
+	for _, r := range msg.renderers {
+		renderer := r
+		label := renderer.Name + " (DLNA)"
+		menu.Items = append(menu.Items, menuItem{label, func(m Model) (tea.Model, tea.Cmd) {
+			return m, m.startCast(renderer.Name, func(ctx context.Context) (castTarget, error) {
+				return StartDLNA(ctx, renderer)
+			})
+		}})
+	}
+	m.contextMenu = menu
+	return m, nil
+}
+
+// startCast connects to a device in the background.
+func (m Model) startCast(name string, connect func(ctx context.Context) (castTarget, error)) tea.Cmd {
+	ctx := m.ctx
+	return tea.Batch(
+		func() tea.Msg { return statusMsg(Tf("Connecting to %s...", name)) },
+		func() tea.Msg {
+			session, err := connect(ctx)
+			return castStartedMsg{session: session, err: err}
+		},
+	)
+}
+
+// castStarted switches output to a new cast session. The next sync loads
+// the playing track on the device.
+func (m Model) castStarted(msg castStartedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	if m.cast != nil {
+		m.cast.session.Close()
+	}
+
+	state := m.player.GetState()
+	m.cast = &castMirror{session: msg.session, wasMuted: state.Muted}
+	m.player.SetMuted(true)
+	return m, func() tea.Msg { return statusMsg(Tf("Casting to %s", msg.session.Name())) }
+}
+
+// stopCast ends casting and restores local output.
+func (m Model) stopCast() Model {
+	if m.cast == nil {
+		return m
+	}
+	m.cast.session.Close()
+	m.player.SetMuted(m.cast.wasMuted)
+	m.cast = nil
+	return m
+}
+
+// syncCast relays changes of the local player to the cast device.
+func (m Model) syncCast() (Model, tea.Cmd) {
+	if m.cast == nil {
+		return m, nil
+	}
+	c := m.cast
+	if err := c.session.Err(); err != nil {
+		m = m.stopCast()
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+
+	state := m.player.GetState()
+	now := time.Now()
+	switch {
+	case !state.IsPlaying:
+		if c.file != "" {
+			c.session.Stop()
+			c.file = ""
+		}
+
+	case state.CurrentFile != c.file:
+		c.session.Load(state.CurrentFile, musicFileFromPath(state.CurrentFile).Name, state.Position, !state.IsPaused)
+		c.file, c.paused = state.CurrentFile, state.IsPaused
+
+	default:
+		if state.IsPaused != c.paused {
+			if state.IsPaused {
+				c.session.Pause()
+			} else {
+				c.session.Play()
+			}
+			c.paused = state.IsPaused
+		}
+
+		// A position that moved further than the elapsed time is a seek
+		expected := c.position
+		if !state.IsPaused {
+			expected += now.Sub(c.synced)
+		}
+		if drift := state.Position - expected; drift > castSeekThreshold || drift < -castSeekThreshold {
+			c.session.Seek(state.Position)
+		}
+	}
+	c.position, c.synced = state.Position, now
+	return m, nil
+}
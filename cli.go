@@ -0,0 +1,195 @@
+// Package main provides command-line flag parsing for Personal Musician.
+// Flags override the config file so the player can be pointed at another
+// library or adapted to a limited terminal without editing any settings.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// version is the release version, set at build time with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// command is a subcommand that runs instead of the TUI.
+type command struct {
+	name  string
+	usage string // Arguments, for the usage text
+	help  string // One-line description
+	run   func(args []string) error
+
+	// Shell completion of the arguments: a list for "__complete", or ""
+	complete string
+	options  []string // Flags the subcommand accepts
+	hidden   bool     // Left out of the usage text and completions
+}
+
+// commands are the available subcommands. Subcommands that need this list
+// themselves register in an init function.
+var commands = []command{
+	{name: "play", usage: "<file|playlist>", help: "play a file, M3U file, directory or saved playlist without the TUI", run: runPlay, complete: "targets"},
+	{name: "download", usage: "<url|query>", help: "download a YouTube URL or the top result for a query into the library", run: runDownload},
+	{name: "search", usage: "<query> [--json]", help: "print YouTube results for a query, as JSON with --json", run: runSearch, options: []string{"json"}},
+	{name: "setup", usage: "[--standalone]", help: "install yt-dlp and ffmpeg with the package manager or as standalone builds", run: runSetup, options: []string{"standalone"}},
+	{name: "ctl", usage: "play-pause|next|prev|add <file|url>...|status [--json]", help: "control the running player", run: runCtl, complete: "ctl", options: []string{"json"}},
+	{name: "share", usage: "[--addr addr] [--password password]", help: "serve the library on the local network for phones, with a QR code", run: runShare, options: []string{"addr", "password"}},
+	{name: "history", usage: "[--limit n] [--json]", help: "print the most recently played tracks, as JSON with --json", run: runHistory, options: []string{"limit", "json"}},
+	{name: "tag", usage: "[--write] [--all] [--identify] [--min-score n] [file|dir...]", help: "look up tags on MusicBrainz for the library or the given files, by name or by sound with --identify, and write them with --write", run: runTag, options: []string{"write", "all", "identify", "min-score"}},
+	{name: "export", usage: "[--format json|csv] [--output file]", help: "write the library with tags, lengths, play counts and source URLs as JSON or CSV", run: runExport, options: []string{"format", "output"}},
+	{name: "backup", usage: "[--remote remote]", help: "mirror the music and library data to an rclone remote", run: runBackup, options: []string{"remote"}},
+	{name: "doctor", help: "check yt-dlp, ffmpeg, audio output, network and music directory", run: runDoctor},
+}
+
+// findCommand returns the subcommand with the given name.
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// Flags holds the parsed command-line flags.
+type Flags struct {
+	MusicDir    string   // Library directory, replacing the configured ones
+	ConfigPath  string   // Config file to read instead of the default one
+	Theme       string   // Color theme to start with
+	NoMouse     bool     // Disable mouse support
+	NoAltScreen bool     // Draw inline instead of on the alternate screen
+	Version     bool     // Print the version and exit
+	Debug       bool     // Write detailed records to the log file
+	Listen      string   // Address of the local HTTP server
+	DLNA        bool     // Share the library with DLNA devices
+	Sync        bool     // Let other instances join this one
+	Join        string   // Leader to follow
+	Args        []string // Arguments after the flags
+}
+
+// parseFlags parses the command-line arguments, excluding the program name.
+// Usage goes to out; flag.ErrHelp is returned when help was requested.
+func parseFlags(args []string, out io.Writer) (Flags, error) {
+	var f Flags
+	fs := newFlagSet(&f, out)
+	if err := fs.Parse(args); err != nil {
+		return Flags{}, err
+	}
+	f.Args = fs.Args()
+	if f.MusicDir != "" {
+		f.MusicDir = expandHome(f.MusicDir)
+	}
+	return f, nil
+}
+
+// newFlagSet defines the global flags, storing their values in f.
+func newFlagSet(f *Flags, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("personal-musician", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&f.MusicDir, "music-dir", "", "play and download music in `dir` instead of the configured directories")
+	fs.StringVar(&f.ConfigPath, "config", "", "read settings from `file` (default "+ConfigPath()+")")
+	fs.StringVar(&f.Theme, "theme", "", "start with the color theme `name`")
+	fs.BoolVar(&f.NoMouse, "no-mouse", false, "disable mouse support")
+	fs.BoolVar(&f.NoAltScreen, "no-altscreen", false, "draw in the normal terminal buffer instead of the alternate screen")
+	fs.BoolVar(&f.Version, "version", false, "print the version and exit")
+	fs.BoolVar(&f.Debug, "debug", false, "write detailed records to the log file")
+	fs.BoolVar(&f.Debug, "v", false, "shorthand for --debug")
+	fs.StringVar(&f.Listen, "listen", "", "serve the event stream on `addr`, e.g. 127.0.0.1:8765")
+	fs.BoolVar(&f.DLNA, "dlna", false, "share the library with DLNA devices on the local network")
+	fs.BoolVar(&f.Sync, "sync", false, "let other instances join this one to play in sync (needs --listen)")
+	fs.StringVar(&f.Join, "join", "", "follow the playback of the instance at `addr`, e.g. livingroom.local:8765")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: personal-musician [flags] [command | file|url...]\n\nCommands:\n")
+		for _, c := range commands {
+			if !c.hidden {
+				fmt.Fprintf(fs.Output(), "  %s\n    \t%s\n", strings.TrimSpace(c.name+" "+c.usage), c.help)
+			}
+		}
+		fmt.Fprintf(fs.Output(), "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// loadConfig reads the config file named by --config or PM_CONFIG, which
+// must exist, or the default one, which may be missing, and applies the
+// environment overrides.
+func (f Flags) loadConfig() (Config, error) {
+	path := f.ConfigPath
+	if path == "" {
+		path = os.Getenv("PM_CONFIG")
+	}
+
+	var cfg Config
+	var err error
+	if path == "" {
+		cfg, err = LoadConfig(ConfigPath())
+	} else if _, statErr := os.Stat(path); statErr != nil {
+		err = fmt.Errorf("failed to read config: %w", statErr)
+	} else {
+		cfg, err = LoadConfig(path)
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	return ApplyEnv(cfg)
+}
+
+// applyFlags lets the flags override the config file.
+func applyFlags(f Flags) {
+	if f.MusicDir != "" {
+		MusicDir = f.MusicDir
+		extraMusicDirs = nil
+	}
+	if f.Theme != "" {
+		config.UI.Theme = f.Theme
+	}
+	if f.Listen != "" {
+		config.Server.Listen = f.Listen
+	}
+	if f.DLNA {
+		config.Server.DLNA = true
+	}
+	if f.Sync {
+		config.Server.Sync = true
+	}
+	if f.Join != "" {
+		config.Sync.Join = f.Join
+	}
+}
+
+// parseInterspersed parses a subcommand's flags, which may appear before,
+// between or after its arguments, and returns the arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		consumed := len(args) - fs.NArg()
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(rest, fs.Args()...), nil // Everything after -- is an argument
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// versionString returns the version, falling back to the module version
+// recorded by go install.
+func versionString() string {
+	if version == "dev" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			return info.Main.Version
+		}
+	}
+	return version
+}
@@ -0,0 +1,317 @@
+// Package main provides shell completion for Personal Musician.
+// "personal-musician completion bash|zsh|fish" prints a completion script
+// for the flags and subcommands. Playlist names, library tracks and themes
+// are completed dynamically: the scripts call the hidden "__complete"
+// subcommand, which prints the current candidates one per line.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// completionShells are the shells a completion script can be generated for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// init registers the completion subcommands, which list the other commands.
+func init() {
+	commands = append(commands,
+		command{name: "completion", usage: "bash|zsh|fish", help: "print a shell completion script", run: runCompletion, complete: "shells"},
+		command{name: "__complete", run: runComplete, hidden: true},
+	)
+}
+
+// runCompletion prints the completion script for a shell.
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: personal-musician completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", args[0])
+	}
+	return nil
+}
+
+// runComplete prints the candidates of a dynamic completion list:
+// "targets" (saved playlists and library tracks), "themes", "shells" or "ctl".
+func runComplete(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: personal-musician __complete targets|themes|shells|ctl")
+	}
+
+	var candidates []string
+	switch args[0] {
+	case "targets":
+		names, _ := ListPlaylists()
+		candidates = append(candidates, names...)
+		for _, f := range IndexedMusicFiles() { // Completion must be quick; walking a big library isn't
+			candidates = append(candidates, f.Path)
+		}
+	case "themes":
+		LoadThemes(ThemesFile)
+		for _, t := range themes {
+			candidates = append(candidates, t.Name)
+		}
+	case "shells":
+		candidates = completionShells
+	case "ctl":
+		candidates = ctlCommands
+	default:
+		return fmt.Errorf("unknown completion list %q", args[0])
+	}
+	for _, c := range candidates {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+// completionFlag describes a global flag for the completion scripts.
+type completionFlag struct {
+	name  string
+	usage string
+	value string // Value completion: "dir", "file", "themes" or "" for none
+}
+
+// completionFlags returns the global flags.
+func completionFlags() []completionFlag {
+	var flags []completionFlag
+	newFlagSet(&Flags{}, io.Discard).VisitAll(func(f *flag.Flag) {
+		valueName, usage := flag.UnquoteUsage(f)
+		cf := completionFlag{name: f.Name, usage: usage}
+		switch valueName {
+		case "dir", "file":
+			cf.value = valueName
+		case "name":
+			cf.value = "themes"
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+// visibleCommands returns the subcommands offered for completion.
+func visibleCommands() []command {
+	var visible []command
+	for _, c := range commands {
+		if !c.hidden {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
+
+// flagArg returns the command-line form of a flag name.
+func flagArg(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// writeBashCompletion writes the bash completion script.
+func writeBashCompletion(w io.Writer) {
+	var names, valueFlags, allFlags []string
+	for _, c := range visibleCommands() {
+		names = append(names, c.name)
+	}
+	for _, f := range completionFlags() {
+		allFlags = append(allFlags, flagArg(f.name))
+		if f.value != "" {
+			valueFlags = append(valueFlags, flagArg(f.name))
+		}
+	}
+
+	fmt.Fprintf(w, `# bash completion for personal-musician
+# Load with: source <(personal-musician completion bash)
+_personal_musician() {
+    local cur prev cmd i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local IFS=$'\n'
+
+    case "$prev" in
+        --music-dir) compopt -o filenames; COMPREPLY=($(compgen -d -- "$cur")); return ;;
+        --config) compopt -o filenames; COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        --theme) COMPREPLY=($(compgen -W "$(personal-musician __complete themes 2>/dev/null)" -- "$cur")); return ;;
+    esac
+
+    # Find the subcommand, skipping flags and their values
+    cmd=""
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            %s) ((i++)) ;;
+            -*) ;;
+            *) cmd="${COMP_WORDS[i]}"; break ;;
+        esac
+    done
+
+    case "$cmd" in
+        "")
+            if [[ "$cur" == -* ]]; then
+                COMPREPLY=($(compgen -W "%s" -- "$cur"))
+            else
+                COMPREPLY=($(compgen -W "%s" -- "$cur"))
+            fi
+            ;;
+`, strings.Join(valueFlags, "|"), strings.Join(allFlags, "\n"), strings.Join(names, "\n"))
+
+	for _, c := range visibleCommands() {
+		var words []string
+		for _, o := range c.options {
+			words = append(words, flagArg(o))
+		}
+		switch {
+		case c.complete == "targets":
+			fmt.Fprintf(w, `        %s)
+            compopt -o filenames
+            COMPREPLY=($(compgen -W "$(personal-musician __complete targets 2>/dev/null)" -- "$cur") $(compgen -f -- "$cur"))
+            ;;
+`, c.name)
+		case c.complete != "":
+			fmt.Fprintf(w, `        %s) COMPREPLY=($(compgen -W "$(personal-musician __complete %s 2>/dev/null)" -- "$cur")) ;;
+`, c.name, c.complete)
+		case len(words) > 0:
+			fmt.Fprintf(w, `        %s) COMPREPLY=($(compgen -W "%s" -- "$cur")) ;;
+`, c.name, strings.Join(words, "\n"))
+		}
+	}
+
+	fmt.Fprint(w, `    esac
+}
+complete -F _personal_musician personal-musician
+`)
+}
+
+// writeZshCompletion writes the zsh completion script.
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprint(w, `#compdef personal-musician
+# Load with: source <(personal-musician completion zsh)
+
+_personal_musician() {
+  local -a commands targets
+  local state
+  commands=(
+`)
+	for _, c := range visibleCommands() {
+		fmt.Fprintf(w, "    %s\n", zshQuote(c.name+":"+c.help))
+	}
+	fmt.Fprint(w, `  )
+
+  _arguments -C \
+`)
+	for _, f := range completionFlags() {
+		spec := flagArg(f.name) + "[" + zshEscape(f.usage) + "]"
+		switch f.value {
+		case "dir":
+			spec += ":directory:_files -/"
+		case "file":
+			spec += ":file:_files"
+		case "themes":
+			spec += ":theme:->themes"
+		}
+		fmt.Fprintf(w, "    %s \\\n", zshQuote(spec))
+	}
+	fmt.Fprint(w, `    '1: :->command' \
+    '*:: :->args'
+
+  case $state in
+    themes)
+      compadd -- ${(f)"$(personal-musician __complete themes 2>/dev/null)"}
+      ;;
+    command)
+      _describe command commands
+      ;;
+    args)
+      case $words[1] in
+`)
+	for _, c := range visibleCommands() {
+		switch {
+		case c.complete == "targets":
+			fmt.Fprintf(w, `        %s)
+          targets=(${(f)"$(personal-musician __complete targets 2>/dev/null)"})
+          compadd -a targets
+          _files
+          ;;
+`, c.name)
+		case c.complete != "":
+			fmt.Fprintf(w, `        %s) compadd -- ${(f)"$(personal-musician __complete %s 2>/dev/null)"} ;;
+`, c.name, c.complete)
+		case len(c.options) > 0:
+			var specs []string
+			for _, o := range c.options {
+				specs = append(specs, zshQuote(flagArg(o)))
+			}
+			fmt.Fprintf(w, "        %s) _arguments %s '*: :' ;;\n", c.name, strings.Join(specs, " "))
+		}
+	}
+	fmt.Fprint(w, `      esac
+      ;;
+  esac
+}
+
+_personal_musician "$@"
+`)
+}
+
+// writeFishCompletion writes the fish completion script.
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprint(w, `# fish completion for personal-musician
+# Load with: personal-musician completion fish | source
+complete -c personal-musician -f
+`)
+	for _, c := range visibleCommands() {
+		fmt.Fprintf(w, "complete -c personal-musician -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.help))
+	}
+	for _, f := range completionFlags() {
+		opt := "-l " + f.name
+		if len(f.name) == 1 {
+			opt = "-o " + f.name
+		}
+		switch f.value {
+		case "dir":
+			opt += " -x -a '(__fish_complete_directories)'"
+		case "file":
+			opt += " -r -F"
+		case "themes":
+			opt += " -x -a '(personal-musician __complete themes 2>/dev/null)'"
+		}
+		fmt.Fprintf(w, "complete -c personal-musician -n __fish_use_subcommand %s -d %s\n", opt, fishQuote(f.usage))
+	}
+	for _, c := range visibleCommands() {
+		cond := fishQuote("__fish_seen_subcommand_from " + c.name)
+		switch {
+		case c.complete == "targets":
+			fmt.Fprintf(w, "complete -c personal-musician -n %s -F -a '(personal-musician __complete targets 2>/dev/null)'\n", cond)
+		case c.complete != "":
+			fmt.Fprintf(w, "complete -c personal-musician -n %s -a '(personal-musician __complete %s 2>/dev/null)'\n", cond, c.complete)
+		}
+		for _, o := range c.options {
+			fmt.Fprintf(w, "complete -c personal-musician -n %s -l %s\n", cond, o)
+		}
+	}
+}
+
+// zshQuote quotes s as a single-quoted zsh word.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the characters _arguments treats specially in descriptions.
+func zshEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+// fishQuote quotes s as a single-quoted fish word.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,453 @@
+// Package main provides the configuration file for Personal Musician.
+// Settings are read from $XDG_CONFIG_HOME/personal-musician/config.toml at
+// startup. Environment variables such as PM_LANG or PM_MUSIC_DIR take
+// precedence over the file, which in turn takes precedence over the built-in
+// defaults, so containers and CI jobs can run without a config file.
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configAppName is the directory name used under the config directory.
+const configAppName = "personal-musician"
+
+// Config holds the settings read from config.toml. Unset values keep the
+// defaults.
+type Config struct {
+	Library    LibraryConfig     `toml:"library"`
+	Playback   PlaybackConfig    `toml:"playback"`
+	UI         UIConfig          `toml:"ui"`
+	Keys       map[string]string `toml:"keys"` // Extra bindings: key = key it acts as
+	Download   DownloadConfig    `toml:"download"`
+	Providers  ProvidersConfig   `toml:"providers"`
+	Network    NetworkConfig     `toml:"network"`
+	Server     ServerConfig      `toml:"server"`
+	Jellyfin   JellyfinConfig    `toml:"jellyfin"`
+	Sync       SyncConfig        `toml:"sync"`
+	NowPlaying NowPlayingConfig  `toml:"now_playing"`
+	Backup     BackupConfig      `toml:"backup"`
+	Plugins    PluginsConfig     `toml:"plugins"`
+
+	SmartPlaylists []SmartPlaylist `toml:"smart_playlists"` // See smartplaylist.go
+}
+
+// LibraryConfig selects where music is kept.
+type LibraryConfig struct {
+	// MusicDirs are scanned for music; downloads go into the first one.
+	MusicDirs []string `toml:"music_dirs"`
+	// LongTrack is the length from which a file remembers its own position
+	// and played status, e.g. "20m"; "0" disables.
+	LongTrack string `toml:"long_track"`
+	// RecentDays is how far back the recently added view reaches; 0 uses
+	// 30 days.
+	RecentDays int `toml:"recent_days"`
+	// Watch refreshes the library when files change in the music
+	// directories; unset means on.
+	Watch *bool `toml:"watch"`
+}
+
+// PlaybackConfig controls how tracks are played.
+type PlaybackConfig struct {
+	// Crossfade overlaps the end of a track with the start of the next,
+	// e.g. "4s"; empty or "0" plays them back to back.
+	Crossfade string `toml:"crossfade"`
+	// Resume selects what happens to the track that was playing at the
+	// last quit: "ask" (the default), "play" or "never".
+	Resume string `toml:"resume"`
+	// TrimSilence skips silence at the start and end of tracks.
+	TrimSilence bool `toml:"trim_silence"`
+}
+
+// UIConfig holds the look and behavior of the interface.
+type UIConfig struct {
+	Theme        string `toml:"theme"`
+	Keymap       string `toml:"keymap"`        // "default" or "vim"
+	Language     string `toml:"language"`      // "en", "es" or "hi"
+	Refresh      string `toml:"refresh"`       // Duration, e.g. "500ms"
+	Screensaver  string `toml:"screensaver"`   // Duration, "0" disables
+	ASCII        *bool  `toml:"ascii"`         // Unset means detect
+	Accessible   *bool  `toml:"accessible"`    // Screen-reader friendly mode
+	BatterySaver *bool  `toml:"battery_saver"` // Slower refresh when idle
+	Thumbnails   *bool  `toml:"thumbnails"`    // Unset means detect
+	AlbumColors  *bool  `toml:"album_colors"`
+}
+
+// DownloadConfig controls how yt-dlp is run.
+type DownloadConfig struct {
+	Format  string `toml:"format"`  // Audio format, e.g. "mp3"
+	Quality string `toml:"quality"` // yt-dlp --audio-quality: 0 (best) to 10, or a bitrate like "192K"
+	YtDlp   string `toml:"yt_dlp"`  // Path to the yt-dlp executable
+}
+
+// ProvidersConfig holds credentials for online services.
+type ProvidersConfig struct {
+	// YouTubeAPIKey switches search to the YouTube Data API.
+	YouTubeAPIKey string `toml:"youtube_api_key"`
+	// AcoustIDKey lets tracks be identified by their sound.
+	AcoustIDKey string `toml:"acoustid_api_key"`
+	// Search lists the providers asked by every search, in order of
+	// preference, e.g. ["api", "page"]. Empty picks one automatically.
+	Search []string `toml:"search"`
+	// Invidious lists the Invidious instances searched by the "invidious"
+	// provider, tried in order until one answers.
+	Invidious []string `toml:"invidious"`
+	// Piped lists the Piped API instances searched by the "piped" provider,
+	// tried in order until one answers.
+	Piped []string `toml:"piped"`
+}
+
+// NetworkConfig controls how the player reaches online services.
+type NetworkConfig struct {
+	// Proxy is used for searches, thumbnails and yt-dlp, e.g.
+	// "http://proxy:8080" or "socks5://127.0.0.1:1080".
+	Proxy string `toml:"proxy"`
+}
+
+// ServerConfig controls the local HTTP server for external integrations.
+type ServerConfig struct {
+	// Listen is the address to serve on, e.g. "127.0.0.1:8765"; empty disables the server.
+	Listen string `toml:"listen"`
+
+	// DLNA shares the library with DLNA devices on the local network,
+	// announced as DLNAName (default "Personal Musician on <host>").
+	DLNA     bool   `toml:"dlna"`
+	DLNAName string `toml:"dlna_name"`
+
+	// Sync lets other instances join this one to play in sync.
+	Sync bool `toml:"sync"`
+}
+
+// JellyfinConfig selects a Jellyfin server to browse and play from.
+type JellyfinConfig struct {
+	URL      string `toml:"url"` // e.g. "https://jellyfin.example.com"; empty disables the client
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// SyncConfig makes this instance follow another one's playback.
+type SyncConfig struct {
+	Join string `toml:"join"` // Leader address, e.g. "livingroom.local:8765"; empty disables
+}
+
+// NowPlayingConfig writes the current track to a file for stream overlays
+// and status lines.
+type NowPlayingConfig struct {
+	File   string `toml:"file"`   // Path to write; ".json" writes JSON, anything else text. Empty disables
+	Format string `toml:"format"` // Text template, default "{artist} - {title}"
+	Art    bool   `toml:"art"`    // Also save the cover next to the file
+}
+
+// BackupConfig mirrors the library to an rclone remote.
+type BackupConfig struct {
+	Remote   string `toml:"remote"`   // rclone remote, e.g. "s3:my-bucket/music"; empty disables
+	Interval string `toml:"interval"` // Back up this often while the player runs, e.g. "24h"; empty backs up on demand only
+	Rclone   string `toml:"rclone"`   // Path to rclone; empty looks it up in PATH
+}
+
+// PluginsConfig selects where Lua plugins are loaded from.
+type PluginsConfig struct {
+	Dir string `toml:"dir"` // Directory of *.lua scripts; empty uses plugins/ next to config.toml
+}
+
+// envOverrides are the environment variables that override config settings.
+// Settings with their own variables (PM_LANG, PM_KEYMAP, PM_TICK, ...) are
+// read where they are detected.
+var envOverrides = []struct {
+	name  string
+	apply func(c *Config, value string)
+}{
+	{"PM_MUSIC_DIR", func(c *Config, v string) { c.Library.MusicDirs = filepath.SplitList(v) }},
+	{"PM_CROSSFADE", func(c *Config, v string) { c.Playback.Crossfade = v }},
+	{"PM_RESUME", func(c *Config, v string) { c.Playback.Resume = v }},
+	{"PM_TRIM_SILENCE", func(c *Config, v string) { c.Playback.TrimSilence, _ = strconv.ParseBool(v) }},
+	{"PM_THEME", func(c *Config, v string) { c.UI.Theme = v }},
+	{"PM_YTDLP_PATH", func(c *Config, v string) { c.Download.YtDlp = v }},
+	{"PM_DOWNLOAD_FORMAT", func(c *Config, v string) { c.Download.Format = v }},
+	{"PM_DOWNLOAD_QUALITY", func(c *Config, v string) { c.Download.Quality = v }},
+	{"PM_YOUTUBE_API_KEY", func(c *Config, v string) { c.Providers.YouTubeAPIKey = v }},
+	{"PM_ACOUSTID_API_KEY", func(c *Config, v string) { c.Providers.AcoustIDKey = v }},
+	{"PM_SEARCH_PROVIDERS", func(c *Config, v string) { c.Providers.Search = strings.Fields(strings.ReplaceAll(v, ",", " ")) }},
+	{"PM_INVIDIOUS", func(c *Config, v string) { c.Providers.Invidious = strings.Fields(strings.ReplaceAll(v, ",", " ")) }},
+	{"PM_PIPED", func(c *Config, v string) { c.Providers.Piped = strings.Fields(strings.ReplaceAll(v, ",", " ")) }},
+	{"PM_PROXY", func(c *Config, v string) { c.Network.Proxy = v }},
+	{"PM_LISTEN", func(c *Config, v string) { c.Server.Listen = v }},
+	{"PM_DLNA", func(c *Config, v string) { c.Server.DLNA, _ = strconv.ParseBool(v) }},
+	{"PM_SYNC", func(c *Config, v string) { c.Server.Sync, _ = strconv.ParseBool(v) }},
+	{"PM_JELLYFIN_URL", func(c *Config, v string) { c.Jellyfin.URL = v }},
+	{"PM_JELLYFIN_USER", func(c *Config, v string) { c.Jellyfin.Username = v }},
+	{"PM_JELLYFIN_PASSWORD", func(c *Config, v string) { c.Jellyfin.Password = v }},
+	{"PM_JOIN", func(c *Config, v string) { c.Sync.Join = v }},
+	{"PM_NOWPLAYING_FILE", func(c *Config, v string) { c.NowPlaying.File = v }},
+	{"PM_BACKUP_REMOTE", func(c *Config, v string) { c.Backup.Remote = v }},
+	{"PM_PLUGIN_DIR", func(c *Config, v string) { c.Plugins.Dir = v }},
+}
+
+// config is the configuration in effect, loaded once at startup.
+var config Config
+
+// downloadFormats are the audio formats the player can play.
+var downloadFormats = []string{"mp3"}
+
+// audioQuality matches the values yt-dlp accepts for --audio-quality.
+var audioQuality = regexp.MustCompile(`^(10|[0-9]|[0-9]+[kK])$`)
+
+// ConfigPath returns the default location of config.toml, honoring
+// XDG_CONFIG_HOME and falling back to ~/.config.
+func ConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".", "config.toml")
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, configAppName, "config.toml")
+}
+
+// LoadConfig reads and validates a config file. A missing file yields the
+// defaults. Errors name the file and the offending setting.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	meta, err := toml.DecodeFile(path, &cfg)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	// Misspelled settings would otherwise be ignored silently
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return Config{}, fmt.Errorf("%s: unknown setting(s): %s", path, strings.Join(keys, ", "))
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg.expandPaths(), nil
+}
+
+// ApplyEnv overrides settings of cfg with the PM_* environment variables
+// that are set. Errors name the offending variable.
+func ApplyEnv(cfg Config) (Config, error) {
+	for _, env := range envOverrides {
+		value, ok := os.LookupEnv(env.name)
+		if !ok || value == "" {
+			continue
+		}
+		env.apply(&cfg, value)
+		if err := cfg.validate(); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", env.name, err)
+		}
+	}
+	return cfg.expandPaths(), nil
+}
+
+// expandPaths expands a leading ~ in the configured paths.
+func (c Config) expandPaths() Config {
+	dirs := make([]string, len(c.Library.MusicDirs))
+	for i, dir := range c.Library.MusicDirs {
+		dirs[i] = expandHome(dir)
+	}
+	c.Library.MusicDirs = dirs
+	if c.Download.YtDlp != "" {
+		c.Download.YtDlp = expandHome(c.Download.YtDlp)
+	}
+	c.NowPlaying.File = expandHome(c.NowPlaying.File)
+	if c.Backup.Rclone != "" {
+		c.Backup.Rclone = expandHome(c.Backup.Rclone)
+	}
+	if c.Plugins.Dir != "" {
+		c.Plugins.Dir = expandHome(c.Plugins.Dir)
+	}
+	return c
+}
+
+// validate checks every set value and reports the first invalid one.
+func (c Config) validate() error {
+	for _, dir := range c.Library.MusicDirs {
+		if strings.TrimSpace(dir) == "" {
+			return fmt.Errorf("library.music_dirs: empty directory")
+		}
+	}
+
+	if l := c.Library.LongTrack; l != "" {
+		if d, err := time.ParseDuration(l); err != nil || d < 0 {
+			return fmt.Errorf("library.long_track: invalid value %q (want a duration such as \"20m\", or \"0\" to disable)", l)
+		}
+	}
+
+	if c.Library.RecentDays < 0 {
+		return fmt.Errorf("library.recent_days: invalid value %d (want a number of days)", c.Library.RecentDays)
+	}
+
+	if f := c.Playback.Crossfade; f != "" {
+		if d, err := time.ParseDuration(f); err != nil || d < 0 || d > maxCrossfade {
+			return fmt.Errorf("playback.crossfade: invalid value %q (want a duration of up to %s, such as \"4s\")", f, maxCrossfade)
+		}
+	}
+
+	if r := c.Playback.Resume; r != "" && !slices.Contains(resumeModes, r) {
+		return fmt.Errorf("playback.resume: invalid value %q (want %s)", r, strings.Join(resumeModes, ", "))
+	}
+
+	for i, sp := range c.SmartPlaylists {
+		if err := sp.validate(); err != nil {
+			return fmt.Errorf("smart_playlists[%d]: %w", i, err)
+		}
+	}
+
+	if _, err := ParseKeyPreset(c.UI.Keymap); err != nil {
+		return fmt.Errorf("ui.keymap: %w", err)
+	}
+	if c.UI.Language != "" {
+		if _, err := parseLocale(c.UI.Language); err != nil {
+			return fmt.Errorf("ui.language: %w", err)
+		}
+	}
+	if c.UI.Refresh != "" {
+		if d, err := time.ParseDuration(c.UI.Refresh); err != nil || d < minTickInterval {
+			return fmt.Errorf("ui.refresh: invalid value %q (want a duration of at least %s)", c.UI.Refresh, minTickInterval)
+		}
+	}
+	if c.UI.Screensaver != "" {
+		if d, err := time.ParseDuration(c.UI.Screensaver); err != nil || d < 0 {
+			return fmt.Errorf("ui.screensaver: invalid value %q (want a duration such as \"10m\", or \"0\" to disable)", c.UI.Screensaver)
+		}
+	}
+
+	for from, to := range c.Keys {
+		if _, ok := parseKey(from); !ok {
+			return fmt.Errorf("keys: unknown key %q", from)
+		}
+		if _, ok := parseKey(to); !ok {
+			return fmt.Errorf("keys.%s: unknown key %q", from, to)
+		}
+	}
+
+	if f := c.Download.Format; f != "" && !slices.Contains(downloadFormats, f) {
+		return fmt.Errorf("download.format: unsupported format %q (supported: %s)", f, strings.Join(downloadFormats, ", "))
+	}
+	if q := c.Download.Quality; q != "" && !audioQuality.MatchString(q) {
+		return fmt.Errorf("download.quality: invalid value %q (want 0-10 or a bitrate such as \"192K\")", q)
+	}
+
+	for i, name := range c.Providers.Search {
+		if _, ok := searchProviderFuncs[name]; !ok {
+			return fmt.Errorf("providers.search: unknown provider %q (known: %s)", name, strings.Join(searchProviderNames(), ", "))
+		}
+		if slices.Contains(c.Providers.Search[:i], name) {
+			return fmt.Errorf("providers.search: provider %q listed twice", name)
+		}
+	}
+	for key, instances := range map[string][]string{"invidious": c.Providers.Invidious, "piped": c.Providers.Piped} {
+		for _, instance := range instances {
+			if u, err := url.Parse(instance); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("providers.%s: invalid instance %q (want a URL such as \"https://example.org\")", key, instance)
+			}
+		}
+	}
+
+	if p := c.Network.Proxy; p != "" {
+		u, err := url.Parse(p)
+		if err != nil || u.Host == "" || !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) {
+			return fmt.Errorf("network.proxy: invalid value %q (want a URL such as \"http://proxy:8080\")", p)
+		}
+	}
+
+	if l := c.Server.Listen; l != "" {
+		if _, _, err := net.SplitHostPort(l); err != nil {
+			return fmt.Errorf("server.listen: invalid address %q (want host:port such as \"127.0.0.1:8765\")", l)
+		}
+	}
+	if c.Server.Sync && c.Server.Listen == "" {
+		return fmt.Errorf("server.sync: requires server.listen, e.g. \"0.0.0.0:8765\"")
+	}
+
+	if j := c.Sync.Join; j != "" {
+		if _, _, err := net.SplitHostPort(j); err != nil {
+			return fmt.Errorf("sync.join: invalid address %q (want host:port such as \"livingroom.local:8765\")", j)
+		}
+	}
+
+	if i := c.Backup.Interval; i != "" {
+		if d, err := time.ParseDuration(i); err != nil || d < time.Minute {
+			return fmt.Errorf("backup.interval: invalid value %q (want a duration of at least 1m, such as \"24h\")", i)
+		}
+	}
+
+	if j := c.Jellyfin.URL; j != "" {
+		u, err := url.Parse(j)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("jellyfin.url: invalid value %q (want a URL such as \"https://jellyfin.example.com\")", j)
+		}
+		if c.Jellyfin.Username == "" {
+			return fmt.Errorf("jellyfin.username: required when jellyfin.url is set")
+		}
+	}
+	return nil
+}
+
+// DownloadOptions returns the yt-dlp options set in the config file.
+func (c Config) DownloadOptions() DownloadOptions {
+	return DownloadOptions{
+		Format:  c.Download.Format,
+		Quality: c.Download.Quality,
+		YtDlp:   c.Download.YtDlp,
+		Proxy:   c.Network.Proxy,
+	}
+}
+
+// ApplyConfig makes cfg the configuration in effect.
+func ApplyConfig(cfg Config) {
+	config = cfg
+	if len(cfg.Library.MusicDirs) > 0 {
+		MusicDir = cfg.Library.MusicDirs[0]
+		extraMusicDirs = cfg.Library.MusicDirs[1:]
+	}
+	youtubeAPIKey = cfg.Providers.YouTubeAPIKey
+	if cfg.Plugins.Dir != "" {
+		PluginDir = cfg.Plugins.Dir
+	}
+	loadKeyBindings(cfg.Keys)
+
+	// Route the shared HTTP client, and libraries using the default
+	// transport, through the proxy
+	if u, err := url.Parse(cfg.Network.Proxy); err == nil && cfg.Network.Proxy != "" {
+		httpTransport.Proxy = http.ProxyURL(u)
+		if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,201 @@
+// Package main provides the context action menu for Personal Musician.
+// Pressing 'm' (or right-clicking) on a track or search result opens a
+// small menu listing the actions available for that item.
+package main
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// menuItem is a single action in the context menu.
+type menuItem struct {
+	Label string
+	run   func(m Model) (tea.Model, tea.Cmd)
+}
+
+// ContextMenu is a list of actions for one track or search result.
+type ContextMenu struct {
+	Title  string
+	Items  []menuItem
+	cursor int
+}
+
+// openContextMenu opens the context menu for the item under the cursor.
+func (m Model) openContextMenu() (tea.Model, tea.Cmd) {
+	var menu *ContextMenu
+	switch m.currentView {
+	case ViewLibrary:
+		files := m.visibleLibrary()
+		if m.libraryCursor < len(files) {
+			menu = m.trackMenu(files[m.libraryCursor])
+		}
+	case ViewResults:
+		if m.resultsCursor < len(m.youtubeResults) {
+			menu = m.resultMenu(m.youtubeResults[m.resultsCursor])
+		}
+	case ViewBrowse:
+		if tracks, start, _ := m.browseSelection(); m.browseLevel == browseTracks && start < len(tracks) {
+			menu = m.trackMenu(tracks[start])
+		}
+	case ViewDuplicates:
+		if m.duplicatesCursor < len(m.duplicates) {
+			menu = m.trackMenu(m.duplicates[m.duplicatesCursor].file)
+		}
+	}
+
+	if menu == nil {
+		return m, nil
+	}
+	m.contextMenu = menu
+	return m, nil
+}
+
+// trackMenu builds the context menu for a library track.
+func (m Model) trackMenu(file MusicFile) *ContextMenu {
+	menu := &ContextMenu{
+		Title: file.Name,
+		Items: []menuItem{
+			{T("Play"), func(m Model) (tea.Model, tea.Cmd) {
+				if err := m.player.PlayTrack(file); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				return m, func() tea.Msg { return statusMsg(Tf("Now playing: %s", file.Name)) }
+			}},
+			{T("Play next"), func(m Model) (tea.Model, tea.Cmd) {
+				m.player.PlayNext(file)
+				return m, func() tea.Msg { return statusMsg(Tf("Playing next: %s", file.Name)) }
+			}},
+			{T("Add to queue"), func(m Model) (tea.Model, tea.Cmd) {
+				m.player.Enqueue(file)
+				return m, func() tea.Msg { return statusMsg(Tf("Queued: %s", file.Name)) }
+			}},
+			{T("Add to playlist"), func(m Model) (tea.Model, tea.Cmd) {
+				return m.promptAddToPlaylist([]MusicFile{file})
+			}},
+			{T("Show info"), func(m Model) (tea.Model, tea.Cmd) {
+				return m, m.showTrackInfo(file)
+			}},
+			{T("Look up tags"), func(m Model) (tea.Model, tea.Cmd) {
+				if !isMP3(file.Path) {
+					return m, func() tea.Msg { return errorMsg(T("Tags can only be written to MP3 files")) }
+				}
+				return m, m.lookupTags(file)
+			}},
+			{T("Set genre"), func(m Model) (tea.Model, tea.Cmd) {
+				if m.currentView == ViewLibrary && m.librarySel.count() > 0 {
+					return m.promptSetGenre(m.markedLibrary()) // Every marked song
+				}
+				return m.promptSetGenre([]MusicFile{file})
+			}},
+			{T("Identify by sound"), func(m Model) (tea.Model, tea.Cmd) {
+				if !isMP3(file.Path) {
+					return m, func() tea.Msg { return errorMsg(T("Tags can only be written to MP3 files")) }
+				}
+				if err := checkIdentifyTools(); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				return m, m.identifyTrack(file)
+			}},
+			{T("Rename or move"), func(m Model) (tea.Model, tea.Cmd) {
+				if files := m.visibleLibrary(); m.currentView == ViewLibrary && m.libraryCursor < len(files) && files[m.libraryCursor].Path == file.Path {
+					return m.startRename() // Edit it in place
+				}
+				return m.renameTrack(file)
+			}},
+			{T("Reveal in file manager"), func(m Model) (tea.Model, tea.Cmd) {
+				if err := RevealInFileManager(file.Path); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				return m, nil
+			}},
+			{T("Delete"), func(m Model) (tea.Model, tea.Cmd) {
+				return m.openDialog(NewConfirmDialog(T("Delete"),
+					Tf("Move %q to the trash?", file.Name),
+					func(m Model, _ string) (tea.Model, tea.Cmd) {
+						if file.Path == m.player.GetState().CurrentFile {
+							m.player.Stop()
+						}
+						return m, m.library.Delete([]MusicFile{file})
+					}))
+			}},
+		},
+	}
+
+	// Each of the track's genres can be played, just after Play
+	for i, genre := range genreNames(file.Tags.Genre) {
+		item := menuItem{Tf("Play all %s", genre), func(m Model) (tea.Model, tea.Cmd) {
+			return m.playGenre(genre)
+		}}
+		menu.Items = slices.Insert(menu.Items, 1+i, item)
+	}
+
+	// Episodes can be marked played or unplayed, just above Delete
+	if item, ok := m.episodeMenuItem(file); ok {
+		last := len(menu.Items) - 1
+		menu.Items = append(menu.Items[:last], item, menu.Items[last])
+	}
+	return menu
+}
+
+// resultMenu builds the context menu for a search result.
+func (m Model) resultMenu(result SearchResult) *ContextMenu {
+	return &ContextMenu{
+		Title: result.Title,
+		Items: []menuItem{
+			{T("Download"), func(m Model) (tea.Model, tea.Cmd) {
+				return m.startDownload(result)
+			}},
+			{T("Open in browser"), func(m Model) (tea.Model, tea.Cmd) {
+				if err := OpenExternal(GetYouTubeURL(result.VideoID)); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				return m, nil
+			}},
+		},
+	}
+}
+
+// handleContextMenuKeys handles keys while the context menu is open.
+func (m Model) handleContextMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	menu := m.contextMenu
+
+	switch msg.String() {
+	case "esc", "m", "q":
+		m.contextMenu = nil
+	case "up", "k":
+		if menu.cursor > 0 {
+			menu.cursor--
+		}
+	case "down", "j":
+		if menu.cursor < len(menu.Items)-1 {
+			menu.cursor++
+		}
+	case "enter":
+		m.contextMenu = nil
+		return menu.Items[menu.cursor].run(m)
+	}
+	return m, nil
+}
+
+// renderContextMenu renders the context menu.
+func (m Model) renderContextMenu() string {
+	menu := m.contextMenu
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(" "+truncate(menu.Title, 40)+" ") + "\n\n")
+	for i, item := range menu.Items {
+		if i == menu.cursor {
+			b.WriteString(selectedStyle.Render("> "+item.Label) + "\n")
+		} else {
+			b.WriteString(normalStyle.Render("  "+item.Label) + "\n")
+		}
+	}
+	b.WriteString("\n" + mutedStyle.Render(T("↑/↓: select • enter: run • esc: close")))
+
+	box := boxStyle.Render(b.String())
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, box)
+}
@@ -0,0 +1,123 @@
+// Package main provides the crash handler for Personal Musician.
+// The TUI model is wrapped in a guard that recovers panics in Update and View.
+// A panic writes a crash report with the stack trace next to the log file,
+// saves the UI state (queue, playing track and position) and quits cleanly, so
+// the terminal is restored and the next start reopens where the crash happened.
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crashGuard wraps the model and recovers panics in Update and View.
+type crashGuard struct {
+	Model
+	crash *crashInfo // Shared by all copies of the guard
+}
+
+// crashInfo records a recovered panic.
+type crashInfo struct {
+	crashed bool
+	value   any    // The panic value
+	report  string // Path of the crash report, "" if it could not be written
+}
+
+// newCrashGuard wraps a model in a crash guard.
+func newCrashGuard(m Model) crashGuard {
+	return crashGuard{Model: m, crash: &crashInfo{}}
+}
+
+// Update forwards a message to the model, quitting after a panic.
+func (g crashGuard) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	if g.crash.crashed {
+		return g, tea.Quit
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.recovered("Update", msg, r)
+			model, cmd = g, tea.Quit
+		}
+	}()
+
+	next, cmd := g.Model.Update(msg)
+	if m, ok := next.(Model); ok {
+		g.Model = m
+	}
+	return g, cmd
+}
+
+// View renders the model. After a panic it renders nothing; the next
+// message quits the program.
+func (g crashGuard) View() (view string) {
+	if g.crash.crashed {
+		return ""
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.recovered("View", nil, r)
+			view = ""
+		}
+	}()
+	return g.Model.View()
+}
+
+// recovered handles a panic: it writes the crash report and saves the state
+// of the model as it was before the failing call.
+func (g crashGuard) recovered(where string, msg tea.Msg, r any) {
+	stack := debug.Stack()
+	g.crash.crashed = true
+	g.crash.value = r
+	slog.Error("panic", "in", where, "value", fmt.Sprint(r), "stack", string(stack))
+
+	path, err := writeCrashReport(where, msg, r, stack)
+	if err != nil {
+		slog.Error("failed to write crash report", "err", err)
+	}
+	g.crash.report = path
+
+	if err := g.Model.SaveState(StateFile); err != nil {
+		slog.Error("failed to save state after crash", "err", err)
+	}
+	if err := g.Model.SaveSession(); err != nil {
+		slog.Error("failed to save session after crash", "err", err)
+	}
+}
+
+// writeCrashReport writes a crash report into the data directory and
+// returns its path.
+func writeCrashReport(where string, msg tea.Msg, r any, stack []byte) (string, error) {
+	now := time.Now()
+	path := filepath.Join(DataDir(), "crash-"+now.Format("20060102-150405")+".log")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Personal Musician crash report\n\n")
+	fmt.Fprintf(&b, "Time:    %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version: %s\n", versionString())
+	fmt.Fprintf(&b, "In:      %s\n", where)
+	if msg != nil {
+		fmt.Fprintf(&b, "Message: %T\n", msg)
+	}
+	fmt.Fprintf(&b, "Panic:   %v\n\n%s", r, stack)
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// crashMessage describes a crash for the terminal after the TUI has exited.
+func (c *crashInfo) crashMessage() string {
+	msg := fmt.Sprintf("Personal Musician crashed: %v\nYour queue and position were saved and will be restored on the next start.", c.value)
+	if c.report != "" {
+		msg += fmt.Sprintf("\nA crash report was written to %s; please attach it when reporting the problem.", c.report)
+	}
+	return msg
+}
@@ -0,0 +1,97 @@
+// Package main provides crossfading for Personal Musician.
+// With a crossfade set, the last seconds of a track play under the start
+// of the next one: the outgoing track fades out while the incoming one
+// fades in, both when a track ends and when skipping with next or prev.
+// The fade uses equal-power curves, so the loudness stays even while the
+// two overlap.
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// maxCrossfade bounds the crossfade setting.
+const maxCrossfade = 30 * time.Second
+
+// crossfadeDuration returns the crossfade from crossfade under [playback].
+// 0 plays tracks back to back.
+func crossfadeDuration() time.Duration {
+	// The config file was validated when loaded
+	d, _ := time.ParseDuration(config.Playback.Crossfade)
+	return d
+}
+
+// SetCrossfade sets how long tracks overlap; 0 turns crossfading off. It
+// applies from the next track started.
+func (p *Player) SetCrossfade(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.crossfade = max(0, min(d, maxCrossfade))
+}
+
+// startCrossfade starts fading into the prepared next track once the
+// current one is within the crossfade of its end.
+func (s *gaplessSource) startCrossfade() {
+	if s.crossfade <= 0 || s.fadeOut != nil || s.loopB > 0 {
+		return
+	}
+	left := s.remaining()
+	if left > s.crossfade {
+		return
+	}
+	next := s.player.next.Swap(nil)
+	if next == nil {
+		return
+	}
+	finished := s.track
+	s.fadeTo(next, left)
+	go s.player.finishSwitch(s, finished, next)
+}
+
+// fadeTo makes track the current one and fades the previous one out over
+// d. It returns the track that was still fading out, if any, for the
+// caller to close. Called on the audio thread or with the speaker locked.
+func (s *gaplessSource) fadeTo(track *decodedTrack, d time.Duration) (dropped *decodedTrack) {
+	dropped = s.fadeOut
+	s.fadeOut = s.track
+	s.track = track
+	s.fadePos = 0
+	s.fadeLen = s.rate.N(d)
+	s.loopB = 0
+	return dropped
+}
+
+// mixFadeOut fades the current track's n samples in and mixes the fading
+// track under them. It returns how many samples hold audio.
+func (s *gaplessSource) mixFadeOut(samples [][2]float64, n int) int {
+	if cap(s.buf) < len(samples) {
+		s.buf = make([][2]float64, len(samples))
+	}
+	buf := s.buf[:len(samples)]
+	m, _ := s.fadeOut.audio.Stream(buf)
+
+	filled := max(n, m)
+	clear(samples[n:filled])
+	for i := range filled {
+		x := 1.0
+		if s.fadeLen > 0 {
+			x = min(float64(s.fadePos+i)/float64(s.fadeLen), 1)
+		}
+		in, out := math.Sin(x*math.Pi/2), math.Cos(x*math.Pi/2)
+		for c := range samples[i] {
+			samples[i][c] *= in
+			if i < m {
+				samples[i][c] += buf[i][c] * out
+			}
+		}
+	}
+	s.fadePos += filled
+
+	// Keep fading in after a short outgoing track ran out, then let it go
+	if s.fadePos >= s.fadeLen {
+		go s.fadeOut.streamer.Close()
+		s.fadeOut = nil
+	}
+	return filled
+}
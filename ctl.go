@@ -0,0 +1,152 @@
+// Package main provides remote control of a running Personal Musician.
+// "personal-musician ctl <command>" sends play-pause, next, prev, add and
+// status requests over the instance socket, so window-manager keybindings and
+// scripts can drive playback. "ctl status --json" prints machine-readable state.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ctlCommands are the requests ctl can send.
+var ctlCommands = []string{"play-pause", "next", "prev", "add", "status"}
+
+// playerStatus is the playback state reported to "ctl status" and the
+// event stream.
+type playerStatus struct {
+	State    string  `json:"state"` // "playing", "paused" or "stopped"
+	Title    string  `json:"title,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Position float64 `json:"position"` // Seconds
+	Duration float64 `json:"duration"` // Seconds
+	Volume   int     `json:"volume"`
+	Muted    bool    `json:"muted"`
+	Shuffle  bool    `json:"shuffle"`
+	Repeat   string  `json:"repeat"`
+	Queue    int     `json:"queue"` // Number of queued tracks
+}
+
+// runCtl sends a control request to the running instance and prints its answer.
+func runCtl(args []string) error {
+	usage := fmt.Errorf("usage: personal-musician ctl play-pause|next|prev|add <file|url>...|status [--json]")
+	if len(args) == 0 {
+		return usage
+	}
+
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	req := instanceRequest{Command: args[0]}
+	switch req.Command {
+	case "play-pause", "next", "prev", "status":
+		if len(rest) > 0 {
+			return usage
+		}
+	case "add":
+		if len(rest) == 0 {
+			return usage
+		}
+		if req.Args, err = resolveOpenArgs(rest); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown ctl command %q", req.Command)
+	}
+
+	resp, err := SendToInstance(req)
+	if errors.Is(err, errNoInstance) {
+		return fmt.Errorf("Personal Musician is not running")
+	}
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return errors.New(resp.Message)
+	}
+
+	switch {
+	case resp.Status != nil && *asJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp.Status)
+	case resp.Status != nil:
+		fmt.Println(resp.Status.String())
+	case resp.Message != "":
+		fmt.Println(resp.Message)
+	}
+	return nil
+}
+
+// String formats the status as one line, e.g. "playing: Song (1:23 / 3:45)".
+func (s playerStatus) String() string {
+	if s.State == "stopped" {
+		return "stopped"
+	}
+	position := time.Duration(s.Position * float64(time.Second))
+	duration := time.Duration(s.Duration * float64(time.Second))
+	return fmt.Sprintf("%s: %s (%s / %s)", s.State, s.Title, FormatDuration(position), FormatDuration(duration))
+}
+
+// controlRemote answers a ctl request other than adding tracks.
+func (m Model) controlRemote(req instanceRequest) (Model, instanceResponse, tea.Cmd) {
+	switch req.Command {
+	case "play-pause":
+		if !m.player.GetState().IsPlaying {
+			if err := m.player.NextSong(); err != nil {
+				return m, instanceResponse{Message: err.Error()}, nil
+			}
+			return m, instanceResponse{OK: true}, m.refreshLibrary()
+		}
+		m.player.TogglePause()
+		return m, instanceResponse{OK: true}, nil
+
+	case "next", "prev":
+		skip := m.player.NextSong
+		if req.Command == "prev" {
+			skip = m.player.PrevSong
+		}
+		if err := skip(); err != nil {
+			return m, instanceResponse{Message: err.Error()}, nil
+		}
+		return m, instanceResponse{OK: true}, m.refreshLibrary()
+
+	case "status":
+		status := newPlayerStatus(m.player.GetState())
+		return m, instanceResponse{OK: true, Status: &status}, nil
+	}
+	return m, instanceResponse{Message: fmt.Sprintf("unknown request %q", req.Command)}, nil
+}
+
+// newPlayerStatus converts the player's state into its reported form.
+func newPlayerStatus(state PlaybackState) playerStatus {
+	status := playerStatus{
+		State:    "stopped",
+		Position: state.Position.Seconds(),
+		Duration: state.Duration.Seconds(),
+		Volume:   state.Volume,
+		Muted:    state.Muted,
+		Shuffle:  state.Shuffle,
+		Repeat:   state.Repeat.String(),
+		Queue:    state.QueueLength,
+	}
+	if state.IsPlaying {
+		status.State = "playing"
+		if state.IsPaused {
+			status.State = "paused"
+		}
+		status.Title = musicFileFromPath(state.CurrentFile).Name
+		status.Path = state.CurrentFile
+	}
+	return status
+}
@@ -0,0 +1,110 @@
+// Package main provides the modal dialog component for Personal Musician.
+// Dialogs ask for confirmation (yes/no) or a line of text before an action
+// runs, so destructive actions never execute silently.
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// dialogAction runs when a dialog is confirmed. value holds the text input
+// for prompts and is empty for confirmations.
+type dialogAction func(m Model, value string) (tea.Model, tea.Cmd)
+
+// Dialog is a modal confirmation or text prompt.
+type Dialog struct {
+	Title     string
+	Message   string
+	onConfirm dialogAction
+
+	prompt bool            // Whether the dialog asks for text
+	input  textinput.Model // Text input for prompts
+}
+
+// NewConfirmDialog creates a yes/no dialog.
+func NewConfirmDialog(title, message string, onConfirm dialogAction) *Dialog {
+	return &Dialog{Title: title, Message: message, onConfirm: onConfirm}
+}
+
+// NewPromptDialog creates a dialog that asks for a line of text.
+func NewPromptDialog(title, message, initial string, onConfirm dialogAction) *Dialog {
+	ti := textinput.New()
+	ti.CharLimit = 200
+	ti.Width = 50
+	ti.SetValue(initial)
+	ti.Focus()
+
+	return &Dialog{Title: title, Message: message, onConfirm: onConfirm, prompt: true, input: ti}
+}
+
+// openDialog shows a dialog on top of the current view.
+func (m Model) openDialog(d *Dialog) (tea.Model, tea.Cmd) {
+	m.dialog = d
+	if d.prompt {
+		return m, textinput.Blink
+	}
+	return m, nil
+}
+
+// handleDialogKeys handles keys while a dialog is open.
+func (m Model) handleDialogKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := m.dialog
+
+	switch msg.String() {
+	case "esc":
+		m.dialog = nil
+		return m, nil
+	case "enter":
+		m.dialog = nil
+		if d.prompt {
+			value := strings.TrimSpace(d.input.Value())
+			if value == "" {
+				return m, nil
+			}
+			return d.onConfirm(m, value)
+		}
+		return d.onConfirm(m, "")
+	}
+
+	if d.prompt {
+		var cmd tea.Cmd
+		d.input, cmd = d.input.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "y", "Y":
+		m.dialog = nil
+		return d.onConfirm(m, "")
+	case "n", "N":
+		m.dialog = nil
+	}
+	return m, nil
+}
+
+// renderDialog renders the open dialog centered horizontally.
+func (m Model) renderDialog() string {
+	d := m.dialog
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(" "+d.Title+" ") + "\n\n")
+	b.WriteString(normalStyle.Render(d.Message) + "\n\n")
+
+	if d.prompt {
+		b.WriteString(d.input.View() + "\n\n")
+		b.WriteString(mutedStyle.Render(T("enter: confirm • esc: cancel")))
+	} else {
+		b.WriteString(mutedStyle.Render(T("y/enter: yes • n/esc: no")))
+	}
+
+	width := m.width / 2
+	if width < 40 {
+		width = 40
+	}
+	box := boxStyle.Width(width).Render(b.String())
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, box)
+}
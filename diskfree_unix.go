@@ -0,0 +1,20 @@
+//go:build !windows
+
+// Package main provides the free disk space lookup on Unix systems for
+// Personal Musician.
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// freeSpace returns the bytes available to the user on the file system
+// holding dir.
+func freeSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, fmt.Errorf("failed to stat file system: %w", err)
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
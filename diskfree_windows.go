@@ -0,0 +1,24 @@
+//go:build windows
+
+// Package main provides the free disk space lookup on Windows for Personal
+// Musician.
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// freeSpace returns the bytes available to the user on the volume holding dir.
+func freeSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, fmt.Errorf("invalid path: %w", err)
+	}
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, nil, nil); err != nil {
+		return 0, fmt.Errorf("failed to query free space: %w", err)
+	}
+	return available, nil
+}
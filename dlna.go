@@ -0,0 +1,272 @@
+// Package main provides DLNA renderer output for Personal Musician.
+// Renderers (smart TVs, network amplifiers and speakers) are discovered with
+// SSDP. Casting to one hands it the URL of the track on a small local HTTP
+// server and drives it over UPnP AV Transport: load, play, pause, seek and
+// stop.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"mime"
+	"net"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huin/goupnp/dcps/av1"
+)
+
+// dlnaRequestTimeout bounds each request to a renderer.
+const dlnaRequestTimeout = 10 * time.Second
+
+// DLNARenderer is a UPnP media renderer found on the local network.
+type DLNARenderer struct {
+	Name      string // Friendly name, e.g. "Living Room TV"
+	Model     string
+	transport *av1.AVTransport1
+}
+
+// DiscoverDLNARenderers looks for media renderers on the local network for
+// the given time and returns them sorted by name.
+func DiscoverDLNARenderers(timeout time.Duration) ([]DLNARenderer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	clients, errs, err := av1.NewAVTransport1ClientsCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for DLNA renderers: %w", err)
+	}
+	for _, err := range errs {
+		slog.Debug("DLNA device ignored", "err", err)
+	}
+
+	seen := make(map[string]bool)
+	var renderers []DLNARenderer
+	for _, client := range clients {
+		device := client.RootDevice.Device
+		if seen[device.UDN] {
+			continue
+		}
+		seen[device.UDN] = true
+		renderers = append(renderers, DLNARenderer{
+			Name:      device.FriendlyName,
+			Model:     device.ModelName,
+			transport: client,
+		})
+	}
+
+	sort.Slice(renderers, func(i, j int) bool {
+		return strings.ToLower(renderers[i].Name) < strings.ToLower(renderers[j].Name)
+	})
+	return renderers, nil
+}
+
+// DLNASession drives a renderer. Requests are sent one at a time by a
+// background goroutine, so a slow renderer never blocks the TUI.
+type DLNASession struct {
+	Renderer DLNARenderer
+
+	files *castFileServer
+	queue chan func(ctx context.Context) error
+	done  chan struct{}
+	once  sync.Once
+
+	mu  sync.Mutex
+	err error // Why the session ended
+}
+
+// StartDLNA starts a session with a renderer.
+func StartDLNA(ctx context.Context, renderer DLNARenderer) (*DLNASession, error) {
+	// Serve files on the interface the renderer was found through
+	files, err := startCastFileServer(renderer.transport.LocalAddr())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DLNASession{
+		Renderer: renderer,
+		files:    files,
+		queue:    make(chan func(ctx context.Context) error, 16),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	slog.Info("casting", "renderer", renderer.Name, "location", renderer.transport.Location.String())
+	return s, nil
+}
+
+// run sends queued requests until the session ends. Network errors end the
+// session; errors reported by the renderer are only logged.
+func (s *DLNASession) run() {
+	for {
+		select {
+		case req := <-s.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), dlnaRequestTimeout)
+			err := req(ctx)
+			cancel()
+
+			var urlErr *url.Error
+			switch {
+			case errors.As(err, &urlErr):
+				s.fail(fmt.Errorf("lost connection to %s: %w", s.Renderer.Name, err))
+				return
+			case err != nil:
+				slog.Error("DLNA request failed", "renderer", s.Renderer.Name, "err", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// enqueue queues a request. Requests are dropped if the renderer stops
+// responding.
+func (s *DLNASession) enqueue(req func(ctx context.Context) error) {
+	select {
+	case s.queue <- req:
+	case <-s.done:
+	default:
+		slog.Warn("DLNA request dropped", "renderer", s.Renderer.Name)
+	}
+}
+
+// Name returns the name of the renderer.
+func (s *DLNASession) Name() string {
+	return s.Renderer.Name
+}
+
+// Load starts playing a file on the renderer at the given position.
+func (s *DLNASession) Load(path, title string, position time.Duration, autoplay bool) {
+	uri := s.files.serve(path)
+	metadata := didlLite(didlItem("0", "-1", title, uri, audioContentType(path)))
+	t := s.Renderer.transport
+	s.enqueue(func(ctx context.Context) error {
+		if err := t.StopCtx(ctx, 0); err != nil {
+			slog.Debug("DLNA stop before load failed", "err", err) // Fails when already stopped
+		}
+		if err := t.SetAVTransportURICtx(ctx, 0, uri, metadata); err != nil {
+			return err
+		}
+		if !autoplay {
+			return nil
+		}
+		if err := t.PlayCtx(ctx, 0, "1"); err != nil {
+			return err
+		}
+		if position > castSeekThreshold {
+			return t.SeekCtx(ctx, 0, "REL_TIME", formatUPnPTime(position))
+		}
+		return nil
+	})
+}
+
+// Play resumes playback on the renderer.
+func (s *DLNASession) Play() {
+	t := s.Renderer.transport
+	s.enqueue(func(ctx context.Context) error { return t.PlayCtx(ctx, 0, "1") })
+}
+
+// Pause pauses playback on the renderer.
+func (s *DLNASession) Pause() {
+	t := s.Renderer.transport
+	s.enqueue(func(ctx context.Context) error { return t.PauseCtx(ctx, 0) })
+}
+
+// Seek moves playback on the renderer to the given position.
+func (s *DLNASession) Seek(position time.Duration) {
+	t := s.Renderer.transport
+	s.enqueue(func(ctx context.Context) error {
+		return t.SeekCtx(ctx, 0, "REL_TIME", formatUPnPTime(position))
+	})
+}
+
+// Stop stops playback on the renderer.
+func (s *DLNASession) Stop() {
+	t := s.Renderer.transport
+	s.enqueue(func(ctx context.Context) error { return t.StopCtx(ctx, 0) })
+}
+
+// Err returns why the session ended, or nil while it is running.
+func (s *DLNASession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// fail ends the session with an error.
+func (s *DLNASession) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.shutdown()
+}
+
+// Close stops the renderer and ends the session.
+func (s *DLNASession) Close() {
+	if s.Err() == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), dlnaRequestTimeout)
+		s.Renderer.transport.StopCtx(ctx, 0)
+		cancel()
+	}
+	s.fail(errors.New("session closed"))
+}
+
+// shutdown stops the worker and the file server once.
+func (s *DLNASession) shutdown() {
+	s.once.Do(func() {
+		close(s.done)
+		s.files.Close()
+	})
+}
+
+// formatUPnPTime formats a position as H:MM:SS for AV Transport.
+func formatUPnPTime(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// audioContentType returns the MIME type of an audio file.
+func audioContentType(path string) string {
+	if format, ok := audioFormatOf(path); ok {
+		return format.mime
+	}
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); t != "" {
+		return t
+	}
+	return "audio/mpeg"
+}
+
+// didlItem describes an audio track in DIDL-Lite.
+func didlItem(id, parentID, title, uri, contentType string) string {
+	return fmt.Sprintf(`<item id="%s" parentID="%s" restricted="1">`+
+		`<dc:title>%s</dc:title><upnp:class>object.item.audioItem.musicTrack</upnp:class>`+
+		`<res protocolInfo="http-get:*:%s:*">%s</res></item>`,
+		html.EscapeString(id), html.EscapeString(parentID), html.EscapeString(title),
+		contentType, html.EscapeString(uri))
+}
+
+// didlLite wraps DIDL-Lite objects in the document element.
+func didlLite(objects ...string) string {
+	return `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" ` +
+		`xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">` +
+		strings.Join(objects, "") + `</DIDL-Lite>`
+}
+
+// outboundIP returns the local address used to reach the local network.
+func outboundIP() (net.IP, error) {
+	// No packets are sent when connecting a UDP socket
+	conn, err := net.Dial("udp4", "239.255.255.250:1900")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the local network address: %w", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,491 @@
+// Package main provides the DLNA media server of Personal Musician.
+// With dlna enabled under [server] (or PM_DLNA, or --dlna) the library is
+// announced on the local network as a UPnP MediaServer, so TVs, receivers
+// and phone apps can browse all tracks and saved playlists and stream them.
+// Only files in the library are served.
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koron/go-ssdp"
+)
+
+// UPnP identifiers of the media server.
+const (
+	dlnaDeviceType    = "urn:schemas-upnp-org:device:MediaServer:1"
+	dlnaContentDir    = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	dlnaConnectionMgr = "urn:schemas-upnp-org:service:ConnectionManager:1"
+	dlnaMaxAge        = 1800 // Seconds an announcement stays valid
+	dlnaAliveInterval = 5 * time.Minute
+)
+
+// Object IDs of the fixed containers.
+const (
+	dlnaRootID      = "0"
+	dlnaTracksID    = "tracks"
+	dlnaPlaylistsID = "playlists"
+	dlnaPlaylistPfx = "playlist:" // Followed by the playlist name
+	dlnaTrackPfx    = "track:"    // Followed by a hash of the path
+)
+
+// DLNAServer serves the library to DLNA clients.
+type DLNAServer struct {
+	name        string
+	udn         string // Unique device name, stable across runs
+	base        string // URL prefix, e.g. "http://192.168.1.10:41234"
+	server      *http.Server
+	advertisers []*ssdp.Advertiser
+	stop        chan struct{}
+
+	mu     sync.Mutex
+	tracks map[string]string // Track object ID to path
+}
+
+// StartDLNAServer starts serving the library and announcing it under the
+// given name.
+func StartDLNAServer(name string) (*DLNAServer, error) {
+	ip, err := outboundIP()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start DLNA server: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	sum := sha1.Sum([]byte("personal-musician/" + hostname + "/" + name))
+	s := &DLNAServer{
+		name:   name,
+		udn:    fmt.Sprintf("uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]),
+		base:   "http://" + listener.Addr().String(),
+		stop:   make(chan struct{}),
+		tracks: make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dlna/device.xml", s.handleDevice)
+	mux.HandleFunc("GET /dlna/cds.xml", serveXML(contentDirectorySCPD))
+	mux.HandleFunc("GET /dlna/cms.xml", serveXML(connectionManagerSCPD))
+	mux.HandleFunc("POST /dlna/control/cds", s.handleContentDirectory)
+	mux.HandleFunc("POST /dlna/control/cms", s.handleConnectionManager)
+	mux.HandleFunc("/dlna/event/", handleEventSubscription)
+	mux.HandleFunc("GET /dlna/media/{file}", s.handleMedia)
+	mux.HandleFunc("HEAD /dlna/media/{file}", s.handleMedia)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.server.Serve(listener)
+
+	// Announce the device and each of its services
+	location := s.base + "/dlna/device.xml"
+	targets := map[string]string{
+		"upnp:rootdevice": s.udn + "::upnp:rootdevice",
+		s.udn:             s.udn,
+		dlnaDeviceType:    s.udn + "::" + dlnaDeviceType,
+		dlnaContentDir:    s.udn + "::" + dlnaContentDir,
+		dlnaConnectionMgr: s.udn + "::" + dlnaConnectionMgr,
+	}
+	for st, usn := range targets {
+		ad, err := ssdp.Advertise(st, usn, location, "Personal-Musician UPnP/1.0", dlnaMaxAge)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to announce DLNA server: %w", err)
+		}
+		s.advertisers = append(s.advertisers, ad)
+	}
+	go s.announce()
+
+	slog.Info("DLNA server started", "name", name, "location", location)
+	return s, nil
+}
+
+// announce sends alive notifications until the server stops.
+func (s *DLNAServer) announce() {
+	ticker := time.NewTicker(dlnaAliveInterval)
+	defer ticker.Stop()
+	for {
+		for _, ad := range s.advertisers {
+			if err := ad.Alive(); err != nil {
+				slog.Debug("DLNA announcement failed", "err", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close withdraws the announcements and stops the server.
+func (s *DLNAServer) Close() error {
+	close(s.stop)
+	for _, ad := range s.advertisers {
+		ad.Bye()
+		ad.Close()
+	}
+	return s.server.Close()
+}
+
+// handleDevice serves the device description.
+func (s *DLNAServer) handleDevice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	fmt.Fprintf(w, deviceDescription, html.EscapeString(s.name), s.udn)
+}
+
+// serveXML returns a handler serving a fixed XML document.
+func serveXML(doc string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		io.WriteString(w, doc)
+	}
+}
+
+// handleEventSubscription accepts event subscriptions. The server never
+// sends events, but some clients refuse to browse without subscribing.
+func handleEventSubscription(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("SID", "uuid:personal-musician-events")
+	w.Header().Set("TIMEOUT", fmt.Sprintf("Second-%d", dlnaMaxAge))
+	w.WriteHeader(http.StatusOK)
+}
+
+// soapRequest holds the arguments of the actions the server implements.
+type soapRequest struct {
+	Body struct {
+		Action struct {
+			XMLName        xml.Name
+			ObjectID       string `xml:"ObjectID"`
+			BrowseFlag     string `xml:"BrowseFlag"`
+			StartingIndex  int    `xml:"StartingIndex"`
+			RequestedCount int    `xml:"RequestedCount"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+// readSOAP decodes a SOAP request.
+func readSOAP(r *http.Request) (soapRequest, error) {
+	var req soapRequest
+	err := xml.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req)
+	return req, err
+}
+
+// writeSOAP writes a SOAP response with the given arguments, which are
+// name-value pairs in order.
+func writeSOAP(w http.ResponseWriter, service, action string, args ...string) {
+	var b strings.Builder
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, "<%s>%s</%s>", args[i], html.EscapeString(args[i+1]), args[i])
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+		`<s:Body><u:%sResponse xmlns:u="%s">%s</u:%sResponse></s:Body></s:Envelope>`,
+		action, service, b.String(), action)
+}
+
+// writeSOAPError writes a UPnP error.
+func writeSOAPError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`+
+		`<s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, code, html.EscapeString(description))
+}
+
+// handleContentDirectory answers ContentDirectory actions.
+func (s *DLNAServer) handleContentDirectory(w http.ResponseWriter, r *http.Request) {
+	req, err := readSOAP(r)
+	if err != nil {
+		writeSOAPError(w, 402, "Invalid Args")
+		return
+	}
+
+	action := req.Body.Action
+	switch action.XMLName.Local {
+	case "Browse":
+		objects, total, err := s.browse(action.ObjectID, action.BrowseFlag == "BrowseMetadata")
+		if err != nil {
+			writeSOAPError(w, 701, err.Error())
+			return
+		}
+		objects = page(objects, action.StartingIndex, action.RequestedCount)
+		writeSOAP(w, dlnaContentDir, "Browse",
+			"Result", didlLite(objects...),
+			"NumberReturned", fmt.Sprint(len(objects)),
+			"TotalMatches", fmt.Sprint(total),
+			"UpdateID", "1")
+	case "GetSearchCapabilities":
+		writeSOAP(w, dlnaContentDir, "GetSearchCapabilities", "SearchCaps", "")
+	case "GetSortCapabilities":
+		writeSOAP(w, dlnaContentDir, "GetSortCapabilities", "SortCaps", "")
+	case "GetSystemUpdateID":
+		writeSOAP(w, dlnaContentDir, "GetSystemUpdateID", "Id", "1")
+	default:
+		writeSOAPError(w, 401, "Invalid Action")
+	}
+}
+
+// handleConnectionManager answers ConnectionManager actions.
+func (s *DLNAServer) handleConnectionManager(w http.ResponseWriter, r *http.Request) {
+	req, err := readSOAP(r)
+	if err != nil {
+		writeSOAPError(w, 402, "Invalid Args")
+		return
+	}
+
+	switch req.Body.Action.XMLName.Local {
+	case "GetProtocolInfo":
+		writeSOAP(w, dlnaConnectionMgr, "GetProtocolInfo",
+			"Source", "http-get:*:audio/mpeg:*,http-get:*:audio/flac:*,http-get:*:audio/wav:*,http-get:*:audio/aiff:*", "Sink", "")
+	case "GetCurrentConnectionIDs":
+		writeSOAP(w, dlnaConnectionMgr, "GetCurrentConnectionIDs", "ConnectionIDs", "0")
+	default:
+		writeSOAPError(w, 401, "Invalid Action")
+	}
+}
+
+// browse returns the DIDL-Lite objects of a container's children, or of
+// the object itself with metadata set, and the number of children.
+func (s *DLNAServer) browse(id string, metadata bool) ([]string, int, error) {
+	switch {
+	case id == dlnaRootID:
+		if metadata {
+			return []string{didlContainer(dlnaRootID, "-1", s.name, 2)}, 1, nil
+		}
+		tracks, _ := ScanMusicFiles()
+		playlists, _ := ListPlaylists()
+		return []string{
+			didlContainer(dlnaTracksID, dlnaRootID, "All tracks", len(tracks)),
+			didlContainer(dlnaPlaylistsID, dlnaRootID, "Playlists", len(playlists)),
+		}, 2, nil
+
+	case id == dlnaTracksID:
+		tracks, err := ScanMusicFiles()
+		if err != nil {
+			return nil, 0, err
+		}
+		if metadata {
+			return []string{didlContainer(dlnaTracksID, dlnaRootID, "All tracks", len(tracks))}, 1, nil
+		}
+		return s.trackObjects(tracks, dlnaTracksID), len(tracks), nil
+
+	case id == dlnaPlaylistsID:
+		names, err := ListPlaylists()
+		if err != nil {
+			return nil, 0, err
+		}
+		if metadata {
+			return []string{didlContainer(dlnaPlaylistsID, dlnaRootID, "Playlists", len(names))}, 1, nil
+		}
+		var objects []string
+		for _, name := range names {
+			count := 0
+			if pl, err := LoadPlaylist(name); err == nil {
+				count = len(servableTracks(pl.Tracks))
+			}
+			objects = append(objects, didlContainer(dlnaPlaylistPfx+name, dlnaPlaylistsID, name, count))
+		}
+		return objects, len(objects), nil
+
+	case strings.HasPrefix(id, dlnaPlaylistPfx):
+		name := strings.TrimPrefix(id, dlnaPlaylistPfx)
+		if ValidatePlaylistName(name) != nil {
+			return nil, 0, fmt.Errorf("no such object") // Not a name in the Playlists folder
+		}
+		pl, err := LoadPlaylist(name)
+		if err != nil {
+			return nil, 0, fmt.Errorf("no such object")
+		}
+		tracks := servableTracks(pl.Tracks)
+		if metadata {
+			return []string{didlContainer(id, dlnaPlaylistsID, pl.Name, len(tracks))}, 1, nil
+		}
+		return s.trackObjects(tracks, id), len(tracks), nil
+
+	case strings.HasPrefix(id, dlnaTrackPfx) && metadata:
+		s.mu.Lock()
+		path, ok := s.tracks[id]
+		s.mu.Unlock()
+		if !ok {
+			return nil, 0, fmt.Errorf("no such object")
+		}
+		return s.trackObjects([]MusicFile{musicFileFromPath(path)}, dlnaTracksID), 1, nil
+	}
+	return nil, 0, fmt.Errorf("no such object")
+}
+
+// servableTracks returns the tracks that may be streamed to clients: those
+// in the music directories, whatever else a playlist lists.
+func servableTracks(files []MusicFile) []MusicFile {
+	var tracks []MusicFile
+	for _, f := range files {
+		if inMusicDirs(f.Path) {
+			tracks = append(tracks, f)
+		}
+	}
+	return tracks
+}
+
+// trackObjects describes tracks and registers them for streaming. Tracks
+// outside the music directories are left out.
+func (s *DLNAServer) trackObjects(files []MusicFile, parentID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects := make([]string, 0, len(files))
+	for _, f := range files {
+		if !inMusicDirs(f.Path) {
+			continue
+		}
+		sum := sha1.Sum([]byte(f.Path))
+		id := dlnaTrackPfx + hex.EncodeToString(sum[:8])
+		s.tracks[id] = f.Path
+
+		uri := s.base + "/dlna/media/" + strings.TrimPrefix(id, dlnaTrackPfx) + strings.ToLower(filepath.Ext(f.Path))
+		objects = append(objects, didlItem(id, parentID, f.Name, uri, audioContentType(f.Path)))
+	}
+	return objects
+}
+
+// handleMedia streams a track that has been listed to a client.
+func (s *DLNAServer) handleMedia(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+	id := dlnaTrackPfx + strings.TrimSuffix(name, filepath.Ext(name))
+	s.mu.Lock()
+	path, ok := s.tracks[id]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", "DLNA.ORG_OP=01;DLNA.ORG_CI=0")
+	http.ServeFile(w, r, path)
+}
+
+// didlContainer describes a container in DIDL-Lite.
+func didlContainer(id, parentID, title string, children int) string {
+	return fmt.Sprintf(`<container id="%s" parentID="%s" childCount="%d" restricted="1">`+
+		`<dc:title>%s</dc:title><upnp:class>object.container</upnp:class></container>`,
+		html.EscapeString(id), html.EscapeString(parentID), children, html.EscapeString(title))
+}
+
+// page returns the requested slice of objects; a count of 0 means all.
+func page(objects []string, start, count int) []string {
+	if start < 0 || start >= len(objects) {
+		return nil
+	}
+	objects = objects[start:]
+	if count > 0 && count < len(objects) {
+		objects = objects[:count]
+	}
+	return objects
+}
+
+// deviceDescription is the UPnP device description; the friendly name and
+// UDN are filled in.
+const deviceDescription = `<?xml version="1.0" encoding="utf-8"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaServer:1</deviceType>
+    <friendlyName>%s</friendlyName>
+    <manufacturer>Personal Musician</manufacturer>
+    <modelName>Personal Musician</modelName>
+    <UDN>%s</UDN>
+    <serviceList>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:ContentDirectory:1</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ContentDirectory</serviceId>
+        <SCPDURL>/dlna/cds.xml</SCPDURL>
+        <controlURL>/dlna/control/cds</controlURL>
+        <eventSubURL>/dlna/event/cds</eventSubURL>
+      </service>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:ConnectionManager:1</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ConnectionManager</serviceId>
+        <SCPDURL>/dlna/cms.xml</SCPDURL>
+        <controlURL>/dlna/control/cms</controlURL>
+        <eventSubURL>/dlna/event/cms</eventSubURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+// contentDirectorySCPD describes the ContentDirectory actions implemented.
+const contentDirectorySCPD = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action><name>Browse</name><argumentList>
+      <argument><name>ObjectID</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_ObjectID</relatedStateVariable></argument>
+      <argument><name>BrowseFlag</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_BrowseFlag</relatedStateVariable></argument>
+      <argument><name>Filter</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Filter</relatedStateVariable></argument>
+      <argument><name>StartingIndex</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Index</relatedStateVariable></argument>
+      <argument><name>RequestedCount</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+      <argument><name>SortCriteria</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_SortCriteria</relatedStateVariable></argument>
+      <argument><name>Result</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Result</relatedStateVariable></argument>
+      <argument><name>NumberReturned</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+      <argument><name>TotalMatches</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+      <argument><name>UpdateID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_UpdateID</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetSearchCapabilities</name><argumentList>
+      <argument><name>SearchCaps</name><direction>out</direction><relatedStateVariable>SearchCapabilities</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetSortCapabilities</name><argumentList>
+      <argument><name>SortCaps</name><direction>out</direction><relatedStateVariable>SortCapabilities</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetSystemUpdateID</name><argumentList>
+      <argument><name>Id</name><direction>out</direction><relatedStateVariable>SystemUpdateID</relatedStateVariable></argument>
+    </argumentList></action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ObjectID</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_BrowseFlag</name><dataType>string</dataType>
+      <allowedValueList><allowedValue>BrowseMetadata</allowedValue><allowedValue>BrowseDirectChildren</allowedValue></allowedValueList></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Filter</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Index</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Count</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_SortCriteria</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Result</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_UpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SearchCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SortCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SystemUpdateID</name><dataType>ui4</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
+
+// connectionManagerSCPD describes the ConnectionManager actions implemented.
+const connectionManagerSCPD = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action><name>GetProtocolInfo</name><argumentList>
+      <argument><name>Source</name><direction>out</direction><relatedStateVariable>SourceProtocolInfo</relatedStateVariable></argument>
+      <argument><name>Sink</name><direction>out</direction><relatedStateVariable>SinkProtocolInfo</relatedStateVariable></argument>
+    </argumentList></action>
+    <action><name>GetCurrentConnectionIDs</name><argumentList>
+      <argument><name>ConnectionIDs</name><direction>out</direction><relatedStateVariable>CurrentConnectionIDs</relatedStateVariable></argument>
+    </argumentList></action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes"><name>SourceProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SinkProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>CurrentConnectionIDs</name><dataType>string</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
@@ -0,0 +1,176 @@
+// Package main provides the doctor diagnostics command for Personal Musician.
+// "personal-musician doctor" checks everything the player depends on: yt-dlp
+// and ffmpeg and their versions, the audio output, whether the search
+// endpoints can be reached, and the music directory's permissions and free
+// space. It prints a pass/fail line per check, so problems can be found
+// without starting the TUI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// doctorTimeout bounds each external command and network request.
+const doctorTimeout = 10 * time.Second
+
+// minFreeSpace is the free space below which downloads are likely to fail.
+const minFreeSpace = 100 << 20 // 100 MiB
+
+// checkResult is the outcome of one diagnostic check.
+type checkResult struct {
+	name   string
+	ok     bool
+	skip   bool   // Not applicable, e.g. no API key configured
+	detail string // Version, path or reason for the failure
+}
+
+// runDoctor runs all checks and prints the report. It fails if any check failed.
+func runDoctor(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: personal-musician doctor")
+	}
+
+	ytDlp := config.Download.YtDlp
+	if ytDlp == "" {
+		ytDlp = "yt-dlp"
+	}
+	checks := []func() checkResult{
+		func() checkResult { return checkTool("yt-dlp", ytDlp, "--version") },
+		func() checkResult { return checkTool("ffmpeg", "ffmpeg", "-version") },
+		func() checkResult { return checkTool("ffprobe", "ffprobe", "-version") },
+		func() checkResult {
+			if config.Providers.AcoustIDKey == "" {
+				return checkResult{name: "fpcalc", skip: true, detail: "no AcoustID API key configured"}
+			}
+			return checkTool("fpcalc", "fpcalc", "-version")
+		},
+		checkAudio,
+		func() checkResult {
+			return checkReachable("YouTube search", "https://www.youtube.com/results?search_query=test", false)
+		},
+		func() checkResult {
+			return checkReachable("YouTube Data API", "https://www.googleapis.com/youtube/v3/search", youtubeAPIKey == "")
+		},
+		checkMusicDir,
+		checkFreeSpace,
+	}
+
+	failed := 0
+	for _, check := range checks {
+		r := check()
+		status := "PASS"
+		switch {
+		case r.skip:
+			status = "SKIP"
+		case !r.ok:
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("[%s] %-18s %s\n", status, r.name, r.detail)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	fmt.Println("All checks passed.")
+	return nil
+}
+
+// checkTool finds a program and reports the first line of its version output.
+func checkTool(name, program, versionFlag string) checkResult {
+	path, ok := findTool(program)
+	if !ok {
+		return checkResult{name: name, detail: "not found; run \"personal-musician setup\""}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, versionFlag).Output()
+	if err != nil {
+		return checkResult{name: name, detail: fmt.Sprintf("%s does not run: %v", path, err)}
+	}
+	version := lastLine(strings.SplitN(string(out), "\n", 2)[0], "unknown version")
+	return checkResult{name: name, ok: true, detail: fmt.Sprintf("%s (%s)", version, path)}
+}
+
+// checkAudio opens and closes the audio output.
+func checkAudio() checkResult {
+	const sampleRate = beep.SampleRate(44100)
+	if err := speaker.Init(sampleRate, sampleRate.N(time.Second/10)); err != nil {
+		return checkResult{name: "audio output", detail: fmt.Sprintf("failed to initialize: %v", err)}
+	}
+	speaker.Close()
+	return checkResult{name: "audio output", ok: true, detail: "initialized"}
+}
+
+// checkReachable requests url and passes on any HTTP response; an error
+// status still shows that the endpoint can be reached.
+func checkReachable(name, url string, skip bool) checkResult {
+	if skip {
+		return checkResult{name: name, skip: true, detail: "no API key configured"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return checkResult{name: name, detail: err.Error()}
+	}
+	start := time.Now()
+	resp, err := httpDo("doctor", req)
+	if err != nil {
+		return checkResult{name: name, detail: fmt.Sprintf("unreachable: %v", err)}
+	}
+	resp.Body.Close()
+	return checkResult{name: name, ok: true, detail: fmt.Sprintf("%s in %s", resp.Status, time.Since(start).Round(time.Millisecond))}
+}
+
+// checkMusicDir checks that the music directory exists or can be created,
+// and that files can be written to it.
+func checkMusicDir() checkResult {
+	const name = "music directory"
+	if err := os.MkdirAll(MusicDir, 0755); err != nil {
+		return checkResult{name: name, detail: fmt.Sprintf("cannot create %s: %v", MusicDir, err)}
+	}
+	f, err := os.CreateTemp(MusicDir, ".doctor-*")
+	if err != nil {
+		return checkResult{name: name, detail: fmt.Sprintf("%s is not writable: %v", MusicDir, err)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return checkResult{name: name, ok: true, detail: MusicDir + " is writable"}
+}
+
+// checkFreeSpace checks that the music directory has room for downloads.
+func checkFreeSpace() checkResult {
+	const name = "free space"
+	free, err := freeSpace(MusicDir)
+	if err != nil {
+		return checkResult{name: name, detail: fmt.Sprintf("cannot determine: %v", err)}
+	}
+	detail := fmt.Sprintf("%s available in %s", formatBytes(free), MusicDir)
+	return checkResult{name: name, ok: free >= minFreeSpace, detail: detail}
+}
+
+// formatBytes formats a size in bytes using binary units, e.g. "1.5 GiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -3,20 +3,31 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Downloader manages YouTube downloads using yt-dlp.
+// Downloads run one at a time; additional downloads wait in a queue.
 type Downloader struct {
-	musicDir string
-	mu       sync.Mutex
+	musicDir  string
+	options   DownloadOptions
+	ffmpegDir string // Directory of a standalone ffmpeg, passed to yt-dlp
+	mu        sync.Mutex
+
+	// Set once yt-dlp has been found, so queueing skips the PATH search
+	ytDlpFound atomic.Bool
 
 	// Current download state
 	downloadedFiles []string
@@ -25,6 +36,53 @@ type Downloader struct {
 	isDownloading   bool
 	cancelFunc      context.CancelFunc
 	cmd             *exec.Cmd
+	worker          bool // Whether a download goroutine is running
+
+	// All downloads of this session, in the order they were added
+	items     []*DownloadItem
+	nextID    int
+	completed int // Number of successfully finished downloads
+}
+
+// DownloadState is the lifecycle state of a download.
+type DownloadState int
+
+const (
+	DownloadQueued    DownloadState = iota // Waiting for another download to finish
+	DownloadActive                         // Currently downloading
+	DownloadDone                           // Finished successfully
+	DownloadFailed                         // yt-dlp reported an error
+	DownloadCancelled                      // Cancelled by the user
+)
+
+// String returns the display name of the download state.
+func (s DownloadState) String() string {
+	switch s {
+	case DownloadActive:
+		return "active"
+	case DownloadDone:
+		return "done"
+	case DownloadFailed:
+		return "failed"
+	case DownloadCancelled:
+		return "cancelled"
+	default:
+		return "queued"
+	}
+}
+
+// DownloadItem is a single download tracked by the Downloader.
+type DownloadItem struct {
+	ID       int
+	VideoID  string
+	Title    string
+	State    DownloadState
+	Progress float64 // Percentage 0-100
+	Speed    string  // Current speed as reported by yt-dlp, e.g. "1.20MiB/s"
+	Error    string  // Failure reason
+	File     string  // Downloaded file path
+
+	ctx context.Context // Parent context the download runs under
 }
 
 // DownloadProgress holds the current download progress information.
@@ -35,9 +93,48 @@ type DownloadProgress struct {
 	Files         []string // List of downloaded file paths
 }
 
+// DownloadSummary aggregates the downloads that are still in flight.
+type DownloadSummary struct {
+	Active   int     // Downloads currently running
+	Queued   int     // Downloads waiting to start
+	Progress float64 // Mean percentage 0-100 over active and queued downloads
+	Speed    float64 // Combined speed in bytes per second
+}
+
+// speedValue matches yt-dlp speeds such as "1.20MiB/s" or "512KiB/s".
+var speedValue = regexp.MustCompile(`^([\d.]+)\s*([KMG]?)(i?)B/s$`)
+
+// progressLine matches yt-dlp progress lines such as
+// "[download]  42.3% of 3.45MiB at 1.20MiB/s ETA 00:02".
+var progressLine = regexp.MustCompile(`\[download\]\s+([\d.]+)%.*?(?:at\s+(\S+))?(?:\s+ETA|$)`)
+
+// DownloadOptions control how yt-dlp is run. Empty fields keep the defaults.
+type DownloadOptions struct {
+	Format  string // Audio format, "mp3" by default
+	Quality string // yt-dlp --audio-quality, "0" (best) by default
+	YtDlp   string // yt-dlp executable, looked up in PATH and the tools directory by default
+	Proxy   string // Proxy URL passed to yt-dlp, none by default
+}
+
+// withDefaults fills in the empty fields of the options.
+func (o DownloadOptions) withDefaults() DownloadOptions {
+	if o.Format == "" {
+		o.Format = "mp3"
+	}
+	if o.Quality == "" {
+		o.Quality = "0"
+	}
+	if o.YtDlp == "" {
+		o.YtDlp = "yt-dlp"
+	}
+	return o
+}
+
 // NewDownloader creates a new Downloader instance.
 // The musicDir is where downloaded audio files will be saved.
-func NewDownloader(musicDir string) (*Downloader, error) {
+func NewDownloader(musicDir string, options DownloadOptions) (*Downloader, error) {
+	options = options.withDefaults()
+
 	// Get absolute path for the music directory
 	absPath, err := filepath.Abs(musicDir)
 	if err != nil {
@@ -49,20 +146,37 @@ func NewDownloader(musicDir string) (*Downloader, error) {
 		return nil, fmt.Errorf("failed to create music directory: %w", err)
 	}
 
-	// Check if yt-dlp is available
-	if _, err := exec.LookPath("yt-dlp"); err != nil {
-		return nil, fmt.Errorf("yt-dlp not found. Please install it: pip install yt-dlp")
+	// Use the standalone builds installed by setup unless PATH has its own
+	if path, ok := findTool(options.YtDlp); ok {
+		options.YtDlp = path
 	}
-
-	return &Downloader{
+	d := &Downloader{
 		musicDir: absPath,
+		options:  options,
 		status:   "Idle",
-	}, nil
+	}
+	if path, ok := findTool("ffmpeg"); ok && filepath.Dir(path) == ToolsDir() {
+		d.ffmpegDir = ToolsDir()
+	}
+	return d, nil
+}
+
+// Available reports whether yt-dlp can be run. Downloads fail with this
+// error until it is installed, while the rest of the player keeps working.
+func (d *Downloader) Available() error {
+	if d.ytDlpFound.Load() {
+		return nil
+	}
+	if _, err := exec.LookPath(d.options.YtDlp); err != nil {
+		return fmt.Errorf("yt-dlp not found; run \"personal-musician setup\" to install it")
+	}
+	d.ytDlpFound.Store(true)
+	return nil
 }
 
 // Close shuts down the downloader gracefully.
 func (d *Downloader) Close() error {
-	d.CancelDownload()
+	d.CancelAll()
 	return nil
 }
 
@@ -70,37 +184,272 @@ func (d *Downloader) Close() error {
 // This method is non-blocking and downloads in the background.
 // Use GetProgress() to monitor the download status.
 func (d *Downloader) DownloadFromYouTube(ctx context.Context, videoID string, title string) error {
+	if err := d.Available(); err != nil {
+		return err
+	}
 	d.mu.Lock()
-	if d.isDownloading {
+	if d.worker {
 		d.mu.Unlock()
 		return fmt.Errorf("a download is already in progress")
 	}
+	item := d.addItemLocked(ctx, videoID, title)
+	d.startLocked(item)
+	d.mu.Unlock()
+
+	return nil
+}
+
+// QueueDownload starts a download, or queues it if another download is running.
+// Queued downloads start automatically one after another.
+func (d *Downloader) QueueDownload(ctx context.Context, videoID string, title string) error {
+	if err := d.Available(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item := d.addItemLocked(ctx, videoID, title)
+	if !d.worker {
+		d.startLocked(item)
+	}
+	return nil
+}
+
+// addItemLocked records a new queued download. Caller must hold d.mu.
+func (d *Downloader) addItemLocked(ctx context.Context, videoID, title string) *DownloadItem {
+	d.nextID++
+	item := &DownloadItem{ID: d.nextID, VideoID: videoID, Title: title, State: DownloadQueued, ctx: ctx}
+	d.items = append(d.items, item)
+	return item
+}
+
+// startLocked starts a queued download in the background. Caller must hold d.mu.
+func (d *Downloader) startLocked(item *DownloadItem) {
+	item.State = DownloadActive
+	item.Progress = 0
+	item.Speed = ""
+	item.Error = ""
+
+	d.worker = true
 	d.isDownloading = true
 	d.progress = 0
 	d.status = "Starting download..."
 	d.downloadedFiles = nil
 
 	// Create cancellable context
-	downloadCtx, cancel := context.WithCancel(ctx)
+	downloadCtx, cancel := context.WithCancel(item.ctx)
 	d.cancelFunc = cancel
-	d.mu.Unlock()
 
 	// Start the download in a goroutine
-	go d.downloadVideo(downloadCtx, videoID, title)
+	go d.downloadVideo(downloadCtx, item)
+}
+
+// PendingCount returns the number of queued downloads.
+func (d *Downloader) PendingCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	count := 0
+	for _, item := range d.items {
+		if item.State == DownloadQueued {
+			count++
+		}
+	}
+	return count
+}
+
+// CompletedCount returns how many downloads finished successfully this session.
+func (d *Downloader) CompletedCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.completed
+}
+
+// Downloads returns a snapshot of all downloads of this session.
+func (d *Downloader) Downloads() []DownloadItem {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	items := make([]DownloadItem, len(d.items))
+	for i, item := range d.items {
+		items[i] = *item
+	}
+	return items
+}
 
+// Summary aggregates the active and queued downloads.
+func (d *Downloader) Summary() DownloadSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var s DownloadSummary
+	var total float64
+	for _, item := range d.items {
+		switch item.State {
+		case DownloadActive:
+			s.Active++
+			total += item.Progress
+			s.Speed += parseSpeed(item.Speed)
+		case DownloadQueued:
+			s.Queued++
+		}
+	}
+	if n := s.Active + s.Queued; n > 0 {
+		s.Progress = total / float64(n)
+	}
+	return s
+}
+
+// parseSpeed converts a yt-dlp speed string to bytes per second.
+// Unknown or missing speeds count as zero.
+func parseSpeed(speed string) float64 {
+	match := speedValue.FindStringSubmatch(strings.TrimSpace(speed))
+	if match == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+
+	base := 1000.0
+	if match[3] == "i" {
+		base = 1024
+	}
+	switch match[2] {
+	case "K":
+		value *= base
+	case "M":
+		value *= base * base
+	case "G":
+		value *= base * base * base
+	}
+	return value
+}
+
+// Cancel cancels a queued or active download by ID.
+func (d *Downloader) Cancel(id int) error {
+	d.mu.Lock()
+	item := d.findLocked(id)
+	if item == nil {
+		d.mu.Unlock()
+		return fmt.Errorf("download not found")
+	}
+
+	switch item.State {
+	case DownloadQueued:
+		item.State = DownloadCancelled
+		d.mu.Unlock()
+		return nil
+	case DownloadActive:
+		d.mu.Unlock()
+		d.CancelDownload()
+		return nil
+	default:
+		d.mu.Unlock()
+		return fmt.Errorf("download already finished")
+	}
+}
+
+// Retry queues a failed or cancelled download again.
+func (d *Downloader) Retry(id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item := d.findLocked(id)
+	if item == nil {
+		return fmt.Errorf("download not found")
+	}
+	if item.State != DownloadFailed && item.State != DownloadCancelled {
+		return fmt.Errorf("only failed or cancelled downloads can be retried")
+	}
+
+	item.State = DownloadQueued
+	item.Error = ""
+	if item.ctx.Err() != nil {
+		item.ctx = context.Background()
+	}
+	if !d.worker {
+		d.startLocked(item)
+	}
 	return nil
 }
 
+// CancelAll cancels every queued download and the active one.
+func (d *Downloader) CancelAll() {
+	d.mu.Lock()
+	for _, item := range d.items {
+		if item.State == DownloadQueued {
+			item.State = DownloadCancelled
+		}
+	}
+	d.mu.Unlock()
+
+	d.CancelDownload()
+}
+
+// ClearFinished removes completed, failed and cancelled downloads from the list.
+func (d *Downloader) ClearFinished() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var kept []*DownloadItem
+	for _, item := range d.items {
+		if item.State == DownloadQueued || item.State == DownloadActive {
+			kept = append(kept, item)
+		}
+	}
+	d.items = kept
+}
+
+// findLocked returns the download with the given ID. Caller must hold d.mu.
+func (d *Downloader) findLocked(id int) *DownloadItem {
+	for _, item := range d.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// startNextPending starts the next queued download, if any.
+func (d *Downloader) startNextPending() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.worker {
+		return
+	}
+	for _, item := range d.items {
+		if item.State != DownloadQueued {
+			continue
+		}
+		if item.ctx.Err() != nil {
+			item.State = DownloadCancelled
+			continue
+		}
+		d.startLocked(item)
+		return
+	}
+}
+
 // downloadVideo handles the actual download process using yt-dlp.
-func (d *Downloader) downloadVideo(ctx context.Context, videoID string, title string) {
+func (d *Downloader) downloadVideo(ctx context.Context, item *DownloadItem) {
 	defer func() {
 		d.mu.Lock()
+		d.worker = false
 		d.isDownloading = false
 		d.cancelFunc = nil
 		d.cmd = nil
 		d.mu.Unlock()
+
+		d.startNextPending()
 	}()
 
+	d.mu.Lock()
+	videoID, title := item.VideoID, item.Title
+	d.mu.Unlock()
+
 	// Create safe filename
 	safeTitle := sanitizeFilename(title)
 	if safeTitle == "" {
@@ -112,42 +461,58 @@ func (d *Downloader) downloadVideo(ctx context.Context, videoID string, title st
 
 	d.setStatus("Downloading with yt-dlp...", true)
 
-	// Use yt-dlp to download audio and convert to mp3
-	cmd := exec.CommandContext(ctx, "yt-dlp",
-		"-x",                    // Extract audio
-		"--audio-format", "mp3", // Convert to MP3
-		"--audio-quality", "0",  // Best quality
-		"-o", outputPath,        // Output path template
-		"--no-playlist",         // Don't download playlists
-		"--quiet",               // Less output
-		"--progress",            // Show progress
+	// Use yt-dlp to download audio and convert it to the configured format
+	cmd := exec.CommandContext(ctx, d.options.YtDlp, d.ytDlpArgs(
+		"-x",                               // Extract audio
+		"--audio-format", d.options.Format, // Convert to MP3 by default
+		"--audio-quality", d.options.Quality, // Best quality by default
+		"-o", outputPath, // Output path template
+		"--no-playlist",                 // Don't download playlists
+		"--quiet",                       // Less output
+		"--progress",                    // Show progress
+		"--newline",                     // One progress update per line
+		"--print", downloadMetaTemplate, // Channel and upload date, for the tags
 		videoURL,
-	)
+	)...)
 
-	d.mu.Lock()
-	d.cmd = cmd
-	d.mu.Unlock()
+	slog.Info("yt-dlp", "args", cmd.Args)
+
+	// Read progress from stdout while collecting errors from stderr
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		d.mu.Lock()
+		d.cmd = cmd
+		d.mu.Unlock()
+		err = cmd.Start()
+	}
+	var meta videoMeta
+	if err == nil {
+		meta = d.readProgress(item, stdout)
+		err = cmd.Wait()
+	}
+	output := stderr.String()
 
-	// Capture output for progress
-	output, err := cmd.CombinedOutput()
-	
 	if ctx.Err() != nil {
+		d.finish(item, DownloadCancelled, "", "")
 		d.setStatus("Download cancelled", false)
 		return
 	}
 
 	if err != nil {
+		d.finish(item, DownloadFailed, "", lastLine(output, err.Error()))
 		d.setStatus(fmt.Sprintf("Download failed: %v", err), false)
-		// Log the output for debugging
-		if len(output) > 0 {
-			fmt.Printf("yt-dlp output: %s\n", string(output))
-		}
+		// Keep the output in the in-app log for debugging
+		appLog.Add("error", fmt.Sprintf("download of %q failed: %v", title, err))
+		appLog.Add("yt-dlp", output)
+		slog.Error("yt-dlp failed", "video", videoID, "err", err, "stderr", output)
 		return
 	}
 
 	// Find the downloaded file
-	mp3Path := filepath.Join(d.musicDir, safeTitle+".mp3")
-	
+	mp3Path := filepath.Join(d.musicDir, safeTitle+"."+d.options.Format)
+
 	// Check if file exists
 	if _, err := os.Stat(mp3Path); os.IsNotExist(err) {
 		// Try to find any file that matches the pattern
@@ -155,12 +520,22 @@ func (d *Downloader) downloadVideo(ctx context.Context, videoID string, title st
 		if len(matches) > 0 {
 			mp3Path = matches[0]
 		} else {
+			appLog.Add("error", fmt.Sprintf("download of %q finished but no file was found", title))
+			d.finish(item, DownloadFailed, "", "file not found after download")
 			d.setStatus("Download completed but file not found", false)
 			return
 		}
 	}
 
+	// Tag the file before the library picks it up
+	if err := tagDownload(ctx, mp3Path, videoID, title, meta); err != nil {
+		appLog.Add("error", fmt.Sprintf("failed to tag %q: %v", title, err))
+		slog.Warn("failed to tag download", "file", mp3Path, "err", err)
+	}
+
 	// Success!
+	slog.Info("download finished", "video", videoID, "file", mp3Path)
+	d.finish(item, DownloadDone, mp3Path, "")
 	d.mu.Lock()
 	d.downloadedFiles = []string{mp3Path}
 	d.progress = 100
@@ -169,21 +544,74 @@ func (d *Downloader) downloadVideo(ctx context.Context, videoID string, title st
 	d.mu.Unlock()
 }
 
+// readProgress parses yt-dlp progress lines and updates the item's
+// progress. It returns the video details printed at the end.
+func (d *Downloader) readProgress(item *DownloadItem, r io.Reader) videoMeta {
+	var meta videoMeta
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if m, ok := parseVideoMeta(scanner.Text()); ok {
+			meta = m
+			continue
+		}
+		match := progressLine.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			appLog.Add("yt-dlp", scanner.Text())
+			continue
+		}
+		pct, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+
+		d.mu.Lock()
+		item.Progress = pct
+		item.Speed = match[2]
+		d.progress = pct
+		d.mu.Unlock()
+	}
+	return meta
+}
+
+// finish records the final state of a download.
+func (d *Downloader) finish(item *DownloadItem, state DownloadState, file, reason string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item.State = state
+	item.File = file
+	item.Error = reason
+	item.Speed = ""
+	if state == DownloadDone {
+		item.Progress = 100
+		d.completed++
+	}
+}
+
+// lastLine returns the last non-empty line of output, or fallback if there is none.
+func lastLine(output, fallback string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return fallback
+}
+
 // sanitizeFilename removes invalid characters from a filename.
 func sanitizeFilename(name string) string {
 	// Remove or replace invalid characters
 	re := regexp.MustCompile(`[<>:"/\\|?*]`)
 	safe := re.ReplaceAllString(name, "")
-	
+
 	// Trim spaces and dots
 	safe = strings.TrimSpace(safe)
 	safe = strings.Trim(safe, ".")
-	
+
 	// Limit length
 	if len(safe) > 100 {
 		safe = safe[:100]
 	}
-	
+
 	return safe
 }
 
@@ -228,3 +656,38 @@ func (d *Downloader) IsDownloading() bool {
 	defer d.mu.Unlock()
 	return d.isDownloading
 }
+
+// FetchTitle asks yt-dlp for the title of a video without downloading it.
+func (d *Downloader) FetchTitle(ctx context.Context, videoID string) (string, error) {
+	if err := d.Available(); err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, d.options.YtDlp, d.ytDlpArgs(
+		"--get-title",
+		"--no-playlist",
+		"--skip-download",
+		GetYouTubeURL(videoID),
+	)...)
+	slog.Info("yt-dlp", "args", cmd.Args)
+	out, err := cmd.Output()
+	if err != nil {
+		slog.Error("yt-dlp failed", "video", videoID, "err", err)
+		return "", fmt.Errorf("failed to get video title: %w", err)
+	}
+	title := strings.TrimSpace(string(out))
+	if title == "" {
+		return "", fmt.Errorf("failed to get video title: empty response")
+	}
+	return title, nil
+}
+
+// ytDlpArgs adds the options shared by every yt-dlp invocation to args.
+func (d *Downloader) ytDlpArgs(args ...string) []string {
+	if d.options.Proxy != "" {
+		args = append([]string{"--proxy", d.options.Proxy}, args...)
+	}
+	if d.ffmpegDir != "" {
+		args = append([]string{"--ffmpeg-location", d.ffmpegDir}, args...)
+	}
+	return args
+}
@@ -0,0 +1,125 @@
+// Package main provides the Downloads view for Personal Musician.
+// It lists queued, active, completed and failed downloads with
+// per-item cancel and retry.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleDownloadsKeys handles keys in the downloads view.
+func (m Model) handleDownloadsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	items := m.downloader.Downloads()
+	if cursor, ok := m.navigateList(msg.String(), m.downloadsCursor, len(items)); ok {
+		m.downloadsCursor = cursor
+		return m, nil
+	}
+	if m.downloadsCursor >= len(items) {
+		return m, nil
+	}
+	item := items[m.downloadsCursor]
+
+	switch msg.String() {
+	case "x", "delete": // Cancel
+		if err := m.downloader.Cancel(item.ID); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		return m, func() tea.Msg { return statusMsg(Tf("Cancelled: %s", item.Title)) }
+	case "r": // Retry
+		if err := m.downloader.Retry(item.ID); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		return m, tea.Batch(
+			m.downloadSpinner.Tick,
+			func() tea.Msg { return statusMsg(Tf("Retrying: %s", item.Title)) },
+		)
+	case "C": // Clear finished
+		m.downloader.ClearFinished()
+		m.downloadsCursor = 0
+	}
+	return m, nil
+}
+
+// renderDownloadsView renders the list of downloads.
+func (m Model) renderDownloadsView() string {
+	var b strings.Builder
+
+	// The backup's state shares the header line, keeping the list in place
+	header := headerStyle.Render(" ⇩ " + T("Downloads") + " ")
+	if backup := m.renderBackupStatus(); backup != "" {
+		header += "  " + backup
+	}
+	b.WriteString(header + "\n\n")
+
+	items := m.downloader.Downloads()
+	if len(items) == 0 {
+		b.WriteString(mutedStyle.Render(T("No downloads yet") + "\n"))
+		b.WriteString(mutedStyle.Render(T("Press 's' to search, then enter on a result to download it") + "\n"))
+		return b.String()
+	}
+
+	b.WriteString(m.downloadsScroll.render(len(items), 1, m.maxVisible(), func(i int) string {
+		line := renderDownloadItem(items[i])
+		if i == m.downloadsCursor {
+			return selectedStyle.Render("> ") + line
+		}
+		return "  " + line
+	}))
+
+	return b.String()
+}
+
+// renderDownloadSummary renders the compact header widget for downloads in
+// flight, e.g. "⇩ 3 active · 42% · 1.2 MB/s". It is empty when idle.
+func (m Model) renderDownloadSummary() string {
+	s := m.downloader.Summary()
+	if s.Active+s.Queued == 0 {
+		return ""
+	}
+
+	parts := []string{m.downloadSpinner.View() + " ⇩ " + Tf("%d active", s.Active)}
+	if s.Queued > 0 {
+		parts = append(parts, Tf("%d queued", s.Queued))
+	}
+	parts = append(parts, fmt.Sprintf("%.0f%%", s.Progress))
+	if s.Speed > 0 {
+		parts = append(parts, formatSpeed(s.Speed))
+	}
+	return nowPlayingStyle.Render(strings.Join(parts, " · "))
+}
+
+// formatSpeed formats bytes per second as e.g. "1.2 MB/s".
+func formatSpeed(bytesPerSec float64) string {
+	units := []string{"B/s", "KB/s", "MB/s", "GB/s"}
+	i := 0
+	for bytesPerSec >= 1000 && i < len(units)-1 {
+		bytesPerSec /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", bytesPerSec, units[i])
+}
+
+// renderDownloadItem renders a single download row.
+func renderDownloadItem(item DownloadItem) string {
+	switch item.State {
+	case DownloadActive:
+		filled := int(item.Progress / 100 * progressBarWidth)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+		info := fmt.Sprintf("%s %3.0f%%", bar, item.Progress)
+		if item.Speed != "" {
+			info += " " + item.Speed
+		}
+		return nowPlayingStyle.Render("⇩ "+item.Title) + "  " + mutedStyle.Render(info)
+	case DownloadDone:
+		return statusStyle.Render("✓ ") + normalStyle.Render(item.Title)
+	case DownloadFailed:
+		return normalStyle.Render("✗ "+item.Title) + "  " + mutedStyle.Render(item.Error)
+	case DownloadCancelled:
+		return mutedStyle.Render("⊘ " + item.Title + "  " + T("(cancelled)"))
+	default:
+		return mutedStyle.Render("… " + item.Title + "  " + T("(queued)"))
+	}
+}
@@ -0,0 +1,72 @@
+// Package main provides tagging of downloads for Personal Musician.
+// A downloaded MP3 is given ID3 tags as soon as yt-dlp has finished: the
+// video title as the title, the channel as the artist, the upload year,
+// the video's URL as a comment and its thumbnail as the cover, so it shows
+// up properly here and in other players instead of as a bare file name.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// downloadMetaPrefix marks the line yt-dlp prints with the video's details.
+const downloadMetaPrefix = "PM-META\t"
+
+// downloadMetaTemplate is the --print template of that line, printed once
+// the file is in place.
+const downloadMetaTemplate = "after_move:" + downloadMetaPrefix + "%(channel)s\t%(upload_date)s"
+
+// videoMeta holds the details of a downloaded video.
+type videoMeta struct {
+	Channel    string
+	UploadDate string // YYYYMMDD
+}
+
+// parseVideoMeta reads a line printed with downloadMetaTemplate. yt-dlp
+// writes "NA" for fields it doesn't know.
+func parseVideoMeta(line string) (videoMeta, bool) {
+	rest, ok := strings.CutPrefix(line, downloadMetaPrefix)
+	if !ok {
+		return videoMeta{}, false
+	}
+	fields := strings.Split(rest, "\t")
+	for i, f := range fields {
+		if f == "NA" {
+			fields[i] = ""
+		}
+	}
+	meta := videoMeta{Channel: fields[0]}
+	if len(fields) > 1 {
+		meta.UploadDate = fields[1]
+	}
+	return meta, true
+}
+
+// downloadCoverURL returns the URL of a video's large JPEG thumbnail.
+func downloadCoverURL(videoID string) string {
+	return fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", videoID)
+}
+
+// tagDownload writes the tags of a downloaded MP3 file. A cover that can't
+// be fetched is left out.
+func tagDownload(ctx context.Context, path, videoID, title string, meta videoMeta) error {
+	if !isMP3(path) {
+		return nil // Only MP3 tags can be written
+	}
+	tags := Tags{
+		Title:   title,
+		Artist:  meta.Channel,
+		Year:    releaseYear(meta.UploadDate),
+		Comment: GetYouTubeURL(videoID),
+	}
+
+	cover, err := httpGet(ctx, "thumbnail", downloadCoverURL(videoID), nil)
+	if err != nil {
+		slog.Warn("failed to fetch cover", "video", videoID, "err", err)
+		return WriteTags(path, tags)
+	}
+	return WriteTagsWithCover(path, tags, cover)
+}
@@ -0,0 +1,368 @@
+// Package main provides the duplicate finder of Personal Musician.
+// Downloading a video twice, or the same song from two uploads, leaves
+// copies in the library. Copies are found by the audio hash kept in the
+// library index, which ignores tags, and by titles that are the same once
+// video decorations and copy numbers are dropped, provided the durations
+// agree. The Duplicates view lists them in groups, best copy first, and
+// deletes single copies or merges a group into one copy: the others are
+// deleted and their play counts, ratings and playlist entries move to it.
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// duplicateDurationSlack is how far apart the durations of two songs with
+// the same title may be for them to count as copies.
+const duplicateDurationSlack = 5 * time.Second
+
+// copySuffix matches what file managers and downloaders append to the
+// names of copies, such as " (1)" or " - Copy".
+var copySuffix = regexp.MustCompile(`(?i)(\s*\(\d+\)|\s*-?\s*copy(\s*\d+)?)$`)
+
+// duplicateRow is a file in the Duplicates view.
+type duplicateRow struct {
+	file      MusicFile
+	group     int  // Index of the group, counted from 0
+	first     bool // First, and best, copy of its group
+	last      bool // Last copy of its group
+	sameAudio bool // Every copy in the group has the same audio
+}
+
+// duplicateTitleKey returns the title a file is compared by: its display
+// name without video decorations and copy numbers, in lower case and with
+// only letters and digits. It is empty when nothing is left.
+func duplicateTitleKey(file MusicFile) string {
+	name := copySuffix.ReplaceAllString(file.DisplayName(), "")
+	name = titleNoise.ReplaceAllString(name, "")
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// similarDuration reports whether two files play about as long, or either
+// duration is not known yet.
+func similarDuration(a, b MusicFile) bool {
+	if a.Duration == 0 || b.Duration == 0 {
+		return true
+	}
+	return (a.Duration - b.Duration).Abs() <= duplicateDurationSlack
+}
+
+// findDuplicates groups the copies of songs among files. Each group lists
+// the copy most worth keeping first: the most played, then the one with
+// tags, then the largest, then the oldest. Groups are ordered by name.
+func findDuplicates(files []MusicFile, stats *StatsStore) []duplicateRow {
+	// Union-find over the files; copies end up with the same root
+	parent := make([]int, len(files))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) { parent[root(i)] = root(j) }
+
+	byHash := make(map[string]int)
+	byTitle := make(map[string][]int)
+	for i, file := range files {
+		if file.Hash != "" {
+			if j, ok := byHash[file.Hash]; ok {
+				union(i, j)
+			} else {
+				byHash[file.Hash] = i
+			}
+		}
+		if key := duplicateTitleKey(file); key != "" {
+			for _, j := range byTitle[key] {
+				if similarDuration(file, files[j]) {
+					union(i, j)
+				}
+			}
+			byTitle[key] = append(byTitle[key], i)
+		}
+	}
+
+	members := make(map[int][]MusicFile)
+	for i, file := range files {
+		members[root(i)] = append(members[root(i)], file)
+	}
+	var groups [][]MusicFile
+	for _, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+
+	better := func(a, b MusicFile) int {
+		sa, sb := stats.Get(a.Path), stats.Get(b.Path)
+		if c := cmp.Compare(sb.PlayCount, sa.PlayCount); c != 0 {
+			return c
+		}
+		if ta, tb := a.Tags.Title != "", b.Tags.Title != ""; ta != tb {
+			if ta {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(b.Size, a.Size); c != 0 {
+			return c
+		}
+		return a.ModTime.Compare(b.ModTime)
+	}
+	for _, group := range groups {
+		slices.SortFunc(group, better)
+	}
+	slices.SortFunc(groups, func(a, b []MusicFile) int {
+		return cmp.Compare(strings.ToLower(a[0].DisplayName()), strings.ToLower(b[0].DisplayName()))
+	})
+
+	var rows []duplicateRow
+	for g, group := range groups {
+		sameAudio := true
+		for _, file := range group {
+			sameAudio = sameAudio && file.Hash != "" && file.Hash == group[0].Hash
+		}
+		for i, file := range group {
+			rows = append(rows, duplicateRow{file: file, group: g, first: i == 0, last: i == len(group)-1, sameAudio: sameAudio})
+		}
+	}
+	return rows
+}
+
+// openDuplicates shows the Duplicates view for the library as it is now.
+func (m Model) openDuplicates() (tea.Model, tea.Cmd) {
+	m.currentView = ViewDuplicates
+	m.duplicatesCursor = 0
+	m = m.refreshDuplicates()
+	if m.metadataScan != nil {
+		return m, func() tea.Msg { return statusMsg(T("Still reading tags; more duplicates may show up")) }
+	}
+	return m, nil
+}
+
+// refreshDuplicates finds the duplicates again, keeping the cursor on the
+// same file where possible.
+func (m Model) refreshDuplicates() Model {
+	var selected string
+	if m.duplicatesCursor < len(m.duplicates) {
+		selected = m.duplicates[m.duplicatesCursor].file.Path
+	}
+	m.duplicates = findDuplicates(m.libraryFiles, m.stats)
+	if i := slices.IndexFunc(m.duplicates, func(r duplicateRow) bool { return r.file.Path == selected }); i >= 0 {
+		m.duplicatesCursor = i
+	}
+	m.duplicatesCursor = max(min(m.duplicatesCursor, len(m.duplicates)-1), 0)
+	return m
+}
+
+// duplicateGroup returns the rows of the group the row at i belongs to.
+func (m Model) duplicateGroup(i int) []duplicateRow {
+	var group []duplicateRow
+	for _, row := range m.duplicates {
+		if row.group == m.duplicates[i].group {
+			group = append(group, row)
+		}
+	}
+	return group
+}
+
+// duplicatesMergedMsg reports the outcome of merging copies.
+type duplicatesMergedMsg struct {
+	merged int   // Copies deleted
+	err    error // Last failure, if any
+}
+
+// mergeDuplicates returns a command that merges copies: for each file to
+// keep, the listed copies are moved to the trash, as one delete, and their
+// statistics and playlist entries are moved to it. The library is rescanned
+// afterwards.
+func (m Model) mergeDuplicates(merges map[string][]string) tea.Cmd {
+	current := m.player.GetState().CurrentFile
+	for _, copies := range merges {
+		if slices.Contains(copies, current) {
+			m.player.Stop()
+		}
+	}
+
+	stats, library := m.stats, m.library
+	return func() tea.Msg {
+		var msg duplicatesMergedMsg
+		var all []string
+		for _, copies := range merges {
+			all = append(all, copies...)
+		}
+		trashed, err := TrashMusicFiles(all)
+		if err != nil {
+			msg.err = err
+		}
+
+		moved := make(map[string]string)
+		for keep, copies := range merges {
+			var deleted []string
+			for _, path := range copies {
+				if slices.Contains(trashed, path) {
+					deleted = append(deleted, path)
+					moved[path] = keep
+				}
+			}
+			if err := stats.Merge(keep, deleted); err != nil {
+				msg.err = err
+			}
+			msg.merged += len(deleted)
+		}
+		if _, err := RepointPlaylists(moved); err != nil {
+			msg.err = fmt.Errorf("failed to update playlists: %w", err)
+		}
+		library.Refresh()
+		return msg
+	}
+}
+
+// applyDuplicatesMerged reports merged copies in the status line.
+func (m Model) applyDuplicatesMerged(msg duplicatesMergedMsg) (Model, tea.Cmd) {
+	status := Tf("Merged %d copies", msg.merged)
+	if msg.err != nil {
+		status = Tf("Merged %d copies, error: %v", msg.merged, msg.err)
+	}
+	return m, func() tea.Msg { return statusMsg(status) }
+}
+
+// handleDuplicatesKeys handles keys in the Duplicates view.
+func (m Model) handleDuplicatesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if cursor, ok := m.navigateList(msg.String(), m.duplicatesCursor, len(m.duplicates)); ok {
+		m.duplicatesCursor = cursor
+		return m, nil
+	}
+
+	if msg.String() == "D" && len(m.duplicates) > 0 { // Merge every group into its first copy
+		merges := make(map[string][]string)
+		keep := ""
+		for _, row := range m.duplicates {
+			if row.first {
+				keep = row.file.Path
+				continue
+			}
+			merges[keep] = append(merges[keep], row.file.Path)
+		}
+		copies := len(m.duplicates) - len(merges)
+		return m.openDialog(NewConfirmDialog(T("Merge all duplicates"),
+			Tf("Keep the first copy of each of %d songs and delete the other %d copies? Their play counts, ratings and playlist entries move to the copy that is kept.", len(merges), copies),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				return m, m.mergeDuplicates(merges)
+			}))
+	}
+
+	if m.duplicatesCursor >= len(m.duplicates) {
+		return m, nil
+	}
+	row := m.duplicates[m.duplicatesCursor]
+
+	switch msg.String() {
+	case "enter": // Play this copy
+		if err := m.player.PlayTrack(row.file); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		return m, nil
+	case "m": // Context menu
+		return m.openContextMenu()
+	case "x", "delete": // Delete this copy
+		return m.openDialog(NewConfirmDialog(T("Delete"),
+			Tf("Move %q to the trash?", row.file.FileName),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				if m.player.GetState().CurrentFile == row.file.Path {
+					m.player.Stop()
+				}
+				return m, m.library.Delete([]MusicFile{row.file})
+			}))
+	case "K": // Keep this copy and merge the others into it
+		var copies []string
+		for _, other := range m.duplicateGroup(m.duplicatesCursor) {
+			if other.file.Path != row.file.Path {
+				copies = append(copies, other.file.Path)
+			}
+		}
+		return m.openDialog(NewConfirmDialog(T("Merge copies"),
+			Tf("Keep %q and delete the other %d copies? Their play counts, ratings and playlist entries move to it.", row.file.FileName, len(copies)),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				return m, m.mergeDuplicates(map[string][]string{row.file.Path: copies})
+			}))
+	}
+	return m, nil
+}
+
+// renderDuplicatesView renders the groups of copies, each bracketed on the
+// left, with the file name, duration, size and play count of each copy.
+func (m Model) renderDuplicatesView() string {
+	var b strings.Builder
+
+	groups := 0
+	if len(m.duplicates) > 0 {
+		groups = m.duplicates[len(m.duplicates)-1].group + 1
+	}
+	b.WriteString(headerStyle.Render(" ⧉ "+T("Duplicates")+" ") + " " + mutedStyle.Render(Tf("%d songs with copies", groups)) + "\n\n")
+
+	if len(m.duplicates) == 0 {
+		b.WriteString(mutedStyle.Render(T("No duplicates found") + "\n"))
+		return b.String()
+	}
+
+	width := m.width - 4
+	if m.isWide() {
+		width = m.leftPaneWidth() - 6
+	}
+
+	b.WriteString(m.duplicatesScroll.render(len(m.duplicates), 1, m.maxVisible(), func(i int) string {
+		row := m.duplicates[i]
+		bracket := "│ "
+		switch {
+		case row.first:
+			bracket = "╭ "
+		case row.last:
+			bracket = "╰ "
+		}
+
+		details := []string{row.file.FileName}
+		if row.file.Duration > 0 {
+			details = append(details, FormatDuration(row.file.Duration))
+		}
+		if row.file.Size > 0 {
+			details = append(details, formatBytes(uint64(row.file.Size)))
+		}
+		if plays := m.stats.Get(row.file.Path).PlayCount; plays > 0 {
+			details = append(details, fmt.Sprintf("%d×", plays))
+		}
+		if row.first {
+			reason := T("similar titles")
+			if row.sameAudio {
+				reason = T("same audio")
+			}
+			details = append(details, reason)
+		}
+		detail := mutedStyle.Render("  " + strings.Join(details, " · "))
+
+		name := truncate(row.file.DisplayName(), max(width/2, 10))
+		if i == m.duplicatesCursor {
+			return mutedStyle.Render(bracket) + selectedStyle.Render("> "+name) + detail
+		}
+		return mutedStyle.Render(bracket) + normalStyle.Render("  "+name) + detail
+	}))
+
+	return b.String()
+}
@@ -0,0 +1,307 @@
+// Package main provides episode progress for Personal Musician.
+// Podcast episodes, audiobooks and other long files (20 minutes or more by
+// default) remember where playback stopped, each on its own, and continue
+// from there when played again. An episode that plays to the end, or is
+// stopped within its last seconds, is marked played; the library shows
+// each episode's progress and the mark can be changed from the track menu.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Episode progress tuning.
+const (
+	defaultLongTrack     = 20 * time.Minute
+	episodeCheckInterval = time.Second      // How often the position is recorded
+	episodeSaveInterval  = 10 * time.Second // How often changes are written to disk
+	episodeEndMargin     = 30 * time.Second // Stopping this close to the end finishes an episode
+	episodeRewind        = 5 * time.Second  // Replayed when resuming, to pick up the thread
+	episodeReplayAfter   = episodeEndMargin // Playing a played episode this far unmarks it
+)
+
+// EpisodesFile is where episode progress is stored.
+var EpisodesFile = dataPath("episodes.json")
+
+// EpisodeProgress is how far an episode has been listened to.
+type EpisodeProgress struct {
+	Position float64   `json:"position,omitempty"` // Seconds; 0 when played
+	Duration float64   `json:"duration"`           // Seconds
+	Played   bool      `json:"played,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Percent returns the share listened to, from 0 to 100.
+func (e EpisodeProgress) Percent() int {
+	if e.Played {
+		return 100
+	}
+	if e.Duration <= 0 {
+		return 0
+	}
+	return min(int(e.Position*100/e.Duration), 99)
+}
+
+// EpisodeStore keeps episode progress keyed by file path and persists it.
+type EpisodeStore struct {
+	mu        sync.Mutex
+	path      string
+	minLength time.Duration // Shortest file tracked; 0 disables
+	episodes  map[string]EpisodeProgress
+	replaying map[string]bool // Played episodes started again from the beginning
+	dirty     bool            // Changed since the last save
+}
+
+// longTrackLength returns the shortest file whose progress is kept, from
+// long_track under [library]. 0 disables progress tracking.
+func longTrackLength() time.Duration {
+	if config.Library.LongTrack == "" {
+		return defaultLongTrack
+	}
+	// The config file was validated when loaded
+	d, _ := time.ParseDuration(config.Library.LongTrack)
+	return d
+}
+
+// LoadEpisodes reads the progress file. A missing file yields an empty
+// store.
+func LoadEpisodes(path string, minLength time.Duration) (*EpisodeStore, error) {
+	s := &EpisodeStore{
+		path:      path,
+		minLength: minLength,
+		episodes:  make(map[string]EpisodeProgress),
+		replaying: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read episode progress: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.episodes); err != nil {
+		return s, fmt.Errorf("failed to parse episode progress: %w", err)
+	}
+	return s, nil
+}
+
+// IsLong reports whether a file of the given duration is tracked as an
+// episode.
+func (s *EpisodeStore) IsLong(duration time.Duration) bool {
+	return s.minLength > 0 && duration >= s.minLength
+}
+
+// Get returns the progress of an episode, if it has been played.
+func (s *EpisodeStore) Get(path string) (EpisodeProgress, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.episodes[path]
+	return e, ok
+}
+
+// ResumePosition returns where an episode continues: a little before where
+// it was left, or the beginning when it is new or was played to the end.
+func (s *EpisodeStore) ResumePosition(path string, duration time.Duration) time.Duration {
+	if !s.IsLong(duration) {
+		return 0
+	}
+	e, ok := s.Get(path)
+	if !ok || e.Played {
+		return 0
+	}
+	return max(time.Duration(e.Position*float64(time.Second))-episodeRewind, 0)
+}
+
+// Update records the position of a playing episode. Reaching the last
+// seconds marks it played.
+func (s *EpisodeStore) Update(path string, position, duration time.Duration) {
+	if !s.IsLong(duration) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.episodes[path]
+	switch {
+	case position >= duration-episodeEndMargin:
+		if e.Played {
+			return
+		}
+		e.Played, e.Position = true, 0
+	case e.Played && position < episodeReplayAfter:
+		s.replaying[path] = true // Still played until it gets going
+		return
+	case e.Played && !s.replaying[path]:
+		return // Marked played while playing
+	default:
+		delete(s.replaying, path)
+		if !e.Played && e.Position == position.Seconds() {
+			return
+		}
+		e.Played, e.Position = false, position.Seconds()
+	}
+	e.Duration = duration.Seconds()
+	e.Updated = time.Now()
+	s.episodes[path] = e
+	s.dirty = true
+}
+
+// Finished marks an episode that played to the end.
+func (s *EpisodeStore) Finished(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.episodes[path]
+	if !ok || e.Played {
+		return // Not an episode, or already marked
+	}
+	e.Played, e.Position, e.Updated = true, 0, time.Now()
+	s.episodes[path] = e
+	s.dirty = true
+}
+
+// Move keeps the progress of renamed or moved episodes under their new
+// paths, given by old path in moved, and saves the store.
+func (s *EpisodeStore) Move(moved map[string]string) error {
+	s.mu.Lock()
+	for from, to := range moved {
+		if e, ok := s.episodes[from]; ok {
+			delete(s.episodes, from)
+			s.episodes[to] = e
+			s.dirty = true
+		}
+		if s.replaying[from] {
+			delete(s.replaying, from)
+			s.replaying[to] = true
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// SetPlayed marks an episode played or unplayed and saves the store.
+// Either way it starts from the beginning next time.
+func (s *EpisodeStore) SetPlayed(path string, duration time.Duration, played bool) error {
+	s.mu.Lock()
+	s.episodes[path] = EpisodeProgress{Duration: duration.Seconds(), Played: played, Updated: time.Now()}
+	delete(s.replaying, path)
+	s.dirty = true
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Save writes the store to disk if it changed.
+func (s *EpisodeStore) Save() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(s.episodes, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode episode progress: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write episode progress: %w", err)
+	}
+	return nil
+}
+
+// StartEpisodeTracking records the position of long tracks as they play
+// and saves it regularly. The returned function records the final
+// position, saves and stops.
+func StartEpisodeTracking(s *EpisodeStore, player *Player) func() {
+	player.SetResumePosition(s.ResumePosition)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(episodeCheckInterval)
+		defer ticker.Stop()
+		record := func() {
+			if state := player.GetState(); state.IsPlaying {
+				s.Update(state.CurrentFile, state.Position, state.Duration)
+			}
+		}
+
+		lastSave := time.Now()
+		for {
+			record()
+			if time.Since(lastSave) >= episodeSaveInterval {
+				if err := s.Save(); err != nil {
+					appLog.Add("error", err.Error())
+				}
+				lastSave = time.Now()
+			}
+
+			select {
+			case <-stop:
+				record()
+				if err := s.Save(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: Could not save episode progress: %v\n", err)
+				}
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// renderEpisodeProgress renders the progress of an episode for its library
+// row: "✓" once played, the share listened to while in progress, and
+// nothing for other tracks.
+func (m Model) renderEpisodeProgress(file MusicFile) string {
+	if m.episodes == nil || !m.episodes.IsLong(file.Duration) {
+		return ""
+	}
+	e, ok := m.episodes.Get(file.Path)
+	switch {
+	case !ok:
+		return ""
+	case e.Played:
+		return "  " + mutedStyle.Render("✓")
+	case e.Position > 0:
+		return "  " + mutedStyle.Render(fmt.Sprintf("%d%%", e.Percent()))
+	}
+	return ""
+}
+
+// episodeMenuItem returns the track menu's action that marks an episode
+// played or unplayed.
+func (m Model) episodeMenuItem(file MusicFile) (menuItem, bool) {
+	if m.episodes == nil || !m.episodes.IsLong(file.Duration) {
+		return menuItem{}, false
+	}
+	e, _ := m.episodes.Get(file.Path)
+	played := !e.Played
+	label := T("Mark played")
+	if !played {
+		label = T("Mark unplayed")
+	}
+	return menuItem{label, func(m Model) (tea.Model, tea.Cmd) {
+		if err := m.episodes.SetPlayed(file.Path, file.Duration, played); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		if played {
+			return m, func() tea.Msg { return statusMsg(Tf("Marked played: %s", file.Name)) }
+		}
+		return m, func() tea.Msg { return statusMsg(Tf("Marked unplayed: %s", file.Name)) }
+	}}, true
+}
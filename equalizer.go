@@ -0,0 +1,254 @@
+// Package main provides the equalizer for Personal Musician.
+// Five peaking filters, from deep bass to treble, sit in the audio chain
+// before the visualizer tap, so every track is shaped the same way and the
+// visualizers show what is heard. Pressing 'e' opens a panel to pick a
+// preset or adjust each band; the gains are saved with the session state.
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/speaker"
+)
+
+// Equalizer tuning.
+const (
+	eqMaxGain = 12.0 // Largest boost or cut of a band, in dB
+	eqStep    = 1.0  // Gain change per key press, in dB
+	eqQ       = 0.9  // Width of each band; wide enough for neighbours to overlap
+)
+
+// eqBands are the center frequencies of the bands, in Hz.
+var eqBands = []float64{60, 250, 1000, 4000, 12000}
+
+// eqPreset is a named set of band gains.
+type eqPreset struct {
+	name  string
+	gains []float64
+}
+
+// eqPresets are the presets offered in the panel, Flat first.
+var eqPresets = []eqPreset{
+	{"Flat", []float64{0, 0, 0, 0, 0}},
+	{"Bass boost", []float64{6, 4, 0, 0, 0}},
+	{"Vocal", []float64{-2, -1, 3, 4, 1}},
+	{"Treble boost", []float64{0, 0, 0, 3, 6}},
+	{"Loudness", []float64{5, 2, 0, 2, 4}},
+}
+
+// eqPresetIndex returns the preset with the given gains, or -1 for custom
+// settings.
+func eqPresetIndex(gains []float64) int {
+	return slices.IndexFunc(eqPresets, func(p eqPreset) bool { return slices.Equal(p.gains, gains) })
+}
+
+// biquad is a peaking filter with its state for both channels.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     [2]float64
+}
+
+// peaking sets the filter to boost or cut gain dB around f0 Hz.
+func (f *biquad) peaking(rate beep.SampleRate, f0, gain float64) {
+	a := math.Pow(10, gain/40)
+	w0 := 2 * math.Pi * f0 / float64(rate)
+	alpha := math.Sin(w0) / (2 * eqQ)
+	a0 := 1 + alpha/a
+	f.b0 = (1 + alpha*a) / a0
+	f.b1 = -2 * math.Cos(w0) / a0
+	f.b2 = (1 - alpha*a) / a0
+	f.a1 = f.b1
+	f.a2 = (1 - alpha/a) / a0
+}
+
+// process filters samples in place.
+func (f *biquad) process(samples [][2]float64) {
+	for i := range samples {
+		for c := range samples[i] {
+			x := samples[i][c]
+			y := f.b0*x + f.b1*f.x1[c] + f.b2*f.x2[c] - f.a1*f.y1[c] - f.a2*f.y2[c]
+			f.x2[c], f.x1[c] = f.x1[c], x
+			f.y2[c], f.y1[c] = f.y1[c], y
+			samples[i][c] = y
+		}
+	}
+}
+
+// equalizer applies the band gains to the wrapped streamer. Its settings
+// change with the speaker locked.
+type equalizer struct {
+	Streamer beep.Streamer
+	rate     beep.SampleRate
+	gains    []float64
+	filters  []biquad // One per band; bands at 0 dB are skipped
+}
+
+// newEqualizer returns a flat equalizer.
+func newEqualizer() *equalizer {
+	return &equalizer{gains: make([]float64, len(eqBands)), filters: make([]biquad, len(eqBands))}
+}
+
+// configure sets the sample rate and band gains and recomputes the filters.
+func (e *equalizer) configure(rate beep.SampleRate, gains []float64) {
+	e.rate = rate
+	copy(e.gains, gains)
+	if rate <= 0 {
+		return
+	}
+	for i, f0 := range eqBands {
+		if f0 < float64(rate)/2 {
+			e.filters[i].peaking(rate, f0, e.gains[i])
+		}
+	}
+}
+
+// reset clears the filter state, so a new track starts without the tail
+// of the last one.
+func (e *equalizer) reset() {
+	for i := range e.filters {
+		f := &e.filters[i]
+		f.x1, f.x2, f.y1, f.y2 = [2]float64{}, [2]float64{}, [2]float64{}, [2]float64{}
+	}
+}
+
+// Stream streams from the wrapped streamer and filters the samples.
+func (e *equalizer) Stream(samples [][2]float64) (n int, ok bool) {
+	if e.Streamer == nil {
+		return 0, false
+	}
+	n, ok = e.Streamer.Stream(samples)
+	if e.rate <= 0 {
+		return n, ok
+	}
+	for i, gain := range e.gains {
+		if gain != 0 && eqBands[i] < float64(e.rate)/2 {
+			e.filters[i].process(samples[:n])
+		}
+	}
+	return n, ok
+}
+
+// Err propagates the wrapped streamer's error.
+func (e *equalizer) Err() error {
+	if e.Streamer == nil {
+		return nil
+	}
+	return e.Streamer.Err()
+}
+
+// EqualizerGains returns the gain of each band in dB.
+func (p *Player) EqualizerGains() []float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.eq.gains)
+}
+
+// SetEqualizer sets the gain of each band in dB, clamped to ±12 dB.
+// Missing bands are flat.
+func (p *Player) SetEqualizer(gains []float64) {
+	clamped := make([]float64, len(eqBands))
+	for i := range clamped {
+		if i < len(gains) {
+			clamped[i] = max(-eqMaxGain, min(gains[i], eqMaxGain))
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.speakerInit {
+		speaker.Lock()
+		defer speaker.Unlock()
+	}
+	p.eq.configure(p.sampleRate, clamped)
+}
+
+// eqRows is the number of rows in the equalizer panel: the preset, then
+// one per band.
+var eqRows = 1 + len(eqBands)
+
+// handleEqualizerKeys handles keys while the equalizer panel is open.
+func (m Model) handleEqualizerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "e", "enter":
+		m.eqOpen = false
+	case "up", "k":
+		if m.eqCursor > 0 {
+			m.eqCursor--
+		}
+	case "down", "j":
+		if m.eqCursor < eqRows-1 {
+			m.eqCursor++
+		}
+	case "left", "h":
+		m.changeEqualizerRow(-1)
+	case "right", "l", " ":
+		m.changeEqualizerRow(1)
+	case "0":
+		m.player.SetEqualizer(nil)
+	}
+	return m, nil
+}
+
+// changeEqualizerRow steps the preset, or the gain of the selected band.
+func (m Model) changeEqualizerRow(delta int) {
+	gains := m.player.EqualizerGains()
+	if m.eqCursor == 0 {
+		i := eqPresetIndex(gains)
+		if i < 0 && delta < 0 {
+			i = 0 // Custom settings step to the first or last preset
+		}
+		i = (i + delta + len(eqPresets)) % len(eqPresets)
+		m.player.SetEqualizer(eqPresets[i].gains)
+		return
+	}
+	gains[m.eqCursor-1] += float64(delta) * eqStep
+	m.player.SetEqualizer(gains)
+}
+
+// renderEqualizer renders the equalizer panel.
+func (m Model) renderEqualizer() string {
+	gains := m.player.EqualizerGains()
+	preset := T("Custom")
+	if i := eqPresetIndex(gains); i >= 0 {
+		preset = T(eqPresets[i].name)
+	}
+
+	rows := []string{fmt.Sprintf("%s‹ %s ›", padLabel("Preset", 8), preset)}
+	for i, f0 := range eqBands {
+		label := fmt.Sprintf("%.0f Hz", f0)
+		if f0 >= 1000 {
+			label = fmt.Sprintf("%.0f kHz", f0/1000)
+		}
+		rows = append(rows, fmt.Sprintf("%-9s%s %+3.0f dB", label, eqSlider(gains[i]), gains[i]))
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(" 🎚 "+T("Equalizer")+" ") + "\n\n")
+	for i, row := range rows {
+		if i == m.eqCursor {
+			b.WriteString(selectedStyle.Render("> "+row) + "\n")
+		} else {
+			b.WriteString(normalStyle.Render("  "+row) + "\n")
+		}
+	}
+	b.WriteString("\n" + mutedStyle.Render(T("↑/↓: select • ←/→: change • 0: flat • esc: close")))
+
+	box := boxStyle.Render(b.String())
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, box)
+}
+
+// eqSlider draws a band gain as a horizontal slider centered on 0 dB.
+func eqSlider(gain float64) string {
+	half := int(eqMaxGain / eqStep / 2) // Two steps per cell
+	pos := half + int(math.Round(gain/eqStep/2))
+	track := []rune(strings.Repeat("─", 2*half+1))
+	track[half] = '┼'
+	track[pos] = '●'
+	return "[" + string(track) + "]"
+}
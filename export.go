@@ -0,0 +1,202 @@
+// Package main provides the library export of Personal Musician.
+// personal-musician export writes every track of the library with its
+// tags, length, play count, rating and the URL it was downloaded from, as
+// JSON or CSV, for backups or to analyze it in other programs. Tags come
+// from the library index; files it doesn't know yet are read on the spot
+// and added to it.
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportTrack is a track as exported.
+type exportTrack struct {
+	Path       string     `json:"path"`
+	Title      string     `json:"title,omitempty"`
+	Artist     string     `json:"artist,omitempty"`
+	Album      string     `json:"album,omitempty"`
+	Track      string     `json:"track,omitempty"`
+	Genre      string     `json:"genre,omitempty"`
+	Year       string     `json:"year,omitempty"`
+	Duration   float64    `json:"duration"` // Seconds
+	Size       int64      `json:"size"`     // Bytes
+	Modified   time.Time  `json:"modified"`
+	PlayCount  int        `json:"play_count"`
+	LastPlayed *time.Time `json:"last_played,omitempty"`
+	Liked      bool       `json:"liked"`
+	Rating     int        `json:"rating"`
+	SourceURL  string     `json:"source_url,omitempty"`
+}
+
+// exportColumns are the CSV columns, in the order of exportTrack.row.
+var exportColumns = []string{"path", "title", "artist", "album", "track", "genre", "year", "duration", "size", "modified", "play_count", "last_played", "liked", "rating", "source_url"}
+
+// row returns the track as a CSV row.
+func (t exportTrack) row() []string {
+	lastPlayed := ""
+	if t.LastPlayed != nil {
+		lastPlayed = t.LastPlayed.Format(time.RFC3339)
+	}
+	return []string{
+		t.Path, t.Title, t.Artist, t.Album, t.Track, t.Genre, t.Year,
+		strconv.FormatFloat(t.Duration, 'f', 1, 64),
+		strconv.FormatInt(t.Size, 10),
+		t.Modified.Format(time.RFC3339),
+		strconv.Itoa(t.PlayCount),
+		lastPlayed,
+		strconv.FormatBool(t.Liked),
+		strconv.Itoa(t.Rating),
+		t.SourceURL,
+	}
+}
+
+// sourceURL returns the URL a track was downloaded from, kept in its
+// comment tag, or "" for other comments.
+func sourceURL(tags Tags) string {
+	comment := strings.TrimSpace(tags.Comment)
+	if strings.HasPrefix(comment, "https://") || strings.HasPrefix(comment, "http://") {
+		return comment
+	}
+	return ""
+}
+
+// exportTracks returns the tracks of files, ordered by path, with their
+// statistics.
+func exportTracks(files []MusicFile, stats *StatsStore) []exportTrack {
+	tracks := make([]exportTrack, 0, len(files))
+	for _, file := range files {
+		st := stats.Get(file.Path)
+		track := exportTrack{
+			Path:      file.Path,
+			Title:     file.Tags.Title,
+			Artist:    file.Tags.Artist,
+			Album:     file.Tags.Album,
+			Track:     file.Tags.Track,
+			Genre:     file.Tags.Genre,
+			Year:      file.Tags.Year,
+			Duration:  file.Duration.Round(100 * time.Millisecond).Seconds(),
+			Size:      file.Size,
+			Modified:  file.ModTime,
+			PlayCount: st.PlayCount,
+			Liked:     st.Liked,
+			Rating:    st.Rating,
+			SourceURL: sourceURL(file.Tags),
+		}
+		if !st.LastPlayed.IsZero() {
+			track.LastPlayed = &st.LastPlayed
+		}
+		tracks = append(tracks, track)
+	}
+	slices.SortFunc(tracks, func(a, b exportTrack) int { return cmp.Compare(a.Path, b.Path) })
+	return tracks
+}
+
+// writeExport writes tracks to w in format, "json" or "csv".
+func writeExport(w io.Writer, tracks []exportTrack, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(tracks); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(exportColumns)
+		for _, track := range tracks {
+			cw.Write(track.row())
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown export format %q (want json or csv)", format)
+	}
+	return nil
+}
+
+// readLibrary scans the music directories and fills in the tags and
+// durations of the tracks, reading those the library index lacks.
+func readLibrary() ([]MusicFile, error) {
+	files, err := ScanMusicFiles()
+	if err != nil {
+		return nil, err
+	}
+	missing := libraryMetadata.apply(files)
+	if len(missing) == 0 {
+		return files, nil
+	}
+
+	read := make(map[string]MusicFile, len(missing))
+	for file := range startMetadataScan(0, missing).results {
+		read[file.Path] = file
+	}
+	for i, file := range files {
+		if r, ok := read[file.Path]; ok {
+			files[i].Tags, files[i].Duration, files[i].Hash = r.Tags, r.Duration, r.Hash
+		}
+	}
+	return files, nil
+}
+
+// runExport writes the library to a file or the standard output.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "json or csv; by default taken from the output file's extension, or json")
+	output := fs.String("output", "-", "file to write, or - for the standard output")
+	if rest, err := parseInterspersed(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return fmt.Errorf("usage: personal-musician export [--format json|csv] [--output file]")
+	}
+	if *format == "" {
+		*format = "json"
+		if strings.EqualFold(filepath.Ext(*output), ".csv") {
+			*format = "csv"
+		}
+	}
+	*format = strings.ToLower(*format)
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown export format %q (want json or csv)", *format)
+	}
+
+	stats, err := LoadStats(StatsFile)
+	if err != nil {
+		return err
+	}
+	files, err := readLibrary()
+	if err != nil {
+		return err
+	}
+	tracks := exportTracks(files, stats)
+
+	if *output == "-" {
+		return writeExport(os.Stdout, tracks, *format)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+	if err := writeExport(f, tracks, *format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d tracks to %s\n", len(tracks), *output)
+	return nil
+}
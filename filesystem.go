@@ -3,19 +3,50 @@
 package main
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 )
 
-// MusicDir is the default directory where downloaded MP3 files are stored.
-const MusicDir = "./Music"
+// MusicDir is the directory where downloaded MP3 files are stored.
+// The config file can change it.
+var MusicDir = defaultMusicDir()
 
-// MusicFile represents a local MP3 file with its metadata.
+// extraMusicDirs are further directories scanned for music, set from the
+// config file.
+var extraMusicDirs []string
+
+// MusicFile represents a local audio file with its metadata.
 type MusicFile struct {
-	Name     string // Display name (filename without extension)
-	Path     string // Full path to the file
-	FileName string // Filename with extension
+	Name     string    // Display name (filename without extension)
+	Path     string    // Full path to the file
+	FileName string    // Filename with extension
+	ModTime  time.Time // Last modification time (date added for downloads)
+	Size     int64     // Size in bytes
+
+	// Read in the background after scanning; empty until then
+	Tags     Tags          // ID3 tags
+	Duration time.Duration // Playing time
+	Hash     string        // Hash of the audio; see audioHash
+}
+
+// DisplayName returns "Artist – Title" from the file's tags, the title
+// alone when there is no artist, or the filename without extension when
+// the tags have no title or have not been read yet.
+func (f MusicFile) DisplayName() string {
+	title := strings.TrimSpace(f.Tags.Title)
+	if title == "" {
+		return f.Name
+	}
+	if artist := strings.TrimSpace(f.Tags.Artist); artist != "" {
+		return artist + " – " + title
+	}
+	return title
 }
 
 // InitMusicDir creates the Music directory if it doesn't exist.
@@ -25,88 +56,148 @@ func InitMusicDir() error {
 	return os.MkdirAll(MusicDir, 0755)
 }
 
-// ScanMusicFiles scans the Music directory and returns all MP3 files.
-// Returns an empty slice if no files are found or if the directory doesn't exist.
+// ScanMusicFiles scans the Music directory and any extra music directories
+// and returns all playable audio files.
+// Returns an empty slice if no files are found or if the directories don't exist.
 func ScanMusicFiles() ([]MusicFile, error) {
 	var files []MusicFile
+	for _, dir := range append([]string{MusicDir}, extraMusicDirs...) {
+		found, err := scanMusicDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+	return files, nil
+}
 
+// scanMusicDir returns all playable audio files below dir.
+func scanMusicDir(dir string) ([]MusicFile, error) {
+	var files []MusicFile
+	if err := walkMusicDir(dir, func(file MusicFile) { files = append(files, file) }); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// walkMusicDir calls found with each playable audio file below dir as the
+// walk reaches it. dir may also be a single file. A missing dir has none.
+func walkMusicDir(dir string, found func(MusicFile)) error {
 	// Check if directory exists
-	if _, err := os.Stat(MusicDir); os.IsNotExist(err) {
-		return files, nil // Return empty slice, not an error
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil // Nothing found, not an error
 	}
 
-	// Walk through the Music directory
-	err := filepath.Walk(MusicDir, func(path string, info os.FileInfo, err error) error {
+	// Walk through the directory
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip directories and non-MP3 files
+		// Skip directories, the trash (see trash.go) and files in formats the
+		// player cannot play
 		if info.IsDir() {
+			if info.Name() == trashDirName {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		// Check if file is an MP3 (case-insensitive)
-		if strings.ToLower(filepath.Ext(path)) == ".mp3" {
+		// Check the extension against the known formats (case-insensitive)
+		if isAudioFile(path) {
 			fileName := filepath.Base(path)
 			name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
-			files = append(files, MusicFile{
+			found(MusicFile{
 				Name:     name,
 				Path:     path,
 				FileName: fileName,
+				ModTime:  info.ModTime(),
+				Size:     info.Size(),
 			})
 		}
 
 		return nil
 	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return files, nil
 }
 
-// FileExists checks if a file with a similar name already exists in the Music directory.
-// Uses case-insensitive comparison and ignores file extensions.
-func FileExists(name string) bool {
-	files, err := ScanMusicFiles()
-	if err != nil {
-		return false
+// GetFilePath returns the full path to a music file by name, looked up in
+// the library index. Returns empty string if the file is not found.
+func GetFilePath(name string) string {
+	searchName := strings.ToLower(name)
+
+	for _, file := range IndexedMusicFiles() {
+		if strings.ToLower(file.Name) == searchName {
+			return file.Path
+		}
 	}
 
-	// Normalize the search name (lowercase, no extension)
-	searchName := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	return ""
+}
 
-	for _, file := range files {
-		// Compare normalized names
-		existingName := strings.ToLower(file.Name)
-		if existingName == searchName || strings.Contains(existingName, searchName) {
-			return true
+// MoveMusicFile renames or moves a music file, creating the folders it
+// goes into. An existing file at to is never replaced, though the case of
+// a name can change on systems that ignore it. The library index is left
+// to the caller, so moves can be recorded together.
+func MoveMusicFile(from, to string) error {
+	if info, err := os.Stat(to); err == nil {
+		if fromInfo, err := os.Stat(from); err != nil || !os.SameFile(info, fromInfo) {
+			return fmt.Errorf("failed to move %s: %w", filepath.Base(from), fs.ErrExist)
 		}
 	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return fmt.Errorf("failed to create folder: %w", err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("failed to move %s: %w", filepath.Base(from), err)
+	}
+	return nil
+}
 
-	return false
+// RevealInFileManager opens the folder containing path in the system file manager.
+func RevealInFileManager(path string) error {
+	return OpenExternal(filepath.Dir(path))
 }
 
-// GetFilePath returns the full path to a music file by name.
-// Returns empty string if the file is not found.
-func GetFilePath(name string) string {
-	files, err := ScanMusicFiles()
-	if err != nil {
-		return ""
+// OpenExternal opens a file, folder or URL with the system's default handler.
+func OpenExternal(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
 	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	// Reap the launcher in the background
+	go cmd.Wait()
+	return nil
+}
 
-	searchName := strings.ToLower(name)
-
-	for _, file := range files {
-		if strings.ToLower(file.Name) == searchName {
-			return file.Path
+// CopyToClipboard puts text on the system clipboard.
+func CopyToClipboard(text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("clip")
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			cmd = exec.Command("wl-copy")
+		} else {
+			cmd = exec.Command("xclip", "-selection", "clipboard")
 		}
 	}
-
-	return ""
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	return nil
 }
 
 // GetMusicDirAbsPath returns the absolute path to the Music directory.
@@ -0,0 +1,312 @@
+// Package main provides the fuzzy-finder overlay for Personal Musician.
+// Ctrl+P opens a prompt that fuzzy-matches library tracks and commands at once
+// and jumps to (or runs) the selection.
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// finderMaxResults is the number of matches shown in the overlay.
+const finderMaxResults = 12
+
+// finderItem is a single entry the finder can match.
+type finderItem struct {
+	Kind  string // "track", "playlist", "smart" (playlist), "genre" or "command"
+	Label string
+	run   func(m Model) (tea.Model, tea.Cmd)
+}
+
+// finderMatch is a finder item with its match score.
+type finderMatch struct {
+	item  finderItem
+	score int
+}
+
+// finderCommands returns the commands available in the finder.
+func finderCommands() []finderItem {
+	return []finderItem{
+		{Kind: "command", Label: T("Search YouTube"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.currentView = ViewSearch
+			m.searchInput.SetValue("")
+			m.searchInput.Focus()
+			return m, textinput.Blink
+		}},
+		{Kind: "command", Label: T("Show library"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.currentView = ViewLibrary
+			return m, nil
+		}},
+		{Kind: "command", Label: T("Show queue"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.currentView = ViewQueue
+			return m, nil
+		}},
+		{Kind: "command", Label: T("Show playlists"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.openPlaylists()
+		}},
+		{Kind: "command", Label: T("Show Jellyfin"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.openJellyfin()
+		}},
+		{Kind: "command", Label: T("Browse by artist"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.openBrowse()
+		}},
+		{Kind: "command", Label: T("Show log"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.openLog()
+		}},
+		{Kind: "command", Label: T("Toggle visualizer"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.handleKeyPress(keyRunes("v"))
+		}},
+		{Kind: "command", Label: T("Play / pause"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.player.TogglePause()
+			return m, nil
+		}},
+		{Kind: "command", Label: T("Next track"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.player.NextSong()
+			return m, nil
+		}},
+		{Kind: "command", Label: T("Previous track"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.player.PrevSong()
+			return m, nil
+		}},
+		{Kind: "command", Label: T("Toggle mute"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.handleKeyPress(keyRunes("M"))
+		}},
+		{Kind: "command", Label: T("Cycle theme"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.handleKeyPress(keyRunes("t"))
+		}},
+		{Kind: "command", Label: T("Cast to device"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.discoverCastDevices()
+		}},
+		{Kind: "command", Label: T("Stop casting"), run: func(m Model) (tea.Model, tea.Cmd) {
+			if m.cast == nil {
+				return m, nil
+			}
+			m = m.stopCast()
+			return m, func() tea.Msg { return statusMsg(T("Stopped casting")) }
+		}},
+		{Kind: "command", Label: T("Back up library"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.startBackup()
+		}},
+		{Kind: "command", Label: T("Cancel backup"), run: func(m Model) (tea.Model, tea.Cmd) {
+			if m.backup == nil {
+				return m, nil
+			}
+			if err := m.backup.Cancel(); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m, func() tea.Msg { return statusMsg(T("Backup cancelled")) }
+		}},
+		{Kind: "command", Label: T("Identify unknown songs"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.startIdentifyJob()
+		}},
+		{Kind: "command", Label: T("Stop identifying songs"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.cancelIdentifyJob()
+		}},
+		{Kind: "command", Label: T("Find duplicates"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.openDuplicates()
+		}},
+		{Kind: "command", Label: T("Organize library"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.openOrganize()
+		}},
+		{Kind: "command", Label: T("Undo delete"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m, m.undoDelete()
+		}},
+		{Kind: "command", Label: T("Empty trash"), run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.confirmEmptyTrash()
+		}},
+		{Kind: "command", Label: T("Clear queue"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.player.ClearQueue()
+			m.queueCursor = 0
+			return m, func() tea.Msg { return statusMsg(T("Queue cleared")) }
+		}},
+		{Kind: "command", Label: T("Quit"), run: func(m Model) (tea.Model, tea.Cmd) {
+			m.cancelFunc()
+			return m, tea.Quit
+		}},
+	}
+}
+
+// keyRunes builds a key message for a plain character key.
+func keyRunes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// finderItems returns every item the finder can match: tracks first, then
+// playlists and smart playlists, then genres, then commands.
+func (m Model) finderItems() []finderItem {
+	var items []finderItem
+	for _, f := range m.libraryFiles {
+		file := f
+		items = append(items, finderItem{Kind: "track", Label: file.Name, run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.jumpToTrack(file, true)
+		}})
+	}
+	for _, n := range m.playlistNames[:len(m.playlistNames)-len(m.smartPlaylists)] {
+		name := n
+		items = append(items, finderItem{Kind: "playlist", Label: name, run: func(m Model) (tea.Model, tea.Cmd) {
+			pl, err := LoadPlaylist(name)
+			if err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m.editPlaylist(pl, false)
+		}})
+	}
+	for _, sp := range m.smartPlaylists {
+		smart := sp
+		items = append(items, finderItem{Kind: "smart", Label: smart.Name, run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.playSmartPlaylist(smart)
+		}})
+	}
+	for _, g := range libraryGenres(m.libraryFiles) {
+		genre := g
+		items = append(items, finderItem{Kind: "genre", Label: genre, run: func(m Model) (tea.Model, tea.Cmd) {
+			return m.playGenre(genre)
+		}})
+	}
+	return append(items, finderCommands()...)
+}
+
+// finderMatches returns the best matches for the finder's current query.
+func (m Model) finderMatches() []finderMatch {
+	query := m.finderInput.Value()
+
+	var matches []finderMatch
+	for _, item := range m.finderItems() {
+		if score, ok := fuzzyScore(query, item.Label); ok {
+			matches = append(matches, finderMatch{item: item, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > finderMaxResults {
+		matches = matches[:finderMaxResults]
+	}
+	return matches
+}
+
+// fuzzyScore reports whether all characters of pattern appear in text in order
+// (case-insensitive) and scores the match. Consecutive characters and matches at
+// word starts score higher; an empty pattern matches everything.
+func fuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+
+	score, pi, streak := 0, 0, 0
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if t[ti] != p[pi] {
+			streak = 0
+			continue
+		}
+		score++
+		streak++
+		score += streak * 2
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 5 // Word start
+		}
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, false
+	}
+	// Prefer shorter labels among equal matches
+	return score*100 - len(t), true
+}
+
+// openFinder shows the fuzzy-finder overlay.
+func (m Model) openFinder() (tea.Model, tea.Cmd) {
+	m.finderOpen = true
+	m.finderCursor = 0
+	m.finderInput.SetValue("")
+	m.finderInput.Focus()
+	return m, textinput.Blink
+}
+
+// handleFinderKeys handles keys while the finder overlay is open.
+func (m Model) handleFinderKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+p":
+		m.finderOpen = false
+		m.finderInput.Blur()
+		return m, nil
+	case "up", "ctrl+k":
+		if m.finderCursor > 0 {
+			m.finderCursor--
+		}
+		return m, nil
+	case "down", "ctrl+j":
+		if m.finderCursor < len(m.finderMatches())-1 {
+			m.finderCursor++
+		}
+		return m, nil
+	case "enter":
+		matches := m.finderMatches()
+		m.finderOpen = false
+		m.finderInput.Blur()
+		if m.finderCursor < len(matches) {
+			return matches[m.finderCursor].item.run(m)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.finderInput, cmd = m.finderInput.Update(msg)
+	m.finderCursor = 0
+	return m, cmd
+}
+
+// jumpToTrack selects a track in the library, clearing any filter that hides it,
+// and optionally plays it.
+func (m Model) jumpToTrack(file MusicFile, play bool) (tea.Model, tea.Cmd) {
+	m.filterInput.SetValue("")
+	m.currentView = ViewLibrary
+	for i, f := range m.libraryFiles {
+		if f.Path == file.Path {
+			m.libraryCursor = i
+			break
+		}
+	}
+
+	if !play {
+		return m, nil
+	}
+	if err := m.player.PlayTrack(file); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	return m, func() tea.Msg { return statusMsg(Tf("Now playing: %s", file.Name)) }
+}
+
+// renderFinder renders the fuzzy-finder overlay.
+func (m Model) renderFinder() string {
+	var b strings.Builder
+
+	b.WriteString(headerStyle.Render(" 🔎 "+T("Go to track, playlist or command")+" ") + "\n\n")
+	b.WriteString(m.finderInput.View() + "\n\n")
+
+	matches := m.finderMatches()
+	if len(matches) == 0 {
+		b.WriteString(mutedStyle.Render(T("No matches")))
+	}
+	for i, match := range matches {
+		kind := mutedStyle.Render(padLabel(match.item.Kind, 8))
+		if i == m.finderCursor {
+			b.WriteString(selectedStyle.Render("> "+match.item.Label) + "  " + kind + "\n")
+		} else {
+			b.WriteString(normalStyle.Render("  "+match.item.Label) + "  " + kind + "\n")
+		}
+	}
+
+	width := m.width * 2 / 3
+	if width < 40 {
+		width = 40
+	}
+	overlay := boxStyle.Width(width).Render(strings.TrimRight(b.String(), "\n"))
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, overlay)
+}
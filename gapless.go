@@ -0,0 +1,263 @@
+// Package main provides gapless playback for Personal Musician.
+// A few seconds before a track ends, the track that follows it is opened
+// and decoded in the background. When the current track runs out, the
+// audio thread carries straight on with the prepared one inside the same
+// speaker buffer, so there is no pause between tracks. Anything that
+// changes what comes next (the queue, the playlist, shuffle or repeat)
+// drops the prepared track, and it is prepared again.
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// gaplessLead is how long before the end of a track the next one is
+// prepared.
+const gaplessLead = 5 * time.Second
+
+// decodedTrack is an opened track, ready to stream.
+type decodedTrack struct {
+	path      string
+	index     int  // Playlist index to select when it starts
+	fromQueue bool // Taken from the head of the play queue
+	playGen   int  // Player.playGen when it was prepared
+	streamer  beep.StreamSeekCloser
+	format    beep.Format
+	audio     beep.Streamer // streamer, resampled to the speaker rate
+	start     int           // Sample where the sound starts (see silence.go)
+	end       int           // Sample after which only silence follows; 0 plays to the end
+	played    int           // Samples streamed at the speaker rate, for the listening history
+}
+
+// openTrack opens and decodes an audio file. With trim set, leading and
+// trailing silence is found so playback can skip it.
+func openTrack(path string, trim bool) (*decodedTrack, error) {
+	streamer, format, err := decodeAudioFile(path)
+	if err != nil {
+		return nil, err
+	}
+	track := &decodedTrack{path: path, streamer: streamer, format: format, audio: streamer}
+	if trim {
+		track.findSilence()
+	}
+	return track, nil
+}
+
+// cueLocked moves a freshly opened track to where it should start playing:
+// where it was left if it resumes, otherwise past any leading silence.
+// Caller must hold p.mu.
+func (p *Player) cueLocked(track *decodedTrack) {
+	start := track.start
+	duration := track.format.SampleRate.D(track.streamer.Len())
+	if p.resumeAt != nil {
+		if position := p.resumeAt(track.path, duration); position > 0 && position < duration {
+			start = track.format.SampleRate.N(position)
+		}
+	}
+	if start == 0 {
+		return
+	}
+	if err := track.streamer.Seek(start); err != nil {
+		slog.Warn("failed to cue track", "file", track.path, "err", err)
+	}
+}
+
+// resampleTo converts the track to the speaker's sample rate if it differs.
+func (t *decodedTrack) resampleTo(rate beep.SampleRate) {
+	if t.format.SampleRate != rate {
+		t.audio = beep.Resample(4, t.format.SampleRate, rate, t.streamer)
+	}
+}
+
+// gaplessSource streams the current track and, once it runs out, the
+// prepared next track. It is what the audio tap plays. The tracks it holds
+// are its own: it closes them when it is done with them, and Player closes
+// them through close after clearing the speaker.
+type gaplessSource struct {
+	player    *Player
+	rate      beep.SampleRate // Speaker sample rate
+	crossfade time.Duration   // Overlap between tracks (see crossfade.go); 0 plays them back to back
+
+	// Only touched by the audio thread, or with the speaker cleared
+	track   *decodedTrack
+	fadeOut *decodedTrack // Previous track, fading out under track; nil when not fading
+	fadePos int           // Samples of the fade played so far
+	fadeLen int           // Samples the fade lasts
+	buf     [][2]float64  // Scratch buffer for the fading track
+	loopA   int           // Start of the A–B loop, in track samples (see abloop.go)
+	loopB   int           // End of the A–B loop; 0 when not looping
+}
+
+// Stream fills samples from the current track, fading into or moving on to
+// the prepared track when it ends. Without one, it ends the stream so the
+// end of track callback runs.
+func (s *gaplessSource) Stream(samples [][2]float64) (n int, ok bool) {
+	s.startCrossfade()
+	n, ok = s.streamTrack(samples)
+	if s.fadeOut != nil {
+		n = s.mixFadeOut(samples, n)
+		ok = n > 0
+	}
+	return n, ok
+}
+
+// streamTrack fills samples from the current track and, if it runs out,
+// from the prepared next track.
+func (s *gaplessSource) streamTrack(samples [][2]float64) (n int, ok bool) {
+	p := s.player
+	for n < len(samples) {
+		if s.loopB == 0 && s.remaining() < gaplessLead+s.crossfade && p.nextRequested.CompareAndSwap(false, true) {
+			go p.prepareNext(s)
+		}
+
+		filled, more := s.track.audio.Stream(s.trimChunk(s.loopChunk(samples[n:])))
+		n += filled
+		s.track.played += filled
+		if more && filled > 0 {
+			continue
+		}
+
+		next := p.next.Swap(nil)
+		if next == nil {
+			return n, n > 0
+		}
+		finished := s.track
+		s.track = next
+		s.loopB = 0
+		go finished.streamer.Close()
+		go p.finishSwitch(s, finished, next)
+	}
+	return n, true
+}
+
+// remaining returns how much of the current track is left to play.
+func (s *gaplessSource) remaining() time.Duration {
+	end := s.track.streamer.Len()
+	if s.track.end > 0 {
+		end = s.track.end
+	}
+	return s.track.format.SampleRate.D(end - s.track.streamer.Position())
+}
+
+// Err propagates the current track's error.
+func (s *gaplessSource) Err() error {
+	return s.track.audio.Err()
+}
+
+// close closes the tracks the source holds. The speaker must no longer
+// play the source.
+func (s *gaplessSource) close() {
+	s.track.streamer.Close()
+	if s.fadeOut != nil {
+		s.fadeOut.streamer.Close()
+		s.fadeOut = nil
+	}
+}
+
+// prepareNext opens the track that follows the one src is playing and
+// keeps it ready for src to switch to.
+func (p *Player) prepareNext(src *gaplessSource) {
+	p.mu.Lock()
+	if p.source != src {
+		p.mu.Unlock()
+		return
+	}
+	gen, playGen := p.nextGen, p.playGen
+	path, index, fromQueue, ok := p.peekNextLocked()
+	rate, trim := p.sampleRate, p.trimSilence
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	// Decode outside the lock; it reads from disk
+	track, err := openTrack(path, trim)
+	if err != nil {
+		slog.Warn("failed to prepare next track", "file", path, "err", err)
+		return
+	}
+	track.index, track.fromQueue, track.playGen = index, fromQueue, playGen
+	track.resampleTo(rate)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.source != src || p.nextGen != gen {
+		// What comes next changed meanwhile
+		track.streamer.Close()
+		return
+	}
+	p.cueLocked(track)
+	if old := p.next.Swap(track); old != nil {
+		old.streamer.Close()
+	}
+}
+
+// peekNextLocked returns the track that plays when the current one ends,
+// as autoAdvance would choose it, without changing any state. Returns
+// false when playback stops instead. Caller must hold p.mu.
+func (p *Player) peekNextLocked() (path string, index int, fromQueue bool, ok bool) {
+	switch {
+	case p.repeat == RepeatTrack:
+		return p.currentFile, p.currentIndex, false, p.currentFile != ""
+	case len(p.queue) > 0:
+		return p.queue[0].Path, p.playlistIndexLocked(p.queue[0].Path), true, true
+	case len(p.playlist) == 0, p.repeat == RepeatOff && p.atPlaylistEndLocked():
+		return "", 0, false, false
+	}
+
+	index = (p.currentIndex + 1) % len(p.playlist)
+	if p.shuffle {
+		index, _ = p.pickShuffledLocked()
+	}
+	return p.playlist[index].Path, index, false, true
+}
+
+// finishSwitch updates the player after src moved from the finished track
+// to next, as autoAdvance would after a track ends.
+func (p *Player) finishSwitch(src *gaplessSource, finished, next *decodedTrack) {
+	p.mu.Lock()
+	if p.source != src || p.playGen != next.playGen {
+		// Playback was stopped or another track started before this ran
+		p.mu.Unlock()
+		return
+	}
+	if next.fromQueue && len(p.queue) > 0 && p.queue[0].Path == next.path {
+		p.queue = p.queue[1:]
+		p.queueVersion++
+	}
+	if p.shuffle && !next.fromQueue && next.path != p.currentFile {
+		p.recordShuffledLocked()
+	}
+	p.currentIndex = next.index
+	p.currentFile = next.path
+	p.streamer = next.streamer
+	p.format = next.format
+	p.duration = next.format.SampleRate.D(next.streamer.Len())
+	p.nextGen++
+	p.nextRequested.Store(false)
+	p.loop = ABLoop{}
+	onEnd := p.onTrackEnd
+	callback := p.onSongChange
+	listened := p.sampleRate.D(finished.played)
+	p.mu.Unlock()
+
+	if onEnd != nil {
+		onEnd(finished.path, listened)
+	}
+	if callback != nil {
+		callback()
+	}
+}
+
+// invalidateNextLocked drops the prepared next track after something
+// changed what comes next; it is prepared again. Caller must hold p.mu.
+func (p *Player) invalidateNextLocked() {
+	p.nextGen++
+	if old := p.next.Swap(nil); old != nil {
+		old.streamer.Close()
+	}
+	p.nextRequested.Store(false)
+}
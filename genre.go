@@ -0,0 +1,219 @@
+// Package main provides genre tagging for Personal Musician.
+// Genres are read from the TCON frame of ID3v2 tags and the genre byte of
+// ID3v1 trailers, where old taggers store numbers such as "(17)" that are
+// turned into names here. Several genres in one tag, separated by ";", "/"
+// or ",", count separately. The library can be filtered to a genre from
+// the sort/filter menu, every genre can be played from the finder (Ctrl+P),
+// and the genre of MP3s can be set from a track's context menu.
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// id3v1Genres are the genres numbered by ID3v1, including the Winamp
+// extensions.
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop",
+	"Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap",
+	"Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska", "Death Metal", "Pranks",
+	"Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance",
+	"Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock",
+	"Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle",
+	"Native American", "Cabaret", "New Wave", "Psychedelic", "Rave", "Showtunes", "Trailer", "Lo-Fi",
+	"Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebop", "Latin", "Revival",
+	"Celtic", "Bluegrass", "Avantgarde", "Gothic Rock", "Progressive Rock", "Psychedelic Rock", "Symphonic Rock", "Slow Rock",
+	"Big Band", "Chorus", "Easy Listening", "Acoustic", "Humour", "Speech", "Chanson", "Opera",
+	"Chamber Music", "Sonata", "Symphony", "Booty Bass", "Primus", "Porn Groove", "Satire", "Slow Jam",
+	"Club", "Tango", "Samba", "Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle",
+	"Duet", "Punk Rock", "Drum Solo", "A Cappella", "Euro-House", "Dance Hall", "Goa", "Drum & Bass",
+	"Club-House", "Hardcore", "Terror", "Indie", "BritPop", "Afro-Punk", "Polsk Punk", "Beat",
+	"Christian Gangsta Rap", "Heavy Metal", "Black Metal", "Crossover", "Contemporary Christian", "Christian Rock", "Merengue", "Salsa",
+	"Thrash Metal", "Anime", "JPop", "Synthpop",
+}
+
+// id3v1Genre returns the name of an ID3v1 genre number, or "" for numbers
+// without one, such as 255 for no genre.
+func id3v1Genre(n int) string {
+	if n < 0 || n >= len(id3v1Genres) {
+		return ""
+	}
+	return id3v1Genres[n]
+}
+
+// normalizeGenre turns the genre numbers of old taggers into names: "17",
+// "(17)" and "(17)Rock" all become "Rock". "(RX)" and "(CR)" stand for
+// remix and cover. Other genres are returned as they are.
+func normalizeGenre(genre string) string {
+	genre = strings.TrimSpace(genre)
+	if n, err := strconv.Atoi(genre); err == nil {
+		return id3v1Genre(n)
+	}
+
+	var names []string
+	for strings.HasPrefix(genre, "(") && !strings.HasPrefix(genre, "((") {
+		ref, rest, ok := strings.Cut(genre[1:], ")")
+		if !ok {
+			break
+		}
+		switch ref {
+		case "RX":
+			names = append(names, "Remix")
+		case "CR":
+			names = append(names, "Cover")
+		default:
+			n, err := strconv.Atoi(ref)
+			if err != nil {
+				return genre
+			}
+			if name := id3v1Genre(n); name != "" {
+				names = append(names, name)
+			}
+		}
+		genre = strings.TrimSpace(rest)
+	}
+	if genre != "" { // A refinement, or the whole genre, spelled out
+		return strings.TrimPrefix(genre, "(") // "((" escapes a parenthesis
+	}
+	return strings.Join(names, "; ")
+}
+
+// genreNames returns the genres in a genre tag.
+func genreNames(genre string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(normalizeGenre(genre), func(r rune) bool { return strings.ContainsRune(";/,\x00", r) }) {
+		if name = normalizeGenre(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hasGenre reports whether a file is tagged with genre, ignoring case.
+func hasGenre(file MusicFile, genre string) bool {
+	return slices.ContainsFunc(genreNames(file.Tags.Genre), func(name string) bool { return strings.EqualFold(name, genre) })
+}
+
+// libraryGenres returns the genres of files in alphabetical order, each
+// spelled the way it was first found.
+func libraryGenres(files []MusicFile) []string {
+	seen := make(map[string]bool)
+	var genres []string
+	for _, file := range files {
+		for _, name := range genreNames(file.Tags.Genre) {
+			if key := strings.ToLower(name); !seen[key] {
+				seen[key] = true
+				genres = append(genres, name)
+			}
+		}
+	}
+	slices.SortFunc(genres, func(a, b string) int { return cmp.Compare(strings.ToLower(a), strings.ToLower(b)) })
+	return genres
+}
+
+// genreTracks returns the files tagged with genre, in library order.
+func genreTracks(files []MusicFile, genre string) []MusicFile {
+	var tracks []MusicFile
+	for _, file := range files {
+		if hasGenre(file, genre) {
+			tracks = append(tracks, file)
+		}
+	}
+	return tracks
+}
+
+// playGenre plays every song of a genre, in library order.
+func (m Model) playGenre(genre string) (tea.Model, tea.Cmd) {
+	tracks := genreTracks(m.libraryFiles, genre)
+	if len(tracks) == 0 {
+		return m, func() tea.Msg { return statusMsg(Tf("No songs match %s", genre)) }
+	}
+	return m.playTracks(tracks, 0, genre)
+}
+
+// cycleGenre returns the genre delta steps away from the current one in
+// the library's genres, with "" for every genre before the first.
+func cycleGenre(genres []string, current string, delta int) string {
+	options := append([]string{""}, genres...)
+	i := slices.IndexFunc(options, func(g string) bool { return strings.EqualFold(g, current) })
+	if i < 0 {
+		i = 0
+	}
+	return options[(i+delta+len(options))%len(options)]
+}
+
+// genreSetMsg reports the outcome of setting the genre of songs.
+type genreSetMsg struct {
+	tags map[string]Tags // The new tags of each song written, by path
+	err  error           // Last failure, if any
+}
+
+// setGenre returns a command that sets the genre of MP3s, keeping their
+// other tags as they are on disk.
+func setGenre(files []MusicFile, genre string) tea.Cmd {
+	return func() tea.Msg {
+		msg := genreSetMsg{tags: make(map[string]Tags)}
+		for _, file := range files {
+			tags, err := ReadTags(file.Path)
+			if err != nil {
+				msg.err = err
+				continue
+			}
+			tags.Genre = genre
+			if err := WriteTags(file.Path, tags); err != nil {
+				msg.err = err
+				continue
+			}
+			msg.tags[file.Path] = tags
+		}
+		return msg
+	}
+}
+
+// applyGenreSet shows the new genres in the library.
+func (m Model) applyGenreSet(msg genreSetMsg) (Model, tea.Cmd) {
+	for path, tags := range msg.tags {
+		m = m.updateLibraryTags(path, tags)
+	}
+	status := Tf("Set the genre of %d songs", len(msg.tags))
+	if msg.err != nil {
+		status = Tf("Set the genre of %d songs, error: %v", len(msg.tags), msg.err)
+	}
+	return m, func() tea.Msg { return statusMsg(status) }
+}
+
+// promptSetGenre asks for a genre and sets it on the MP3s among files. An
+// empty genre removes it.
+func (m Model) promptSetGenre(files []MusicFile) (tea.Model, tea.Cmd) {
+	files = slices.DeleteFunc(slices.Clone(files), func(f MusicFile) bool { return !isMP3(f.Path) })
+	if len(files) == 0 {
+		return m, func() tea.Msg { return errorMsg(T("Tags can only be written to MP3 files")) }
+	}
+	message := Tf("Genre of %q:", files[0].DisplayName())
+	if len(files) > 1 {
+		message = Tf("Genre of %d songs:", len(files))
+	}
+
+	return m.openDialog(NewPromptDialog(T("Set genre"), message, files[0].Tags.Genre,
+		func(m Model, genre string) (tea.Model, tea.Cmd) {
+			m.librarySel.clear()
+			return m, setGenre(files, strings.TrimSpace(genre))
+		}))
+}
+
+// genreLabel returns how the genre filter is shown in the sort/filter menu.
+func genreLabel(genre string, files []MusicFile) string {
+	if genre == "" {
+		return T("Any")
+	}
+	return fmt.Sprintf("%s (%d)", genre, len(genreTracks(files, genre)))
+}
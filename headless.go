@@ -0,0 +1,374 @@
+// Package main provides headless playback, downloads and search for Personal Musician.
+// "personal-musician play <file|playlist>" plays audio without the TUI and
+// "personal-musician download <url|query>" adds a song to the library, both
+// printing simple progress to stdout, so they can run from scripts, cron jobs
+// or over SSH. Ctrl+C, SIGTERM or SIGHUP stops playback or cancels the download.
+// "personal-musician search <query>" prints results as text or JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// headlessProgressInterval is how often the progress line is refreshed.
+const headlessProgressInterval = time.Second
+
+// runPlay plays a file, an M3U file, a directory or a saved playlist and
+// returns when the last track ends or the process is interrupted.
+func runPlay(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: personal-musician play <file|playlist>")
+	}
+
+	tracks, err := resolvePlayTarget(args[0])
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return fmt.Errorf("nothing to play in %s", args[0])
+	}
+
+	player := NewPlayer()
+	defer player.Close()
+	player.SetCrossfade(crossfadeDuration())
+	player.SetTrimSilence(config.Playback.TrimSilence)
+
+	// Sound the same as in the TUI
+	if state, err := LoadState(StateFile); err == nil && state != nil {
+		player.SetEqualizer(state.Equalizer)
+	}
+	player.SetPlaylist(tracks)
+
+	// Continue episodes where they were left
+	episodes, err := LoadEpisodes(EpisodesFile, longTrackLength())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load episode progress: %v\n", err)
+	}
+	defer StartEpisodeTracking(episodes, player)()
+
+	// Stop after the last track instead of wrapping around. Tracks that
+	// can't be played count as finished, so play still returns.
+	done := make(chan struct{})
+	var mu sync.Mutex
+	ended, failed := 0, 0
+	finish := func(ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ended == len(tracks) {
+			return // Already done
+		}
+		ended++
+		if !ok {
+			failed++
+		}
+		if ended == len(tracks) {
+			player.SetPlaylist(nil)
+			close(done)
+		}
+	}
+	player.SetOnTrackEnd(func(path string, _ time.Duration) {
+		episodes.Finished(path)
+		finish(true)
+	})
+
+	// playFrom plays the first track from index on that opens
+	playFrom := func(index int) {
+		for ; index < len(tracks); index++ {
+			err := player.PlayIndex(index)
+			if err == nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", filepath.Base(tracks[index].Path), err)
+			finish(false)
+		}
+	}
+	playFrom(0)
+
+	if config.NowPlaying.File != "" {
+		defer StartNowPlayingFile(config.NowPlaying, player)()
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, shutdownSignals...)
+	defer signal.Stop(interrupt)
+
+	progress := newHeadlessProgress(os.Stdout)
+	ticker := time.NewTicker(headlessProgressInterval)
+	defer ticker.Stop()
+
+	idle := false // Stopped at the last tick
+	for {
+		state := player.GetState()
+		progress.update(state)
+		select {
+		case <-done:
+			progress.finish()
+			if failed == len(tracks) {
+				return fmt.Errorf("could not play any track of %s", args[0])
+			}
+			return nil
+		case <-interrupt:
+			progress.finish()
+			fmt.Println("Stopped")
+			return nil
+		case <-ticker.C:
+		}
+
+		// A track that fails to open after the one before ended leaves the
+		// player stopped; stopped for two ticks in a row, it isn't just
+		// between tracks, so skip the track and carry on with the next one
+		if state.IsPlaying {
+			idle = false
+			continue
+		}
+		if !idle {
+			idle = true
+			continue
+		}
+		idle = false
+		if state.CurrentIndex < 0 || state.CurrentIndex >= len(tracks) {
+			continue
+		}
+		select {
+		case <-done:
+			continue // The last track ended
+		default:
+		}
+		progress.finish()
+		fmt.Fprintf(os.Stderr, "Skipping %s: could not be played\n", filepath.Base(tracks[state.CurrentIndex].Path))
+		finish(false)
+		playFrom(state.CurrentIndex + 1)
+	}
+}
+
+// resolvePlayTarget returns the tracks named by a play argument: an audio
+// file, an M3U file, a directory, or the name of a saved playlist.
+func resolvePlayTarget(target string) ([]MusicFile, error) {
+	info, err := os.Stat(target)
+	switch {
+	case err == nil && info.IsDir():
+		return scanMusicDir(target)
+	case err == nil && strings.EqualFold(filepath.Ext(target), playlistExt):
+		pl, err := LoadM3U(target)
+		return pl.Tracks, err
+	case err == nil:
+		return []MusicFile{musicFileFromPath(target)}, nil
+	}
+
+	// Not a path; look for a saved playlist of that name
+	names, listErr := ListPlaylists()
+	if listErr != nil {
+		return nil, listErr
+	}
+	if !slices.Contains(names, target) {
+		return nil, fmt.Errorf("no such file or playlist: %s", target)
+	}
+	pl, err := LoadPlaylist(target)
+	return pl.Tracks, err
+}
+
+// headlessProgress prints playback progress. On a terminal one line is
+// redrawn in place; otherwise only track changes are printed, one per line.
+type headlessProgress struct {
+	out      *os.File
+	terminal bool
+	current  string // Track whose start has been printed
+	width    int    // Width of the last line drawn in place
+}
+
+// newHeadlessProgress creates a progress printer writing to out.
+func newHeadlessProgress(out *os.File) *headlessProgress {
+	return &headlessProgress{out: out, terminal: isTerminal(out)}
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// update prints the progress of the current track.
+func (p *headlessProgress) update(state PlaybackState) {
+	if state.CurrentFile == "" || !state.IsPlaying {
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(state.CurrentFile), filepath.Ext(state.CurrentFile))
+	track := fmt.Sprintf("[%d/%d] %s", state.CurrentIndex+1, state.TotalTracks, name)
+
+	if !p.terminal {
+		if state.CurrentFile != p.current {
+			fmt.Fprintln(p.out, Glyphs("♪ "+track))
+		}
+		p.current = state.CurrentFile
+		return
+	}
+
+	if state.CurrentFile != p.current && p.current != "" {
+		fmt.Fprintln(p.out) // Keep the finished track's line
+	}
+	p.current = state.CurrentFile
+	line := Glyphs(fmt.Sprintf("♪ %s  %s / %s", track, FormatDuration(state.Position), FormatDuration(state.Duration)))
+	fmt.Fprintf(p.out, "\r%-*s", p.width, line)
+	p.width = len([]rune(line))
+}
+
+// finish ends the line drawn in place; the next update starts a new one.
+func (p *headlessProgress) finish() {
+	if p.terminal && p.current != "" {
+		fmt.Fprintln(p.out)
+	}
+	p.current = ""
+}
+
+// runDownload downloads a YouTube URL, or the top search result for a query,
+// into the library and returns when the download has finished.
+func runDownload(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: personal-musician download <url|query>")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
+
+	downloader, err := NewDownloader(MusicDir, config.DownloadOptions())
+	if err != nil {
+		return err
+	}
+	defer downloader.Close()
+	if err := downloader.Available(); err != nil {
+		return err
+	}
+
+	videoID, title, err := resolveDownloadTarget(ctx, downloader, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	fmt.Println(Glyphs("⇩ " + title))
+
+	if err := downloader.DownloadFromYouTube(ctx, videoID, title); err != nil {
+		return err
+	}
+
+	terminal := isTerminal(os.Stdout)
+	ticker := time.NewTicker(headlessProgressInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		items := downloader.Downloads()
+		if len(items) == 0 {
+			continue
+		}
+		item := items[len(items)-1]
+
+		switch item.State {
+		case DownloadDone:
+			if terminal {
+				fmt.Println()
+			}
+			fmt.Println(Glyphs("✓ Saved to " + item.File))
+			return nil
+		case DownloadFailed:
+			if terminal {
+				fmt.Println()
+			}
+			return fmt.Errorf("download failed: %s", item.Error)
+		case DownloadCancelled:
+			if terminal {
+				fmt.Println()
+			}
+			return fmt.Errorf("download cancelled")
+		case DownloadActive:
+			if terminal {
+				fmt.Printf("\r%5.1f%%  %-12s", item.Progress, item.Speed)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveDownloadTarget returns the video ID and title for a URL, or for the
+// top search result of a query.
+func resolveDownloadTarget(ctx context.Context, downloader *Downloader, target string) (videoID, title string, err error) {
+	if id, ok := ParseVideoURL(target); ok {
+		title, err := downloader.FetchTitle(ctx, id)
+		if err != nil {
+			return "", "", err
+		}
+		return id, title, nil
+	}
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return "", "", fmt.Errorf("not a YouTube video URL: %s", target)
+	}
+
+	results, err := SearchYouTube(target)
+	if err != nil {
+		return "", "", err
+	}
+	if len(results) == 0 {
+		return "", "", fmt.Errorf("no results for %q", target)
+	}
+	return results[0].VideoID, results[0].Title, nil
+}
+
+// searchResultJSON is a search result as printed by search --json.
+type searchResultJSON struct {
+	VideoID   string `json:"video_id"`
+	Title     string `json:"title"`
+	Channel   string `json:"channel"`
+	Duration  string `json:"duration,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	URL       string `json:"url"`
+}
+
+// runSearch searches YouTube and prints the results, one per line as
+// "URL<tab>title [duration] - channel" or as a JSON array with --json.
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	terms, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(terms) == 0 {
+		return fmt.Errorf("usage: personal-musician search <query> [--json]")
+	}
+
+	results, err := SearchYouTube(strings.Join(terms, " "))
+	if err != nil {
+		return err
+	}
+
+	if !*asJSON {
+		for _, r := range results {
+			fmt.Printf("%s\t%s\n", GetYouTubeURL(r.VideoID), FormatSearchResult(r))
+		}
+		return nil
+	}
+
+	out := make([]searchResultJSON, len(results))
+	for i, r := range results {
+		out[i] = searchResultJSON{
+			VideoID:   r.VideoID,
+			Title:     r.Title,
+			Channel:   r.Channel,
+			Duration:  r.Duration,
+			Thumbnail: r.Thumbnail,
+			URL:       GetYouTubeURL(r.VideoID),
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package main provides the listening history for Personal Musician.
+// Every track that plays to the end is recorded with when it finished and
+// how long it was actually played, one JSON object per line in
+// history.jsonl, so the file only ever grows by appending. Play counts in
+// stats.json are kept alongside; the history keeps each play, for listening
+// statistics and recommendations.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryFile is where the listening history is stored.
+var HistoryFile = dataPath("history.jsonl")
+
+// historyMu keeps appended plays from interleaving.
+var historyMu sync.Mutex
+
+// Play is one completed playback of a track.
+type Play struct {
+	Path     string    `json:"path"`
+	Time     time.Time `json:"time"`     // When it finished
+	Listened float64   `json:"listened"` // Seconds actually played
+}
+
+// AppendHistory adds a play to the end of the history file.
+func AppendHistory(path string, play Play) error {
+	line, err := json.Marshal(play)
+	if err != nil {
+		return fmt.Errorf("failed to encode play: %w", err)
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// ReadHistory returns the recorded plays, oldest first. A missing file
+// yields none; lines that can't be read, such as one cut short by a
+// crash, are skipped.
+func ReadHistory(path string) ([]Play, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var plays []Play
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var play Play
+		if err := json.Unmarshal(scanner.Bytes(), &play); err == nil && play.Path != "" {
+			plays = append(plays, play)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return plays, fmt.Errorf("failed to read history: %w", err)
+	}
+	return plays, nil
+}
+
+// runHistory prints the most recent plays, newest first, one per line as
+// "date time<tab>listened<tab>track" or as a JSON array with --json.
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "number of plays to print; 0 prints all")
+	asJSON := fs.Bool("json", false, "print plays as JSON")
+	if rest, err := parseInterspersed(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 || *limit < 0 {
+		return fmt.Errorf("usage: personal-musician history [--limit n] [--json]")
+	}
+
+	plays, err := ReadHistory(HistoryFile)
+	if err != nil {
+		return err
+	}
+
+	// Newest first, up to the limit
+	recent := make([]Play, 0, len(plays))
+	for i := len(plays) - 1; i >= 0 && (*limit == 0 || len(recent) < *limit); i-- {
+		recent = append(recent, plays[i])
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(recent); err != nil {
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+		return nil
+	}
+	for _, play := range recent {
+		name := strings.TrimSuffix(filepath.Base(play.Path), filepath.Ext(play.Path))
+		listened := time.Duration(play.Listened * float64(time.Second))
+		fmt.Printf("%s\t%s\t%s\n", play.Time.Local().Format("2006-01-02 15:04"), FormatDuration(listened), name)
+	}
+	return nil
+}
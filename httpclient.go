@@ -0,0 +1,242 @@
+// Package main provides the shared HTTP client of Personal Musician.
+// Every outbound request goes through one transport, so connections to
+// YouTube, image hosts and media servers are pooled and reused. Each
+// provider has its own timeout and request rate, and GET responses from
+// providers that allow it are cached on disk, so repeating a search or
+// showing the same thumbnails again costs no network round trip.
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// httpCacheMaxAge is the age at which cached responses are deleted, whatever
+// their provider.
+const httpCacheMaxAge = 30 * 24 * time.Hour
+
+// httpMaxCachedBody is the largest response body that is cached.
+const httpMaxCachedBody = 8 << 20
+
+// HTTPCacheDir holds cached HTTP responses.
+var HTTPCacheDir = dataPath("http-cache")
+
+// httpProvider holds the request settings for one kind of remote service.
+type httpProvider struct {
+	timeout  time.Duration // Whole-request limit; 0 for streams and large downloads
+	interval time.Duration // Minimum time between requests; 0 for no limit
+	cacheTTL time.Duration // How long GET responses are cached; 0 disables
+}
+
+// httpProviders are the services requests are made to. Unknown names use
+// "default".
+var httpProviders = map[string]httpProvider{
+	"youtube":     {timeout: 15 * time.Second, interval: 500 * time.Millisecond, cacheTTL: 10 * time.Minute},
+	"youtube-api": {timeout: 15 * time.Second, interval: 100 * time.Millisecond, cacheTTL: 10 * time.Minute},
+	"invidious":   {timeout: 8 * time.Second, cacheTTL: 10 * time.Minute}, // Short, so the next instance gets a turn
+	"piped":       {timeout: 8 * time.Second, cacheTTL: 10 * time.Minute},
+	"thumbnail":   {timeout: 10 * time.Second, cacheTTL: 7 * 24 * time.Hour},
+	"jellyfin":    {},                          // Streams tracks; requests carry their own contexts
+	"sync":        {},                          // Fetches whole tracks from the leader
+	"doctor":      {timeout: 5 * time.Second},  // Reachability checks
+	"plugin":      {timeout: 15 * time.Second}, // Requests made by Lua plugins
+	"musicbrainz": {timeout: 15 * time.Second, interval: time.Second, cacheTTL: 24 * time.Hour},
+	"acoustid":    {timeout: 15 * time.Second, interval: 350 * time.Millisecond, cacheTTL: 24 * time.Hour},
+	"default":     {timeout: 30 * time.Second},
+}
+
+// httpTransport is shared by all clients, so connections are reused across
+// providers.
+var httpTransport = newHTTPTransport()
+
+// newHTTPTransport returns a transport like the default one with a larger
+// pool of idle connections per host.
+func newHTTPTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = 8
+	return t
+}
+
+var (
+	httpMu       sync.Mutex
+	httpClients  = make(map[string]*http.Client)
+	httpLimiters = make(map[string]*rateLimiter)
+	httpPrune    sync.Once
+)
+
+// httpProviderFor returns the settings of a provider.
+func httpProviderFor(name string) httpProvider {
+	if p, ok := httpProviders[name]; ok {
+		return p
+	}
+	return httpProviders["default"]
+}
+
+// httpClient returns the shared client for a provider, with its timeout.
+func httpClient(provider string) *http.Client {
+	httpMu.Lock()
+	defer httpMu.Unlock()
+	client, ok := httpClients[provider]
+	if !ok {
+		client = &http.Client{Transport: httpTransport, Timeout: httpProviderFor(provider).timeout}
+		httpClients[provider] = client
+	}
+	return client
+}
+
+// httpDo sends a request through the shared client for a provider, waiting
+// for its rate limit first. Responses are not cached.
+func httpDo(provider string, req *http.Request) (*http.Response, error) {
+	if err := httpLimiter(provider).wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return httpClient(provider).Do(req)
+}
+
+// httpStatusError is returned for responses other than 200 OK. The body is
+// kept, as APIs explain errors in it.
+type httpStatusError struct {
+	Status string
+	Body   []byte
+}
+
+// Error returns the status line.
+func (e *httpStatusError) Error() string {
+	return e.Status
+}
+
+// httpGet fetches url for a provider and returns the body, from the disk
+// cache while a cached copy is fresh.
+func httpGet(ctx context.Context, provider, url string, header http.Header) ([]byte, error) {
+	ttl := httpProviderFor(provider).cacheTTL
+	path := httpCachePath(provider, url)
+	if ttl > 0 {
+		if body, ok := readHTTPCache(path, ttl); ok {
+			return body, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := httpDo(provider, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{Status: resp.Status, Body: body}
+	}
+
+	if ttl > 0 && len(body) <= httpMaxCachedBody {
+		writeHTTPCache(path, body)
+	}
+	return body, nil
+}
+
+// httpCachePath returns the cache file of a URL.
+func httpCachePath(provider, url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(HTTPCacheDir, provider, hex.EncodeToString(sum[:]))
+}
+
+// readHTTPCache returns a cached body written less than ttl ago.
+func readHTTPCache(path string, ttl time.Duration) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > ttl {
+		return nil, false
+	}
+	body, err := os.ReadFile(path)
+	return body, err == nil
+}
+
+// writeHTTPCache caches a body, and on first use removes expired entries
+// in the background. Failures only cost a later cache miss.
+func writeHTTPCache(path string, body []byte) {
+	httpPrune.Do(func() { go pruneHTTPCache() })
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		slog.Debug("failed to create HTTP cache", "err", err)
+		return
+	}
+	if err := writeFileAtomic(path, body); err != nil {
+		slog.Debug("failed to cache response", "err", err)
+	}
+}
+
+// pruneHTTPCache deletes cached responses older than httpCacheMaxAge.
+func pruneHTTPCache() {
+	filepath.WalkDir(HTTPCacheDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && time.Since(info.ModTime()) > httpCacheMaxAge {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// rateLimiter spaces requests at least interval apart.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // When the next request may start
+}
+
+// httpLimiter returns the rate limiter of a provider.
+func httpLimiter(provider string) *rateLimiter {
+	httpMu.Lock()
+	defer httpMu.Unlock()
+	limiter, ok := httpLimiters[provider]
+	if !ok {
+		limiter = &rateLimiter{interval: httpProviderFor(provider).interval}
+		httpLimiters[provider] = limiter
+	}
+	return limiter
+}
+
+// wait blocks until a request may start or ctx ends.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
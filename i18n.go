@@ -0,0 +1,946 @@
+// Package main provides interface localization for Personal Musician.
+// UI strings are looked up in a message catalog keyed by their English text,
+// so untranslated strings fall back to English automatically.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Locales lists the supported interface languages.
+var Locales = []string{"en", "es", "hi"}
+
+// locale is the active interface language.
+var locale = "en"
+
+// catalogs maps a locale to its translations, keyed by the English message.
+var catalogs = map[string]map[string]string{
+	"es": catalogES,
+	"hi": catalogHI,
+}
+
+// SetLocale selects the interface language. Region suffixes such as
+// "es_MX.UTF-8" are accepted.
+func SetLocale(name string) error {
+	lang, err := parseLocale(name)
+	if err != nil {
+		return err
+	}
+	locale = lang
+	return nil
+}
+
+// parseLocale returns the supported language named by a locale string.
+func parseLocale(name string) (string, error) {
+	lang := strings.ToLower(name)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	for _, l := range Locales {
+		if l == lang {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported language %q (want %s)", name, strings.Join(Locales, ", "))
+}
+
+// DetectLocale returns the language requested by PM_LANG, the config file or
+// the system locale.
+func DetectLocale() string {
+	if v := os.Getenv("PM_LANG"); v != "" {
+		return v
+	}
+	if config.UI.Language != "" {
+		return config.UI.Language
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" && v != "C" && v != "POSIX" {
+			return v
+		}
+	}
+	return "en"
+}
+
+// T translates a message into the active language.
+func T(msg string) string {
+	if s, ok := catalogs[locale][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// Tf translates a format string and formats it with args.
+func Tf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// padLabel translates a label and pads it to width display columns.
+func padLabel(label string, width int) string {
+	s := T(label)
+	if pad := width - lipgloss.Width(s); pad > 0 {
+		s += strings.Repeat(" ", pad)
+	}
+	return s + " "
+}
+
+// catalogES holds the Spanish translations.
+var catalogES = map[string]string{
+	// Views and headers
+	"Library":                          "Biblioteca",
+	"Queue":                            "Cola",
+	"Playlists":                        "Listas",
+	"Playlist editor":                  "Editor de lista",
+	"Downloads":                        "Descargas",
+	"Visualizer":                       "Visualizador",
+	"Search":                           "Búsqueda",
+	"Results":                          "Resultados",
+	"YouTube Search":                   "Búsqueda en YouTube",
+	"Results for '%s'":                 "Resultados para '%s'",
+	"Now Playing":                      "Reproduciendo",
+	"Sort & Filter":                    "Ordenar y filtrar",
+	"Go to track, playlist or command": "Ir a canción, lista o comando",
+	"Insert into %s":                   "Insertar en %s",
+	"(unsaved)":                        "(sin guardar)",
+
+	// Empty states and placeholders
+	"Loading...":                               "Cargando...",
+	"No song playing":                          "Nada en reproducción",
+	"No music files found in %s":               "No hay música en %s",
+	"Press 's' to search and download music":   "Pulsa 's' para buscar y descargar música",
+	"No songs match the filter":                "Ninguna canción coincide con el filtro",
+	"Queue is empty":                           "La cola está vacía",
+	"Press 'a' in the library to queue a song": "Pulsa 'a' en la biblioteca para añadir una canción a la cola",
+	"No results":                               "Sin resultados",
+	"%s search failed: %v":                     "Falló la búsqueda en %s: %v",
+	"No results found":                         "No se encontraron resultados",
+	"Searching YouTube...":                     "Buscando en YouTube...",
+	"No downloads yet":                         "Aún no hay descargas",
+	"Press 's' to search, then enter on a result to download it": "Pulsa 's' para buscar y enter en un resultado para descargarlo",
+	"No playlists yet": "Aún no hay listas",
+	"Press 'n' to create one, or 'p' in the library to add songs to one": "Pulsa 'n' para crear una o 'p' en la biblioteca para añadirle canciones",
+	"Playlist is empty":                              "La lista está vacía",
+	"Press 'a' to insert songs from the library":     "Pulsa 'a' para insertar canciones de la biblioteca",
+	"No matches":                                     "Sin coincidencias",
+	"Search for music on YouTube...":                 "Buscar música en YouTube...",
+	"filter":                                         "filtro",
+	"Type to find tracks, playlists and commands...": "Escribe para buscar canciones, listas y comandos...",
+	"Type to find a track...":                        "Escribe para buscar una canción...",
+
+	// Status messages
+	"Theme: %s":                   "Tema: %s",
+	"Now playing: %s":             "Reproduciendo: %s",
+	"Download finished: %s":       "Descarga terminada: %s",
+	"Download failed: %s":         "Descarga fallida: %s",
+	"Error: %s":                   "Error: %s",
+	"Find":                        "Buscar",
+	"empty":                       "vacío",
+	"(%d of %d)":                  "(%d de %d)",
+	"Playing next: %s":            "A continuación: %s",
+	"Queued: %s":                  "En cola: %s",
+	"Queued %d songs":             "%d canciones en cola",
+	"Queued %d downloads":         "%d descargas en cola",
+	"+%d queued":                  "+%d en cola",
+	"Liked: %s":                   "Me gusta: %s",
+	"Unliked: %s":                 "Ya no me gusta: %s",
+	"Muted":                       "Silenciado",
+	"Unmuted":                     "Sonido activado",
+	"mute":                        "silencio",
+	"Queue cleared":               "Cola vaciada",
+	"Download error: %v":          "Error de descarga: %v",
+	"Downloading: %s":             "Descargando: %s",
+	"Download cancelled":          "Descarga cancelada",
+	"All downloads cancelled":     "Todas las descargas canceladas",
+	"Cancelled: %s":               "Cancelada: %s",
+	"Retrying: %s":                "Reintentando: %s",
+	"(cancelled)":                 "(cancelada)",
+	"(queued)":                    "(en cola)",
+	"Deleted playlist: %s":        "Lista eliminada: %s",
+	"Saved playlist: %s":          "Lista guardada: %s",
+	"Playing playlist: %s":        "Reproduciendo lista: %s",
+	"Playing playlist %s from %s": "Reproduciendo la lista %s desde %s",
+	"Added %d songs to %s":        "%d canciones añadidas a %s",
+	"Inserted: %s":                "Insertada: %s",
+	"%d tracks":                   "%d canciones",
+	"%d active":                   "%d activas",
+	"%d queued":                   "%d en cola",
+	"[E: dismiss]":                "[E: descartar]",
+	"shuffle":                     "aleatorio",
+	"repeat":                      "repetir",
+	"repeat one":                  "repetir una",
+
+	// Dialogs
+	"Cancel all downloads":                               "Cancelar todas las descargas",
+	"Stop the active download and drop all queued ones?": "¿Detener la descarga activa y descartar las que están en cola?",
+	"Cancel download":                                    "Cancelar descarga",
+	"Stop the download in progress?":                     "¿Detener la descarga en curso?",
+	"Clear queue":                                        "Vaciar cola",
+	"Remove all %d tracks from the queue?":               "¿Quitar las %d canciones de la cola?",
+	"Already downloaded":                                 "Ya descargada",
+	"%q is already in the library. Download again and overwrite it?": "%q ya está en la biblioteca. ¿Descargarla de nuevo y sobrescribirla?",
+	"Delete":                    "Eliminar",
+	"New playlist":              "Nueva lista",
+	"Name of the new playlist:": "Nombre de la nueva lista:",
+	"Delete playlist":           "Eliminar lista",
+	"Delete the playlist %q? The songs stay in the library.": "¿Eliminar la lista %q? Las canciones se quedan en la biblioteca.",
+	"Unsaved changes":              "Cambios sin guardar",
+	"Discard the changes to %q?":   "¿Descartar los cambios en %q?",
+	"Add to playlist":              "Añadir a lista",
+	"Add %q to playlist:":          "Añadir %q a la lista:",
+	"Add %d songs to playlist:":    "Añadir %d canciones a la lista:",
+	"enter: confirm • esc: cancel": "enter: confirmar • esc: cancelar",
+	"y/enter: yes • n/esc: no":     "y/enter: sí • n/esc: no",
+
+	// Menus and commands
+	"Play":                   "Reproducir",
+	"Play next":              "Reproducir a continuación",
+	"Add to queue":           "Añadir a la cola",
+	"Show info":              "Ver información",
+	"Reveal in file manager": "Mostrar en el gestor de archivos",
+	"Download":               "Descargar",
+	"Open in browser":        "Abrir en el navegador",
+	"Search YouTube":         "Buscar en YouTube",
+	"Show library":           "Mostrar biblioteca",
+	"Show queue":             "Mostrar cola",
+	"Show playlists":         "Mostrar listas",
+	"The playing track is not in the library": "La canción en reproducción no está en la biblioteca",
+	"Log":                 "Registro",
+	"Nothing logged yet":  "Nada registrado todavía",
+	"Copied log line":     "Línea del registro copiada",
+	"Copied %d log lines": "%d líneas del registro copiadas",
+	"Show log":            "Mostrar registro",
+	"y: copy line":        "y: copiar línea",
+	"c: copy all":         "c: copiar todo",
+	"Toggle visualizer":   "Mostrar/ocultar visualizador",
+	"Play / pause":        "Reproducir / pausar",
+	"Next track":          "Siguiente canción",
+	"Previous track":      "Canción anterior",
+	"Toggle mute":         "Silenciar/activar sonido",
+	"Cycle theme":         "Cambiar tema",
+	"Quit":                "Salir",
+	"track":               "canción",
+	"playlist":            "lista",
+	"command":             "comando",
+	"Sort by":             "Ordenar por",
+	"Direction":           "Dirección",
+	"Liked only":          "Solo me gusta",
+	"Unplayed only":       "Solo sin reproducir",
+	"Ascending":           "Ascendente",
+	"Descending":          "Descendente",
+	"Name":                "Nombre",
+	"Date added":          "Fecha de alta",
+	"Play count":          "Reproducciones",
+	"Last played":         "Última reproducción",
+
+	// Casting
+	"Cast to device":                "Transmitir a un dispositivo",
+	"Cast to":                       "Transmitir a",
+	"Stop casting":                  "Dejar de transmitir",
+	"Stopped casting":               "Transmisión detenida",
+	"Searching for cast devices...": "Buscando dispositivos de transmisión...",
+	"No cast devices found":         "No se encontraron dispositivos de transmisión",
+	"Connecting to %s...":           "Conectando con %s...",
+	"Casting to %s":                 "Transmitiendo a %s",
+
+	// Multi-room
+	"Following %s":                        "Siguiendo a %s",
+	"Lost connection to %s, retrying: %v": "Se perdió la conexión con %s, reintentando: %v",
+
+	// Library scan
+	"Reading tags %d/%d": "Leyendo etiquetas %d/%d",
+
+	// Backup
+	"Back up library":  "Hacer copia de la biblioteca",
+	"Cancel backup":    "Cancelar copia de seguridad",
+	"Backup cancelled": "Copia de seguridad cancelada",
+	"No backup remote set (set remote under [backup])": "No hay destino de copia (define remote en [backup])",
+	"Backing up to %s":  "Copiando a %s",
+	"Backing up %s":     "Copiando %s",
+	"music":             "música",
+	"library data":      "datos de la biblioteca",
+	"Backup failed":     "La copia de seguridad falló",
+	"Last backup %s":    "Última copia %s",
+	"Not backed up yet": "Sin copia de seguridad aún",
+
+	// Episodes
+	"Mark played":         "Marcar como escuchado",
+	"Mark unplayed":       "Marcar como no escuchado",
+	"Marked played: %s":   "Marcado como escuchado: %s",
+	"Marked unplayed: %s": "Marcado como no escuchado: %s",
+
+	// Equalizer
+	"Equalizer":    "Ecualizador",
+	"Preset":       "Preajuste",
+	"Custom":       "Personalizado",
+	"Flat":         "Plano",
+	"Bass boost":   "Refuerzo de graves",
+	"Vocal":        "Voz",
+	"Treble boost": "Refuerzo de agudos",
+	"Loudness":     "Sonoridad",
+	"↑/↓: select • ←/→: change • 0: flat • esc: close": "↑/↓: elegir • ←/→: cambiar • 0: plano • esc: cerrar",
+
+	// A–B loop
+	"Loop start: %s": "Inicio del bucle: %s",
+	"Looping %s–%s":  "Repitiendo %s–%s",
+	"Loop cleared":   "Bucle quitado",
+
+	// Resume
+	"Resume playback?":                        "¿Reanudar la reproducción?",
+	"Continue %s from %s where you left off?": "¿Continuar %s desde %s, donde lo dejaste?",
+
+	// Visualizer modes
+	"Spectrum":             "Espectro",
+	"Waveform":             "Forma de onda",
+	"w: spectrum/waveform": "w: espectro/forma de onda",
+
+	// Smart playlists
+	"New smart playlist":                                           "Nueva lista inteligente",
+	"Name of the new smart playlist:":                              "Nombre de la nueva lista inteligente:",
+	"%s is defined in the config file":                             "%s está definida en el archivo de configuración",
+	"Delete smart playlist":                                        "Eliminar lista inteligente",
+	"Delete the smart playlist %q? The songs stay in the library.": "¿Eliminar la lista inteligente %q? Las canciones permanecen en la biblioteca.",
+	"Deleted smart playlist: %s":                                   "Lista inteligente eliminada: %s",
+	"Rules of %s":                                                  "Reglas de %s",
+	"Rules separated by ';', e.g. artist contains Queen; year < 1990. Start with 'match any;' to match any rule.": "Reglas separadas por ';', p. ej. artist contains Queen; year < 1990. Empieza con 'match any;' para que baste con una regla.",
+	"Saved smart playlist: %s (%d songs)":                      "Lista inteligente guardada: %s (%d canciones)",
+	"No songs match %s":                                        "Ninguna canción coincide con %s",
+	"Press 'S' for a smart playlist that picks songs by rules": "Pulsa 'S' para una lista inteligente que elige canciones por reglas",
+	"S: new smart":                                             "S: nueva inteligente",
+
+	// Ratings
+	"Rating":                   "Valoración",
+	"Any":                      "Cualquiera",
+	"Min. rating":              "Valoración mín.",
+	"Cleared the rating of %s": "Valoración de %s borrada",
+	"Rated %s: %s":             "Valorada %s: %s",
+	"1-5: rate":                "1-5: valorar",
+
+	// Recently added and recently played
+	"Recently added":                     "Añadidas recientemente",
+	"Recently played":                    "Escuchadas recientemente",
+	"Show":                               "Mostrar",
+	"No songs added in the last %d days": "No se añadieron canciones en los últimos %d días",
+	"Nothing played to the end yet":      "Aún no se ha escuchado nada hasta el final",
+
+	// Tag lookup
+	"Look up tags":                          "Buscar etiquetas",
+	"Tags can only be written to MP3 files": "Solo se pueden escribir etiquetas en archivos MP3",
+	"Looking up %s…":                        "Buscando %s…",
+	"No match found for %s":                 "No se encontró ninguna coincidencia para %s",
+	"Tags are already correct: %s":          "Las etiquetas ya son correctas: %s",
+	"Write these tags to %s? (%d%% match)":  "¿Escribir estas etiquetas en %s? (coincidencia del %d%%)",
+	"Tagged: %s":                            "Etiquetada: %s",
+	"track %d":                              "pista %d",
+
+	// Identifying songs by sound
+	"Identify by sound":                     "Identificar por el sonido",
+	"Identifying %s…":                       "Identificando %s…",
+	"Identify unknown songs":                "Identificar canciones desconocidas",
+	"Stop identifying songs":                "Dejar de identificar canciones",
+	"Already identifying songs":             "Ya se están identificando canciones",
+	"Every song has a title":                "Todas las canciones tienen título",
+	"Identifying %d songs":                  "Identificando %d canciones",
+	"Identified %d of %d songs":             "Identificadas %d de %d canciones",
+	"Stopped identifying songs (%d tagged)": "Se dejó de identificar canciones (%d etiquetadas)",
+	"Identifying %d/%d":                     "Identificando %d/%d",
+
+	// Duplicates
+	"Duplicates":      "Duplicados",
+	"Find duplicates": "Buscar duplicados",
+	"Still reading tags; more duplicates may show up": "Aún se leen etiquetas; pueden aparecer más duplicados",
+	"Merged %d copies":            "%d copias fusionadas",
+	"Merged %d copies, error: %v": "%d copias fusionadas, error: %v",
+	"Merge all duplicates":        "Fusionar todos los duplicados",
+	"Keep the first copy of each of %d songs and delete the other %d copies? Their play counts, ratings and playlist entries move to the copy that is kept.": "¿Conservar la primera copia de cada una de %d canciones y eliminar las otras %d copias? Sus reproducciones, valoraciones y entradas de listas pasan a la copia conservada.",
+	"Merge copies": "Fusionar copias",
+	"Keep %q and delete the other %d copies? Their play counts, ratings and playlist entries move to it.": "¿Conservar %q y eliminar las otras %d copias? Sus reproducciones, valoraciones y entradas de listas pasan a ella.",
+	"%d songs with copies": "%d canciones con copias",
+	"No duplicates found":  "No se encontraron duplicados",
+	"similar titles":       "títulos parecidos",
+	"same audio":           "mismo audio",
+	"x: delete copy":       "x: eliminar copia",
+	"K: keep this copy":    "K: conservar esta copia",
+	"D: merge all":         "D: fusionar todo",
+
+	// Artists view
+	"Unknown artist":       "Artista desconocido",
+	"Unknown album":        "Álbum desconocido",
+	"%d albums · %d songs": "%d álbumes · %d canciones",
+	"%d songs":             "%d canciones",
+	"Browse by artist":     "Explorar por artista",
+
+	// Genres
+	"Set genre":                            "Definir género",
+	"Play all %s":                          "Reproducir todo %s",
+	"Genre of %q:":                         "Género de %q:",
+	"Genre of %d songs:":                   "Género de %d canciones:",
+	"Set the genre of %d songs":            "Género definido en %d canciones",
+	"Set the genre of %d songs, error: %v": "Género definido en %d canciones, error: %v",
+	"genre":                                "género",
+
+	// Renaming and moving
+	"R: rename":                                     "R: renombrar",
+	"enter: rename or move • esc: cancel":           "enter: renombrar o mover • esc: cancelar",
+	"Rename or move":                                "Renombrar o mover",
+	"New name of %q, relative to its music folder:": "Nuevo nombre de %q, relativo a su carpeta de música:",
+	"Move songs":                                    "Mover canciones",
+	"Move %d songs to the folder, relative to the music folder:": "Mover %d canciones a la carpeta, relativa a la carpeta de música:",
+	"Renamed to %s":             "Renombrado a %s",
+	"Moved %d songs":            "%d canciones movidas",
+	"Moved %d songs, error: %v": "%d canciones movidas, error: %v",
+
+	// Library organizer
+	"Organize library": "Organizar biblioteca",
+	"Still reading tags; open Organize library again once done": "Aún leyendo etiquetas; abre Organizar biblioteca de nuevo al terminar",
+	"Move %d songs into artist and album folders?":              "¿Mover %d canciones a carpetas de artista y álbum?",
+	"%d of %d songs to move · dry run":                          "%d de %d canciones por mover · simulación",
+	"Every tagged song is already in place":                     "Todas las canciones etiquetadas ya están en su sitio",
+	"x: leave out":                                              "x: omitir",
+	"enter: organize":                                           "enter: organizar",
+
+	// Library sorting
+	"Sorted by %s": "Ordenado por %s",
+
+	// Library scanning
+	"Scanning folders: %d songs": "Explorando carpetas: %d canciones",
+
+	// Trash
+	"Move %q to the trash?":                      "¿Mover %q a la papelera?",
+	"Move %d songs to the trash?":                "¿Mover %d canciones a la papelera?",
+	"Moved to the trash: %s (u: undo)":           "Movida a la papelera: %s (u: deshacer)",
+	"Moved %d songs to the trash (u: undo)":      "%d canciones movidas a la papelera (u: deshacer)",
+	"Moved %d songs to the trash, error: %v":     "%d canciones movidas a la papelera, error: %v",
+	"Restored: %s":                               "Restaurada: %s",
+	"Restored %d songs":                          "%d canciones restauradas",
+	"Restored %d songs, error: %v":               "%d canciones restauradas, error: %v",
+	"Nothing to undo":                            "Nada que deshacer",
+	"Undo delete":                                "Deshacer eliminación",
+	"Empty trash":                                "Vaciar papelera",
+	"Permanently delete the songs in the trash?": "¿Eliminar de forma permanente las canciones de la papelera?",
+	"Emptied the trash: %d songs deleted":        "Papelera vaciada: %d canciones eliminadas",
+
+	// Forwarded requests
+	"1 song":           "1 canción",
+	"1 download":       "1 descarga",
+	"%d downloads":     "%d descargas",
+	"Queued %s":        "En cola: %s",
+	"Queued %s and %s": "En cola: %s y %s",
+	"Nothing to queue": "Nada que poner en cola",
+
+	// Jellyfin
+	"Show Jellyfin":         "Mostrar Jellyfin",
+	"Artists":               "Artistas",
+	"Albums":                "Álbumes",
+	"Nothing here":          "Aquí no hay nada",
+	"Fetching %s...":        "Obteniendo %s...",
+	"Fetching %d tracks...": "Obteniendo %d canciones...",
+	"No Jellyfin server configured; set url, username and password under [jellyfin]": "No hay servidor Jellyfin configurado; define url, username y password en [jellyfin]",
+
+	// Track details
+	"Path":     "Ruta",
+	"Title":    "Título",
+	"Artist":   "Artista",
+	"Album":    "Álbum",
+	"Track":    "Pista",
+	"Genre":    "Género",
+	"Year":     "Año",
+	"Comment":  "Comentario",
+	"Duration": "Duración",
+	"Bitrate":  "Tasa de bits",
+	"Size":     "Tamaño",
+	"never":    "nunca",
+
+	// Key hints
+	"↑/↓: navigate":                             "↑/↓: navegar",
+	"↑/↓: select • enter: run • esc: close":     "↑/↓: elegir • enter: ejecutar • esc: cerrar",
+	"↑/↓: select • ←/→: change • esc: close":    "↑/↓: elegir • ←/→: cambiar • esc: cerrar",
+	"↑/↓: navigate • enter: go • esc: close":    "↑/↓: navegar • enter: ir • esc: cerrar",
+	"↑/↓: navigate • enter: insert • esc: done": "↑/↓: navegar • enter: insertar • esc: listo",
+	"enter: search":             "enter: buscar",
+	"enter: play":               "enter: reproducir",
+	".: playing":                ".: en reproducción",
+	"enter: download":           "enter: descargar",
+	"enter: download marked":    "enter: descargar marcadas",
+	"enter: edit":               "enter: editar",
+	"esc: cancel":               "esc: cancelar",
+	"esc: back":                 "esc: volver",
+	"esc: done":                 "esc: listo",
+	"esc: close":                "esc: cerrar",
+	"tab: library":              "tab: biblioteca",
+	"a: queue":                  "a: a la cola",
+	"a: queue marked":           "a: marcadas a la cola",
+	"a: insert":                 "a: insertar",
+	"p: playlist":               "p: a una lista",
+	"p: add marked to playlist": "p: marcadas a una lista",
+	"p: play":                   "p: reproducir",
+	"m: menu":                   "m: menú",
+	"i: info":                   "i: info",
+	"f: like":                   "f: me gusta",
+	"o: sort":                   "o: ordenar",
+	"x: delete":                 "x: eliminar",
+	"x: delete marked":          "x: eliminar marcadas",
+	"x: remove":                 "x: quitar",
+	"x: cancel":                 "x: cancelar",
+	"X: cancel all":             "X: cancelar todas",
+	"/: filter":                 "/: filtrar",
+	"s: search":                 "s: buscar",
+	"space: pause":              "espacio: pausa",
+	"space: mark":               "espacio: marcar",
+	"t: theme":                  "t: tema",
+	"shift+↑/↓: move":           "shift+↑/↓: mover",
+	"ctrl+s: save":              "ctrl+s: guardar",
+	"d: remove":                 "d: quitar",
+	"c: clear":                  "c: vaciar",
+	"v: close":                  "v: cerrar",
+	"v: mark range":             "v: marcar rango",
+	"r: retry":                  "r: reintentar",
+	"C: clear finished":         "C: limpiar terminadas",
+	"n: new":                    "n: nueva",
+	"M: mute":                   "M: silencio",
+	"b: expand":                 "b: expandir",
+	"←/→: prev/next":            "←/→: anterior/siguiente",
+	"+/-: volume":               "+/-: volumen",
+	"Shuffle on":                "Aleatorio activado",
+	"Shuffle off":               "Aleatorio desactivado",
+	"Repeat off":                "Repetir desactivado",
+	"Repeat all":                "Repetir todo",
+	"Repeat one":                "Repetir una",
+	"ctrl+w: focus":             "ctrl+w: foco",
+	"[/]: resize":               "[/]: redimensionar",
+	"enter: open":               "enter: abrir",
+	"A: queue all":              "A: poner todo en cola",
+	"backspace: up":             "retroceso: subir",
+	"q: quit":                   "q: salir",
+}
+
+// catalogHI holds the Hindi translations.
+var catalogHI = map[string]string{
+	// Views and headers
+	"Library":                          "लाइब्रेरी",
+	"Queue":                            "कतार",
+	"Playlists":                        "प्लेलिस्ट",
+	"Playlist editor":                  "प्लेलिस्ट संपादक",
+	"Downloads":                        "डाउनलोड",
+	"Visualizer":                       "विज़ुअलाइज़र",
+	"Search":                           "खोज",
+	"Results":                          "परिणाम",
+	"YouTube Search":                   "YouTube खोज",
+	"Results for '%s'":                 "'%s' के परिणाम",
+	"Now Playing":                      "अभी चल रहा है",
+	"Sort & Filter":                    "क्रम और फ़िल्टर",
+	"Go to track, playlist or command": "गाने, प्लेलिस्ट या कमांड पर जाएँ",
+	"Insert into %s":                   "%s में जोड़ें",
+	"(unsaved)":                        "(सहेजा नहीं गया)",
+
+	// Empty states and placeholders
+	"Loading...":                               "लोड हो रहा है...",
+	"No song playing":                          "कोई गाना नहीं चल रहा",
+	"No music files found in %s":               "%s में कोई संगीत नहीं मिला",
+	"Press 's' to search and download music":   "संगीत खोजने और डाउनलोड करने के लिए 's' दबाएँ",
+	"No songs match the filter":                "फ़िल्टर से कोई गाना मेल नहीं खाता",
+	"Queue is empty":                           "कतार खाली है",
+	"Press 'a' in the library to queue a song": "गाना कतार में जोड़ने के लिए लाइब्रेरी में 'a' दबाएँ",
+	"No results":                               "कोई परिणाम नहीं",
+	"%s search failed: %v":                     "%s खोज विफल: %v",
+	"No results found":                         "कोई परिणाम नहीं मिला",
+	"Searching YouTube...":                     "YouTube पर खोज रहे हैं...",
+	"No downloads yet":                         "अभी कोई डाउनलोड नहीं",
+	"Press 's' to search, then enter on a result to download it": "खोजने के लिए 's' दबाएँ, फिर डाउनलोड के लिए परिणाम पर enter दबाएँ",
+	"No playlists yet": "अभी कोई प्लेलिस्ट नहीं",
+	"Press 'n' to create one, or 'p' in the library to add songs to one": "नई बनाने के लिए 'n' दबाएँ, या लाइब्रेरी में गाने जोड़ने के लिए 'p'",
+	"Playlist is empty":                              "प्लेलिस्ट खाली है",
+	"Press 'a' to insert songs from the library":     "लाइब्रेरी से गाने जोड़ने के लिए 'a' दबाएँ",
+	"No matches":                                     "कोई मेल नहीं",
+	"Search for music on YouTube...":                 "YouTube पर संगीत खोजें...",
+	"filter":                                         "फ़िल्टर",
+	"Type to find tracks, playlists and commands...": "गाने, प्लेलिस्ट और कमांड खोजने के लिए लिखें...",
+	"Type to find a track...":                        "गाना खोजने के लिए लिखें...",
+
+	// Status messages
+	"Theme: %s":                   "थीम: %s",
+	"Now playing: %s":             "अभी चल रहा है: %s",
+	"Download finished: %s":       "डाउनलोड पूरा: %s",
+	"Download failed: %s":         "डाउनलोड विफल: %s",
+	"Error: %s":                   "त्रुटि: %s",
+	"Find":                        "खोजें",
+	"empty":                       "खाली",
+	"(%d of %d)":                  "(%d में से %d)",
+	"Playing next: %s":            "इसके बाद: %s",
+	"Queued: %s":                  "कतार में जोड़ा: %s",
+	"Queued %d songs":             "%d गाने कतार में जोड़े",
+	"Queued %d downloads":         "%d डाउनलोड कतार में",
+	"+%d queued":                  "+%d कतार में",
+	"Liked: %s":                   "पसंद किया: %s",
+	"Unliked: %s":                 "पसंद हटाई: %s",
+	"Muted":                       "आवाज़ बंद",
+	"Unmuted":                     "आवाज़ चालू",
+	"mute":                        "मौन",
+	"Queue cleared":               "कतार खाली की गई",
+	"Download error: %v":          "डाउनलोड त्रुटि: %v",
+	"Downloading: %s":             "डाउनलोड हो रहा है: %s",
+	"Download cancelled":          "डाउनलोड रद्द किया गया",
+	"All downloads cancelled":     "सभी डाउनलोड रद्द किए गए",
+	"Cancelled: %s":               "रद्द: %s",
+	"Retrying: %s":                "फिर से कोशिश: %s",
+	"(cancelled)":                 "(रद्द)",
+	"(queued)":                    "(कतार में)",
+	"Deleted playlist: %s":        "प्लेलिस्ट हटाई गई: %s",
+	"Saved playlist: %s":          "प्लेलिस्ट सहेजी गई: %s",
+	"Playing playlist: %s":        "प्लेलिस्ट चल रही है: %s",
+	"Playing playlist %s from %s": "प्लेलिस्ट %s, %s से चल रही है",
+	"Added %d songs to %s":        "%d गाने %s में जोड़े गए",
+	"Inserted: %s":                "जोड़ा गया: %s",
+	"%d tracks":                   "%d गाने",
+	"%d active":                   "%d सक्रिय",
+	"%d queued":                   "%d कतार में",
+	"[E: dismiss]":                "[E: हटाएँ]",
+	"shuffle":                     "शफ़ल",
+	"repeat":                      "दोहराएँ",
+	"repeat one":                  "एक दोहराएँ",
+
+	// Dialogs
+	"Cancel all downloads":                               "सभी डाउनलोड रद्द करें",
+	"Stop the active download and drop all queued ones?": "चल रहा डाउनलोड रोकें और कतार के सभी डाउनलोड हटाएँ?",
+	"Cancel download":                                    "डाउनलोड रद्द करें",
+	"Stop the download in progress?":                     "चल रहा डाउनलोड रोकें?",
+	"Clear queue":                                        "कतार खाली करें",
+	"Remove all %d tracks from the queue?":               "कतार से सभी %d गाने हटाएँ?",
+	"Already downloaded":                                 "पहले से डाउनलोड है",
+	"%q is already in the library. Download again and overwrite it?": "%q पहले से लाइब्रेरी में है। फिर से डाउनलोड करके बदलें?",
+	"Delete":                    "हटाएँ",
+	"New playlist":              "नई प्लेलिस्ट",
+	"Name of the new playlist:": "नई प्लेलिस्ट का नाम:",
+	"Delete playlist":           "प्लेलिस्ट हटाएँ",
+	"Delete the playlist %q? The songs stay in the library.": "प्लेलिस्ट %q हटाएँ? गाने लाइब्रेरी में रहेंगे।",
+	"Unsaved changes":              "बिना सहेजे बदलाव",
+	"Discard the changes to %q?":   "%q के बदलाव छोड़ दें?",
+	"Add to playlist":              "प्लेलिस्ट में जोड़ें",
+	"Add %q to playlist:":          "%q को प्लेलिस्ट में जोड़ें:",
+	"Add %d songs to playlist:":    "%d गाने प्लेलिस्ट में जोड़ें:",
+	"enter: confirm • esc: cancel": "enter: पुष्टि • esc: रद्द",
+	"y/enter: yes • n/esc: no":     "y/enter: हाँ • n/esc: नहीं",
+
+	// Menus and commands
+	"Play":                   "चलाएँ",
+	"Play next":              "इसके बाद चलाएँ",
+	"Add to queue":           "कतार में जोड़ें",
+	"Show info":              "जानकारी देखें",
+	"Reveal in file manager": "फ़ाइल मैनेजर में दिखाएँ",
+	"Download":               "डाउनलोड करें",
+	"Open in browser":        "ब्राउज़र में खोलें",
+	"Search YouTube":         "YouTube पर खोजें",
+	"Show library":           "लाइब्रेरी दिखाएँ",
+	"Show queue":             "कतार दिखाएँ",
+	"Show playlists":         "प्लेलिस्ट दिखाएँ",
+	"The playing track is not in the library": "चल रहा गाना लाइब्रेरी में नहीं है",
+	"Log":                 "लॉग",
+	"Nothing logged yet":  "अभी तक कुछ लॉग नहीं हुआ",
+	"Copied log line":     "लॉग पंक्ति कॉपी की गई",
+	"Copied %d log lines": "%d लॉग पंक्तियाँ कॉपी की गईं",
+	"Show log":            "लॉग दिखाएँ",
+	"y: copy line":        "y: पंक्ति कॉपी करें",
+	"c: copy all":         "c: सब कॉपी करें",
+	"Toggle visualizer":   "विज़ुअलाइज़र दिखाएँ/छिपाएँ",
+	"Play / pause":        "चलाएँ / रोकें",
+	"Next track":          "अगला गाना",
+	"Previous track":      "पिछला गाना",
+	"Toggle mute":         "आवाज़ बंद/चालू",
+	"Cycle theme":         "थीम बदलें",
+	"Quit":                "बाहर निकलें",
+	"track":               "गाना",
+	"playlist":            "प्लेलिस्ट",
+	"command":             "कमांड",
+	"Sort by":             "क्रम",
+	"Direction":           "दिशा",
+	"Liked only":          "केवल पसंदीदा",
+	"Unplayed only":       "केवल अनसुने",
+	"Ascending":           "आरोही",
+	"Descending":          "अवरोही",
+	"Name":                "नाम",
+	"Date added":          "जोड़ने की तारीख",
+	"Play count":          "बार चलाया",
+	"Last played":         "पिछली बार चलाया",
+
+	// Casting
+	"Cast to device":                "डिवाइस पर कास्ट करें",
+	"Cast to":                       "कास्ट करें",
+	"Stop casting":                  "कास्ट करना बंद करें",
+	"Stopped casting":               "कास्ट करना बंद हुआ",
+	"Searching for cast devices...": "कास्ट डिवाइस खोजे जा रहे हैं...",
+	"No cast devices found":         "कोई कास्ट डिवाइस नहीं मिला",
+	"Connecting to %s...":           "%s से जुड़ रहे हैं...",
+	"Casting to %s":                 "%s पर कास्ट हो रहा है",
+
+	// Multi-room
+	"Following %s":                        "%s के साथ चल रहे हैं",
+	"Lost connection to %s, retrying: %v": "%s से कनेक्शन टूट गया, फिर से कोशिश हो रही है: %v",
+
+	// Library scan
+	"Reading tags %d/%d": "%d/%d के टैग पढ़े जा रहे हैं",
+
+	// Backup
+	"Back up library":  "लाइब्रेरी का बैकअप लें",
+	"Cancel backup":    "बैकअप रद्द करें",
+	"Backup cancelled": "बैकअप रद्द किया गया",
+	"No backup remote set (set remote under [backup])": "कोई बैकअप रिमोट सेट नहीं है ([backup] में remote सेट करें)",
+	"Backing up to %s":  "%s पर बैकअप हो रहा है",
+	"Backing up %s":     "%s का बैकअप हो रहा है",
+	"music":             "संगीत",
+	"library data":      "लाइब्रेरी डेटा",
+	"Backup failed":     "बैकअप विफल रहा",
+	"Last backup %s":    "पिछला बैकअप %s",
+	"Not backed up yet": "अभी तक बैकअप नहीं हुआ",
+
+	// Episodes
+	"Mark played":         "सुना हुआ चिह्नित करें",
+	"Mark unplayed":       "अनसुना चिह्नित करें",
+	"Marked played: %s":   "सुना हुआ चिह्नित: %s",
+	"Marked unplayed: %s": "अनसुना चिह्नित: %s",
+
+	// Equalizer
+	"Equalizer":    "इक्वलाइज़र",
+	"Preset":       "प्रीसेट",
+	"Custom":       "कस्टम",
+	"Flat":         "सपाट",
+	"Bass boost":   "बास बूस्ट",
+	"Vocal":        "वोकल",
+	"Treble boost": "ट्रेबल बूस्ट",
+	"Loudness":     "लाउडनेस",
+	"↑/↓: select • ←/→: change • 0: flat • esc: close": "↑/↓: चुनें • ←/→: बदलें • 0: सपाट • esc: बंद करें",
+
+	// A–B loop
+	"Loop start: %s": "लूप की शुरुआत: %s",
+	"Looping %s–%s":  "%s–%s दोहराया जा रहा है",
+	"Loop cleared":   "लूप हटाया गया",
+
+	// Resume
+	"Resume playback?":                        "प्लेबैक फिर से शुरू करें?",
+	"Continue %s from %s where you left off?": "%s को %s से जारी रखें, जहाँ आपने छोड़ा था?",
+
+	// Visualizer modes
+	"Spectrum":             "स्पेक्ट्रम",
+	"Waveform":             "तरंग रूप",
+	"w: spectrum/waveform": "w: स्पेक्ट्रम/तरंग रूप",
+
+	// Smart playlists
+	"New smart playlist":                                           "नई स्मार्ट प्लेलिस्ट",
+	"Name of the new smart playlist:":                              "नई स्मार्ट प्लेलिस्ट का नाम:",
+	"%s is defined in the config file":                             "%s कॉन्फ़िग फ़ाइल में परिभाषित है",
+	"Delete smart playlist":                                        "स्मार्ट प्लेलिस्ट हटाएँ",
+	"Delete the smart playlist %q? The songs stay in the library.": "स्मार्ट प्लेलिस्ट %q हटाएँ? गाने लाइब्रेरी में रहेंगे।",
+	"Deleted smart playlist: %s":                                   "स्मार्ट प्लेलिस्ट हटाई गई: %s",
+	"Rules of %s":                                                  "%s के नियम",
+	"Rules separated by ';', e.g. artist contains Queen; year < 1990. Start with 'match any;' to match any rule.": "';' से अलग किए नियम, जैसे artist contains Queen; year < 1990. किसी भी एक नियम से मिलान के लिए 'match any;' से शुरू करें।",
+	"Saved smart playlist: %s (%d songs)":                      "स्मार्ट प्लेलिस्ट सहेजी गई: %s (%d गाने)",
+	"No songs match %s":                                        "%s से कोई गाना मेल नहीं खाता",
+	"Press 'S' for a smart playlist that picks songs by rules": "नियमों से गाने चुनने वाली स्मार्ट प्लेलिस्ट के लिए 'S' दबाएँ",
+	"S: new smart":                                             "S: नई स्मार्ट",
+
+	// Ratings
+	"Rating":                   "रेटिंग",
+	"Any":                      "कोई भी",
+	"Min. rating":              "न्यूनतम रेटिंग",
+	"Cleared the rating of %s": "%s की रेटिंग हटाई गई",
+	"Rated %s: %s":             "%s को रेटिंग दी: %s",
+	"1-5: rate":                "1-5: रेटिंग",
+
+	// Recently added and recently played
+	"Recently added":                     "हाल ही में जोड़े गए",
+	"Recently played":                    "हाल ही में सुने गए",
+	"Show":                               "दिखाएँ",
+	"No songs added in the last %d days": "पिछले %d दिनों में कोई गाना नहीं जोड़ा गया",
+	"Nothing played to the end yet":      "अभी तक कुछ भी अंत तक नहीं सुना गया",
+
+	// Tag lookup
+	"Look up tags":                          "टैग खोजें",
+	"Tags can only be written to MP3 files": "टैग केवल MP3 फ़ाइलों में लिखे जा सकते हैं",
+	"Looking up %s…":                        "%s खोजा जा रहा है…",
+	"No match found for %s":                 "%s के लिए कोई मिलान नहीं मिला",
+	"Tags are already correct: %s":          "टैग पहले से सही हैं: %s",
+	"Write these tags to %s? (%d%% match)":  "ये टैग %s में लिखें? (%d%% मिलान)",
+	"Tagged: %s":                            "टैग किया गया: %s",
+	"track %d":                              "ट्रैक %d",
+
+	// Identifying songs by sound
+	"Identify by sound":                     "ध्वनि से पहचानें",
+	"Identifying %s…":                       "%s की पहचान हो रही है…",
+	"Identify unknown songs":                "अज्ञात गाने पहचानें",
+	"Stop identifying songs":                "गाने पहचानना बंद करें",
+	"Already identifying songs":             "गाने पहले से पहचाने जा रहे हैं",
+	"Every song has a title":                "हर गाने का शीर्षक है",
+	"Identifying %d songs":                  "%d गाने पहचाने जा रहे हैं",
+	"Identified %d of %d songs":             "%[2]d में से %[1]d गाने पहचाने गए",
+	"Stopped identifying songs (%d tagged)": "गाने पहचानना बंद किया (%d टैग किए गए)",
+	"Identifying %d/%d":                     "पहचान %d/%d",
+
+	// Duplicates
+	"Duplicates":      "डुप्लिकेट",
+	"Find duplicates": "डुप्लिकेट खोजें",
+	"Still reading tags; more duplicates may show up": "टैग अभी पढ़े जा रहे हैं; और डुप्लिकेट दिख सकते हैं",
+	"Merged %d copies":            "%d कॉपियाँ मिलाई गईं",
+	"Merged %d copies, error: %v": "%d कॉपियाँ मिलाई गईं, त्रुटि: %v",
+	"Merge all duplicates":        "सभी डुप्लिकेट मिलाएँ",
+	"Keep the first copy of each of %d songs and delete the other %d copies? Their play counts, ratings and playlist entries move to the copy that is kept.": "%d गानों में से हर एक की पहली कॉपी रखें और बाकी %d कॉपियाँ हटाएँ? उनकी प्ले गिनती, रेटिंग और प्लेलिस्ट प्रविष्टियाँ रखी गई कॉपी में चली जाएँगी।",
+	"Merge copies": "कॉपियाँ मिलाएँ",
+	"Keep %q and delete the other %d copies? Their play counts, ratings and playlist entries move to it.": "%q रखें और बाकी %d कॉपियाँ हटाएँ? उनकी प्ले गिनती, रेटिंग और प्लेलिस्ट प्रविष्टियाँ इसमें चली जाएँगी।",
+	"%d songs with copies": "कॉपियों वाले %d गाने",
+	"No duplicates found":  "कोई डुप्लिकेट नहीं मिला",
+	"similar titles":       "मिलते-जुलते शीर्षक",
+	"same audio":           "एक जैसा ऑडियो",
+	"x: delete copy":       "x: कॉपी हटाएँ",
+	"K: keep this copy":    "K: यह कॉपी रखें",
+	"D: merge all":         "D: सब मिलाएँ",
+
+	// Artists view
+	"Unknown artist":       "अज्ञात कलाकार",
+	"Unknown album":        "अज्ञात एल्बम",
+	"%d albums · %d songs": "%d एल्बम · %d गाने",
+	"%d songs":             "%d गाने",
+	"Browse by artist":     "कलाकार के अनुसार देखें",
+
+	// Genres
+	"Set genre":                            "शैली सेट करें",
+	"Play all %s":                          "सभी %s चलाएँ",
+	"Genre of %q:":                         "%q की शैली:",
+	"Genre of %d songs:":                   "%d गानों की शैली:",
+	"Set the genre of %d songs":            "%d गानों की शैली सेट की गई",
+	"Set the genre of %d songs, error: %v": "%d गानों की शैली सेट की गई, त्रुटि: %v",
+	"genre":                                "शैली",
+
+	// Renaming and moving
+	"R: rename":                                     "R: नाम बदलें",
+	"enter: rename or move • esc: cancel":           "enter: नाम बदलें या ले जाएँ • esc: रद्द करें",
+	"Rename or move":                                "नाम बदलें या ले जाएँ",
+	"New name of %q, relative to its music folder:": "%q का नया नाम, उसके संगीत फ़ोल्डर के सापेक्ष:",
+	"Move songs":                                    "गाने ले जाएँ",
+	"Move %d songs to the folder, relative to the music folder:": "%d गानों को इस फ़ोल्डर में ले जाएँ, संगीत फ़ोल्डर के सापेक्ष:",
+	"Renamed to %s":             "नया नाम: %s",
+	"Moved %d songs":            "%d गाने ले जाए गए",
+	"Moved %d songs, error: %v": "%d गाने ले जाए गए, त्रुटि: %v",
+
+	// Library organizer
+	"Organize library": "लाइब्रेरी व्यवस्थित करें",
+	"Still reading tags; open Organize library again once done": "टैग अभी पढ़े जा रहे हैं; पूरा होने पर लाइब्रेरी व्यवस्थित करें फिर खोलें",
+	"Move %d songs into artist and album folders?":              "%d गानों को कलाकार और एल्बम फ़ोल्डरों में ले जाएँ?",
+	"%d of %d songs to move · dry run":                          "%[2]d में से %[1]d गाने ले जाने हैं · पूर्वावलोकन",
+	"Every tagged song is already in place":                     "सभी टैग वाले गाने पहले से अपनी जगह पर हैं",
+	"x: leave out":                                              "x: छोड़ें",
+	"enter: organize":                                           "enter: व्यवस्थित करें",
+
+	// Library sorting
+	"Sorted by %s": "%s के अनुसार क्रमबद्ध",
+
+	// Library scanning
+	"Scanning folders: %d songs": "फ़ोल्डर स्कैन हो रहे हैं: %d गाने",
+
+	// Trash
+	"Move %q to the trash?":                      "%q को कचरे में ले जाएँ?",
+	"Move %d songs to the trash?":                "%d गाने कचरे में ले जाएँ?",
+	"Moved to the trash: %s (u: undo)":           "कचरे में ले जाया गया: %s (u: पूर्ववत करें)",
+	"Moved %d songs to the trash (u: undo)":      "%d गाने कचरे में ले जाए गए (u: पूर्ववत करें)",
+	"Moved %d songs to the trash, error: %v":     "%d गाने कचरे में ले जाए गए, त्रुटि: %v",
+	"Restored: %s":                               "वापस लाया गया: %s",
+	"Restored %d songs":                          "%d गाने वापस लाए गए",
+	"Restored %d songs, error: %v":               "%d गाने वापस लाए गए, त्रुटि: %v",
+	"Nothing to undo":                            "पूर्ववत करने को कुछ नहीं",
+	"Undo delete":                                "हटाना पूर्ववत करें",
+	"Empty trash":                                "कचरा खाली करें",
+	"Permanently delete the songs in the trash?": "कचरे के गानों को स्थायी रूप से हटाएँ?",
+	"Emptied the trash: %d songs deleted":        "कचरा खाली किया गया: %d गाने हटाए गए",
+
+	// Forwarded requests
+	"1 song":           "1 गाना",
+	"1 download":       "1 डाउनलोड",
+	"%d downloads":     "%d डाउनलोड",
+	"Queued %s":        "कतार में जोड़े: %s",
+	"Queued %s and %s": "कतार में जोड़े: %s और %s",
+	"Nothing to queue": "कतार में जोड़ने को कुछ नहीं",
+
+	// Jellyfin
+	"Show Jellyfin":         "Jellyfin दिखाएँ",
+	"Artists":               "कलाकार",
+	"Albums":                "एल्बम",
+	"Nothing here":          "यहाँ कुछ नहीं है",
+	"Fetching %s...":        "%s लाया जा रहा है...",
+	"Fetching %d tracks...": "%d गाने लाए जा रहे हैं...",
+	"No Jellyfin server configured; set url, username and password under [jellyfin]": "कोई Jellyfin सर्वर सेट नहीं है; [jellyfin] में url, username और password सेट करें",
+
+	// Track details
+	"Path":     "पथ",
+	"Title":    "शीर्षक",
+	"Artist":   "कलाकार",
+	"Album":    "एल्बम",
+	"Track":    "ट्रैक",
+	"Genre":    "शैली",
+	"Year":     "वर्ष",
+	"Comment":  "टिप्पणी",
+	"Duration": "अवधि",
+	"Bitrate":  "बिटरेट",
+	"Size":     "आकार",
+	"never":    "कभी नहीं",
+
+	// Key hints
+	"↑/↓: navigate":                             "↑/↓: चलें",
+	"↑/↓: select • enter: run • esc: close":     "↑/↓: चुनें • enter: चलाएँ • esc: बंद",
+	"↑/↓: select • ←/→: change • esc: close":    "↑/↓: चुनें • ←/→: बदलें • esc: बंद",
+	"↑/↓: navigate • enter: go • esc: close":    "↑/↓: चलें • enter: जाएँ • esc: बंद",
+	"↑/↓: navigate • enter: insert • esc: done": "↑/↓: चलें • enter: जोड़ें • esc: हो गया",
+	"enter: search":             "enter: खोजें",
+	"enter: play":               "enter: चलाएँ",
+	".: playing":                ".: चल रहा गाना",
+	"enter: download":           "enter: डाउनलोड",
+	"enter: download marked":    "enter: चिह्नित डाउनलोड",
+	"enter: edit":               "enter: संपादित करें",
+	"esc: cancel":               "esc: रद्द",
+	"esc: back":                 "esc: वापस",
+	"esc: done":                 "esc: हो गया",
+	"esc: close":                "esc: बंद",
+	"tab: library":              "tab: लाइब्रेरी",
+	"a: queue":                  "a: कतार में",
+	"a: queue marked":           "a: चिह्नित कतार में",
+	"a: insert":                 "a: जोड़ें",
+	"p: playlist":               "p: प्लेलिस्ट में",
+	"p: add marked to playlist": "p: चिह्नित प्लेलिस्ट में",
+	"p: play":                   "p: चलाएँ",
+	"m: menu":                   "m: मेनू",
+	"i: info":                   "i: जानकारी",
+	"f: like":                   "f: पसंद",
+	"o: sort":                   "o: क्रम",
+	"x: delete":                 "x: हटाएँ",
+	"x: delete marked":          "x: चिह्नित हटाएँ",
+	"x: remove":                 "x: निकालें",
+	"x: cancel":                 "x: रद्द",
+	"X: cancel all":             "X: सभी रद्द",
+	"/: filter":                 "/: फ़िल्टर",
+	"s: search":                 "s: खोज",
+	"space: pause":              "space: रोकें",
+	"space: mark":               "space: चिह्नित करें",
+	"t: theme":                  "t: थीम",
+	"shift+↑/↓: move":           "shift+↑/↓: खिसकाएँ",
+	"ctrl+s: save":              "ctrl+s: सहेजें",
+	"d: remove":                 "d: निकालें",
+	"c: clear":                  "c: खाली करें",
+	"v: close":                  "v: बंद",
+	"v: mark range":             "v: श्रेणी चिह्नित करें",
+	"r: retry":                  "r: फिर से",
+	"C: clear finished":         "C: पूरे हटाएँ",
+	"n: new":                    "n: नई",
+	"M: mute":                   "M: मौन",
+	"b: expand":                 "b: बड़ा करें",
+	"←/→: prev/next":            "←/→: पिछला/अगला",
+	"+/-: volume":               "+/-: आवाज़",
+	"Shuffle on":                "शफ़ल चालू",
+	"Shuffle off":               "शफ़ल बंद",
+	"Repeat off":                "दोहराना बंद",
+	"Repeat all":                "सभी दोहराएँ",
+	"Repeat one":                "एक दोहराएँ",
+	"ctrl+w: focus":             "ctrl+w: फ़ोकस",
+	"[/]: resize":               "[/]: आकार बदलें",
+	"enter: open":               "enter: खोलें",
+	"A: queue all":              "A: सब कतार में",
+	"backspace: up":             "backspace: ऊपर",
+	"q: quit":                   "q: बाहर",
+}
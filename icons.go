@@ -0,0 +1,94 @@
+// Package main provides the ASCII-only icon fallback for Personal Musician.
+// Some terminals and fonts cannot show emoji or box-drawing glyphs. In ASCII
+// mode every rendered frame has those glyphs replaced with plain characters.
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// asciiGlyphs maps the glyphs used by the UI to plain ASCII replacements.
+// Replacements are padded to the width of the original glyph so boxes and
+// columns stay aligned.
+var asciiGlyphs = []string{
+	// Playback and status icons
+	"▶", ">", "⏸", "||", "♪", "~", "♫", "#", "🎵", "#",
+	"🔀", "SH", "🔁", "RP", "🔂", "R1", "🔇", "<x", "🔉", "<)", "🔊", "<)",
+	"⚠", "!", "⇩", "v", "✓", "+", "✗", "x", "⊘", "-", "♥", "<3", "★", "*", "☆", ".", "×", "x",
+	"●", "*", "○", "o", "✎", "*", "➕", "+", "ℹ", "i", "—", "-", "–", "-", "…", "~",
+	"🔎", "?", "🔍", "?", "⇅", "=", "👋", "o/", "☁", "^",
+	"🎼", "#", "📚", "#", "📋", "#", "📊", "#", "🎬", "#", "📜", "#", "🎧", "@", "🕒", "@", "⧉", "#", "📁", "#",
+
+	// Arrows and separators in key hints and menus
+	"↑", "^", "↓", "v", "←", "<", "→", ">", "‹", "<", "›", ">", "•", "|", "·", "-",
+
+	// Bars, meters and the waveform
+	"█", "#", "░", "-", "▁", "_", "▂", ".", "▃", "-", "▄", "=", "▅", "+", "▆", "*", "▇", "#",
+
+	// Borders and scrollbars
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+", "─", "-", "│", "|", "┃", "#",
+
+	// Spinner frames
+	"⣾", "*", "⣽", "*", "⣻", "*", "⢿", "*", "⡿", "*", "⣟", "*", "⣯", "*", "⣷", "*",
+}
+
+// asciiMode reports whether glyphs are replaced with ASCII.
+var asciiMode bool
+
+// asciiReplacer performs the glyph substitution in ASCII mode.
+var asciiReplacer *strings.Replacer
+
+// SetASCIIMode enables or disables the ASCII-only fallback.
+func SetASCIIMode(on bool) {
+	asciiMode = on
+	if on && asciiReplacer == nil {
+		pairs := make([]string, len(asciiGlyphs))
+		for i := 0; i < len(asciiGlyphs); i += 2 {
+			glyph, plain := asciiGlyphs[i], asciiGlyphs[i+1]
+			if pad := lipgloss.Width(glyph) - len(plain); pad > 0 {
+				plain += strings.Repeat(" ", pad)
+			}
+			pairs[i], pairs[i+1] = glyph, plain
+		}
+		asciiReplacer = strings.NewReplacer(pairs...)
+	}
+}
+
+// DetectASCIIMode decides whether to use ASCII glyphs. PM_ASCII=1/0 or the
+// ascii setting of the config file forces the mode; otherwise it is enabled
+// on the Linux console and for non-UTF-8 locales.
+func DetectASCIIMode() bool {
+	if v := os.Getenv("PM_ASCII"); v != "" {
+		if on, err := strconv.ParseBool(v); err == nil {
+			return on
+		}
+	}
+	if config.UI.ASCII != nil {
+		return *config.UI.ASCII
+	}
+
+	if os.Getenv("TERM") == "linux" {
+		return true // The kernel console has no emoji or rounded corners
+	}
+
+	// The first set locale variable decides, as in setlocale(3)
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
+
+// Glyphs returns s with glyphs replaced by ASCII when ASCII mode is on.
+func Glyphs(s string) string {
+	if !asciiMode {
+		return s
+	}
+	return asciiReplacer.Replace(s)
+}
@@ -0,0 +1,168 @@
+// Package main provides the library index of Personal Musician.
+// The tags, duration and audio hash of every track are kept in a bbolt
+// database in the data directory, keyed by path and stamped with the
+// file's size and modification time. At startup the library is shown from
+// the index straight away, before the music directories have been walked,
+// and afterwards only new and changed files are read again. The database is
+// opened only for the moment it is read or written, so the command line can
+// use it while the player runs.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// LibraryIndexFile is where the library index is stored.
+var LibraryIndexFile = dataPath("library.db")
+
+// indexBucket holds one entry per track, keyed by path.
+var indexBucket = []byte("tracks")
+
+// indexOpenTimeout is how long to wait for another process that has the
+// index open.
+const indexOpenTimeout = time.Second
+
+// indexEntry is what the index knows about a track.
+type indexEntry struct {
+	ModTime  time.Time     `json:"mod_time"`
+	Size     int64         `json:"size"`
+	Tags     Tags          `json:"tags"`
+	Duration time.Duration `json:"duration"`
+	Hash     string        `json:"hash,omitempty"` // See audioHash
+}
+
+// indexEntryOf returns the index entry of a file whose metadata has been
+// read.
+func indexEntryOf(file MusicFile) indexEntry {
+	return indexEntry{ModTime: file.ModTime, Size: file.Size, Tags: file.Tags, Duration: file.Duration, Hash: file.Hash}
+}
+
+// current reports whether the entry still describes file as found on disk.
+func (e indexEntry) current(file MusicFile) bool {
+	return e.ModTime.Equal(file.ModTime) && e.Size == file.Size
+}
+
+// musicFile returns the track at path as the index describes it.
+func (e indexEntry) musicFile(path string) MusicFile {
+	fileName := filepath.Base(path)
+	return MusicFile{
+		Name:     strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		Path:     path,
+		FileName: fileName,
+		ModTime:  e.ModTime,
+		Size:     e.Size,
+		Tags:     e.Tags,
+		Duration: e.Duration,
+		Hash:     e.Hash,
+	}
+}
+
+// openIndex opens the index database, creating it when writing.
+func openIndex(readOnly bool) (*bolt.DB, error) {
+	if !readOnly {
+		if err := os.MkdirAll(filepath.Dir(LibraryIndexFile), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+	db, err := bolt.Open(LibraryIndexFile, 0644, &bolt.Options{Timeout: indexOpenTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open library index: %w", err)
+	}
+	return db, nil
+}
+
+// loadIndex reads every entry of the index. A missing index is empty.
+func loadIndex() (map[string]indexEntry, error) {
+	entries := make(map[string]indexEntry)
+	if _, err := os.Stat(LibraryIndexFile); errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	db, err := openIndex(true)
+	if err != nil {
+		return entries, err
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(indexBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var entry indexEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return nil // Skip entries written by an incompatible version; they are read again
+			}
+			entries[string(key)] = entry
+			return nil
+		})
+	})
+	if err != nil {
+		return entries, fmt.Errorf("failed to read library index: %w", err)
+	}
+	return entries, nil
+}
+
+// updateIndex stores the entries in put and removes the paths in remove, in
+// a single transaction.
+func updateIndex(put map[string]indexEntry, remove []string) error {
+	if len(put) == 0 && len(remove) == 0 {
+		return nil
+	}
+	db, err := openIndex(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(indexBucket)
+		if err != nil {
+			return err
+		}
+		for path, entry := range put {
+			value, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(path), value); err != nil {
+				return err
+			}
+		}
+		for _, path := range remove {
+			if err := bucket.Delete([]byte(path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write library index: %w", err)
+	}
+	return nil
+}
+
+// inMusicDirs reports whether path lies in one of the music directories.
+func inMusicDirs(path string) bool {
+	for _, dir := range append([]string{MusicDir}, extraMusicDirs...) {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexedMusicFiles returns the tracks of the music directories as the
+// index last saw them, without touching the directories themselves. It is
+// empty until the library has been scanned once.
+func IndexedMusicFiles() []MusicFile {
+	return libraryMetadata.files()
+}
@@ -0,0 +1,244 @@
+// Package main provides the single-instance guard for Personal Musician.
+// The first copy of the TUI holds a lock file and listens on a Unix socket in
+// the data directory. A second copy finds the socket, forwards its file and
+// URL arguments to the running instance (which queues them) and exits,
+// instead of two players fighting over the speaker and library.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// instanceTimeout bounds how long a forwarded request may take.
+const instanceTimeout = 5 * time.Second
+
+// errNoInstance is returned when no other instance is listening.
+var errNoInstance = errors.New("no running instance")
+
+// instanceRequest is a request sent to the running instance.
+type instanceRequest struct {
+	Command string   `json:"command"`        // "open" or "add" queue files and URLs; see ctlCommands
+	Args    []string `json:"args,omitempty"` // Absolute paths or URLs
+}
+
+// instanceResponse is the running instance's answer to a request.
+type instanceResponse struct {
+	OK      bool          `json:"ok"`
+	Message string        `json:"message,omitempty"`
+	Status  *playerStatus `json:"status,omitempty"` // Answer to "status"
+}
+
+// remoteMsg delivers a forwarded request to the TUI, which answers on reply.
+type remoteMsg struct {
+	req   instanceRequest
+	reply chan instanceResponse
+}
+
+// socketPath returns the path of the instance socket.
+func socketPath() string {
+	return dataPath("personal-musician.sock")
+}
+
+// SendToInstance forwards a request to the running instance. It returns
+// errNoInstance when none is listening.
+func SendToInstance(req instanceRequest) (instanceResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath(), time.Second)
+	if err != nil {
+		return instanceResponse{}, errNoInstance
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return instanceResponse{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	var resp instanceResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return instanceResponse{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp, nil
+}
+
+// Instance is the lock and socket held by the running instance.
+type Instance struct {
+	lock     *os.File
+	listener net.Listener
+}
+
+// AcquireInstance takes the instance lock and starts listening for requests.
+// It fails if another instance holds the lock.
+func AcquireInstance() (*Instance, error) {
+	lock, err := os.OpenFile(dataPath("personal-musician.lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("another instance is already running")
+	}
+
+	// Holding the lock means any socket left behind is stale
+	os.Remove(socketPath())
+	listener, err := net.Listen("unix", socketPath())
+	if err != nil {
+		unlockFile(lock)
+		lock.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", filepath.Base(socketPath()), err)
+	}
+	return &Instance{lock: lock, listener: listener}, nil
+}
+
+// Serve passes each request to the program until the instance is closed.
+func (in *Instance) Serve(program *tea.Program) {
+	for {
+		conn, err := in.listener.Accept()
+		if err != nil {
+			return // Closed
+		}
+		go in.handle(conn, program)
+	}
+}
+
+// handle answers one connection.
+func (in *Instance) handle(conn net.Conn, program *tea.Program) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceTimeout))
+
+	var req instanceRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		return
+	}
+
+	reply := make(chan instanceResponse, 1)
+	program.Send(remoteMsg{req: req, reply: reply})
+
+	var resp instanceResponse
+	select {
+	case resp = <-reply:
+	case <-time.After(instanceTimeout):
+		resp = instanceResponse{Message: "the running instance did not answer"}
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Close stops listening and releases the lock.
+func (in *Instance) Close() {
+	in.listener.Close()
+	os.Remove(socketPath())
+	unlockFile(in.lock)
+	in.lock.Close()
+}
+
+// resolveOpenArgs checks that every argument is a file, directory or YouTube
+// URL and makes paths absolute, since the running instance has its own
+// working directory.
+func resolveOpenArgs(args []string) ([]string, error) {
+	resolved := make([]string, len(args))
+	for i, arg := range args {
+		if _, ok := ParseVideoURL(arg); ok {
+			resolved[i] = arg
+			continue
+		}
+		if _, err := os.Stat(arg); err != nil {
+			return nil, fmt.Errorf("unknown command, file or URL: %s", arg)
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", arg, err)
+		}
+		resolved[i] = abs
+	}
+	return resolved, nil
+}
+
+// titleResolvedMsg carries a forwarded URL whose title has been looked up.
+type titleResolvedMsg SearchResult
+
+// handleRemote answers a request forwarded by another copy of the program.
+func (m Model) handleRemote(msg remoteMsg) (Model, tea.Cmd) {
+	var resp instanceResponse
+	var cmd tea.Cmd
+	if msg.req.Command == "open" || msg.req.Command == "add" {
+		m, resp, cmd = m.openRemote(msg.req.Args)
+	} else {
+		m, resp, cmd = m.controlRemote(msg.req)
+	}
+	msg.reply <- resp
+	return m, cmd
+}
+
+// openRemote queues forwarded files and downloads forwarded URLs.
+func (m Model) openRemote(args []string) (Model, instanceResponse, tea.Cmd) {
+	var cmds []tea.Cmd
+	queued, downloads := 0, 0
+	for _, arg := range args {
+		if videoID, ok := ParseVideoURL(arg); ok {
+			downloads++
+			cmds = append(cmds, m.resolveTitle(videoID))
+			continue
+		}
+		tracks, err := resolvePlayTarget(arg)
+		if err != nil {
+			cmds = append(cmds, func() tea.Msg { return errorMsg(err.Error()) })
+			continue
+		}
+		for _, t := range tracks {
+			m.player.Enqueue(t)
+		}
+		queued += len(tracks)
+	}
+
+	// Downloads report their own status once their titles are known
+	status := queuedStatus(queued, downloads)
+	if queued > 0 {
+		cmds = append(cmds, func() tea.Msg { return statusMsg(status) })
+	}
+	return m, instanceResponse{OK: true, Message: status}, tea.Batch(cmds...)
+}
+
+// queuedStatus says what a forwarded request queued, such as "Queued 2
+// songs and 1 download", leaving out what it queued none of.
+func queuedStatus(songs, downloads int) string {
+	var counts []string
+	switch {
+	case songs == 1:
+		counts = append(counts, T("1 song"))
+	case songs > 1:
+		counts = append(counts, Tf("%d songs", songs))
+	}
+	switch {
+	case downloads == 1:
+		counts = append(counts, T("1 download"))
+	case downloads > 1:
+		counts = append(counts, Tf("%d downloads", downloads))
+	}
+
+	switch len(counts) {
+	case 0:
+		return T("Nothing to queue")
+	case 1:
+		return Tf("Queued %s", counts[0])
+	}
+	return Tf("Queued %s and %s", counts[0], counts[1])
+}
+
+// resolveTitle looks up the title of a forwarded URL so it can be downloaded
+// under its proper name.
+func (m Model) resolveTitle(videoID string) tea.Cmd {
+	return func() tea.Msg {
+		title, err := m.downloader.FetchTitle(m.ctx, videoID)
+		if err != nil {
+			return errorMsg(err.Error())
+		}
+		return titleResolvedMsg{VideoID: videoID, Title: title}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestOpenRemoteURLOnly(t *testing.T) {
+	_, resp, _ := Model{}.openRemote([]string{"https://www.youtube.com/watch?v=dQw4w9WgXcQ"})
+	if !resp.OK {
+		t.Fatalf("openRemote failed: %s", resp.Message)
+	}
+	if want := "Queued 1 download"; resp.Message != want {
+		t.Errorf("message %q, want %q", resp.Message, want)
+	}
+}
+
+func TestQueuedStatus(t *testing.T) {
+	for _, tt := range []struct {
+		songs, downloads int
+		want             string
+	}{
+		{0, 0, "Nothing to queue"},
+		{1, 0, "Queued 1 song"},
+		{3, 0, "Queued 3 songs"},
+		{0, 2, "Queued 2 downloads"},
+		{2, 1, "Queued 2 songs and 1 download"},
+	} {
+		if got := queuedStatus(tt.songs, tt.downloads); got != tt.want {
+			t.Errorf("queuedStatus(%d, %d) = %q, want %q", tt.songs, tt.downloads, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package main provides the Invidious and Piped search providers of
+// Personal Musician. Both are front ends to YouTube with a JSON API, so
+// searching through them needs no scraping of youtube.com and sends no
+// requests to Google. The instances to use are listed under [providers]
+// invidious and piped; they are tried in order, and the next one is asked
+// when one fails, as public instances come and go; when they are chosen
+// automatically and all fail, the search page is asked instead (see
+// searchFallback). Only searching goes through the instances: results are
+// ordinary YouTube videos, downloaded from YouTube with yt-dlp like any
+// other.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// invidiousVideo is a video in an Invidious search response.
+type invidiousVideo struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	VideoID       string `json:"videoId"`
+	Author        string `json:"author"`
+	LengthSeconds int    `json:"lengthSeconds"`
+	Thumbnails    []struct {
+		Quality string `json:"quality"`
+		URL     string `json:"url"`
+	} `json:"videoThumbnails"`
+}
+
+// pipedSearchResponse is a Piped search response.
+type pipedSearchResponse struct {
+	Items []struct {
+		Type         string `json:"type"`
+		URL          string `json:"url"` // "/watch?v=ID"
+		Title        string `json:"title"`
+		UploaderName string `json:"uploaderName"`
+		Duration     int    `json:"duration"` // Seconds; -1 for live streams
+		Thumbnail    string `json:"thumbnail"`
+	} `json:"items"`
+}
+
+// searchInvidious searches the configured Invidious instances.
+func searchInvidious(ctx context.Context, query string) ([]SearchResult, error) {
+	return searchInstances(ctx, "Invidious", config.Providers.Invidious, func(base string) ([]SearchResult, error) {
+		params := url.Values{"q": {query + " audio"}, "type": {"video"}}
+		body, err := httpGet(ctx, "invidious", base+"/api/v1/search?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var videos []invidiousVideo
+		if err := json.Unmarshal(body, &videos); err != nil {
+			return nil, fmt.Errorf("failed to parse search response: %w", err)
+		}
+		results := make([]SearchResult, 0, len(videos))
+		for _, video := range videos {
+			if video.Type != "video" || video.VideoID == "" {
+				continue
+			}
+			result := SearchResult{
+				VideoID:  video.VideoID,
+				Title:    video.Title,
+				Channel:  video.Author,
+				Duration: videoLength(video.LengthSeconds),
+			}
+			for _, thumb := range video.Thumbnails {
+				if thumb.Quality == "default" || result.Thumbnail == "" {
+					result.Thumbnail = resolveInstanceURL(base, thumb.URL)
+				}
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	})
+}
+
+// searchPiped searches the configured Piped API instances.
+func searchPiped(ctx context.Context, query string) ([]SearchResult, error) {
+	return searchInstances(ctx, "Piped", config.Providers.Piped, func(base string) ([]SearchResult, error) {
+		params := url.Values{"q": {query + " audio"}, "filter": {"videos"}}
+		body, err := httpGet(ctx, "piped", base+"/search?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var data pipedSearchResponse
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse search response: %w", err)
+		}
+		results := make([]SearchResult, 0, len(data.Items))
+		for _, item := range data.Items {
+			id := pipedVideoID(item.URL)
+			if item.Type != "stream" || id == "" {
+				continue
+			}
+			results = append(results, SearchResult{
+				VideoID:   id,
+				Title:     item.Title,
+				Channel:   item.UploaderName,
+				Duration:  videoLength(item.Duration),
+				Thumbnail: resolveInstanceURL(base, item.Thumbnail),
+			})
+		}
+		return results, nil
+	})
+}
+
+// searchInstances asks the instances of a service in turn until one
+// answers, and fails only when all of them did.
+func searchInstances(ctx context.Context, service string, instances []string, search func(base string) ([]SearchResult, error)) ([]SearchResult, error) {
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no %s instances configured", service)
+	}
+	var errs []error
+	for _, base := range instances {
+		results, err := search(strings.TrimSuffix(base, "/"))
+		if err == nil {
+			return results, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", base, err))
+		if ctx.Err() != nil {
+			break // Out of time for the others too
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// pipedVideoID returns the video ID of a Piped "/watch?v=ID" link.
+func pipedVideoID(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("v")
+}
+
+// resolveInstanceURL makes a link from an instance's response absolute, as
+// some instances return thumbnails relative to themselves.
+func resolveInstanceURL(base, link string) string {
+	if link == "" {
+		return ""
+	}
+	b, err := url.Parse(base + "/")
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return b.ResolveReference(ref).String()
+}
+
+// videoLength formats a video's length in seconds the way YouTube shows it,
+// e.g. "3:45" or "1:02:03", or "" when unknown or live.
+func videoLength(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
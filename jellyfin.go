@@ -0,0 +1,323 @@
+// Package main provides the Jellyfin client of Personal Musician.
+// With a server configured under [jellyfin], the player signs in with the
+// user's name and password and browses the server's artists, albums and
+// playlists. Tracks are fetched as MP3, transcoded by the server when stored
+// in another format, into a small cache and then played like local files.
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jellyfinTimeout bounds browsing requests; fetching tracks is only bounded
+// by the context.
+const jellyfinTimeout = 15 * time.Second
+
+// jellyfinCacheSize is the number of fetched tracks kept in the cache.
+const jellyfinCacheSize = 50
+
+// jellyfinBitrate is the bitrate tracks are transcoded to, in bits per second.
+const jellyfinBitrate = 320000
+
+// JellyfinCacheDir holds the tracks fetched from Jellyfin.
+var JellyfinCacheDir = dataPath("jellyfin")
+
+// JellyfinItem is an artist, album, playlist or track on the server.
+type JellyfinItem struct {
+	ID          string `json:"Id"`
+	Name        string `json:"Name"`
+	Type        string `json:"Type"` // "MusicArtist", "MusicAlbum", "Playlist" or "Audio"
+	AlbumArtist string `json:"AlbumArtist"`
+	Album       string `json:"Album"`
+	RunTime     int64  `json:"RunTimeTicks"` // 100 ns units
+}
+
+// Duration returns the length of a track.
+func (item JellyfinItem) Duration() time.Duration {
+	return time.Duration(item.RunTime * 100)
+}
+
+// Title returns the display name of a track, e.g. "Artist - Song".
+func (item JellyfinItem) Title() string {
+	if item.Type == "Audio" && item.AlbumArtist != "" {
+		return item.AlbumArtist + " - " + item.Name
+	}
+	return item.Name
+}
+
+// JellyfinClient is a signed-in connection to a Jellyfin server.
+type JellyfinClient struct {
+	server   *url.URL
+	username string
+	password string
+	deviceID string
+	client   *http.Client
+
+	mu     sync.Mutex
+	token  string // Access token, empty until signed in
+	userID string
+}
+
+// NewJellyfinClient creates a client for the configured server. It signs in
+// on first use.
+func NewJellyfinClient(cfg JellyfinConfig) (*JellyfinClient, error) {
+	server, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Jellyfin URL %q: %w", cfg.URL, err)
+	}
+
+	// A device ID that stays the same across runs keeps the server's
+	// device list tidy
+	hostname, _ := os.Hostname()
+	sum := sha1.Sum([]byte("personal-musician/" + hostname + "/" + cfg.Username))
+	return &JellyfinClient{
+		server:   server,
+		username: cfg.Username,
+		password: cfg.Password,
+		deviceID: hex.EncodeToString(sum[:8]),
+		client:   httpClient("jellyfin"),
+	}, nil
+}
+
+// authorization returns the Authorization header identifying the player.
+func (c *JellyfinClient) authorization(token string) string {
+	hostname, _ := os.Hostname()
+	header := fmt.Sprintf(`MediaBrowser Client="Personal Musician", Device=%q, DeviceId=%q, Version=%q`,
+		hostname, c.deviceID, versionString())
+	if token != "" {
+		header += fmt.Sprintf(", Token=%q", token)
+	}
+	return header
+}
+
+// signIn authenticates with the user name and password unless signed in.
+func (c *JellyfinClient) signIn(ctx context.Context) (token, userID string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" {
+		return c.token, c.userID, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"Username": c.username, "Pw": c.password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.server.String()+"/Users/AuthenticateByName", strings.NewReader(string(body)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.authorization(""))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to reach Jellyfin: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", "", fmt.Errorf("Jellyfin rejected the user name or password")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to sign in to Jellyfin: %s", resp.Status)
+	}
+
+	var auth struct {
+		AccessToken string
+		User        struct {
+			ID string `json:"Id"`
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return "", "", fmt.Errorf("failed to read Jellyfin sign-in response: %w", err)
+	}
+	c.token, c.userID = auth.AccessToken, auth.User.ID
+	slog.Info("signed in to Jellyfin", "server", c.server.Host, "user", c.username)
+	return c.token, c.userID, nil
+}
+
+// get sends an authenticated GET request. An expired session is signed in
+// again once.
+func (c *JellyfinClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, userID, err := c.signIn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("userId", userID)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server.String()+path+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", c.authorization(token))
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach Jellyfin: %w", err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			c.mu.Lock()
+			c.token = ""
+			c.mu.Unlock()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("Jellyfin request failed: %s", resp.Status)
+		}
+		return resp, nil
+	}
+}
+
+// items fetches a list of items.
+func (c *JellyfinClient) items(path string, query url.Values) ([]JellyfinItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jellyfinTimeout)
+	defer cancel()
+
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct{ Items []JellyfinItem }
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to read Jellyfin response: %w", err)
+	}
+	return result.Items, nil
+}
+
+// userItems queries the user's library.
+func (c *JellyfinClient) userItems(query url.Values) ([]JellyfinItem, error) {
+	_, userID, err := c.signIn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	query.Set("Recursive", "true")
+	return c.items("/Users/"+url.PathEscape(userID)+"/Items", query)
+}
+
+// Artists returns the album artists in the library.
+func (c *JellyfinClient) Artists() ([]JellyfinItem, error) {
+	return c.items("/Artists/AlbumArtists", url.Values{"SortBy": {"SortName"}})
+}
+
+// Albums returns the albums in the library, or those of one artist when
+// artistID is set.
+func (c *JellyfinClient) Albums(artistID string) ([]JellyfinItem, error) {
+	query := url.Values{"IncludeItemTypes": {"MusicAlbum"}, "SortBy": {"SortName"}}
+	if artistID != "" {
+		query.Set("AlbumArtistIds", artistID)
+		query.Set("SortBy", "ProductionYear,SortName")
+	}
+	return c.userItems(query)
+}
+
+// Playlists returns the user's playlists.
+func (c *JellyfinClient) Playlists() ([]JellyfinItem, error) {
+	return c.userItems(url.Values{"IncludeItemTypes": {"Playlist"}, "SortBy": {"SortName"}})
+}
+
+// Tracks returns the tracks of an album, in disc and track order, or of a
+// playlist, in playlist order.
+func (c *JellyfinClient) Tracks(parent JellyfinItem) ([]JellyfinItem, error) {
+	if parent.Type == "Playlist" {
+		return c.items("/Playlists/"+url.PathEscape(parent.ID)+"/Items", url.Values{})
+	}
+	return c.userItems(url.Values{
+		"ParentId":         {parent.ID},
+		"IncludeItemTypes": {"Audio"},
+		"SortBy":           {"ParentIndexNumber,IndexNumber,SortName"},
+	})
+}
+
+// Fetch downloads a track into the cache, transcoded to MP3 if needed, and
+// returns it as a music file. Cached tracks are returned at once.
+func (c *JellyfinClient) Fetch(ctx context.Context, item JellyfinItem) (MusicFile, error) {
+	name := sanitizeFilename(item.Title())
+	if name == "" {
+		name = item.ID
+	}
+	dir := filepath.Join(JellyfinCacheDir, item.ID)
+	path := filepath.Join(dir, name+".mp3")
+	if _, err := os.Stat(path); err == nil {
+		os.Chtimes(dir, time.Now(), time.Now()) // Mark as recently used
+		return musicFileFromPath(path), nil
+	}
+
+	resp, err := c.get(ctx, "/Audio/"+url.PathEscape(item.ID)+"/universal", url.Values{
+		"DeviceId":             {c.deviceID},
+		"Container":            {"mp3"},
+		"AudioCodec":           {"mp3"},
+		"TranscodingContainer": {"mp3"},
+		"TranscodingProtocol":  {"http"},
+		"MaxStreamingBitrate":  {fmt.Sprint(jellyfinBitrate)},
+	})
+	if err != nil {
+		return MusicFile{}, fmt.Errorf("failed to stream %s: %w", item.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return MusicFile{}, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".fetch-*")
+	if err != nil {
+		return MusicFile{}, fmt.Errorf("failed to create cache file: %w", err)
+	}
+	_, err = io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return MusicFile{}, fmt.Errorf("failed to fetch %s: %w", item.Name, err)
+	}
+
+	pruneJellyfinCache()
+	return musicFileFromPath(path), nil
+}
+
+// pruneJellyfinCache removes the least recently used tracks beyond the
+// cache size.
+func pruneJellyfinCache() {
+	entries, err := os.ReadDir(JellyfinCacheDir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		path string
+		used time.Time
+	}
+	var tracks []cached
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && e.IsDir() {
+			tracks = append(tracks, cached{filepath.Join(JellyfinCacheDir, e.Name()), info.ModTime()})
+		}
+	}
+	if len(tracks) <= jellyfinCacheSize {
+		return
+	}
+
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].used.After(tracks[j].used) })
+	for _, t := range tracks[jellyfinCacheSize:] {
+		if err := os.RemoveAll(t.path); err != nil {
+			slog.Warn("failed to prune Jellyfin cache", "path", t.path, "err", err)
+		}
+	}
+}
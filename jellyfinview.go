@@ -0,0 +1,275 @@
+// Package main provides the Jellyfin view of Personal Musician.
+// The view (J) browses the configured server from a root menu of artists,
+// albums and playlists down to tracks. Enter opens an entry or plays a
+// track; a queues a track and A queues every track on the page. Backspace
+// goes back up.
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// jellyfinFolder is the item type of the root menu entries.
+const jellyfinFolder = "Folder"
+
+// jellyfinPage is one level of the Jellyfin browser.
+type jellyfinPage struct {
+	parent  JellyfinItem // Item whose children are listed
+	items   []JellyfinItem
+	cursor  int
+	loading bool
+}
+
+// jellyfinItemsMsg is sent when a page has loaded.
+type jellyfinItemsMsg struct {
+	parentID string
+	items    []JellyfinItem
+	err      error
+}
+
+// jellyfinFetchedMsg is sent when tracks have been fetched for playback.
+type jellyfinFetchedMsg struct {
+	files []MusicFile
+	play  bool // Play the first file instead of queueing them all
+	err   error
+}
+
+// jellyfinRoot returns the root menu.
+func jellyfinRoot() jellyfinPage {
+	return jellyfinPage{
+		parent: JellyfinItem{Name: "Jellyfin", Type: jellyfinFolder},
+		items: []JellyfinItem{
+			{ID: "artists", Name: T("Artists"), Type: jellyfinFolder},
+			{ID: "albums", Name: T("Albums"), Type: jellyfinFolder},
+			{ID: "playlists", Name: T("Playlists"), Type: jellyfinFolder},
+		},
+	}
+}
+
+// openJellyfin switches to the Jellyfin view.
+func (m Model) openJellyfin() (tea.Model, tea.Cmd) {
+	if m.jellyfin == nil {
+		return m, func() tea.Msg {
+			return errorMsg(T("No Jellyfin server configured; set url, username and password under [jellyfin]"))
+		}
+	}
+	if len(m.jellyfinPages) == 0 {
+		m.jellyfinPages = []jellyfinPage{jellyfinRoot()}
+	}
+	m.currentView = ViewJellyfin
+	return m, nil
+}
+
+// jellyfinPage returns the page being shown.
+func (m Model) jellyfinPage() *jellyfinPage {
+	if len(m.jellyfinPages) == 0 {
+		return nil
+	}
+	return &m.jellyfinPages[len(m.jellyfinPages)-1]
+}
+
+// jellyfinBack returns to the previous page.
+func (m Model) jellyfinBack() (tea.Model, tea.Cmd) {
+	if len(m.jellyfinPages) > 1 {
+		m.jellyfinPages = m.jellyfinPages[:len(m.jellyfinPages)-1]
+	}
+	return m, nil
+}
+
+// openJellyfinItem lists the children of an entry.
+func (m Model) openJellyfinItem(item JellyfinItem) (tea.Model, tea.Cmd) {
+	// Copy the stack so earlier models keep their pages
+	pages := append([]jellyfinPage(nil), m.jellyfinPages...)
+	m.jellyfinPages = append(pages, jellyfinPage{parent: item, loading: true})
+
+	client := m.jellyfin
+	return m, func() tea.Msg {
+		var items []JellyfinItem
+		var err error
+		switch {
+		case item.ID == "artists":
+			items, err = client.Artists()
+		case item.ID == "albums":
+			items, err = client.Albums("")
+		case item.ID == "playlists":
+			items, err = client.Playlists()
+		case item.Type == "MusicArtist":
+			items, err = client.Albums(item.ID)
+		default:
+			items, err = client.Tracks(item)
+		}
+		return jellyfinItemsMsg{parentID: item.ID, items: items, err: err}
+	}
+}
+
+// showJellyfinItems fills in a page that has loaded.
+func (m Model) showJellyfinItems(msg jellyfinItemsMsg) (tea.Model, tea.Cmd) {
+	for i := range m.jellyfinPages {
+		page := &m.jellyfinPages[i]
+		if page.parent.ID != msg.parentID || !page.loading {
+			continue
+		}
+		page.loading = false
+		page.items = msg.items
+		if msg.err != nil {
+			m.jellyfinPages = m.jellyfinPages[:i] // Back to where the failed page was opened
+			return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+		}
+	}
+	return m, nil
+}
+
+// fetchJellyfin fetches tracks in the background and plays the first or
+// queues them all.
+func (m Model) fetchJellyfin(items []JellyfinItem, play bool) tea.Cmd {
+	client, ctx := m.jellyfin, m.ctx
+	status := Tf("Fetching %d tracks...", len(items))
+	if len(items) == 1 {
+		status = Tf("Fetching %s...", items[0].Title())
+	}
+	return tea.Batch(
+		func() tea.Msg { return statusMsg(status) },
+		func() tea.Msg {
+			var files []MusicFile
+			for _, item := range items {
+				file, err := client.Fetch(ctx, item)
+				if err != nil {
+					return jellyfinFetchedMsg{files: files, play: play, err: err}
+				}
+				files = append(files, file)
+			}
+			return jellyfinFetchedMsg{files: files, play: play}
+		},
+	)
+}
+
+// jellyfinFetched plays or queues fetched tracks.
+func (m Model) jellyfinFetched(msg jellyfinFetchedMsg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+		cmds = append(cmds, func() tea.Msg { return errorMsg(msg.err.Error()) })
+	}
+
+	switch {
+	case len(msg.files) == 0:
+	case msg.play:
+		file := msg.files[0]
+		if err := m.player.PlayTrack(file); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		cmds = append(cmds, func() tea.Msg { return statusMsg(Tf("Now playing: %s", file.Name)) })
+	case len(msg.files) == 1:
+		m.player.Enqueue(msg.files[0])
+		cmds = append(cmds, func() tea.Msg { return statusMsg(Tf("Queued: %s", msg.files[0].Name)) })
+	default:
+		for _, file := range msg.files {
+			m.player.Enqueue(file)
+		}
+		cmds = append(cmds, func() tea.Msg { return statusMsg(Tf("Queued %d songs", len(msg.files))) })
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// handleJellyfinKeys handles keys in the Jellyfin view.
+func (m Model) handleJellyfinKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := m.jellyfinPage()
+	if page == nil || page.loading {
+		if msg.String() == "backspace" {
+			return m.jellyfinBack()
+		}
+		return m, nil
+	}
+	if cursor, ok := m.navigateList(msg.String(), page.cursor, len(page.items)); ok {
+		page.cursor = cursor
+		return m, nil
+	}
+
+	var item JellyfinItem
+	if page.cursor < len(page.items) {
+		item = page.items[page.cursor]
+	}
+	switch msg.String() {
+	case "enter":
+		switch {
+		case item.ID == "":
+		case item.Type == "Audio":
+			return m, m.fetchJellyfin([]JellyfinItem{item}, true)
+		default:
+			return m.openJellyfinItem(item)
+		}
+	case "a": // Queue the selected track
+		if item.Type == "Audio" {
+			return m, m.fetchJellyfin([]JellyfinItem{item}, false)
+		}
+	case "A": // Queue every track on the page
+		var tracks []JellyfinItem
+		for _, it := range page.items {
+			if it.Type == "Audio" {
+				tracks = append(tracks, it)
+			}
+		}
+		if len(tracks) > 0 {
+			return m, m.fetchJellyfin(tracks, false)
+		}
+	case "backspace":
+		return m.jellyfinBack()
+	}
+	return m, nil
+}
+
+// renderJellyfinView renders the page being browsed.
+func (m Model) renderJellyfinView() string {
+	var b strings.Builder
+
+	var crumbs []string
+	for _, p := range m.jellyfinPages {
+		crumbs = append(crumbs, p.parent.Name)
+	}
+	b.WriteString(headerStyle.Render(" 🎧 "+truncate(strings.Join(crumbs, " › "), 60)+" ") + "\n\n")
+
+	page := m.jellyfinPage()
+	switch {
+	case page == nil || page.loading:
+		b.WriteString(mutedStyle.Render(T("Loading...") + "\n"))
+		return b.String()
+	case len(page.items) == 0:
+		b.WriteString(mutedStyle.Render(T("Nothing here") + "\n"))
+		return b.String()
+	}
+
+	width := m.width - 4
+	if m.isWide() {
+		width = m.leftPaneWidth() - 6
+	}
+
+	b.WriteString(m.jellyfinScroll.render(len(page.items), 1, m.maxVisible(), func(i int) string {
+		item := page.items[i]
+		label := item.Name
+		suffix := ""
+		switch item.Type {
+		case "Audio":
+			label = "♪ " + item.Title()
+			if d := item.Duration(); d > 0 {
+				suffix = " " + mutedStyle.Render(FormatDuration(d))
+			}
+		case "MusicAlbum":
+			label = "● " + item.Name
+			if item.AlbumArtist != "" {
+				suffix = " " + mutedStyle.Render(item.AlbumArtist)
+			}
+		default:
+			label = "› " + item.Name
+		}
+		label = truncate(label, max(width-12, 10))
+		if i == page.cursor {
+			return selectedStyle.Render("> "+label) + suffix
+		}
+		return normalStyle.Render("  "+label) + suffix
+	}))
+
+	return b.String()
+}
@@ -0,0 +1,161 @@
+// Package main provides keybinding presets for Personal Musician.
+// The default preset uses arrow keys; the vim preset adds gg/G, ctrl+d/u,
+// dd and h/l on top of it by translating vim sequences into default keys.
+// Extra bindings from the config file make further keys act as built-in ones.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// KeyPreset selects a keybinding scheme.
+type KeyPreset string
+
+const (
+	KeyPresetDefault KeyPreset = "default" // Arrow keys, Home/End
+	KeyPresetVim     KeyPreset = "vim"     // Adds gg/G, dd and friends
+)
+
+// ParseKeyPreset returns the preset with the given name.
+func ParseKeyPreset(name string) (KeyPreset, error) {
+	switch KeyPreset(strings.ToLower(strings.TrimSpace(name))) {
+	case KeyPresetDefault, "":
+		return KeyPresetDefault, nil
+	case KeyPresetVim:
+		return KeyPresetVim, nil
+	default:
+		return KeyPresetDefault, fmt.Errorf("unknown keybinding preset %q (want default or vim)", name)
+	}
+}
+
+// DetectKeyPreset reads the preset from PM_KEYMAP, falling back to the keymap
+// setting of the config file.
+func DetectKeyPreset() (KeyPreset, error) {
+	if v := os.Getenv("PM_KEYMAP"); v != "" {
+		return ParseKeyPreset(v)
+	}
+	return ParseKeyPreset(config.UI.Keymap)
+}
+
+// keyTypes maps key names such as "ctrl+a" or "pgdown" to their key types.
+var keyTypes = func() map[string]tea.KeyType {
+	names := make(map[string]tea.KeyType)
+	for t := tea.KeyType(-128); t <= 127; t++ {
+		if name := t.String(); name != "" && t != tea.KeyRunes {
+			names[name] = t
+		}
+	}
+	names["space"] = tea.KeySpace
+	return names
+}()
+
+// parseKey parses a key as written by tea.KeyMsg.String, e.g. "x", "ctrl+a",
+// "alt+enter" or "f5". The name "space" is accepted for the space bar.
+func parseKey(s string) (tea.KeyMsg, bool) {
+	var alt bool
+	if rest, ok := strings.CutPrefix(s, "alt+"); ok && rest != "" {
+		s, alt = rest, true
+	}
+	if t, ok := keyTypes[s]; ok {
+		return tea.KeyMsg{Type: t, Alt: alt}, true
+	}
+	if r, size := utf8.DecodeRuneInString(s); size == len(s) && r != utf8.RuneError {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}, Alt: alt}, true
+	}
+	return tea.KeyMsg{}, false
+}
+
+// keyBindings maps extra keys from the config file to the keys they act as.
+var keyBindings map[string]tea.KeyMsg
+
+// loadKeyBindings installs extra bindings given as key = key it acts as.
+// Invalid entries are skipped; the config file is validated beforehand.
+func loadKeyBindings(bindings map[string]string) {
+	keyBindings = make(map[string]tea.KeyMsg, len(bindings))
+	for from, to := range bindings {
+		fromKey, ok := parseKey(from)
+		if !ok {
+			continue
+		}
+		if toKey, ok := parseKey(to); ok {
+			keyBindings[fromKey.String()] = toKey
+		}
+	}
+}
+
+// remapKey returns the key an extra binding makes msg act as, or msg itself.
+func remapKey(msg tea.KeyMsg) tea.KeyMsg {
+	if to, ok := keyBindings[msg.String()]; ok {
+		return to
+	}
+	return msg
+}
+
+// translateVimKey maps vim keys and sequences to the keys handled by the views.
+// Returns pending=true when the key starts a sequence (g, d) and more input is needed.
+func (m *Model) translateVimKey(msg tea.KeyMsg) (translated tea.KeyMsg, pending bool) {
+	key := msg.String()
+	prev := m.pendingKey
+	m.pendingKey = ""
+
+	switch {
+	case key == "g" && prev == "g":
+		return tea.KeyMsg{Type: tea.KeyHome}, false
+	case key == "d" && prev == "d":
+		return tea.KeyMsg{Type: tea.KeyDelete}, false
+	case key == "g", key == "d" && (m.currentView == ViewQueue || m.currentView == ViewPlaylistEditor):
+		m.pendingKey = key
+		return msg, true
+	case key == "G":
+		return tea.KeyMsg{Type: tea.KeyEnd}, false
+	case key == "h":
+		return tea.KeyMsg{Type: tea.KeyShiftLeft}, false
+	case key == "l":
+		return tea.KeyMsg{Type: tea.KeyShiftRight}, false
+	}
+	return msg, false
+}
+
+// navigateList moves a list cursor for navigation keys.
+// Returns the new cursor and whether the key was a navigation key.
+func (m Model) navigateList(key string, cursor, total int) (int, bool) {
+	if total == 0 {
+		return 0, false
+	}
+
+	page := m.maxVisible()
+	half := page / 2
+	switch key {
+	case "up", "k":
+		cursor--
+	case "down", "j":
+		cursor++
+	case "home":
+		cursor = 0
+	case "end":
+		cursor = total - 1
+	case "ctrl+u":
+		cursor -= half
+	case "ctrl+d":
+		cursor += half
+	case "pgup":
+		cursor -= page
+	case "pgdown":
+		cursor += page
+	default:
+		return cursor, false
+	}
+
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor > total-1 {
+		cursor = total - 1
+	}
+	return cursor, true
+}
@@ -0,0 +1,107 @@
+// Package main provides the multi-pane layout for Personal Musician.
+// On wide terminals the main list and Now Playing are rendered side by side
+// instead of stacked in a single column.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// wideLayoutMinWidth is the terminal width from which panes are shown side by side.
+const wideLayoutMinWidth = 120
+
+// isWide reports whether the multi-pane layout is active.
+func (m Model) isWide() bool {
+	return m.width >= wideLayoutMinWidth
+}
+
+// leftPaneWidth returns the outer width of the left pane in the wide layout.
+func (m Model) leftPaneWidth() int {
+	return m.width * m.panes.Ratio(m.width) / 100
+}
+
+// leftView returns the view shown in the left pane of the wide layout.
+func (m Model) leftView() View {
+	if m.currentView == ViewQueue {
+		return m.paneLeftView
+	}
+	return m.currentView
+}
+
+// cycleFocus moves focus between the left pane and the queue pane.
+func (m Model) cycleFocus() Model {
+	if m.currentView == ViewQueue {
+		m.currentView = m.paneLeftView
+	} else {
+		m.currentView = ViewQueue
+	}
+	return m
+}
+
+// paneStyle returns the border style for a pane, highlighted when focused.
+func paneStyle(focused bool) lipgloss.Style {
+	border := mutedColor
+	if focused {
+		border = primaryColor
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(border).
+		Padding(0, 1)
+}
+
+// renderPanes renders the main view and the Now Playing pane side by side.
+// The queue lives in the right pane, so focusing it moves focus right.
+func (m Model) renderPanes() string {
+	leftView := m.leftView()
+
+	// Borders and padding take four columns per pane
+	leftWidth := m.leftPaneWidth()
+	rightWidth := m.width - leftWidth
+	height := m.maxVisible() + 4
+
+	left := paneStyle(m.currentView != ViewQueue).
+		Width(leftWidth - 4).
+		Height(height).
+		Render(m.renderMainView(leftView))
+
+	right := paneStyle(m.currentView == ViewQueue).
+		Width(rightWidth - 4).
+		Height(height).
+		Render(m.renderNowPlayingPanel() + "\n\n" + m.renderQueueView())
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+}
+
+// renderNowPlayingPanel renders Now Playing as a vertical panel for the wide layout.
+// The progress bar is always on the fourth line (see progressBarOrigin).
+func (m Model) renderNowPlayingPanel() string {
+	state := m.player.GetState()
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(" ♪ "+T("Now Playing")+" ") + "\n\n")
+
+	if !state.IsPlaying && state.CurrentFile == "" {
+		b.WriteString(mutedStyle.Render(T("No song playing")))
+		return b.String()
+	}
+
+	b.WriteString(nowPlayingHead(state, m.currentSongName(state)) + "\n")
+
+	b.WriteString(fmt.Sprintf("%s  %s/%s\n",
+		m.renderProgressBar(state),
+		FormatDuration(state.Position),
+		FormatDuration(state.Duration),
+	))
+
+	b.WriteString(fmt.Sprintf("%s  [%d/%d]",
+		renderModeIndicators(state),
+		state.CurrentIndex+1,
+		state.TotalTracks,
+	))
+
+	return b.String()
+}
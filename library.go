@@ -0,0 +1,318 @@
+// Package main provides the library service of Personal Musician.
+// Walking the music directories and deleting files happen on background
+// goroutines, never inside Update: the TUI asks for a refresh and receives
+// the scanned library as a message. Refreshes requested while a scan runs
+// are folded into a single follow-up scan, so skipping through tracks or
+// finishing several downloads at once walks the disk only once more. The
+// first scan starts by publishing the library index, so the library appears
+// at once and the walk only brings it up to date; songs the index doesn't
+// know yet appear while the walk goes on, and the header counts the songs
+// found. Changes reported by the watcher, renames and deletions only look
+// at the paths involved instead of walking every folder again. A walk only
+// stats the files; their tags are read again just where the modification
+// time or size differs from the index (see metadataCache.apply).
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// libraryProgressInterval is how often a walk of the music directories
+// reports its progress and publishes the songs found so far.
+const libraryProgressInterval = 250 * time.Millisecond
+
+// Library scans the music directories in the background and remembers the
+// last result.
+type Library struct {
+	mu       sync.Mutex
+	files    []MusicFile         // Result of the last successful scan
+	scanning bool                // A scan is running
+	full     bool                // A walk of every music directory is pending
+	changed  map[string]bool     // Files and folders to scan again, when no walk is pending
+	updates  chan []MusicFile    // Latest scan not yet delivered; holds at most one
+	progress chan libraryScanMsg // Latest progress not yet delivered; holds at most one
+	watching bool                // The music directories are watched (see watcher.go)
+}
+
+// libraryScanMsg reports the progress of a walk of the music directories.
+type libraryScanMsg struct {
+	found int  // Songs found so far
+	done  bool // The walk has finished
+}
+
+// NewLibrary returns a library service that has not scanned yet.
+func NewLibrary() *Library {
+	return &Library{
+		changed:  make(map[string]bool),
+		updates:  make(chan []MusicFile, 1),
+		progress: make(chan libraryScanMsg, 1),
+	}
+}
+
+// Refresh rescans the music directories in the background. It never blocks;
+// while a scan runs, one more is scheduled after it.
+func (l *Library) Refresh() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.full = true
+	l.start()
+}
+
+// RefreshPaths rescans only the given files and folders of the music
+// directories in the background, for changes whose paths are known. Paths
+// that no longer exist drop out of the library with everything below them.
+func (l *Library) RefreshPaths(paths []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, path := range paths {
+		l.changed[path] = true
+	}
+	l.start()
+}
+
+// start starts a scan unless one is running, which picks up the request
+// when it is done. It is called with l.mu held.
+func (l *Library) start() {
+	if l.scanning {
+		return
+	}
+	l.scanning = true
+	go l.scan()
+}
+
+// scan scans the music directories until no further refresh was requested.
+func (l *Library) scan() {
+	l.mu.Lock()
+	first := l.files == nil
+	l.mu.Unlock()
+	if first {
+		// Reading the index can take a moment; don't hold the lock meanwhile
+		if indexed := IndexedMusicFiles(); len(indexed) > 0 {
+			l.mu.Lock()
+			l.files = indexed
+			l.publish(slices.Clone(indexed))
+			l.mu.Unlock()
+		}
+	}
+
+	for {
+		l.mu.Lock()
+		if !l.full && len(l.changed) == 0 {
+			l.scanning = false
+			l.mu.Unlock()
+			return
+		}
+		known, changed := l.files, l.changed
+		full := l.full || known == nil // Paths alone can't list a library never walked
+		l.full, l.changed = false, make(map[string]bool)
+		l.mu.Unlock()
+
+		var files []MusicFile
+		var err error
+		if !full {
+			if files, err = rescanPaths(known, changed); err != nil {
+				full = true // A folder changed again while read; walk everything
+			}
+		}
+		if full {
+			files, err = l.walk(known)
+		}
+		if err != nil {
+			slog.Warn("failed to scan music files", "err", err)
+			appLog.Add("error", "failed to scan music files: "+err.Error())
+		}
+
+		if err == nil {
+			libraryMetadata.prune(files)
+		}
+
+		l.mu.Lock()
+		if err == nil {
+			l.files = files
+			l.publish(slices.Clone(files)) // The UI sorts its copy in place
+		}
+		l.mu.Unlock()
+	}
+}
+
+// walk lists the tracks of every music directory. While it runs, the
+// number of songs found is reported, and songs that known lacks are
+// published along with the known ones, so a new library fills in as a
+// large collection is walked. Known songs not found again only go once
+// the walk is complete.
+func (l *Library) walk(known []MusicFile) ([]MusicFile, error) {
+	knownPaths := make(map[string]bool, len(known))
+	for _, file := range known {
+		knownPaths[file.Path] = true
+	}
+
+	var files []MusicFile
+	found := make(map[string]bool)
+	fresh, published := 0, 0 // Songs found that known lacks, and how many of them were published
+	last := time.Now()
+	defer func() {
+		l.mu.Lock()
+		l.report(libraryScanMsg{found: len(files), done: true})
+		l.mu.Unlock()
+	}()
+
+	for _, dir := range append([]string{MusicDir}, extraMusicDirs...) {
+		err := walkMusicDir(dir, func(file MusicFile) {
+			files = append(files, file)
+			found[file.Path] = true
+			if !knownPaths[file.Path] {
+				fresh++
+			}
+			if time.Since(last) < libraryProgressInterval {
+				return
+			}
+			last = time.Now()
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.report(libraryScanMsg{found: len(files)})
+			if fresh > published {
+				published = fresh
+				partial := slices.Clone(files)
+				for _, file := range known {
+					if !found[file.Path] {
+						partial = append(partial, file)
+					}
+				}
+				l.publish(partial)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// rescanPaths brings known up to date with the files and folders in
+// changed, without walking the rest of the music directories.
+func rescanPaths(known []MusicFile, changed map[string]bool) ([]MusicFile, error) {
+	var files []MusicFile
+	for _, file := range known {
+		if !changed[file.Path] && !changedAbove(changed, file.Path) {
+			files = append(files, file)
+		}
+	}
+	for path := range changed {
+		if changedAbove(changed, path) || !inMusicDirs(path) || inTrash(path) {
+			continue // Scanned with its folder, or none of the library's business
+		}
+		if err := walkMusicDir(path, func(file MusicFile) { files = append(files, file) }); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// changedAbove reports whether a folder containing path is in changed.
+func changedAbove(changed map[string]bool, path string) bool {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if changed[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// publish replaces any scan the UI has not received yet with files. It is
+// called with l.mu held, so the send never blocks.
+func (l *Library) publish(files []MusicFile) {
+	select {
+	case <-l.updates:
+	default:
+	}
+	l.updates <- files
+}
+
+// report replaces any progress the UI has not received yet with msg. It is
+// called with l.mu held, so the send never blocks.
+func (l *Library) report(msg libraryScanMsg) {
+	select {
+	case <-l.progress:
+	default:
+	}
+	l.progress <- msg
+}
+
+// next returns a command that waits for the next scan to finish.
+func (l *Library) next() tea.Cmd {
+	return func() tea.Msg {
+		return libraryRefreshMsg(<-l.updates)
+	}
+}
+
+// nextProgress returns a command that waits for the next progress report
+// of a walk.
+func (l *Library) nextProgress() tea.Cmd {
+	return func() tea.Msg {
+		return <-l.progress
+	}
+}
+
+// Contains reports whether a file with a similar name was found by the last
+// scan. The comparison ignores case and extensions.
+func (l *Library) Contains(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Normalize the search name (lowercase, no extension)
+	searchName := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	for _, file := range l.files {
+		existingName := strings.ToLower(file.Name)
+		if existingName == searchName || strings.Contains(existingName, searchName) {
+			return true
+		}
+	}
+	return false
+}
+
+// filesDeletedMsg reports the outcome of deleting library files.
+type filesDeletedMsg struct {
+	files   []MusicFile // Files that were to be deleted
+	deleted int         // Files actually deleted
+	err     error       // Last failure, if any
+}
+
+// Delete returns a command that moves files to the trash (see trash.go)
+// and then rescans their paths.
+func (l *Library) Delete(files []MusicFile) tea.Cmd {
+	return func() tea.Msg {
+		msg := filesDeletedMsg{files: files}
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		trashed, err := TrashMusicFiles(paths)
+		msg.deleted, msg.err = len(trashed), err
+		l.RefreshPaths(paths)
+		return msg
+	}
+}
+
+// applyFilesDeleted reports deleted files in the status line.
+func (m Model) applyFilesDeleted(msg filesDeletedMsg) (Model, tea.Cmd) {
+	if len(msg.files) == 1 {
+		if msg.err != nil {
+			return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+		}
+		return m, func() tea.Msg { return statusMsg(Tf("Moved to the trash: %s (u: undo)", msg.files[0].Name)) }
+	}
+
+	status := Tf("Moved %d songs to the trash (u: undo)", msg.deleted)
+	if msg.err != nil {
+		status = Tf("Moved %d songs to the trash, error: %v", msg.deleted, msg.err)
+	}
+	return m, func() tea.Msg { return statusMsg(status) }
+}
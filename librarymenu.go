@@ -0,0 +1,245 @@
+// Package main provides the library sort/filter menu for Personal Musician.
+// Pressing 'o' in the library opens a menu to choose the sort key and
+// direction and to toggle filters without leaving the library view.
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SortKey selects how the library is ordered.
+type SortKey int
+
+const (
+	SortByName       SortKey = iota // Alphabetical by display name
+	SortByDateAdded                 // File modification time
+	SortByPlayCount                 // Number of completed plays
+	SortByLastPlayed                // Most recent completed play
+	SortByRating                    // Stars given (see ratings.go)
+	SortByDuration                  // Length of the track
+	sortKeyCount
+)
+
+// String returns the display name of the sort key.
+func (k SortKey) String() string {
+	switch k {
+	case SortByDateAdded:
+		return T("Date added")
+	case SortByPlayCount:
+		return T("Play count")
+	case SortByLastPlayed:
+		return T("Last played")
+	case SortByRating:
+		return T("Rating")
+	case SortByDuration:
+		return T("Duration")
+	default:
+		return T("Name")
+	}
+}
+
+// LibraryView holds the sort and filter settings of the library.
+type LibraryView struct {
+	Sort         SortKey      `json:"sort"`
+	Descending   bool         `json:"descending,omitempty"`
+	LikedOnly    bool         `json:"liked_only,omitempty"`
+	UnplayedOnly bool         `json:"unplayed_only,omitempty"`
+	MinRating    int          `json:"min_rating,omitempty"` // Hide songs rated lower; 0 shows all
+	Scope        LibraryScope `json:"scope,omitempty"`      // All songs, or recently added or played (see recent.go)
+	Genre        string       `json:"genre,omitempty"`      // Only songs of this genre (see genre.go); "" shows all
+}
+
+// filtered reports whether any filter hides songs of the library.
+func (v LibraryView) filtered() bool {
+	return v.LikedOnly || v.UnplayedOnly || v.MinRating > 0 || v.Scope != ScopeAll || v.Genre != ""
+}
+
+// clearFilters shows every song again, keeping the sort order.
+func (v *LibraryView) clearFilters() {
+	v.LikedOnly, v.UnplayedOnly, v.MinRating, v.Scope, v.Genre = false, false, 0, ScopeAll, ""
+}
+
+// sortsByMetadata reports whether the order depends on tags or durations,
+// which are read after the files are found. Names do, as a song's display
+// name comes from its artist and title tags.
+func (v LibraryView) sortsByMetadata() bool {
+	return v.Sort == SortByName || v.Sort == SortByDuration
+}
+
+// libraryMenuRows is the number of rows in the sort/filter menu.
+const libraryMenuRows = 7
+
+// sortLibrary orders files in place according to the view settings.
+func sortLibrary(files []MusicFile, view LibraryView, stats *StatsStore) {
+	less := func(a, b MusicFile) bool {
+		switch view.Sort {
+		case SortByDateAdded:
+			return a.ModTime.Before(b.ModTime)
+		case SortByPlayCount:
+			return stats.Get(a.Path).PlayCount < stats.Get(b.Path).PlayCount
+		case SortByLastPlayed:
+			return stats.Get(a.Path).LastPlayed.Before(stats.Get(b.Path).LastPlayed)
+		case SortByRating:
+			return stats.Get(a.Path).Rating < stats.Get(b.Path).Rating
+		case SortByDuration:
+			return a.Duration < b.Duration
+		default:
+			return strings.ToLower(a.DisplayName()) < strings.ToLower(b.DisplayName())
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if view.Descending {
+			return less(files[j], files[i])
+		}
+		return less(files[i], files[j])
+	})
+}
+
+// matchesFilters reports whether a file passes the view's filters.
+func (v LibraryView) matchesFilters(file MusicFile, stats *StatsStore) bool {
+	st := stats.Get(file.Path)
+	if v.LikedOnly && !st.Liked {
+		return false
+	}
+	if v.UnplayedOnly && st.PlayCount > 0 {
+		return false
+	}
+	if st.Rating < v.MinRating {
+		return false
+	}
+	if v.Genre != "" && !hasGenre(file, v.Genre) {
+		return false
+	}
+	return true
+}
+
+// applyLibraryView re-sorts the library and hands the new order to the player.
+func (m Model) applyLibraryView() Model {
+	m.libraryFiles = slices.Clone(m.libraryFiles) // The player holds the old slice
+	sortLibrary(m.libraryFiles, m.libraryView, m.stats)
+	m.libraryGen++
+	m.player.SetPlaylist(m.libraryFiles)
+	if visible := m.visibleLibrary(); m.libraryCursor >= len(visible) {
+		m.libraryCursor = 0
+	}
+	return m
+}
+
+// resortLibrary sorts the library again after metadata arrived, keeping
+// the cursor on the same song.
+func (m Model) resortLibrary() Model {
+	current := ""
+	if visible := m.visibleLibrary(); m.libraryCursor < len(visible) {
+		current = visible[m.libraryCursor].Path
+	}
+	m = m.applyLibraryView()
+	for i, file := range m.visibleLibrary() {
+		if file.Path == current {
+			m.libraryCursor = i
+			break
+		}
+	}
+	return m
+}
+
+// handleLibraryMenuKeys handles keys while the sort/filter menu is open.
+func (m Model) handleLibraryMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "o", "enter":
+		m.libraryMenuOpen = false
+	case "up", "k":
+		if m.libraryMenuCursor > 0 {
+			m.libraryMenuCursor--
+		}
+	case "down", "j":
+		if m.libraryMenuCursor < libraryMenuRows-1 {
+			m.libraryMenuCursor++
+		}
+	case "left", "h":
+		m = m.changeLibraryMenuRow(-1).applyLibraryView()
+		return m.refreshRecentPlays()
+	case "right", "l", " ":
+		m = m.changeLibraryMenuRow(1).applyLibraryView()
+		return m.refreshRecentPlays()
+	}
+	return m, nil
+}
+
+// cycleLibrarySort switches the library to the next sort key without
+// opening the menu.
+func (m Model) cycleLibrarySort() (tea.Model, tea.Cmd) {
+	m.libraryView.Sort = (m.libraryView.Sort + 1) % sortKeyCount
+	m = m.applyLibraryView()
+	return m, func() tea.Msg { return statusMsg(Tf("Sorted by %s", m.libraryView.Sort)) }
+}
+
+// changeLibraryMenuRow changes the setting on the selected menu row.
+func (m Model) changeLibraryMenuRow(delta int) Model {
+	switch m.libraryMenuCursor {
+	case 0:
+		m.libraryView.Sort = (m.libraryView.Sort + SortKey(delta) + sortKeyCount) % sortKeyCount
+	case 1:
+		m.libraryView.Descending = !m.libraryView.Descending
+	case 2:
+		m.libraryView.LikedOnly = !m.libraryView.LikedOnly
+	case 3:
+		m.libraryView.UnplayedOnly = !m.libraryView.UnplayedOnly
+	case 4:
+		m.libraryView.MinRating = (m.libraryView.MinRating + delta + maxRating + 1) % (maxRating + 1)
+	case 5:
+		m.libraryView.Scope = (m.libraryView.Scope + LibraryScope(delta) + scopeCount) % scopeCount
+	case 6:
+		m.libraryView.Genre = cycleGenre(libraryGenres(m.libraryFiles), m.libraryView.Genre, delta)
+	}
+	return m
+}
+
+// renderLibraryMenu renders the sort/filter menu.
+func (m Model) renderLibraryMenu() string {
+	check := func(on bool) string {
+		if on {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+	direction := T("Ascending")
+	if m.libraryView.Descending {
+		direction = T("Descending")
+	}
+
+	minRating := T("Any")
+	if m.libraryView.MinRating > 0 {
+		minRating = ratingStars(m.libraryView.MinRating, true)
+	}
+
+	rows := []string{
+		fmt.Sprintf("%s‹ %s ›", padLabel("Sort by", 14), m.libraryView.Sort),
+		fmt.Sprintf("%s‹ %s ›", padLabel("Direction", 14), direction),
+		fmt.Sprintf("%s%s", padLabel("Liked only", 14), check(m.libraryView.LikedOnly)),
+		fmt.Sprintf("%s%s", padLabel("Unplayed only", 14), check(m.libraryView.UnplayedOnly)),
+		fmt.Sprintf("%s‹ %s ›", padLabel("Min. rating", 14), minRating),
+		fmt.Sprintf("%s‹ %s ›", padLabel("Show", 14), T(m.libraryView.Scope.String())),
+		fmt.Sprintf("%s‹ %s ›", padLabel("Genre", 14), genreLabel(m.libraryView.Genre, m.libraryFiles)),
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(" ⇅ "+T("Sort & Filter")+" ") + "\n\n")
+	for i, row := range rows {
+		if i == m.libraryMenuCursor {
+			b.WriteString(selectedStyle.Render("> "+row) + "\n")
+		} else {
+			b.WriteString(normalStyle.Render("  "+row) + "\n")
+		}
+	}
+	b.WriteString("\n" + mutedStyle.Render(T("↑/↓: select • ←/→: change • esc: close")))
+
+	box := boxStyle.Render(b.String())
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, box)
+}
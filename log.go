@@ -0,0 +1,149 @@
+// Package main provides the in-app log for Personal Musician.
+// Errors and subprocess output are collected here instead of being printed,
+// which would corrupt the full-screen UI, and shown in the Log view (L).
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxLogEntries bounds the log so long sessions do not grow without limit.
+const maxLogEntries = 1000
+
+// LogEntry is a single line of the in-app log.
+type LogEntry struct {
+	Time   time.Time
+	Source string // What produced the line, e.g. "error" or "yt-dlp"
+	Text   string
+}
+
+// String formats the entry as "15:04:05 [source] text".
+func (e LogEntry) String() string {
+	return fmt.Sprintf("%s [%s] %s", e.Time.Format("15:04:05"), e.Source, e.Text)
+}
+
+// AppLog is a bounded, concurrency-safe log buffer.
+type AppLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// appLog is the log shared by the whole application.
+var appLog = &AppLog{}
+
+// Add records text from source, one entry per non-empty line. The text is
+// also written to the log file, as an error when source is "error".
+func (l *AppLog) Add(source, text string) {
+	now := time.Now()
+
+	level := slog.LevelDebug
+	if source == "error" {
+		level = slog.LevelError
+	}
+	slog.Log(context.Background(), level, "app log", "source", source, "text", text)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r ")
+		if line == "" {
+			continue
+		}
+		l.entries = append(l.entries, LogEntry{Time: now, Source: source, Text: line})
+	}
+	if over := len(l.entries) - maxLogEntries; over > 0 {
+		l.entries = append([]LogEntry(nil), l.entries[over:]...)
+	}
+}
+
+// Entries returns a snapshot of the log, oldest first.
+func (l *AppLog) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]LogEntry, len(l.entries))
+	copy(entries, l.entries)
+	return entries
+}
+
+// openLog shows the Log view with the newest entry selected.
+func (m Model) openLog() (tea.Model, tea.Cmd) {
+	m.currentView = ViewLog
+	m.logCursor = len(appLog.Entries()) - 1
+	if m.logCursor < 0 {
+		m.logCursor = 0
+	}
+	return m, nil
+}
+
+// handleLogKeys handles keys in the Log view.
+func (m Model) handleLogKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	entries := appLog.Entries()
+	if cursor, ok := m.navigateList(msg.String(), m.logCursor, len(entries)); ok {
+		m.logCursor = cursor
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "y", "enter": // Copy the selected line
+		if m.logCursor < len(entries) {
+			return m, copyToClipboard(entries[m.logCursor].String(), T("Copied log line"))
+		}
+	case "c": // Copy the whole log
+		if len(entries) > 0 {
+			lines := make([]string, len(entries))
+			for i, e := range entries {
+				lines[i] = e.String()
+			}
+			return m, copyToClipboard(strings.Join(lines, "\n"), Tf("Copied %d log lines", len(entries)))
+		}
+	}
+	return m, nil
+}
+
+// copyToClipboard returns a command that copies text and reports done on success.
+func copyToClipboard(text, done string) tea.Cmd {
+	return func() tea.Msg {
+		if err := CopyToClipboard(text); err != nil {
+			return errorMsg(err.Error())
+		}
+		return statusMsg(done)
+	}
+}
+
+// renderLogView renders the log entries.
+func (m Model) renderLogView() string {
+	var b strings.Builder
+
+	b.WriteString(headerStyle.Render(" 📜 "+T("Log")+" ") + "\n\n")
+
+	entries := appLog.Entries()
+	if len(entries) == 0 {
+		b.WriteString(mutedStyle.Render(T("Nothing logged yet") + "\n"))
+		return b.String()
+	}
+
+	width := m.width - 4
+	if m.isWide() {
+		width = m.leftPaneWidth() - 6
+	}
+
+	b.WriteString(m.logScroll.render(len(entries), 1, m.maxVisible(), func(i int) string {
+		e := entries[i]
+		line := mutedStyle.Render(e.Time.Format("15:04:05")+" ["+e.Source+"]") + " " + truncate(e.Text, max(width-20-len(e.Source), 10))
+		if i == m.logCursor {
+			return selectedStyle.Render("> ") + line
+		}
+		return "  " + line
+	}))
+
+	return b.String()
+}
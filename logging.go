@@ -0,0 +1,138 @@
+// Package main provides the debug log file for Personal Musician.
+// Structured log records (log/slog) go to personal-musician.log in the data
+// directory, which is rotated once it grows too large. The file covers search
+// requests, yt-dlp invocations and player errors; --debug (or -v) adds
+// detailed records. Only the player that holds the instance lock writes the
+// file, so subcommands running beside it never rotate it under its feet;
+// they log warnings and errors to stderr instead.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LogFileName is the name of the log file in the data directory.
+const LogFileName = "personal-musician.log"
+
+// Log rotation limits.
+const (
+	maxLogFileSize = 1 << 20 // Rotate after 1 MiB
+	maxLogBackups  = 3       // Keep .1 to .3
+)
+
+// SetupLogging sends slog records to the rotating log file. Records below
+// Info are dropped unless debug is set. If the file cannot be opened, records
+// are discarded, since writing them to the terminal would corrupt the UI.
+func SetupLogging(debug bool) (io.Closer, error) {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+
+	file, err := openRotatingFile(filepath.Join(DataDir(), LogFileName), maxLogFileSize, maxLogBackups)
+	if err != nil {
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		return nil, err
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(file, &slog.HandlerOptions{Level: level})))
+	slog.Info("started", "version", versionString(), "debug", debug)
+	return file, nil
+}
+
+// SetupStderrLogging sends warnings and errors to stderr, and everything
+// if debug is set. It is used until the player has the instance lock, and
+// by subcommands throughout.
+func SetupStderrLogging(debug bool) {
+	level := slog.LevelWarn
+	if debug {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+// rotatingFile is a log file that is renamed to path.1 (shifting older
+// backups up) once it exceeds its size limit.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	limit   int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+// openRotatingFile opens path for appending, rotating it first if it is
+// already over the limit.
+func openRotatingFile(path string, limit int64, backups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	r := &rotatingFile{path: path, limit: limit, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	if r.size >= limit {
+		if err := r.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// open opens the log file for appending and records its size.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups, moves the current file to .1 and starts a new
+// one. If the file cannot be moved, logging continues in the current file.
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	for i := r.backups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	renameErr := os.Rename(r.path, r.path+".1")
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return fmt.Errorf("failed to rotate log file: %w", renameErr)
+	}
+	return nil
+}
+
+// Write appends p to the log file, rotating it first if p would exceed the limit.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.limit {
+		r.rotate() // On failure keep writing to the current file
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
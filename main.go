@@ -10,7 +10,16 @@
 //
 // Usage:
 //
-//	personal-musician
+//	personal-musician [--music-dir dir] [--config file] [--theme name]
+//	                  [--no-mouse] [--no-altscreen] [--listen addr] [--debug] [--version]
+//	                  [file|url...]
+//	personal-musician play <file|playlist>
+//	personal-musician download <url|query>
+//	personal-musician search <query> [--json]
+//	personal-musician completion bash|zsh|fish
+//	personal-musician setup [--standalone]
+//	personal-musician ctl play-pause|next|prev|add <file|url>...|status [--json]
+//	personal-musician doctor
 //
 // Controls:
 //
@@ -20,20 +29,122 @@
 //	Enter     - Select/Confirm
 //	s         - Open search
 //	Tab       - Switch views
+//	t         - Cycle color theme
+//	v         - Toggle visualizer
+//	b         - Toggle mini mode
+//	L         - Show log
+//	.         - Jump to the playing track
 //	Esc       - Back to library
 //	q/Ctrl+C  - Quit
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	flags, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2) // The flag package has printed the error and usage
+	}
+	if flags.Version {
+		fmt.Println("personal-musician", versionString())
+		return
+	}
+	var cmd *command
+	var targets []string
+	if len(flags.Args) > 0 {
+		if c, ok := findCommand(flags.Args[0]); ok {
+			cmd = &c
+		} else if targets, err = resolveOpenArgs(flags.Args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	// Read the config file before anything depends on its settings
+	cfg, err := flags.loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	ApplyConfig(cfg)
+	applyFlags(flags)
+
+	// Subcommands may run beside the player, whose log file they must leave
+	// alone; only the player writes it once it holds the instance lock
+	SetupStderrLogging(flags.Debug)
+
+	if err := InitDataDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Pick glyphs and interface language before anything is rendered
+	SetASCIIMode(DetectASCIIMode())
+	SetAccessibleMode(DetectAccessibleMode())
+	SetThumbnails(DetectThumbnails())
+	SetAlbumColorsEnabled(DetectAlbumColors())
+	if err := SetLocale(DetectLocale()); err != nil && (os.Getenv("PM_LANG") != "" || config.UI.Language != "") {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Run a subcommand instead of the TUI
+	if cmd != nil {
+		if err := cmd.run(flags.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Hand files and URLs to a running instance instead of starting another
+	if len(targets) > 0 {
+		resp, err := SendToInstance(instanceRequest{Command: "open", Args: targets})
+		switch {
+		case err == nil && resp.OK:
+			fmt.Println(resp.Message)
+			return
+		case err == nil:
+			fmt.Fprintf(os.Stderr, "Error: %s\n", resp.Message)
+			os.Exit(1)
+		case !errors.Is(err, errNoInstance):
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	instance, err := AcquireInstance()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer instance.Close()
+
+	// Record diagnostics in the log file
+	if logFile, err := SetupLogging(flags.Debug); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not open log file: %v\n", err)
+	} else {
+		defer logFile.Close()
+	}
+
 	// Print welcome banner
-	fmt.Println("🎵 Personal Musician - Starting...")
+	fmt.Println(Glyphs("🎵 Personal Musician - Starting..."))
+
+	// Move files older versions kept next to the program
+	for _, notice := range MigrateLegacyFiles() {
+		fmt.Fprintf(os.Stderr, "Note: %s\n", notice)
+		slog.Info("migration", "notice", notice)
+	}
 
 	// Initialize the Music directory
 	if err := InitMusicDir(); err != nil {
@@ -41,40 +152,207 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load user-defined themes
+	if err := LoadThemes(ThemesFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load themes: %v\n", err)
+	}
+	if config.UI.Theme != "" {
+		if err := SetTheme(config.UI.Theme); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
 	// Initialize the downloader
-	downloader, err := NewDownloader(MusicDir)
+	downloader, err := NewDownloader(MusicDir, config.DownloadOptions())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing downloader: %v\n", err)
 		os.Exit(1)
 	}
 	defer downloader.Close()
+	if err := downloader.Available(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; downloads are disabled\n", err)
+		slog.Warn("downloads disabled", "err", err)
+	}
 
 	// Initialize the player
 	player := NewPlayer()
 	defer player.Close()
+	player.SetCrossfade(crossfadeDuration())
+	player.SetTrimSilence(config.Playback.TrimSilence)
+
+	// Load per-track statistics and count completed plays
+	stats, err := LoadStats(StatsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load stats: %v\n", err)
+	}
+
+	// Remember the position of podcast episodes and other long tracks
+	episodes, err := LoadEpisodes(EpisodesFile, longTrackLength())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load episode progress: %v\n", err)
+	}
+	defer StartEpisodeTracking(episodes, player)()
+
+	player.SetOnTrackEnd(func(path string, listened time.Duration) {
+		stats.RecordPlay(path, listened)
+		episodes.Finished(path)
+	})
+
+	// Load the remembered pane sizes
+	panes, err := LoadPaneSizes(LayoutFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load layout: %v\n", err)
+	}
+
+	// Select the refresh rate
+	ticks, err := DetectTickSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 
-	// Scan existing music files and set as playlist
-	files, err := ScanMusicFiles()
+	// Select the keybinding preset
+	keyPreset, err := DetectKeyPreset()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Could not scan music files: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Idle time before the screensaver starts
+	screensaverDelay, err := DetectScreensaverDelay()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Reopen where the last session left off
+	state, err := LoadState(StateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load state: %v\n", err)
+	}
+
+	// Serve the event stream for external dashboards
+	if config.Server.Listen != "" {
+		server, err := StartServer(config.Server.Listen, player, downloader, config.Server.Sync)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not start server: %v\n", err)
+		} else {
+			defer server.Close()
+		}
+	}
+
+	if config.Server.Sync && config.Server.Listen == "" {
+		fmt.Fprintln(os.Stderr, "Warning: Sync needs the server; set --listen or [server] listen")
+	}
+
+	// Share the library with DLNA devices
+	if config.Server.DLNA {
+		name := config.Server.DLNAName
+		if name == "" {
+			hostname, _ := os.Hostname()
+			name = "Personal Musician on " + hostname
+		}
+		dlna, err := StartDLNAServer(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not start DLNA server: %v\n", err)
+		} else {
+			defer dlna.Close()
+		}
 	}
-	player.SetPlaylist(files)
 
 	// Create the TUI model
-	model := NewModel(player, downloader)
+	model := NewModel(player, downloader, stats, panes, keyPreset, ticks, screensaverDelay).restoreState(state)
+	model.episodes = episodes
+
+	// Browse the Jellyfin server, signing in on first use
+	if config.Jellyfin.URL != "" {
+		if model.jellyfin, err = NewJellyfinClient(config.Jellyfin); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	// Follow another instance's playback
+	model.syncLeader = config.Sync.Join
+
+	// Back up the library on demand, and on schedule if an interval is set
+	if config.Backup.Remote != "" {
+		model.backup = NewBackup(config.Backup)
+		defer model.backup.Close()
+		if config.Backup.Interval != "" {
+			// The config file was validated when loaded
+			interval, _ := time.ParseDuration(config.Backup.Interval)
+			backupCtx, stopBackups := context.WithCancel(context.Background())
+			defer stopBackups()
+			go model.backup.Schedule(backupCtx, interval)
+		}
+	}
 
 	// Create and run the Bubble Tea program
-	program := tea.NewProgram(
-		model,
-		tea.WithAltScreen(),       // Use alternate screen buffer
-		tea.WithMouseCellMotion(), // Enable mouse support
-	)
+	// Signals are handled below so SIGHUP also saves the session
+	options := append(programOptions(flags), tea.WithoutSignalHandler())
+	program := tea.NewProgram(newCrashGuard(model), options...)
+
+	// Accept files and URLs from later launches, and queue our own
+	go instance.Serve(program)
+	if config.Sync.Join != "" {
+		followCtx, stopFollowing := context.WithCancel(context.Background())
+		defer stopFollowing()
+		go FollowLeader(followCtx, config.Sync.Join, program)
+	}
+
+	// Run the user's Lua plugins
+	if plugins, err := StartPlugins(PluginDir, player, downloader, program); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load plugins: %v\n", err)
+	} else if plugins != nil {
+		defer plugins.Close()
+	}
+
+	// Write the current track for stream overlays and status lines
+	if config.NowPlaying.File != "" {
+		defer StartNowPlayingFile(config.NowPlaying, player)()
+	}
+
+	// Show the track in the system media controls and follow the media keys
+	if nowPlaying, err := StartNowPlaying(player, program); err != nil {
+		slog.Warn("media controls unavailable", "err", err)
+	} else {
+		defer nowPlaying.Close()
+	}
+
+	if len(targets) > 0 {
+		go program.Send(remoteMsg{
+			req:   instanceRequest{Command: "open", Args: targets},
+			reply: make(chan instanceResponse, 1),
+		})
+	}
 
 	// Run the program
-	if _, err := program.Run(); err != nil {
+	stopSignals := handleShutdownSignals(program)
+	var final tea.Model
+	runMainLoop(func() { final, err = program.Run() })
+	stopSignals()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("👋 Goodbye!")
+	// After a crash the guard has already saved everything it could
+	guard, ok := final.(crashGuard)
+	if ok && guard.crash.crashed {
+		fmt.Fprintln(os.Stderr, guard.crash.crashMessage())
+		os.Exit(1)
+	}
+
+	// Log the session length into the statistics and remember the UI state
+	if ok {
+		m := guard.Model.stopCast() // Also restores the mute state before saving
+		if err := m.SaveSession(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not save session: %v\n", err)
+		}
+		if err := m.SaveState(StateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not save state: %v\n", err)
+		}
+		if err := libraryMetadata.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not save library index: %v\n", err)
+		}
+	}
+
+	fmt.Println(Glyphs("👋 Goodbye!"))
 }
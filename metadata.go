@@ -0,0 +1,541 @@
+// Package main provides background metadata scanning for Personal Musician.
+// Listing the library only walks the music directories; tags and durations
+// are read afterwards by a bounded pool of workers, so startup stays quick
+// with thousands of files. Results reach the library in batches as they
+// arrive while the header shows the scan's progress, and are kept in the
+// library index (see index.go) so later scans and runs only read new and
+// changed files.
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Metadata scan tuning.
+const (
+	maxMetadataWorkers = 8                      // Files read at once, at most
+	metadataBatchSize  = 200                    // Results delivered to the UI at once, at most
+	metadataBatchDelay = 100 * time.Millisecond // Longest wait to fill a batch
+	mp3SyncWindow      = 64 << 10               // Bytes searched for the first MP3 frame
+	indexFlushSize     = 500                    // Changed entries kept before writing the index
+)
+
+// metadataWorkers returns the number of files read at once: one per CPU,
+// as reading is mostly parsing, within limits that keep disks responsive.
+func metadataWorkers() int {
+	return min(max(runtime.NumCPU(), 2), maxMetadataWorkers)
+}
+
+// metadataCache holds the metadata read so far, keyed by path, and
+// mirrors it to the library index.
+type metadataCache struct {
+	mu      sync.Mutex
+	loaded  bool                  // The index has been read
+	entries map[string]indexEntry // Known tracks
+	dirty   map[string]bool       // Paths changed since the index was written; removed when not in entries
+
+	flushMu sync.Mutex // Serializes writes to the index
+}
+
+// libraryMetadata is the metadata of the library's tracks.
+var libraryMetadata = &metadataCache{entries: make(map[string]indexEntry), dirty: make(map[string]bool)}
+
+// load reads the index the first time the cache is used. It is called with
+// c.mu held.
+func (c *metadataCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	entries, err := loadIndex()
+	if err != nil {
+		slog.Warn("failed to load library index", "err", err)
+	}
+	for path, entry := range entries {
+		if _, changed := c.dirty[path]; !changed {
+			c.entries[path] = entry
+		}
+	}
+}
+
+// files returns the indexed tracks in the music directories.
+func (c *metadataCache) files() []MusicFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	var files []MusicFile
+	for path, entry := range c.entries {
+		if inMusicDirs(path) {
+			files = append(files, entry.musicFile(path))
+		}
+	}
+	return files
+}
+
+// apply fills in the metadata of files that have not changed since they
+// were read and returns the files that still need reading.
+func (c *metadataCache) apply(files []MusicFile) []MusicFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	var missing []MusicFile
+	for i, file := range files {
+		entry, ok := c.entries[file.Path]
+		if !ok || !entry.current(file) {
+			missing = append(missing, file)
+			continue
+		}
+		files[i].Tags, files[i].Duration, files[i].Hash = entry.Tags, entry.Duration, entry.Hash
+	}
+	return missing
+}
+
+// put records the metadata of a file. It reports whether enough changes
+// have piled up to write them to the index.
+func (c *metadataCache) put(file MusicFile) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[file.Path] = indexEntryOf(file)
+	c.dirty[file.Path] = true
+	return len(c.dirty) >= indexFlushSize
+}
+
+// forget drops a file whose contents changed behind the cache's back, such
+// as by writing its tags, and removes it from the index right away.
+func (c *metadataCache) forget(path string) {
+	c.mu.Lock()
+	delete(c.entries, path)
+	c.dirty[path] = true
+	c.mu.Unlock()
+	if err := c.flush(); err != nil {
+		slog.Warn("failed to update library index", "path", path, "err", err)
+	}
+}
+
+// move keeps what is known about renamed or moved files under their new
+// paths, given by old path in moved, and updates the index right away. The
+// contents are the same, so nothing has to be read again.
+func (c *metadataCache) move(moved map[string]string) {
+	c.mu.Lock()
+	c.load()
+	for from, to := range moved {
+		if entry, ok := c.entries[from]; ok {
+			delete(c.entries, from)
+			c.entries[to] = entry
+			c.dirty[from], c.dirty[to] = true, true
+		}
+	}
+	c.mu.Unlock()
+	if err := c.flush(); err != nil {
+		slog.Warn("failed to update library index", "err", err)
+	}
+}
+
+// prune forgets the tracks in the music directories that a completed scan
+// did not find.
+func (c *metadataCache) prune(found []MusicFile) {
+	c.mu.Lock()
+	c.load()
+	present := make(map[string]bool, len(found))
+	for _, file := range found {
+		present[file.Path] = true
+	}
+	for path := range c.entries {
+		if !present[path] && inMusicDirs(path) {
+			delete(c.entries, path)
+			c.dirty[path] = true
+		}
+	}
+	c.mu.Unlock()
+	if err := c.flush(); err != nil {
+		slog.Warn("failed to update library index", "err", err)
+	}
+}
+
+// flush writes the changes made since the last flush to the index.
+func (c *metadataCache) flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	put := make(map[string]indexEntry)
+	var remove []string
+	for path := range c.dirty {
+		if entry, ok := c.entries[path]; ok {
+			put[path] = entry
+		} else {
+			remove = append(remove, path)
+		}
+	}
+	dirty := c.dirty
+	c.dirty = make(map[string]bool)
+	c.mu.Unlock()
+
+	if err := updateIndex(put, remove); err != nil {
+		// Keep the changes for the next attempt
+		c.mu.Lock()
+		for path := range dirty {
+			c.dirty[path] = true
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// readMetadata reads the tags, duration and audio hash of a file.
+// Unreadable parts are left empty.
+func readMetadata(file MusicFile) MusicFile {
+	file.Tags, _ = ReadTags(file.Path)
+	file.Duration, _ = audioDuration(file.Path)
+	file.Hash, _ = audioHash(file.Path)
+	return file
+}
+
+// audioHash returns the SHA-1 of a file's audio, in hex. For MP3 files the
+// ID3 tags are left out, so copies of a song hash the same however they are
+// tagged; other formats are hashed whole.
+func audioHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read file info: %w", err)
+	}
+
+	start, end := int64(0), info.Size()
+	if isMP3(path) {
+		start = id3v2Length(f)
+		trailer := make([]byte, 3)
+		if end-start >= 128 {
+			if _, err := f.ReadAt(trailer, end-128); err == nil && string(trailer) == "TAG" {
+				end -= 128 // ID3v1 tag
+			}
+		}
+	}
+
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, start, max(end-start, 0))); err != nil {
+		return "", fmt.Errorf("failed to read audio: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// id3v2Length returns the length of the ID3v2 tag at the start of f, or 0
+// if it has none.
+func id3v2Length(f *os.File) int64 {
+	header := make([]byte, 10)
+	if _, err := f.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return 0
+	}
+	length := 10 + int64(syncsafe(header[6:10]))
+	if header[5]&0x10 != 0 {
+		length += 10 // Footer
+	}
+	return length
+}
+
+// metadataScan reads the metadata of files in the background.
+type metadataScan struct {
+	id      int            // Identifies the scan's messages
+	total   int            // Files to read
+	done    int            // Files read so far
+	results chan MusicFile // Files with their metadata, closed at the end
+	cancel  context.CancelFunc
+}
+
+// metadataBatchMsg delivers files whose metadata has been read.
+type metadataBatchMsg struct {
+	id    int
+	files []MusicFile
+	done  bool // The scan has finished
+}
+
+// startMetadataScan starts reading the metadata of files with a pool of
+// workers.
+func startMetadataScan(id int, files []MusicFile) *metadataScan {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &metadataScan{
+		id:      id,
+		total:   len(files),
+		results: make(chan MusicFile, metadataBatchSize),
+		cancel:  cancel,
+	}
+
+	jobs := make(chan MusicFile)
+	var wg sync.WaitGroup
+	for range metadataWorkers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				file = readMetadata(file)
+				if libraryMetadata.put(file) {
+					if err := libraryMetadata.flush(); err != nil {
+						slog.Warn("failed to update library index", "err", err)
+					}
+				}
+				select {
+				case s.results <- file:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(s.results)
+		defer func() {
+			if err := libraryMetadata.flush(); err != nil {
+				slog.Warn("failed to update library index", "err", err)
+			}
+		}()
+		defer wg.Wait()
+		defer close(jobs)
+		for _, file := range files {
+			select {
+			case jobs <- file:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// next returns a command that waits for the next batch of results. A batch
+// is delivered once it is full or metadataBatchDelay after its first file,
+// so the library updates smoothly without a message per file.
+func (s *metadataScan) next() tea.Cmd {
+	return func() tea.Msg {
+		file, ok := <-s.results
+		if !ok {
+			return metadataBatchMsg{id: s.id, done: true}
+		}
+		batch := []MusicFile{file}
+		timeout := time.After(metadataBatchDelay)
+		for len(batch) < metadataBatchSize {
+			select {
+			case file, ok := <-s.results:
+				if !ok {
+					return metadataBatchMsg{id: s.id, files: batch, done: true}
+				}
+				batch = append(batch, file)
+			case <-timeout:
+				return metadataBatchMsg{id: s.id, files: batch}
+			}
+		}
+		return metadataBatchMsg{id: s.id, files: batch}
+	}
+}
+
+// scanLibraryMetadata fills in the library's cached metadata and starts
+// reading the rest, replacing any scan in progress. The library is copied
+// first, as the player may hold the old slice.
+func (m Model) scanLibraryMetadata() (Model, tea.Cmd) {
+	if m.metadataScan != nil {
+		m.metadataScan.cancel()
+		m.metadataScan = nil
+	}
+	m.libraryFiles = slices.Clone(m.libraryFiles)
+	missing := libraryMetadata.apply(m.libraryFiles)
+	if len(missing) == 0 {
+		return m, nil
+	}
+	m.metadataScanID++
+	m.metadataScan = startMetadataScan(m.metadataScanID, missing)
+	return m, m.metadataScan.next()
+}
+
+// applyMetadataBatch merges a batch of results into the library. The
+// library is copied first, as the player holds the old slice.
+func (m Model) applyMetadataBatch(msg metadataBatchMsg) (Model, tea.Cmd) {
+	if m.metadataScan == nil || msg.id != m.metadataScan.id {
+		return m, nil // From a replaced scan
+	}
+
+	files := slices.Clone(m.libraryFiles)
+	index := make(map[string]int, len(files))
+	for i, file := range files {
+		index[file.Path] = i
+	}
+	for _, file := range msg.files {
+		if i, ok := index[file.Path]; ok {
+			files[i].Tags, files[i].Duration, files[i].Hash = file.Tags, file.Duration, file.Hash
+		}
+	}
+	m.libraryFiles = files
+	m.libraryGen++
+	m.metadataScan.done += len(msg.files)
+	if m.libraryView.sortsByMetadata() {
+		m = m.resortLibrary()
+	}
+
+	if msg.done {
+		m.metadataScan = nil
+		return m, nil
+	}
+	return m, m.metadataScan.next()
+}
+
+// renderScanProgress renders the header's indicator for a walk of the
+// music directories or a metadata scan in progress, e.g. "Scanning folders:
+// 5200 songs" or "Reading tags 120/3400". It is empty when idle.
+func (m Model) renderScanProgress() string {
+	var parts []string
+	if m.libraryScan != nil {
+		parts = append(parts, Tf("Scanning folders: %d songs", m.libraryScan.found))
+	}
+	if m.metadataScan != nil {
+		parts = append(parts, Tf("Reading tags %d/%d", m.metadataScan.done, m.metadataScan.total))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return mutedStyle.Render(strings.Join(parts, " · "))
+}
+
+// mp3Frame describes an MP3 frame header.
+type mp3Frame struct {
+	mpeg1      bool // MPEG-1; otherwise MPEG-2 or 2.5
+	mono       bool
+	bitrate    int // Bits per second
+	sampleRate int // Samples per second
+	length     int // Bytes in the frame
+}
+
+// MPEG audio layer III bitrates in kbit/s and sample rates in Hz, by index.
+var (
+	mp3Bitrates1    = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mp3Bitrates2    = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+	mp3SampleRates1 = [3]int{44100, 48000, 32000}
+)
+
+// parseMP3Frame parses the four-byte header of a layer III frame.
+func parseMP3Frame(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := b[1] >> 3 & 3 // 3 = MPEG-1, 2 = MPEG-2, 0 = MPEG-2.5
+	layer := b[1] >> 1 & 3   // 1 = layer III
+	bitrateIndex := b[2] >> 4
+	rateIndex := b[2] >> 2 & 3
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	f := mp3Frame{mpeg1: version == 3, mono: b[3]>>6 == 3}
+	f.sampleRate = mp3SampleRates1[rateIndex]
+	if f.mpeg1 {
+		f.bitrate = mp3Bitrates1[bitrateIndex] * 1000
+	} else {
+		f.bitrate = mp3Bitrates2[bitrateIndex] * 1000
+		f.sampleRate /= 2
+		if version == 0 {
+			f.sampleRate /= 2
+		}
+	}
+	padding := int(b[2] >> 1 & 1)
+	f.length = f.samples()/8*f.bitrate/f.sampleRate + padding
+	return f, true
+}
+
+// samples returns the number of samples per frame.
+func (f mp3Frame) samples() int {
+	if f.mpeg1 {
+		return 1152
+	}
+	return 576
+}
+
+// vbrFrames returns the frame count of the Xing/Info or VBRI header in the
+// first frame, or 0 if it has none.
+func (f mp3Frame) vbrFrames(frame []byte) int {
+	// The Xing header follows the side information
+	side := 32
+	switch {
+	case f.mpeg1 && f.mono:
+		side = 17
+	case !f.mpeg1 && !f.mono:
+		side = 17
+	case !f.mpeg1 && f.mono:
+		side = 9
+	}
+	if x := frame[min(4+side, len(frame)):]; len(x) >= 12 && (string(x[:4]) == "Xing" || string(x[:4]) == "Info") {
+		if x[7]&1 != 0 { // Frame count present
+			return int(uint32(x[8])<<24 | uint32(x[9])<<16 | uint32(x[10])<<8 | uint32(x[11]))
+		}
+		return 0
+	}
+
+	// The VBRI header is always 32 bytes in
+	if v := frame[min(36, len(frame)):]; len(v) >= 18 && string(v[:4]) == "VBRI" {
+		return int(uint32(v[14])<<24 | uint32(v[15])<<16 | uint32(v[16])<<8 | uint32(v[17]))
+	}
+	return 0
+}
+
+// mp3Duration returns the duration of an MP3 file without decoding it:
+// exactly from the Xing/Info or VBRI header that variable-bitrate encoders
+// write, otherwise estimated from the first frame's bitrate as for a
+// constant-bitrate file.
+func mp3Duration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file info: %w", err)
+	}
+
+	// Audio starts after the ID3v2 tag, if any
+	start := id3v2Length(f)
+
+	buf := make([]byte, mp3SyncWindow)
+	n, err := f.ReadAt(buf, start)
+	if n == 0 && err != nil {
+		return 0, fmt.Errorf("failed to read audio: %w", err)
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMP3Frame(buf[i:])
+		if !ok {
+			continue
+		}
+		// A real frame is followed by another; this rules out stray sync bytes
+		if next := i + frame.length; next+4 <= len(buf) {
+			if _, ok := parseMP3Frame(buf[next:]); !ok {
+				continue
+			}
+		}
+
+		if frames := frame.vbrFrames(buf[i:]); frames > 0 {
+			return time.Duration(frames) * time.Duration(frame.samples()) * time.Second / time.Duration(frame.sampleRate), nil
+		}
+		audio := info.Size() - start - int64(i)
+		return time.Duration(float64(audio*8) / float64(frame.bitrate) * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("no MP3 frame found")
+}
@@ -0,0 +1,46 @@
+// Package main provides the mini (compact) mode for Personal Musician.
+// Mini mode collapses the UI to a few lines with the current track,
+// progress and key hints so the player fits in a small terminal pane.
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// renderMini renders the compact UI: now playing, progress and key hints.
+func (m Model) renderMini() string {
+	var lines []string
+
+	state := m.player.GetState()
+	if !state.IsPlaying && state.CurrentFile == "" {
+		lines = append(lines, mutedStyle.Render("♪ "+T("No song playing")))
+	} else {
+		line := fmt.Sprintf("%s%s  %s/%s  %s",
+			nowPlayingHead(state, m.currentSongName(state)),
+			m.renderProgressBar(state),
+			FormatDuration(state.Position),
+			FormatDuration(state.Duration),
+			renderModeIndicators(state),
+		)
+		if state.QueueLength > 0 {
+			line += mutedStyle.Render("  " + Tf("+%d queued", state.QueueLength))
+		}
+		lines = append(lines, line)
+	}
+
+	// Errors and status messages take the place of a third line
+	if n := len(m.errors); n > 0 {
+		lines = append(lines, statusBarErrorStyle.Render("⚠ "+m.errors[n-1]))
+	} else if m.statusMessage != "" {
+		lines = append(lines, statusStyle.Render(m.statusMessage))
+	}
+
+	keys := []string{"space: pause", "←/→: prev/next", "M: mute", "b: expand", "q: quit"}
+	for i, key := range keys {
+		keys[i] = T(key)
+	}
+	lines = append(lines, mutedStyle.Render(strings.Join(keys, " • ")))
+
+	return strings.Join(lines, "\n")
+}
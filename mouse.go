@@ -0,0 +1,301 @@
+// Package main provides mouse handling for the Personal Musician TUI.
+// Clicking the progress bar seeks, clicking a list row selects it,
+// double-clicking a row plays (or downloads) it and the wheel scrolls lists.
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickInterval is the maximum time between two clicks on the same row
+// for them to count as a double-click.
+const doubleClickInterval = 400 * time.Millisecond
+
+// wheelStep is the number of rows moved per mouse wheel notch.
+const wheelStep = 3
+
+// handleMouse processes mouse input.
+func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.dialog != nil || m.finderOpen || m.libraryMenuOpen || m.eqOpen || m.contextMenu != nil || m.trackInfo != nil || m.pickerOpen || m.renaming != nil || msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+	if msg.Button == tea.MouseButtonRight {
+		return m.handleRightClick(msg)
+	}
+	if msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown {
+		return m.handleWheel(msg)
+	}
+	if msg.Button != tea.MouseButtonLeft {
+		return m, nil
+	}
+
+	// Progress bar click seeks to that position
+	if x, y, ok := m.progressBarOrigin(); ok && msg.Y == y && msg.X >= x && msg.X < x+progressBarWidth {
+		state := m.player.GetState()
+		pct := float64(msg.X-x) / float64(progressBarWidth)
+		target := time.Duration(pct * float64(state.Duration))
+		if err := m.player.SeekTo(target); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		return m, nil
+	}
+
+	// Mini mode has no lists to click
+	if m.miniMode {
+		return m, nil
+	}
+
+	// The header's download summary expands into the Downloads view
+	if summary := m.renderDownloadSummary(); summary != "" && msg.Y == 0 &&
+		msg.X >= downloadSummaryX() && msg.X < downloadSummaryX()+lipgloss.Width(summary) {
+		m.currentView = ViewDownloads
+		return m, nil
+	}
+
+	view, row, ok := m.listRowAt(msg.X, msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	now := time.Now()
+	double := row == m.lastClickRow && now.Sub(m.lastClickTime) < doubleClickInterval
+	m.lastClickRow = row
+	m.lastClickTime = now
+
+	// Clicking a pane in the wide layout also focuses it
+	m.currentView = view
+
+	switch view {
+	case ViewLibrary:
+		m.libraryCursor = row
+		if double {
+			return m.handleLibraryKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	case ViewResults:
+		m.resultsCursor = row
+		if double {
+			return m.handleResultsKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	case ViewQueue:
+		m.queueCursor = row
+		if double {
+			return m.handleQueueKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	case ViewDownloads:
+		m.downloadsCursor = row
+	case ViewLog:
+		m.logCursor = row
+	case ViewDuplicates:
+		m.duplicatesCursor = row
+		if double {
+			return m.handleDuplicatesKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	case ViewOrganize:
+		m.organizeCursor = row
+		if double {
+			return m.handleOrganizeKeys(keyRunes("x"))
+		}
+	case ViewBrowse:
+		m.browseCursor[m.browseLevel] = row
+		if double {
+			return m.handleBrowseKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	case ViewJellyfin:
+		if page := m.jellyfinPage(); page != nil {
+			page.cursor = row
+			if double {
+				return m.handleJellyfinKeys(tea.KeyMsg{Type: tea.KeyEnter})
+			}
+		}
+	case ViewPlaylists:
+		m.playlistsCursor = row
+		if double {
+			return m.handlePlaylistsKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	case ViewPlaylistEditor:
+		m.editorCursor = row
+		if double {
+			return m.handlePlaylistEditorKeys(tea.KeyMsg{Type: tea.KeyEnter})
+		}
+	}
+
+	return m, nil
+}
+
+// handleWheel scrolls the list under the pointer without changing focus.
+func (m Model) handleWheel(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.miniMode {
+		return m, nil
+	}
+
+	delta := wheelStep
+	if msg.Button == tea.MouseButtonWheelUp {
+		delta = -wheelStep
+	}
+
+	// In the wide layout the right pane holds the queue
+	view := m.currentView
+	if m.isWide() {
+		if msg.X >= m.leftPaneWidth() {
+			view = ViewQueue
+		} else {
+			view = m.leftView()
+		}
+	}
+
+	scroll := func(cursor, total int) int {
+		cursor += delta
+		if cursor > total-1 {
+			cursor = total - 1
+		}
+		if cursor < 0 {
+			cursor = 0
+		}
+		return cursor
+	}
+
+	switch view {
+	case ViewLibrary:
+		m.libraryCursor = scroll(m.libraryCursor, len(m.visibleLibrary()))
+	case ViewResults:
+		m.resultsCursor = scroll(m.resultsCursor, len(m.youtubeResults))
+	case ViewQueue:
+		m.queueCursor = scroll(m.queueCursor, m.player.GetState().QueueLength)
+	case ViewDownloads:
+		m.downloadsCursor = scroll(m.downloadsCursor, len(m.downloader.Downloads()))
+	case ViewPlaylists:
+		m.playlistsCursor = scroll(m.playlistsCursor, len(m.playlistNames))
+	case ViewPlaylistEditor:
+		m.editorCursor = scroll(m.editorCursor, len(m.editing.Tracks))
+	case ViewLog:
+		m.logCursor = scroll(m.logCursor, len(appLog.Entries()))
+	case ViewDuplicates:
+		m.duplicatesCursor = scroll(m.duplicatesCursor, len(m.duplicates))
+	case ViewBrowse:
+		m.browseCursor[m.browseLevel] = scroll(m.browseCursor[m.browseLevel], m.browseCount(m.browseLevel))
+	case ViewOrganize:
+		m.organizeCursor = scroll(m.organizeCursor, len(m.organize))
+	case ViewJellyfin:
+		if page := m.jellyfinPage(); page != nil {
+			page.cursor = scroll(page.cursor, len(page.items))
+		}
+	}
+	return m, nil
+}
+
+// handleRightClick selects the clicked track or result and opens its context menu.
+func (m Model) handleRightClick(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.miniMode {
+		return m, nil
+	}
+
+	view, row, ok := m.listRowAt(msg.X, msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	switch view {
+	case ViewLibrary:
+		m.libraryCursor = row
+	case ViewResults:
+		m.resultsCursor = row
+	default:
+		return m, nil
+	}
+	m.currentView = view
+	return m.openContextMenu()
+}
+
+// titleHeight returns the number of lines taken by the title.
+func (m Model) titleHeight() int {
+	return lipgloss.Height(titleStyle.Render(appTitle))
+}
+
+// progressBarOrigin returns the screen position of the now-playing progress bar.
+func (m Model) progressBarOrigin() (x, y int, ok bool) {
+	state := m.player.GetState()
+	if state.Duration <= 0 || (!state.IsPlaying && state.CurrentFile == "") {
+		return 0, 0, false
+	}
+
+	if m.miniMode {
+		// Mini mode draws the progress bar on the first line, unboxed
+		return lipgloss.Width(nowPlayingHead(state, m.currentSongName(state))), 0, true
+	}
+
+	if m.isWide() {
+		// Pane border and padding, then the fourth line of the panel
+		return m.leftPaneWidth() + 2, m.titleHeight() + 1 + 3, true
+	}
+
+	// Border and padding of the now-playing box take two columns and one row
+	head := nowPlayingHead(state, m.currentSongName(state))
+	return 2 + lipgloss.Width(head), m.titleHeight() + 1, true
+}
+
+// listRowAt maps a screen position to the view and item index of a list row.
+func (m Model) listRowAt(x, y int) (View, int, bool) {
+	view := m.currentView
+	var top int
+
+	if m.isWide() {
+		// Lists start below the pane border, view header and a blank line
+		if x < m.leftPaneWidth() {
+			view = m.leftView()
+			top = m.titleHeight() + 1 + 2
+		} else {
+			view = ViewQueue
+			top = m.titleHeight() + 1 + lipgloss.Height(m.renderNowPlayingPanel()) + 1 + 2
+		}
+	} else {
+		// Lists start below Now Playing, the view header and a blank line
+		top = m.titleHeight() + lipgloss.Height(m.renderNowPlaying()) + 2
+	}
+
+	var list scrollList
+	var total, rowHeight int
+	switch view {
+	case ViewLibrary:
+		list, total, rowHeight = m.libraryScroll, len(m.visibleLibrary()), 1
+	case ViewResults:
+		list, total, rowHeight = m.resultsScroll, len(m.youtubeResults), 2
+	case ViewQueue:
+		list, total, rowHeight = m.queueScroll, m.player.GetState().QueueLength, 1
+	case ViewDownloads:
+		list, total, rowHeight = m.downloadsScroll, len(m.downloader.Downloads()), 1
+	case ViewPlaylists:
+		list, total, rowHeight = m.playlistsScroll, len(m.playlistNames), 1
+	case ViewPlaylistEditor:
+		list, total, rowHeight = m.editorScroll, len(m.editing.Tracks), 1
+	case ViewLog:
+		list, total, rowHeight = m.logScroll, len(appLog.Entries()), 1
+	case ViewDuplicates:
+		list, total, rowHeight = m.duplicatesScroll, len(m.duplicates), 1
+	case ViewBrowse:
+		list, total, rowHeight = m.browseScroll, m.browseCount(m.browseLevel), 1
+	case ViewOrganize:
+		list, total, rowHeight = m.organizeScroll, len(m.organize), 2
+	case ViewJellyfin:
+		page := m.jellyfinPage()
+		if page == nil {
+			return view, 0, false
+		}
+		list, total, rowHeight = m.jellyfinScroll, len(page.items), 1
+	default:
+		return view, 0, false
+	}
+
+	if y < top {
+		return view, 0, false
+	}
+
+	offset := (y - top) / rowHeight
+	row := list.firstRow(rowHeight) + offset
+	if offset >= m.maxVisible() || row >= total {
+		return view, 0, false
+	}
+	return view, row, true
+}
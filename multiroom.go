@@ -0,0 +1,409 @@
+// Package main provides multi-room playback for Personal Musician.
+// One instance leads: with sync enabled under [server] its HTTP server
+// accepts followers at /sync. Followers (started with --join host:port)
+// fetch the leader's current track from /sync/media and play it in step
+// with the leader. Every state message carries the leader's clock, and
+// followers estimate the clock offset from ping round trips, so both
+// machines agree on the position to within a few tens of milliseconds.
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gorilla/websocket"
+)
+
+// Multi-room timing.
+const (
+	syncStateInterval = 500 * time.Millisecond // How often the leader sends its state
+	syncPingInterval  = 2 * time.Second        // How often followers measure the clock offset
+	syncPingSamples   = 8                      // Round trips the offset is estimated from
+	syncTolerance     = 200 * time.Millisecond // Drift corrected by seeking
+	syncRetryDelay    = 5 * time.Second        // Wait before reconnecting to the leader
+)
+
+// SyncCacheDir holds the tracks fetched from the leader.
+var SyncCacheDir = dataPath("sync")
+
+// syncMessage is a message between leader and follower.
+type syncMessage struct {
+	Type     string  `json:"type"`            // "state", "ping" or "pong"
+	Token    string  `json:"token,omitempty"` // Names the track at /sync/media/<token><ext>
+	Title    string  `json:"title,omitempty"`
+	Ext      string  `json:"ext,omitempty"` // The track's file extension, such as ".flac"
+	Playing  bool    `json:"playing"`
+	Paused   bool    `json:"paused"`
+	Position float64 `json:"position"` // Seconds, at Sent
+	Sent     int64   `json:"sent"`     // Leader clock, Unix nanoseconds
+	Ping     int64   `json:"ping,omitempty"`
+}
+
+// fileToken names a file in URLs without revealing its path.
+func fileToken(path string) string {
+	sum := sha1.Sum([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// validFileToken reports whether token looks like one from fileToken. A
+// token from the leader names a folder of the cache, so nothing else,
+// such as "../..", may pass.
+func validFileToken(token string) bool {
+	if len(token) != 16 || strings.ToLower(token) != token {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// handleSync streams the leader's playback state to a follower and
+// answers its pings.
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	slog.Info("follower joined", "remote", r.RemoteAddr)
+	defer slog.Info("follower left", "remote", r.RemoteAddr)
+
+	pongs := make(chan syncMessage, 4)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var msg syncMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Type == "ping" {
+				select {
+				case pongs <- syncMessage{Type: "pong", Ping: msg.Ping, Sent: time.Now().UnixNano()}:
+				default:
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(syncStateInterval)
+	defer ticker.Stop()
+	for msg := s.syncState(); ; {
+		conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+		select {
+		case msg = <-pongs:
+		case <-ticker.C:
+			msg = s.syncState()
+		case <-closed:
+			return
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// syncState returns the player's state for followers and makes the
+// current track available to them.
+func (s *Server) syncState() syncMessage {
+	state := s.player.GetState()
+	msg := syncMessage{
+		Type:     "state",
+		Playing:  state.IsPlaying,
+		Paused:   state.IsPaused,
+		Position: state.Position.Seconds(),
+		Sent:     time.Now().UnixNano(),
+	}
+	if state.IsPlaying {
+		msg.Token = fileToken(state.CurrentFile)
+		msg.Title = musicFileFromPath(state.CurrentFile).Name
+		msg.Ext = strings.ToLower(filepath.Ext(state.CurrentFile))
+
+		s.mu.Lock()
+		s.syncFiles[msg.Token] = state.CurrentFile
+		s.mu.Unlock()
+	}
+	return msg
+}
+
+// handleSyncMedia serves a track the leader has played to followers.
+func (s *Server) handleSyncMedia(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+	s.mu.Lock()
+	path, ok := s.syncFiles[name[:len(name)-len(filepath.Ext(name))]]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// syncStateMsg delivers the leader's state to the follower's TUI.
+type syncStateMsg struct {
+	state  syncMessage
+	offset time.Duration // Leader clock minus local clock
+}
+
+// syncFetchedMsg is sent when a follower has fetched the leader's track.
+type syncFetchedMsg struct {
+	token string
+	file  MusicFile
+	err   error
+}
+
+// FollowLeader connects to a leader and forwards its state to the program
+// until ctx ends, reconnecting after failures.
+func FollowLeader(ctx context.Context, addr string, program *tea.Program) {
+	for {
+		err := followOnce(ctx, addr, program)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("lost connection to leader", "addr", addr, "err", err)
+		program.Send(errorMsg(Tf("Lost connection to %s, retrying: %v", addr, err)))
+		select {
+		case <-time.After(syncRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// followOnce follows the leader over one connection.
+func followOnce(ctx context.Context, addr string, program *tea.Program) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws://"+addr+"/sync", nil)
+	if err != nil {
+		return fmt.Errorf("failed to join %s: %w", addr, err)
+	}
+	defer conn.Close()
+	slog.Info("following leader", "addr", addr)
+	program.Send(statusMsg(Tf("Following %s", addr)))
+	context.AfterFunc(ctx, func() { conn.Close() })
+
+	// Ping in the background; only this goroutine writes
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(syncPingInterval)
+		defer ticker.Stop()
+		for {
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if conn.WriteJSON(syncMessage{Type: "ping", Ping: time.Now().UnixNano()}) != nil {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var clock syncClock
+	for {
+		var msg syncMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		switch msg.Type {
+		case "pong":
+			clock.add(msg.Ping, msg.Sent, time.Now().UnixNano())
+		case "state":
+			program.Send(syncStateMsg{state: msg, offset: clock.offset()})
+		}
+	}
+}
+
+// syncClock estimates the offset between the leader's clock and ours.
+type syncClock struct {
+	samples []clockSample
+}
+
+// clockSample is the result of one ping round trip.
+type clockSample struct {
+	rtt    time.Duration
+	offset time.Duration
+}
+
+// add records a round trip: the ping was sent at local time sent, stamped
+// by the leader at leader time and answered at local time received.
+func (c *syncClock) add(sent, leader, received int64) {
+	rtt := time.Duration(received - sent)
+	offset := time.Duration(leader - (sent+received)/2)
+	c.samples = append(c.samples, clockSample{rtt: rtt, offset: offset})
+	if len(c.samples) > syncPingSamples {
+		c.samples = c.samples[1:]
+	}
+}
+
+// offset returns the estimate from the fastest recent round trip, which
+// was least delayed in either direction.
+func (c *syncClock) offset() time.Duration {
+	if len(c.samples) == 0 {
+		return 0
+	}
+	best := c.samples[0]
+	for _, s := range c.samples[1:] {
+		if s.rtt < best.rtt {
+			best = s
+		}
+	}
+	return best.offset
+}
+
+// followSync brings the local player in step with the leader's state.
+func (m Model) followSync(msg syncStateMsg) (tea.Model, tea.Cmd) {
+	m.syncState, m.syncOffset = msg.state, msg.offset
+	return m.applySync()
+}
+
+// applySync plays the leader's track at the leader's position, fetching
+// the track first if needed.
+func (m Model) applySync() (tea.Model, tea.Cmd) {
+	st := m.syncState
+	local := m.player.GetState()
+
+	if !st.Playing {
+		if local.IsPlaying {
+			m.player.Stop()
+		}
+		m.syncToken = ""
+		return m, nil
+	}
+
+	// Start over if the local track ended first or was stopped
+	if !local.IsPlaying {
+		m.syncToken = ""
+	}
+	if st.Token != m.syncToken {
+		if st.Token == m.syncFetching {
+			return m, nil // Still fetching
+		}
+		m.syncFetching = st.Token
+		return m, fetchSyncTrack(m.ctx, m.syncLeader, st)
+	}
+
+	// Where the leader is now, on the leader's clock
+	target := time.Duration(st.Position * float64(time.Second))
+	if !st.Paused {
+		target += time.Duration(time.Now().UnixNano() + int64(m.syncOffset) - st.Sent)
+	}
+
+	if local.IsPaused != st.Paused {
+		m.player.TogglePause()
+	}
+	if drift := local.Position - target; drift > syncTolerance || drift < -syncTolerance {
+		if err := m.player.SeekTo(target); err != nil {
+			slog.Debug("sync seek failed", "err", err)
+		}
+	}
+	return m, nil
+}
+
+// syncFetched plays a track fetched from the leader.
+func (m Model) syncFetched(msg syncFetchedMsg) (tea.Model, tea.Cmd) {
+	if m.syncFetching == msg.token {
+		m.syncFetching = ""
+	}
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	if msg.token != m.syncState.Token {
+		return m, nil // The leader has moved on
+	}
+	if err := m.player.PlayTrack(msg.file); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	m.syncToken = msg.token
+	return m.applySync()
+}
+
+// fetchSyncTrack downloads the leader's track into the cache.
+func fetchSyncTrack(ctx context.Context, leader string, st syncMessage) tea.Cmd {
+	return func() tea.Msg {
+		file, err := downloadSyncTrack(ctx, leader, st)
+		return syncFetchedMsg{token: st.Token, file: file, err: err}
+	}
+}
+
+// syncTrackExt returns the extension a leader's track is fetched and cached
+// under, so it is decoded as what it is. Leaders that send none play MP3.
+func syncTrackExt(ext string) (string, error) {
+	if ext == "" {
+		return ".mp3", nil
+	}
+	ext = strings.ToLower(ext)
+	if _, ok := audioFormats[ext]; !ok {
+		return "", fmt.Errorf("the leader plays an unsupported format (%s)", ext)
+	}
+	return ext, nil
+}
+
+// downloadSyncTrack downloads the track of a leader's state unless cached.
+// Only the tracks of the current session are kept.
+func downloadSyncTrack(ctx context.Context, leader string, st syncMessage) (MusicFile, error) {
+	token, title := st.Token, st.Title
+	if !validFileToken(token) {
+		return MusicFile{}, fmt.Errorf("the leader sent an invalid track token %q", token)
+	}
+	ext, err := syncTrackExt(st.Ext)
+	if err != nil {
+		return MusicFile{}, err
+	}
+	name := sanitizeFilename(title)
+	if name == "" {
+		name = token
+	}
+	dir := filepath.Join(SyncCacheDir, token)
+	path := filepath.Join(dir, name+ext)
+	if _, err := os.Stat(path); err == nil {
+		return musicFileFromPath(path), nil
+	}
+
+	u := url.URL{Scheme: "http", Host: leader, Path: "/sync/media/" + token + ext}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return MusicFile{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := httpDo("sync", req)
+	if err != nil {
+		return MusicFile{}, fmt.Errorf("failed to fetch %s from the leader: %w", title, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return MusicFile{}, fmt.Errorf("failed to fetch %s from the leader: %s", title, resp.Status)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return MusicFile{}, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".fetch-*")
+	if err != nil {
+		return MusicFile{}, fmt.Errorf("failed to create cache file: %w", err)
+	}
+	_, err = io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return MusicFile{}, fmt.Errorf("failed to fetch %s from the leader: %w", title, err)
+	}
+	return musicFileFromPath(path), nil
+}
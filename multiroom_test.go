@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveSyncMedia starts a leader that serves data for every media request
+// and records the paths asked for.
+func serveSyncMedia(t *testing.T, data []byte) (leader string, requested *[]string) {
+	t.Helper()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	SyncCacheDir = t.TempDir()
+	return strings.TrimPrefix(srv.URL, "http://"), &paths
+}
+
+func TestDownloadSyncTrackKeepsExtension(t *testing.T) {
+	data := []byte("fLaC not really")
+	leader, requested := serveSyncMedia(t, data)
+	token := fileToken("/music/Song.flac")
+
+	file, err := downloadSyncTrack(context.Background(), leader, syncMessage{Token: token, Title: "Song", Ext: ".flac"})
+	if err != nil {
+		t.Fatalf("downloadSyncTrack: %v", err)
+	}
+	if want := "/sync/media/" + token + ".flac"; len(*requested) != 1 || (*requested)[0] != want {
+		t.Errorf("requested %q, want [%q]", *requested, want)
+	}
+	if want := filepath.Join(SyncCacheDir, token, "Song.flac"); file.Path != want {
+		t.Errorf("cached as %q, want %q", file.Path, want)
+	}
+	if got, err := os.ReadFile(file.Path); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("cached %q, %v; want %q", got, err, data)
+	}
+}
+
+func TestDownloadSyncTrackWithoutExtension(t *testing.T) {
+	leader, requested := serveSyncMedia(t, []byte("ID3"))
+	token := fileToken("/music/Song.mp3")
+
+	file, err := downloadSyncTrack(context.Background(), leader, syncMessage{Token: token, Title: "Song"})
+	if err != nil {
+		t.Fatalf("downloadSyncTrack: %v", err)
+	}
+	if want := "/sync/media/" + token + ".mp3"; len(*requested) != 1 || (*requested)[0] != want {
+		t.Errorf("requested %q, want [%q]", *requested, want)
+	}
+	if filepath.Ext(file.Path) != ".mp3" {
+		t.Errorf("cached as %q, want an .mp3 file", file.Path)
+	}
+}
+
+func TestDownloadSyncTrackUnsupportedExtension(t *testing.T) {
+	leader, requested := serveSyncMedia(t, nil)
+
+	_, err := downloadSyncTrack(context.Background(), leader, syncMessage{Token: fileToken("/music/a.ogg"), Title: "a", Ext: ".ogg"})
+	if err == nil {
+		t.Fatal("downloadSyncTrack accepted an .ogg track")
+	}
+	if len(*requested) != 0 {
+		t.Errorf("requested %q for an unsupported format", *requested)
+	}
+}
+
+func TestDownloadSyncTrackEveryFormat(t *testing.T) {
+	for ext := range audioFormats {
+		t.Run(ext, func(t *testing.T) {
+			leader, requested := serveSyncMedia(t, []byte("audio"))
+			token := fileToken("/music/Song" + ext)
+
+			// The extension is matched whatever its case
+			file, err := downloadSyncTrack(context.Background(), leader, syncMessage{Token: token, Title: "Song", Ext: strings.ToUpper(ext)})
+			if err != nil {
+				t.Fatalf("downloadSyncTrack: %v", err)
+			}
+			if want := "/sync/media/" + token + ext; len(*requested) != 1 || (*requested)[0] != want {
+				t.Errorf("requested %q, want [%q]", *requested, want)
+			}
+			if filepath.Ext(file.Path) != ext {
+				t.Errorf("cached as %q, want a %s file", file.Path, ext)
+			}
+			if !isAudioFile(file.Path) {
+				t.Errorf("cached as %q, which the player can't open", file.Path)
+			}
+		})
+	}
+}
+
+func TestDownloadSyncTrackRejectsBadToken(t *testing.T) {
+	for _, token := range []string{"", "../..", "../../../etc/x", "0123456789abcdef/..", "0123456789ABCDEF", "0123456789abcdeg", "0123456789abcdef0"} {
+		t.Run(token, func(t *testing.T) {
+			leader, requested := serveSyncMedia(t, []byte("audio"))
+
+			_, err := downloadSyncTrack(context.Background(), leader, syncMessage{Token: token, Title: "Song", Ext: ".mp3"})
+			if err == nil {
+				t.Fatalf("downloadSyncTrack accepted token %q", token)
+			}
+			if len(*requested) != 0 {
+				t.Errorf("requested %q for token %q", *requested, token)
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(SyncCacheDir)); len(entries) != 1 {
+				t.Errorf("wrote next to the sync cache: %v", entries)
+			}
+		})
+	}
+}
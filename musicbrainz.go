@@ -0,0 +1,228 @@
+// Package main provides the MusicBrainz client for Personal Musician.
+// Given a rough title and artist, such as those in a YouTube video's file
+// name, it searches MusicBrainz for the recording and returns its canonical
+// title, artist, album, release year and track number; recordings
+// identified by their sound (see acoustid.go) are fetched by ID. Requests
+// go through the shared HTTP client at MusicBrainz's limit of one per
+// second, and answers are cached for a day.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// musicBrainzAPI is the base URL of the MusicBrainz web service.
+const musicBrainzAPI = "https://musicbrainz.org/ws/2/"
+
+// musicBrainzResults is how many recordings a search asks for.
+const musicBrainzResults = 5
+
+// MusicBrainzMatch is a recording found for a track.
+type MusicBrainzMatch struct {
+	RecordingID string
+	Score       int  // How well it matches the search, 0-100
+	Tags        Tags // Title, artist, album, year and track number
+}
+
+// mbRecordingSearch is the part of a recording search response that is
+// used.
+type mbRecordingSearch struct {
+	Recordings []mbRecording `json:"recordings"`
+}
+
+// mbRecording is a recording as returned by searches and lookups.
+type mbRecording struct {
+	ID           string `json:"id"`
+	Score        int    `json:"score"` // Only set in search results
+	Title        string `json:"title"`
+	FirstRelease string `json:"first-release-date"`
+	ArtistCredit []struct {
+		Name       string `json:"name"`
+		JoinPhrase string `json:"joinphrase"`
+	} `json:"artist-credit"`
+	Releases []mbRelease `json:"releases"`
+}
+
+// mbRelease is a release a recording appears on.
+type mbRelease struct {
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	Date         string `json:"date"`
+	ReleaseGroup struct {
+		PrimaryType    string   `json:"primary-type"`
+		SecondaryTypes []string `json:"secondary-types"`
+	} `json:"release-group"`
+	Media []struct {
+		Track []struct {
+			Number string `json:"number"`
+		} `json:"track"`
+	} `json:"media"`
+}
+
+// SearchMusicBrainz looks up a recording by title and, if known, artist.
+// Matches come best first.
+func SearchMusicBrainz(ctx context.Context, title, artist string) ([]MusicBrainzMatch, error) {
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("nothing to look up: the track has no title")
+	}
+	query := "recording:(" + luceneEscape(title) + ")"
+	if strings.TrimSpace(artist) != "" {
+		query += " AND artist:(" + luceneEscape(artist) + ")"
+	}
+	params := url.Values{"query": {query}, "fmt": {"json"}, "limit": {fmt.Sprint(musicBrainzResults)}}
+
+	var resp mbRecordingSearch
+	if err := musicBrainzGet(ctx, "recording?"+params.Encode(), &resp); err != nil {
+		return nil, fmt.Errorf("failed to search MusicBrainz: %w", err)
+	}
+	matches := make([]MusicBrainzMatch, len(resp.Recordings))
+	for i, rec := range resp.Recordings {
+		matches[i] = rec.match()
+	}
+	return matches, nil
+}
+
+// LookupMusicBrainzRecording fetches a recording by its MusicBrainz ID. The
+// match has no score, as nothing was searched for.
+func LookupMusicBrainzRecording(ctx context.Context, id string) (MusicBrainzMatch, error) {
+	params := url.Values{"inc": {"artist-credits releases release-groups media"}, "fmt": {"json"}}
+	var rec mbRecording
+	if err := musicBrainzGet(ctx, "recording/"+url.PathEscape(id)+"?"+params.Encode(), &rec); err != nil {
+		return MusicBrainzMatch{}, fmt.Errorf("failed to look up recording %s: %w", id, err)
+	}
+	return rec.match(), nil
+}
+
+// musicBrainzGet requests a path of the web service and decodes the JSON
+// answer into v.
+func musicBrainzGet(ctx context.Context, path string, v any) error {
+	header := http.Header{}
+	header.Set("User-Agent", "PersonalMusician/"+version+" ( https://github.com/adi-253/Personal_Musician )")
+	header.Set("Accept", "application/json")
+	body, err := httpGet(ctx, "musicbrainz", musicBrainzAPI+path, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse MusicBrainz response: %w", err)
+	}
+	return nil
+}
+
+// match converts a recording to tags.
+func (rec mbRecording) match() MusicBrainzMatch {
+	var artists strings.Builder
+	for _, credit := range rec.ArtistCredit {
+		artists.WriteString(credit.Name + credit.JoinPhrase)
+	}
+	tags := Tags{Title: rec.Title, Artist: artists.String()}
+	if release, ok := bestRelease(rec.Releases); ok {
+		tags.Album = release.Title
+		tags.Year = releaseYear(release.Date)
+		if len(release.Media) > 0 && len(release.Media[0].Track) > 0 {
+			tags.Track = release.Media[0].Track[0].Number
+		}
+	}
+	if year := releaseYear(rec.FirstRelease); year != "" {
+		tags.Year = year // The original release, not a reissue
+	}
+	return MusicBrainzMatch{RecordingID: rec.ID, Score: rec.Score, Tags: tags}
+}
+
+// bestRelease picks the release to take the album from: an official
+// studio album if there is one, otherwise the earliest release.
+func bestRelease(releases []mbRelease) (mbRelease, bool) {
+	rank := func(r mbRelease) int {
+		n := 0
+		if r.Status == "Official" {
+			n += 2
+		}
+		if r.ReleaseGroup.PrimaryType == "Album" {
+			n += 2
+		}
+		if len(r.ReleaseGroup.SecondaryTypes) == 0 { // Not a compilation, live album or soundtrack
+			n++
+		}
+		return n
+	}
+
+	best, found := mbRelease{}, false
+	for _, r := range releases {
+		switch {
+		case !found, rank(r) > rank(best):
+			best, found = r, true
+		case rank(r) == rank(best) && r.Date != "" && (best.Date == "" || r.Date < best.Date):
+			best = r
+		}
+	}
+	return best, found
+}
+
+// releaseYear returns the year of a MusicBrainz date such as "1997-05-21".
+func releaseYear(date string) string {
+	if len(date) < 4 {
+		return ""
+	}
+	return date[:4]
+}
+
+// luceneEscape escapes the characters that have a meaning in the search
+// query syntax.
+func luceneEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`+-&|!(){}[]^"~*?:\/`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// titleNoise matches the parts of video titles that aren't the song's, such
+// as "(Official Video)" or "[Lyrics]".
+var titleNoise = regexp.MustCompile(`(?i)\s*[(\[][^)\]]*\b(official|video|audio|lyrics?|visuali[sz]er|hd|hq|4k|remaster(ed)?|mv)\b[^)\]]*[)\]]`)
+
+// guessTitleArtist returns the title and artist to look a track up by: its
+// tags when it has a title, otherwise its file name read as "Artist - Title"
+// without the usual video title decorations.
+func guessTitleArtist(file MusicFile) (title, artist string) {
+	if file.Tags.Title != "" {
+		return file.Tags.Title, file.Tags.Artist
+	}
+	name := strings.TrimSpace(titleNoise.ReplaceAllString(file.Name, ""))
+	for _, sep := range []string{" - ", " – ", " — ", " | "} {
+		if before, after, ok := strings.Cut(name, sep); ok {
+			return strings.TrimSpace(after), strings.TrimSpace(before)
+		}
+	}
+	return name, ""
+}
+
+// mergeTags returns old with the fields set in found replacing its own, so
+// fields a lookup can't know, such as the genre, are kept.
+func mergeTags(old, found Tags) Tags {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&old.Title, found.Title},
+		{&old.Artist, found.Artist},
+		{&old.Album, found.Album},
+		{&old.Track, found.Track},
+		{&old.Genre, found.Genre},
+		{&old.Year, found.Year},
+		{&old.Comment, found.Comment},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	return old
+}
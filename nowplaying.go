@@ -0,0 +1,87 @@
+// Package main provides the system media controls of Personal Musician.
+// On macOS (nowplaying_darwin.go) and Windows (nowplaying_windows.go) the
+// current track is shown in the system's media controls and the keyboard's
+// media keys play, pause and skip. Media key presses reach the TUI the same
+// way as ctl requests, so they behave exactly like "personal-musician ctl".
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// nowPlayingInterval is how often the player is checked for changes to show.
+const nowPlayingInterval = time.Second
+
+// nowPlayingInfo is what the system media controls show.
+type nowPlayingInfo struct {
+	Path     string // Empty when stopped
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+	Position time.Duration
+	Paused   bool
+}
+
+// watchNowPlaying calls update whenever the track, the pause state or the
+// position (by seeking) changes, until stop is closed. The system moves the
+// position along on its own between updates.
+func watchNowPlaying(player *Player, stop <-chan struct{}, update func(nowPlayingInfo)) {
+	ticker := time.NewTicker(nowPlayingInterval)
+	defer ticker.Stop()
+
+	var last nowPlayingInfo
+	var lastTime time.Time
+	first := true
+	for {
+		state := player.GetState()
+		info := nowPlayingInfo{}
+		if state.IsPlaying {
+			info = last
+			if state.CurrentFile != last.Path {
+				info = newNowPlayingInfo(state.CurrentFile)
+			}
+			info.Duration = state.Duration
+			info.Position = state.Position
+			info.Paused = state.IsPaused
+		}
+
+		// Where the system thinks playback is by now
+		expected := last.Position
+		if !last.Paused {
+			expected += time.Since(lastTime)
+		}
+		drift := info.Position - expected
+		if first || info.Path != last.Path || info.Paused != last.Paused || drift > 2*time.Second || drift < -2*time.Second {
+			update(info)
+			last, lastTime, first = info, time.Now(), false
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// newNowPlayingInfo describes a track from its tags, falling back to the
+// file name.
+func newNowPlayingInfo(path string) nowPlayingInfo {
+	info := nowPlayingInfo{Path: path, Title: musicFileFromPath(path).Name}
+	if tags, err := ReadTags(path); err == nil && tags.Title != "" {
+		info.Title, info.Artist, info.Album = tags.Title, tags.Artist, tags.Album
+	}
+	return info
+}
+
+// sendMediaCommand passes a media key press to the TUI as a ctl request
+// ("play-pause", "next" or "prev") without waiting for it.
+func sendMediaCommand(program *tea.Program, command string) {
+	go program.Send(remoteMsg{
+		req:   instanceRequest{Command: command},
+		reply: make(chan instanceResponse, 1),
+	})
+}
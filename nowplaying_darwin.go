@@ -0,0 +1,226 @@
+//go:build darwin
+
+// Package main provides the macOS media controls of Personal Musician,
+// using MPNowPlayingInfoCenter for the Now Playing widget in Control Center
+// and MPRemoteCommandCenter for the media keys. The frameworks are called
+// through the Objective-C runtime, so no cgo is needed.
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/objc"
+)
+
+// System frameworks.
+const (
+	coreFoundationPath = "/System/Library/Frameworks/CoreFoundation.framework/CoreFoundation"
+	foundationPath     = "/System/Library/Frameworks/Foundation.framework/Foundation"
+	mediaPlayerPath    = "/System/Library/Frameworks/MediaPlayer.framework/MediaPlayer"
+)
+
+// MPNowPlayingPlaybackState values.
+const (
+	mpPlaybackPlaying = 1
+	mpPlaybackPaused  = 2
+	mpPlaybackStopped = 3
+)
+
+// Media commands are delivered on the main thread, which must run the main
+// run loop for them to arrive. Keeping the main goroutine on the main thread
+// lets runMainLoop do that.
+func init() {
+	runtime.LockOSThread()
+}
+
+// NowPlaying connects the player to the system media controls.
+type NowPlaying struct {
+	center   objc.ID // MPNowPlayingInfoCenter
+	commands objc.ID // MPRemoteCommandCenter
+	targets  []nowPlayingTarget
+	keys     map[string]objc.ID // Now Playing info keys by name
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// nowPlayingTarget is a registered media command handler.
+type nowPlayingTarget struct {
+	command objc.ID
+	target  objc.ID
+	block   objc.Block
+}
+
+// StartNowPlaying shows the player's track in Control Center and passes
+// media key presses to the program.
+func StartNowPlaying(player *Player, program *tea.Program) (*NowPlaying, error) {
+	if _, err := purego.Dlopen(foundationPath, purego.RTLD_NOW|purego.RTLD_GLOBAL); err != nil {
+		return nil, fmt.Errorf("failed to load Foundation: %w", err)
+	}
+	mp, err := purego.Dlopen(mediaPlayerPath, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MediaPlayer: %w", err)
+	}
+
+	n := &NowPlaying{
+		center:   objc.ID(objc.GetClass("MPNowPlayingInfoCenter")).Send(objc.RegisterName("defaultCenter")),
+		commands: objc.ID(objc.GetClass("MPRemoteCommandCenter")).Send(objc.RegisterName("sharedCommandCenter")),
+		keys:     make(map[string]objc.ID),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if n.center == 0 || n.commands == 0 {
+		return nil, fmt.Errorf("failed to reach the Now Playing service")
+	}
+	for _, name := range []string{
+		"MPMediaItemPropertyTitle",
+		"MPMediaItemPropertyArtist",
+		"MPMediaItemPropertyAlbumTitle",
+		"MPMediaItemPropertyPlaybackDuration",
+		"MPNowPlayingInfoPropertyElapsedPlaybackTime",
+		"MPNowPlayingInfoPropertyPlaybackRate",
+	} {
+		key, err := cGlobal(mp, name)
+		if err != nil {
+			return nil, err
+		}
+		n.keys[name] = objc.ID(key)
+	}
+
+	// Media keys and the Control Center buttons
+	n.handle("togglePlayPauseCommand", func(objc.ID) { sendMediaCommand(program, "play-pause") })
+	n.handle("playCommand", func(objc.ID) {
+		if state := player.GetState(); !state.IsPlaying || state.IsPaused {
+			sendMediaCommand(program, "play-pause")
+		}
+	})
+	n.handle("pauseCommand", func(objc.ID) {
+		if state := player.GetState(); state.IsPlaying && !state.IsPaused {
+			sendMediaCommand(program, "play-pause")
+		}
+	})
+	n.handle("nextTrackCommand", func(objc.ID) { sendMediaCommand(program, "next") })
+	n.handle("previousTrackCommand", func(objc.ID) { sendMediaCommand(program, "prev") })
+	n.handle("changePlaybackPositionCommand", func(event objc.ID) {
+		seconds := objc.Send[float64](event, objc.RegisterName("positionTime"))
+		if err := player.SeekTo(time.Duration(seconds * float64(time.Second))); err != nil {
+			slog.Debug("media seek failed", "err", err)
+		}
+	})
+
+	go func() {
+		defer close(n.done)
+		runtime.LockOSThread() // Autorelease pools belong to a thread
+		watchNowPlaying(player, n.stop, n.update)
+	}()
+	return n, nil
+}
+
+// handle registers a handler for a media command such as "playCommand".
+func (n *NowPlaying) handle(name string, fn func(event objc.ID)) {
+	command := n.commands.Send(objc.RegisterName(name))
+	block := objc.NewBlock(func(_ objc.Block, event objc.ID) int {
+		fn(event)
+		return 0 // MPRemoteCommandHandlerStatusSuccess
+	})
+	command.Send(objc.RegisterName("setEnabled:"), true)
+	target := command.Send(objc.RegisterName("addTargetWithHandler:"), block)
+	n.targets = append(n.targets, nowPlayingTarget{command: command, target: target, block: block})
+}
+
+// update shows a track, or clears the widget when stopped.
+func (n *NowPlaying) update(info nowPlayingInfo) {
+	pool := objc.ID(objc.GetClass("NSAutoreleasePool")).Send(objc.RegisterName("new"))
+	defer pool.Send(objc.RegisterName("drain"))
+
+	if info.Path == "" {
+		n.center.Send(objc.RegisterName("setNowPlayingInfo:"), objc.ID(0))
+		n.center.Send(objc.RegisterName("setPlaybackState:"), uint(mpPlaybackStopped))
+		return
+	}
+
+	dict := objc.ID(objc.GetClass("NSMutableDictionary")).Send(objc.RegisterName("dictionary"))
+	set := func(key string, value objc.ID) {
+		dict.Send(objc.RegisterName("setObject:forKey:"), value, n.keys[key])
+	}
+	text := func(s string) objc.ID {
+		return objc.ID(objc.GetClass("NSString")).Send(objc.RegisterName("stringWithUTF8String:"), s)
+	}
+	number := func(f float64) objc.ID {
+		return objc.ID(objc.GetClass("NSNumber")).Send(objc.RegisterName("numberWithDouble:"), f)
+	}
+
+	set("MPMediaItemPropertyTitle", text(info.Title))
+	if info.Artist != "" {
+		set("MPMediaItemPropertyArtist", text(info.Artist))
+	}
+	if info.Album != "" {
+		set("MPMediaItemPropertyAlbumTitle", text(info.Album))
+	}
+	set("MPMediaItemPropertyPlaybackDuration", number(info.Duration.Seconds()))
+	set("MPNowPlayingInfoPropertyElapsedPlaybackTime", number(info.Position.Seconds()))
+	rate, state := 1.0, mpPlaybackPlaying
+	if info.Paused {
+		rate, state = 0, mpPlaybackPaused
+	}
+	set("MPNowPlayingInfoPropertyPlaybackRate", number(rate))
+
+	n.center.Send(objc.RegisterName("setNowPlayingInfo:"), dict)
+	n.center.Send(objc.RegisterName("setPlaybackState:"), uint(state))
+}
+
+// Close removes the track from Control Center and stops handling media keys.
+func (n *NowPlaying) Close() {
+	if n == nil {
+		return
+	}
+	close(n.stop)
+	<-n.done
+	n.update(nowPlayingInfo{})
+	for _, t := range n.targets {
+		t.command.Send(objc.RegisterName("removeTarget:"), t.target)
+		t.block.Release()
+	}
+}
+
+// runMainLoop calls run while the main thread runs the main run loop, so
+// media commands are delivered.
+func runMainLoop(run func()) {
+	cf, err := purego.Dlopen(coreFoundationPath, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		run()
+		return
+	}
+	mode, err := cGlobal(cf, "kCFRunLoopDefaultMode")
+	if err != nil {
+		run()
+		return
+	}
+	var runInMode func(mode uintptr, seconds float64, returnAfterSourceHandled bool) int32
+	purego.RegisterLibFunc(&runInMode, cf, "CFRunLoopRunInMode")
+
+	var done atomic.Bool
+	go func() {
+		defer done.Store(true)
+		run()
+	}()
+	for !done.Load() {
+		runInMode(mode, 0.1, false)
+	}
+}
+
+// cGlobal returns the value of a pointer-sized global variable in a library,
+// such as an exported NSString constant.
+func cGlobal(lib uintptr, name string) (uintptr, error) {
+	addr, err := purego.Dlsym(lib, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find %s: %w", name, err)
+	}
+	return **(**uintptr)(unsafe.Pointer(&addr)), nil
+}
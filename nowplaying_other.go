@@ -0,0 +1,23 @@
+//go:build !darwin && !windows
+
+// Package main provides the system media controls stub for Personal
+// Musician on platforms without a supported Now Playing service.
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// NowPlaying connects the player to the system media controls.
+type NowPlaying struct{}
+
+// StartNowPlaying does nothing on this platform.
+func StartNowPlaying(player *Player, program *tea.Program) (*NowPlaying, error) {
+	return nil, nil
+}
+
+// Close does nothing on this platform.
+func (n *NowPlaying) Close() {}
+
+// runMainLoop calls run.
+func runMainLoop(run func()) {
+	run()
+}
@@ -0,0 +1,385 @@
+//go:build windows
+
+// Package main provides the Windows media controls of Personal Musician,
+// using the System Media Transport Controls (SMTC): the current track shows
+// in the media panel of the volume flyout, and the media keys and panel
+// buttons control the player. A console program has no window to attach
+// the controls to, so they are taken from a WinRT MediaPlayer whose own
+// command handling is switched off.
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sys/windows"
+)
+
+// WinRT entry points.
+var (
+	combase                 = windows.NewLazySystemDLL("combase.dll")
+	procRoInitialize        = combase.NewProc("RoInitialize")
+	procRoActivateInstance  = combase.NewProc("RoActivateInstance")
+	procWindowsCreateString = combase.NewProc("WindowsCreateString")
+	procWindowsDeleteString = combase.NewProc("WindowsDeleteString")
+)
+
+// Interface IDs.
+var (
+	iidIUnknown          = mustGUID("{00000000-0000-0000-C000-000000000046}")
+	iidIAgileObject      = mustGUID("{94EA2B94-E9CC-49E0-C0FF-EE64CA8F5B90}")
+	iidIMediaPlayer2     = mustGUID("{3C841218-2123-4FC5-9082-2F883F77BDF5}")
+	iidIMediaPlayer3     = mustGUID("{EE0660DA-031B-4FEB-BD9B-92E0A0A8D299}")
+	iidButtonPressedHook = mustGUID("{0557E996-7B23-5BAE-AA81-EA0D671143A4}") // TypedEventHandler<SMTC, ButtonPressedEventArgs>
+)
+
+// Method table slots, counting the three IUnknown and three IInspectable
+// methods that come first.
+const (
+	slotQueryInterface = 0
+	slotRelease        = 2
+
+	// IMediaPlayer2, IMediaPlayer3 and IMediaPlaybackCommandManager
+	slotSystemMediaTransportControls = 6
+	slotCommandManager               = 17
+	slotCommandManagerPutIsEnabled   = 7
+
+	// ISystemMediaTransportControls
+	slotPutPlaybackStatus    = 7
+	slotDisplayUpdater       = 8
+	slotPutIsEnabled         = 11
+	slotPutIsPlayEnabled     = 13
+	slotPutIsPauseEnabled    = 17
+	slotPutIsPreviousEnabled = 25
+	slotPutIsNextEnabled     = 27
+	slotAddButtonPressed     = 32
+	slotRemoveButtonPressed  = 33
+
+	// ISystemMediaTransportControlsDisplayUpdater
+	slotPutType         = 7
+	slotMusicProperties = 12
+	slotClearAll        = 16
+	slotUpdate          = 17
+
+	// IMusicDisplayProperties
+	slotPutTitle  = 7
+	slotPutArtist = 11
+
+	// ISystemMediaTransportControlsButtonPressedEventArgs
+	slotButton = 6
+)
+
+// MediaPlaybackStatus values.
+const (
+	smtcClosed  = 0
+	smtcStopped = 2
+	smtcPlaying = 3
+	smtcPaused  = 4
+)
+
+// SystemMediaTransportControlsButton values.
+const (
+	smtcButtonPlay     = 0
+	smtcButtonPause    = 1
+	smtcButtonNext     = 6
+	smtcButtonPrevious = 7
+)
+
+// mediaPlaybackTypeMusic is MediaPlaybackType.Music.
+const mediaPlaybackTypeMusic = 1
+
+// comObject is a COM interface pointer: the object starts with a pointer to
+// its method table.
+type comObject struct {
+	vtbl *[64]uintptr
+}
+
+// call calls a method by its slot and checks the HRESULT.
+func (o *comObject) call(slot int, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(o.vtbl[slot], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(r) < 0 {
+		return fmt.Errorf("WinRT call failed: HRESULT 0x%08X", uint32(r))
+	}
+	return nil
+}
+
+// get calls a property getter that returns an interface.
+func (o *comObject) get(slot int) (*comObject, error) {
+	out := new(*comObject)
+	if err := o.call(slot, uintptr(unsafe.Pointer(out))); err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+// query returns another interface of the object.
+func (o *comObject) query(iid *windows.GUID) (*comObject, error) {
+	out := new(*comObject)
+	if err := o.call(slotQueryInterface, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(out))); err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+// release drops a reference to the object.
+func (o *comObject) release() {
+	if o != nil {
+		syscall.SyscallN(o.vtbl[slotRelease], uintptr(unsafe.Pointer(o)))
+	}
+}
+
+// putString calls a string property setter.
+func (o *comObject) putString(slot int, s string) error {
+	h, err := newHString(s)
+	if err != nil {
+		return err
+	}
+	defer procWindowsDeleteString.Call(h)
+	return o.call(slot, h)
+}
+
+// newHString creates a WinRT string.
+func newHString(s string) (uintptr, error) {
+	u, err := windows.UTF16FromString(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid string %q: %w", s, err)
+	}
+	var h uintptr
+	if r, _, _ := procWindowsCreateString.Call(uintptr(unsafe.Pointer(&u[0])), uintptr(len(u)-1), uintptr(unsafe.Pointer(&h))); int32(r) < 0 {
+		return 0, fmt.Errorf("failed to create string: HRESULT 0x%08X", uint32(r))
+	}
+	return h, nil
+}
+
+// mustGUID parses a constant GUID.
+func mustGUID(s string) windows.GUID {
+	g, err := windows.GUIDFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// buttonHandler is the COM delegate SMTC calls when a button is pressed.
+// It lives for the whole process, so reference counting is only pretended.
+type buttonHandler struct {
+	vtbl *[4]uintptr
+}
+
+// The one button handler and where its presses go.
+var (
+	buttonHandlerVtbl = [4]uintptr{
+		windows.NewCallback(buttonHandlerQueryInterface),
+		windows.NewCallback(buttonHandlerAddRef),
+		windows.NewCallback(buttonHandlerRelease),
+		windows.NewCallback(buttonHandlerInvoke),
+	}
+	theButtonHandler = &buttonHandler{vtbl: &buttonHandlerVtbl}
+	onButtonPressed  func(button int32)
+)
+
+// buttonHandlerQueryInterface answers for the delegate interface only.
+func buttonHandlerQueryInterface(this *buttonHandler, iid *windows.GUID, out **buttonHandler) uintptr {
+	switch *iid {
+	case iidIUnknown, iidIAgileObject, iidButtonPressedHook:
+		*out = this
+		return 0 // S_OK
+	}
+	*out = nil
+	return 0x80004002 // E_NOINTERFACE
+}
+
+// buttonHandlerAddRef keeps the handler alive.
+func buttonHandlerAddRef(this *buttonHandler) uintptr {
+	return 1
+}
+
+// buttonHandlerRelease keeps the handler alive.
+func buttonHandlerRelease(this *buttonHandler) uintptr {
+	return 1
+}
+
+// buttonHandlerInvoke passes a button press on.
+func buttonHandlerInvoke(this *buttonHandler, sender, args *comObject) uintptr {
+	var button int32
+	if err := args.call(slotButton, uintptr(unsafe.Pointer(&button))); err != nil {
+		return 0
+	}
+	if fn := onButtonPressed; fn != nil {
+		fn(button)
+	}
+	return 0
+}
+
+// NowPlaying connects the player to the system media controls.
+type NowPlaying struct {
+	player  *comObject // MediaPlayer, kept to keep the controls
+	smtc    *comObject // ISystemMediaTransportControls
+	display *comObject // ISystemMediaTransportControlsDisplayUpdater
+	music   *comObject // IMusicDisplayProperties
+	token   int64      // ButtonPressed registration
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// StartNowPlaying shows the player's track in the volume flyout and passes
+// media key presses to the program.
+func StartNowPlaying(player *Player, program *tea.Program) (*NowPlaying, error) {
+	onButtonPressed = func(button int32) {
+		state := player.GetState()
+		switch button {
+		case smtcButtonPlay:
+			if !state.IsPlaying || state.IsPaused {
+				sendMediaCommand(program, "play-pause")
+			}
+		case smtcButtonPause:
+			if state.IsPlaying && !state.IsPaused {
+				sendMediaCommand(program, "play-pause")
+			}
+		case smtcButtonNext:
+			sendMediaCommand(program, "next")
+		case smtcButtonPrevious:
+			sendMediaCommand(program, "prev")
+		}
+	}
+
+	n := &NowPlaying{stop: make(chan struct{}), done: make(chan struct{})}
+	started := make(chan error, 1)
+	go func() {
+		defer close(n.done)
+		// WinRT objects are created and used on one thread
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		err := n.setup()
+		started <- err
+		if err != nil {
+			n.teardown()
+			return
+		}
+		watchNowPlaying(player, n.stop, n.update)
+		n.teardown()
+	}()
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// setup creates the media controls and enables their buttons.
+func (n *NowPlaying) setup() error {
+	if err := procRoInitialize.Find(); err != nil {
+		return fmt.Errorf("WinRT is not available: %w", err)
+	}
+	procRoInitialize.Call(1) // RO_INIT_MULTITHREADED; fails harmlessly if already initialized
+
+	class, err := newHString("Windows.Media.Playback.MediaPlayer")
+	if err != nil {
+		return err
+	}
+	defer procWindowsDeleteString.Call(class)
+	var player *comObject
+	if r, _, _ := procRoActivateInstance.Call(class, uintptr(unsafe.Pointer(&player))); int32(r) < 0 {
+		return fmt.Errorf("failed to create a media player: HRESULT 0x%08X", uint32(r))
+	}
+	n.player = player
+
+	// Stop the media player from answering the buttons itself
+	player3, err := player.query(&iidIMediaPlayer3)
+	if err != nil {
+		return fmt.Errorf("failed to reach the media player: %w", err)
+	}
+	defer player3.release()
+	commands, err := player3.get(slotCommandManager)
+	if err != nil {
+		return fmt.Errorf("failed to reach the media commands: %w", err)
+	}
+	defer commands.release()
+	if err := commands.call(slotCommandManagerPutIsEnabled, 0); err != nil {
+		return fmt.Errorf("failed to take over the media commands: %w", err)
+	}
+
+	player2, err := player.query(&iidIMediaPlayer2)
+	if err != nil {
+		return fmt.Errorf("failed to reach the media player: %w", err)
+	}
+	defer player2.release()
+	if n.smtc, err = player2.get(slotSystemMediaTransportControls); err != nil {
+		return fmt.Errorf("failed to reach the media controls: %w", err)
+	}
+	if n.display, err = n.smtc.get(slotDisplayUpdater); err != nil {
+		return fmt.Errorf("failed to reach the media controls: %w", err)
+	}
+	if err := n.display.call(slotPutType, mediaPlaybackTypeMusic); err != nil {
+		return fmt.Errorf("failed to set up the media controls: %w", err)
+	}
+	if n.music, err = n.display.get(slotMusicProperties); err != nil {
+		return fmt.Errorf("failed to set up the media controls: %w", err)
+	}
+
+	for _, slot := range []int{slotPutIsEnabled, slotPutIsPlayEnabled, slotPutIsPauseEnabled, slotPutIsPreviousEnabled, slotPutIsNextEnabled} {
+		if err := n.smtc.call(slot, 1); err != nil {
+			return fmt.Errorf("failed to enable the media controls: %w", err)
+		}
+	}
+	if err := n.smtc.call(slotAddButtonPressed, uintptr(unsafe.Pointer(theButtonHandler)), uintptr(unsafe.Pointer(&n.token))); err != nil {
+		return fmt.Errorf("failed to listen to the media keys: %w", err)
+	}
+	return nil
+}
+
+// update shows a track, or stopped playback.
+func (n *NowPlaying) update(info nowPlayingInfo) {
+	if info.Path == "" {
+		n.display.call(slotClearAll)
+		n.display.call(slotPutType, mediaPlaybackTypeMusic)
+		n.display.call(slotUpdate)
+		n.smtc.call(slotPutPlaybackStatus, smtcStopped)
+		return
+	}
+
+	if err := n.music.putString(slotPutTitle, info.Title); err != nil {
+		slog.Debug("failed to update media controls", "err", err)
+	}
+	n.music.putString(slotPutArtist, info.Artist)
+	n.display.call(slotUpdate)
+
+	status := uintptr(smtcPlaying)
+	if info.Paused {
+		status = smtcPaused
+	}
+	n.smtc.call(slotPutPlaybackStatus, status)
+}
+
+// teardown removes the player from the media controls.
+func (n *NowPlaying) teardown() {
+	if n.smtc != nil {
+		if n.token != 0 {
+			n.smtc.call(slotRemoveButtonPressed, uintptr(n.token))
+		}
+		n.smtc.call(slotPutPlaybackStatus, smtcClosed)
+	}
+	n.music.release()
+	n.display.release()
+	n.smtc.release()
+	n.player.release()
+}
+
+// Close removes the track from the media controls and stops handling
+// media keys.
+func (n *NowPlaying) Close() {
+	if n == nil {
+		return
+	}
+	close(n.stop)
+	<-n.done
+}
+
+// runMainLoop calls run; media key presses arrive on their own threads.
+func runMainLoop(run func()) {
+	run()
+}
@@ -0,0 +1,185 @@
+// Package main provides the now-playing file of Personal Musician.
+// With a file set under [now_playing], the current track is written to it
+// every second, so OBS text sources, browser overlays and tmux status lines
+// can show what is playing. A file ending in .json gets the full state as
+// JSON; any other file gets one line of text from a template. The cover can
+// be saved next to it as well.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nowPlayingFileInterval is how often the now-playing file is refreshed.
+const nowPlayingFileInterval = time.Second
+
+// defaultNowPlayingFormat is the text template used when none is set.
+const defaultNowPlayingFormat = "{artist} - {title}"
+
+// nowPlayingFileStatus is the content of a JSON now-playing file.
+type nowPlayingFileStatus struct {
+	playerStatus
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Art    string `json:"art,omitempty"` // Path of the saved cover
+}
+
+// nowPlayingFile keeps the now-playing file up to date.
+type nowPlayingFile struct {
+	cfg     NowPlayingConfig
+	track   nowPlayingInfo // Tags of the current track
+	art     string         // Path of the current track's saved cover
+	written []byte         // Content last written
+}
+
+// StartNowPlayingFile writes the player's state to the configured file
+// until the returned function is called, which leaves the file showing
+// that nothing is playing.
+func StartNowPlayingFile(cfg NowPlayingConfig, player *Player) (stop func()) {
+	f := &nowPlayingFile{cfg: cfg}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(nowPlayingFileInterval)
+		defer ticker.Stop()
+		for {
+			f.refresh(player.GetState())
+			select {
+			case <-ticker.C:
+			case <-done:
+				f.refresh(PlaybackState{})
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// refresh rewrites the file if its content has changed.
+func (f *nowPlayingFile) refresh(state PlaybackState) {
+	current := ""
+	if state.IsPlaying {
+		current = state.CurrentFile
+	}
+	if current != f.track.Path {
+		f.track = nowPlayingInfo{}
+		if current != "" {
+			f.track = newNowPlayingInfo(current)
+		}
+		if f.cfg.Art {
+			f.saveArt()
+		}
+	}
+
+	content := f.render(state)
+	if bytes.Equal(content, f.written) {
+		return
+	}
+	if err := writeFileAtomic(f.cfg.File, content); err != nil {
+		slog.Warn("failed to write now-playing file", "path", f.cfg.File, "err", err)
+		return
+	}
+	f.written = content
+}
+
+// render returns the file content for a state.
+func (f *nowPlayingFile) render(state PlaybackState) []byte {
+	status := nowPlayingFileStatus{playerStatus: newPlayerStatus(state)}
+	if state.IsPlaying {
+		status.Title = f.track.Title
+		status.Artist = f.track.Artist
+		status.Album = f.track.Album
+		status.Art = f.art
+	}
+
+	if strings.EqualFold(filepath.Ext(f.cfg.File), ".json") {
+		data, _ := json.MarshalIndent(status, "", "  ")
+		return append(data, '\n')
+	}
+
+	if !state.IsPlaying {
+		return nil
+	}
+	format := f.cfg.Format
+	if format == "" {
+		format = defaultNowPlayingFormat
+		if status.Artist == "" {
+			format = "{title}"
+		}
+	}
+	text := strings.NewReplacer(
+		"{title}", status.Title,
+		"{artist}", status.Artist,
+		"{album}", status.Album,
+		"{state}", status.State,
+		"{position}", FormatDuration(state.Position),
+		"{duration}", FormatDuration(state.Duration),
+		"{path}", status.Path,
+		"{art}", status.Art,
+	).Replace(format)
+	return []byte(text + "\n")
+}
+
+// saveArt saves the current track's cover next to the file, named after
+// it (e.g. nowplaying-cover.jpg), or removes the old one.
+func (f *nowPlayingFile) saveArt() {
+	base := strings.TrimSuffix(f.cfg.File, filepath.Ext(f.cfg.File)) + "-cover"
+	var picture []byte
+	if f.track.Path != "" {
+		picture, _ = ReadPicture(f.track.Path)
+	}
+
+	ext := ".jpg"
+	if bytes.HasPrefix(picture, []byte("\x89PNG")) {
+		ext = ".png"
+	}
+	for _, old := range []string{".jpg", ".png"} {
+		if len(picture) == 0 || old != ext {
+			os.Remove(base + old)
+		}
+	}
+	f.art = ""
+	if len(picture) == 0 {
+		return
+	}
+	if err := writeFileAtomic(base+ext, picture); err != nil {
+		slog.Warn("failed to save cover", "path", base+ext, "err", err)
+		return
+	}
+	f.art = base + ext
+}
+
+// writeFileAtomic replaces a file in one step, so readers never see it half
+// written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644) // Readable like a normally created file
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
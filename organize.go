@@ -0,0 +1,223 @@
+// Package main provides the library organizer of Personal Musician.
+// Organize library (in the finder) plans to move every tagged song into an
+// Artist/Album/NN Title layout inside its music folder, for browsing the
+// folders outside the player. The plan is shown first as a dry run: each
+// move can be left out, and nothing changes on disk until it is applied.
+// Moves go through the same path as renaming (see rename.go), so playlists,
+// the queue and statistics follow, and folders left empty are removed.
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// organizeNameLimit is the longest folder or file name the organizer
+// writes, in characters, leaving room for the rest of the path.
+const organizeNameLimit = 100
+
+// organizeMove is a planned move of the organizer.
+type organizeMove struct {
+	file MusicFile
+	to   string
+	skip bool // Left out by the user
+}
+
+// safeFileName turns a tag into a file or folder name that is valid on
+// every system: path separators and reserved characters become "_", and
+// leading and trailing spaces and dots go.
+func safeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	if runes := []rune(s); len(runes) > organizeNameLimit {
+		s = string(runes[:organizeNameLimit])
+	}
+	return strings.Trim(s, " .")
+}
+
+// organizedPath returns where a song belongs in the organized layout:
+// Artist/Album/NN Title, without the album folder when there is no album
+// tag and without the number when there is no track number. Songs without
+// an artist tag stay where they are.
+func organizedPath(file MusicFile) (string, bool) {
+	artist := safeFileName(file.Tags.Artist)
+	if artist == "" {
+		return "", false
+	}
+	name := safeFileName(file.Tags.Title)
+	if name == "" {
+		name = safeFileName(file.Name)
+	}
+	if n := file.Tags.TrackNumber(); n > 0 {
+		name = fmt.Sprintf("%02d %s", n, name)
+	}
+
+	parts := []string{musicRoot(file.Path), artist}
+	if album := safeFileName(file.Tags.Album); album != "" {
+		parts = append(parts, album)
+	}
+	return filepath.Join(append(parts, name+filepath.Ext(file.Path))...), true
+}
+
+// planOrganize returns the moves that organize files, sorted by their new
+// paths. Songs already in place are left out, and a number is added to
+// names that would clash with another song or an existing file.
+func planOrganize(files []MusicFile) []organizeMove {
+	taken := make(map[string]bool) // Lower case, as some systems ignore case
+	for _, file := range files {
+		taken[strings.ToLower(file.Path)] = true
+	}
+
+	var moves []organizeMove
+	for _, file := range files {
+		to, ok := organizedPath(file)
+		if !ok || strings.EqualFold(to, file.Path) {
+			continue // Untagged, or in place but for the case, which isn't worth a move
+		}
+		base := strings.TrimSuffix(to, filepath.Ext(to))
+		for n := 2; taken[strings.ToLower(to)] || fileExists(to); n++ {
+			to = fmt.Sprintf("%s (%d)%s", base, n, filepath.Ext(file.Path))
+		}
+		taken[strings.ToLower(to)] = true
+		moves = append(moves, organizeMove{file: file, to: to})
+	}
+	slices.SortFunc(moves, func(a, b organizeMove) int {
+		return cmp.Compare(strings.ToLower(a.to), strings.ToLower(b.to))
+	})
+	return moves
+}
+
+// fileExists reports whether something exists at path.
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// removeEmptyDirs removes the folders in dirs, and then their parents, as
+// long as they are empty, stopping at the music directories themselves.
+func removeEmptyDirs(dirs []string) {
+	for _, dir := range dirs {
+		root := musicRoot(dir)
+		for dir != root && inMusicDirs(dir) {
+			if os.Remove(dir) != nil { // Fails unless empty
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+}
+
+// openOrganize plans organizing the library and shows the plan.
+func (m Model) openOrganize() (tea.Model, tea.Cmd) {
+	m.currentView = ViewOrganize
+	m.organize = planOrganize(m.libraryFiles)
+	m.organizeCursor = 0
+	if m.metadataScan != nil {
+		return m, func() tea.Msg { return statusMsg(T("Still reading tags; open Organize library again once done")) }
+	}
+	return m, nil
+}
+
+// applyOrganize returns a command that makes the moves not left out and
+// removes the folders they leave empty.
+func (m Model) applyOrganize() tea.Cmd {
+	moves := make(map[string]string)
+	var dirs []string
+	for _, move := range m.organize {
+		if !move.skip {
+			moves[move.file.Path] = move.to
+			dirs = append(dirs, filepath.Dir(move.file.Path))
+		}
+	}
+	move := m.moveTracks(moves)
+	return func() tea.Msg {
+		msg := move()
+		removeEmptyDirs(dirs)
+		return msg
+	}
+}
+
+// handleOrganizeKeys handles keys in the Organize view.
+func (m Model) handleOrganizeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if cursor, ok := m.navigateList(msg.String(), m.organizeCursor, len(m.organize)); ok {
+		m.organizeCursor = cursor
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "x", "delete": // Leave the move out, or put it back
+		if m.organizeCursor < len(m.organize) {
+			m.organize = slices.Clone(m.organize)
+			m.organize[m.organizeCursor].skip = !m.organize[m.organizeCursor].skip
+			m.organizeCursor = min(m.organizeCursor+1, len(m.organize)-1)
+		}
+	case "enter": // Apply the plan
+		count := 0
+		for _, move := range m.organize {
+			if !move.skip {
+				count++
+			}
+		}
+		if count == 0 {
+			return m, nil
+		}
+		return m.openDialog(NewConfirmDialog(T("Organize library"),
+			Tf("Move %d songs into artist and album folders?", count),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				cmd := m.applyOrganize()
+				m.organize = nil
+				m.currentView = ViewLibrary
+				return m, cmd
+			}))
+	}
+	return m, nil
+}
+
+// renderOrganizeView renders the planned moves, old path above new.
+func (m Model) renderOrganizeView() string {
+	var b strings.Builder
+
+	count := 0
+	for _, move := range m.organize {
+		if !move.skip {
+			count++
+		}
+	}
+	b.WriteString(headerStyle.Render(" 📁 "+T("Organize library")+" ") + " " + mutedStyle.Render(Tf("%d of %d songs to move · dry run", count, len(m.organize))) + "\n\n")
+
+	if len(m.organize) == 0 {
+		b.WriteString(mutedStyle.Render(T("Every tagged song is already in place") + "\n"))
+		return b.String()
+	}
+
+	width := m.width - 4
+	if m.isWide() {
+		width = m.leftPaneWidth() - 6
+	}
+
+	b.WriteString(m.organizeScroll.render(len(m.organize), 2, m.maxVisible()*2, func(i int) string {
+		move := m.organize[i]
+		check := "[x] "
+		if move.skip {
+			check = "[ ] "
+		}
+		from := truncate(relToMusicDir(move.file.Path), max(width-8, 10))
+		to := mutedStyle.Render("      → " + truncate(relToMusicDir(move.to), max(width-10, 10)))
+		if i == m.organizeCursor {
+			return selectedStyle.Render("> "+check+from) + "\n" + to
+		}
+		return normalStyle.Render("  "+check+from) + "\n" + to
+	}))
+
+	return b.String()
+}
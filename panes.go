@@ -0,0 +1,81 @@
+// Package main provides resizable panes for Personal Musician.
+// The split between the main list and Now Playing can be changed with
+// [ and ], and the chosen split is remembered per terminal width.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// LayoutFile is where pane sizes are stored.
+var LayoutFile = dataPath("layout.json")
+
+const (
+	defaultPaneRatio = 60 // Left pane width in percent
+	minPaneRatio     = 30
+	maxPaneRatio     = 80
+	paneRatioStep    = 5
+)
+
+// PaneSizes keeps the left pane ratio per terminal width and persists it.
+type PaneSizes struct {
+	mu     sync.Mutex
+	path   string
+	ratios map[string]int // Keyed by terminal width
+}
+
+// LoadPaneSizes reads the layout file. A missing file yields default sizes.
+func LoadPaneSizes(path string) (*PaneSizes, error) {
+	p := &PaneSizes{path: path, ratios: make(map[string]int)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, fmt.Errorf("failed to read layout: %w", err)
+	}
+	if err := json.Unmarshal(data, &p.ratios); err != nil {
+		return p, fmt.Errorf("failed to parse layout: %w", err)
+	}
+	return p, nil
+}
+
+// Ratio returns the left pane ratio in percent for a terminal width.
+func (p *PaneSizes) Ratio(width int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ratio, ok := p.ratios[strconv.Itoa(width)]; ok {
+		return ratio
+	}
+	return defaultPaneRatio
+}
+
+// Resize changes the left pane ratio for a terminal width by delta percent
+// and saves the layout. Returns the new ratio.
+func (p *PaneSizes) Resize(width, delta int) (int, error) {
+	ratio := p.Ratio(width) + delta
+	if ratio < minPaneRatio {
+		ratio = minPaneRatio
+	}
+	if ratio > maxPaneRatio {
+		ratio = maxPaneRatio
+	}
+
+	p.mu.Lock()
+	p.ratios[strconv.Itoa(width)] = ratio
+	data, err := json.MarshalIndent(p.ratios, "", "  ")
+	p.mu.Unlock()
+	if err != nil {
+		return ratio, fmt.Errorf("failed to encode layout: %w", err)
+	}
+
+	if err := os.WriteFile(p.path, data, 0644); err != nil {
+		return ratio, fmt.Errorf("failed to write layout: %w", err)
+	}
+	return ratio, nil
+}
@@ -0,0 +1,212 @@
+// Package main provides the default file locations for Personal Musician.
+// Music goes to $XDG_MUSIC_DIR/PersonalMusician (~/Music/PersonalMusician by
+// default) and statistics, playlists and other data to
+// $XDG_DATA_HOME/personal-musician, instead of the working directory. Files
+// older versions left next to the program are moved over the first time the
+// player starts.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// musicSubdir is the folder created inside the user's music directory.
+const musicSubdir = "PersonalMusician"
+
+// legacyMusicDir is where older versions kept music, relative to the
+// working directory.
+const legacyMusicDir = "./Music"
+
+// DataDir returns the directory for data files, honoring XDG_DATA_HOME and
+// falling back to ~/.local/share.
+func DataDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "."
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, configAppName)
+}
+
+// dataPath returns the path of a file in the data directory.
+func dataPath(name string) string {
+	return filepath.Join(DataDir(), name)
+}
+
+// configPath returns the path of a file next to config.toml.
+func configPath(name string) string {
+	return filepath.Join(filepath.Dir(ConfigPath()), name)
+}
+
+// defaultMusicDir returns the default music directory inside the user's
+// music folder: XDG_MUSIC_DIR from the environment or user-dirs.dirs,
+// falling back to ~/Music.
+func defaultMusicDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return legacyMusicDir
+	}
+	base := os.Getenv("XDG_MUSIC_DIR")
+	if base == "" {
+		base = userDirsMusic(home)
+	}
+	if base == "" {
+		base = filepath.Join(home, "Music")
+	}
+	return filepath.Join(base, musicSubdir)
+}
+
+// userDirsMusic reads XDG_MUSIC_DIR from the user-dirs.dirs file written by
+// xdg-user-dirs, returning "" when it is not set.
+func userDirsMusic(home string) string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	f, err := os.Open(filepath.Join(configHome, "user-dirs.dirs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// Lines look like XDG_MUSIC_DIR="$HOME/Music"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "XDG_MUSIC_DIR=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		if rest, ok := strings.CutPrefix(value, "$HOME"); ok {
+			value = home + rest
+		}
+		if filepath.IsAbs(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// InitDataDir creates the data directory.
+func InitDataDir() error {
+	if err := os.MkdirAll(DataDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return nil
+}
+
+// MigrationFile records that the files of older versions were moved, so
+// they are looked for only once, along with the music folder they left.
+var MigrationFile = dataPath("migrated.json")
+
+// migrationRecord is the content of MigrationFile.
+type migrationRecord struct {
+	From     string `json:"from"`                // Folder the files were looked for in
+	MusicDir string `json:"music_dir,omitempty"` // Music left there, still scanned
+}
+
+// legacyDir returns the folder older versions kept their files in: the one
+// the program is installed in, which they were run from.
+func legacyDir() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the program: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe), nil
+}
+
+// MigrateLegacyFiles moves data files that older versions kept next to the
+// program to their new locations and, unless another music directory was
+// chosen, keeps music left in its Music folder in the library. The files
+// are moved once; later starts only keep scanning the old music folder. It
+// returns notices describing what was done.
+func MigrateLegacyFiles() []string {
+	if data, err := os.ReadFile(MigrationFile); err == nil {
+		var record migrationRecord
+		if json.Unmarshal(data, &record) == nil && record.MusicDir != "" && MusicDir == defaultMusicDir() && hasLegacyMusic(record.MusicDir) {
+			extraMusicDirs = append(extraMusicDirs, record.MusicDir)
+		}
+		return nil
+	}
+
+	dir, err := legacyDir()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	record := migrationRecord{From: dir}
+	var notices []string
+
+	moves := []struct{ from, to string }{
+		{filepath.Join(dir, "stats.json"), StatsFile},
+		{filepath.Join(dir, "layout.json"), LayoutFile},
+		{filepath.Join(dir, "state.json"), StateFile},
+		{filepath.Join(dir, "themes.json"), ThemesFile},
+		{filepath.Join(dir, "Playlists"), PlaylistDir},
+	}
+	for _, m := range moves {
+		if _, err := os.Stat(m.from); err != nil {
+			continue // Nothing to migrate
+		}
+		if _, err := os.Stat(m.to); err == nil {
+			continue // Already migrated; leave the old copy alone
+		}
+		if err := os.MkdirAll(filepath.Dir(m.to), 0755); err == nil {
+			if err := os.Rename(m.from, m.to); err == nil {
+				notices = append(notices, fmt.Sprintf("Moved %s to %s", m.from, m.to))
+				continue
+			}
+		}
+		notices = append(notices, fmt.Sprintf("Could not move %s to %s; please move it by hand", m.from, m.to))
+	}
+
+	// Music is not moved automatically; keep scanning the old folder instead
+	if music := filepath.Join(dir, "Music"); hasLegacyMusic(music) && MusicDir == defaultMusicDir() {
+		record.MusicDir = music
+		extraMusicDirs = append(extraMusicDirs, music)
+		notices = append(notices, fmt.Sprintf(
+			"Found music in %s; it stays in your library, but new downloads go to %s. "+
+				"Move the files there, or list both folders under [library] music_dirs in %s.",
+			music, MusicDir, ConfigPath()))
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err == nil {
+		err = os.WriteFile(MigrationFile, data, 0644)
+	}
+	if err != nil {
+		notices = append(notices, fmt.Sprintf("Could not record the migration: %v", err))
+	}
+	return notices
+}
+
+// hasLegacyMusic reports whether dir holds files and neither contains the
+// music directory nor lies inside it, which would list songs twice.
+func hasLegacyMusic(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		return false
+	}
+	legacy, err1 := filepath.Abs(dir)
+	current, err2 := filepath.Abs(MusicDir)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return !pathWithin(current, legacy) && !pathWithin(legacy, current)
+}
+
+// pathWithin reports whether path is dir or lies below it.
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
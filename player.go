@@ -1,15 +1,17 @@
 // Package main provides the audio playback functionality for Personal Musician.
-// This module uses gopxl/beep for decoding and playing MP3 files.
+// This module uses gopxl/beep for decoding and playing MP3, FLAC, WAV and AIFF files.
 package main
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gopxl/beep/v2"
-	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/effects"
 	"github.com/gopxl/beep/v2/speaker"
 )
 
@@ -20,21 +22,73 @@ type Player struct {
 	// Audio stream components
 	streamer   beep.StreamSeekCloser
 	ctrl       *beep.Ctrl
+	tap        *audioTap
+	eq         *equalizer
+	volumeFx   *effects.Volume
 	sampleRate beep.SampleRate
 	format     beep.Format
 
 	// Playback state
-	currentFile    string
-	isPlaying      bool
-	isPaused       bool
-	speakerInit    bool
-	position       time.Duration
-	duration       time.Duration
+	currentFile string
+	isPlaying   bool
+	isPaused    bool
+	speakerInit bool
+	position    time.Duration
+	duration    time.Duration
+
+	// Output and playback modes
+	volume  int // Volume level 0-100
+	muted   bool
+	shuffle bool
+	repeat  RepeatMode
 
 	// Playlist management
-	playlist      []MusicFile
-	currentIndex  int
-	onSongChange  func() // Callback when song changes
+	playlist     []MusicFile
+	currentIndex int
+	onSongChange func()                                                  // Callback when song changes
+	onTrackEnd   func(path string, listened time.Duration)               // Callback when a track plays to the end
+	resumeAt     func(path string, duration time.Duration) time.Duration // Where a track starts; nil starts at the beginning
+
+	// Play queue (tracks to play before continuing the playlist)
+	queue        []MusicFile
+	queueVersion int // Bumped on every change to queue
+
+	// Gapless playback (see gapless.go)
+	source        *gaplessSource               // What the tap plays; nil when stopped
+	next          atomic.Pointer[decodedTrack] // Prepared next track, taken by the audio thread
+	nextRequested atomic.Bool                  // The next track is being or has been prepared
+	nextGen       int                          // Bumped whenever what comes next changes
+	playGen       int                          // Bumped whenever a track is started by hand
+	crossfade     time.Duration                // Overlap between tracks (see crossfade.go)
+	trimSilence   bool                         // Skip leading and trailing silence (see silence.go)
+
+	// A–B loop of the playing track (see abloop.go)
+	loop ABLoop
+
+	// Shuffle rounds (see shuffle.go)
+	shufflePlayed  map[string]bool // Tracks played in this round
+	shuffleHistory []string        // Tracks played before the current one, oldest first
+}
+
+// RepeatMode controls what happens when a track or the playlist ends.
+type RepeatMode int
+
+const (
+	RepeatOff   RepeatMode = iota // Stop at the end of the playlist
+	RepeatAll                     // Loop the whole playlist
+	RepeatTrack                   // Loop the current track
+)
+
+// String returns the display name of the repeat mode.
+func (r RepeatMode) String() string {
+	switch r {
+	case RepeatAll:
+		return "all"
+	case RepeatTrack:
+		return "track"
+	default:
+		return "off"
+	}
 }
 
 // PlaybackState holds current playback information.
@@ -46,12 +100,22 @@ type PlaybackState struct {
 	Duration     time.Duration
 	CurrentIndex int
 	TotalTracks  int
+	QueueLength  int
+	Volume       int
+	Muted        bool
+	Shuffle      bool
+	Repeat       RepeatMode
+	Loop         ABLoop
 }
 
 // NewPlayer creates a new Player instance.
 func NewPlayer() *Player {
 	return &Player{
-		currentIndex: -1,
+		currentIndex:  -1,
+		tap:           newAudioTap(),
+		eq:            newEqualizer(),
+		volume:        100,
+		shufflePlayed: make(map[string]bool),
 	}
 }
 
@@ -60,6 +124,17 @@ func (p *Player) SetPlaylist(files []MusicFile) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.playlist = files
+	p.invalidateNextLocked()
+
+	// Keep pointing at the current track if the order changed
+	if p.currentFile != "" {
+		for i, f := range files {
+			if f.Path == p.currentFile {
+				p.currentIndex = i
+				return
+			}
+		}
+	}
 	if len(files) > 0 && p.currentIndex < 0 {
 		p.currentIndex = 0
 	}
@@ -72,6 +147,23 @@ func (p *Player) SetOnSongChange(callback func()) {
 	p.onSongChange = callback
 }
 
+// SetOnTrackEnd sets a callback function to be called when a track plays to
+// the end, with how long it was actually played.
+func (p *Player) SetOnTrackEnd(callback func(path string, listened time.Duration)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onTrackEnd = callback
+}
+
+// SetResumePosition sets a function that returns where a track starts
+// playing, given its path and duration. It is called with the player
+// locked, so it must not call back into the player.
+func (p *Player) SetResumePosition(resumeAt func(path string, duration time.Duration) time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resumeAt = resumeAt
+}
+
 // GetPlaylist returns the current playlist.
 func (p *Player) GetPlaylist() []MusicFile {
 	p.mu.Lock()
@@ -79,26 +171,24 @@ func (p *Player) GetPlaylist() []MusicFile {
 	return p.playlist
 }
 
-// PlayFile loads and plays an MP3 file.
-func (p *Player) PlayFile(filePath string) error {
+// PlayFile loads and plays an audio file. Failures are written to the log file.
+func (p *Player) PlayFile(filePath string) (err error) {
+	defer func() {
+		if err != nil {
+			slog.Error("playback failed", "file", filePath, "err", err)
+		}
+	}()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Close any existing stream
-	p.stopInternal()
-
-	// Open the MP3 file
-	file, err := os.Open(filePath)
+	// Open and decode the audio file
+	track, err := openTrack(filePath, p.trimSilence)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-
-	// Decode the MP3 file
-	streamer, format, err := mp3.Decode(file)
-	if err != nil {
-		file.Close()
-		return fmt.Errorf("failed to decode MP3: %w", err)
+		p.stopInternal()
+		return err
 	}
+	streamer, format := track.streamer, track.format
 
 	// Initialize speaker if not already done (only once per app lifetime)
 	if !p.speakerInit {
@@ -108,45 +198,82 @@ func (p *Player) PlayFile(filePath string) error {
 		}
 		p.speakerInit = true
 		p.sampleRate = format.SampleRate
+		p.eq.configure(p.sampleRate, p.eq.gains)
 	}
 
 	// Resample if sample rates differ
-	var resampled beep.Streamer = streamer
-	if format.SampleRate != p.sampleRate {
-		resampled = beep.Resample(4, format.SampleRate, p.sampleRate, streamer)
-	}
+	track.resampleTo(p.sampleRate)
+
+	// Calculate duration
+	duration := format.SampleRate.D(streamer.Len())
 
-	// Create control wrapper for pause/resume functionality
-	p.ctrl = &beep.Ctrl{Streamer: resampled, Paused: false}
+	// Continue long tracks where they were left, or skip leading silence
+	p.cueLocked(track)
+
+	p.playGen++
+	p.loop = ABLoop{}
+	if p.crossfade > 0 && p.source != nil && p.isPlaying && !p.isPaused {
+		// Fade from the playing track into this one
+		p.invalidateNextLocked()
+		speaker.Lock()
+		dropped := p.source.fadeTo(track, p.crossfade)
+		speaker.Unlock()
+		if dropped != nil {
+			dropped.streamer.Close()
+		}
+	} else {
+		// Close any existing stream
+		p.stopInternal()
+
+		// Route audio through the tap so visualizers can read it; the source
+		// moves on to the next track without a gap once it is prepared
+		p.source = &gaplessSource{player: p, rate: p.sampleRate, crossfade: p.crossfade, track: track}
+		p.eq.Streamer = p.source
+		p.tap.Streamer = p.eq
+
+		// Apply volume after the tap so visualizers see the unscaled signal
+		p.volumeFx = &effects.Volume{Streamer: p.tap, Base: 2}
+		p.applyVolumeLocked()
+
+		// Create control wrapper for pause/resume functionality
+		p.ctrl = &beep.Ctrl{Streamer: p.volumeFx, Paused: false}
+
+		// Play the audio
+		speaker.Play(beep.Seq(p.ctrl, beep.Callback(func() {
+			// Called when playback finishes
+			p.mu.Lock()
+			p.isPlaying = false
+			p.isPaused = false
+			finished := p.currentFile // Tracks may have followed gaplessly
+			var listened time.Duration
+			if p.source != nil {
+				listened = p.sampleRate.D(p.source.track.played)
+			}
+			callback := p.onSongChange
+			onEnd := p.onTrackEnd
+			p.mu.Unlock()
+
+			// Auto-advance as the repeat mode says
+			go func() {
+				if onEnd != nil {
+					onEnd(finished, listened)
+				}
+				p.autoAdvance(finished)
+				if callback != nil {
+					callback()
+				}
+			}()
+		})))
+	}
 
 	// Store state
 	p.streamer = streamer
 	p.format = format
 	p.currentFile = filePath
+	p.duration = duration
 	p.isPlaying = true
 	p.isPaused = false
 
-	// Calculate duration
-	p.duration = format.SampleRate.D(streamer.Len())
-
-	// Play the audio
-	speaker.Play(beep.Seq(p.ctrl, beep.Callback(func() {
-		// Called when playback finishes
-		p.mu.Lock()
-		p.isPlaying = false
-		p.isPaused = false
-		callback := p.onSongChange
-		p.mu.Unlock()
-		
-		// Auto-advance to next song
-		go func() {
-			p.NextSong()
-			if callback != nil {
-				callback()
-			}
-		}()
-	})))
-
 	return nil
 }
 
@@ -164,6 +291,24 @@ func (p *Player) PlayIndex(index int) error {
 	return p.PlayFile(filePath)
 }
 
+// PlayTrack plays a track, selecting it in the playlist if it is part of it.
+func (p *Player) PlayTrack(file MusicFile) error {
+	p.mu.Lock()
+	p.currentIndex = p.playlistIndexLocked(file.Path)
+	p.mu.Unlock()
+
+	return p.PlayFile(file.Path)
+}
+
+// CueTrack loads a track paused at the given position, ready to resume.
+func (p *Player) CueTrack(file MusicFile, position time.Duration) error {
+	if err := p.PlayTrack(file); err != nil {
+		return err
+	}
+	p.TogglePause()
+	return p.SeekTo(position)
+}
+
 // TogglePause toggles between pause and resume states.
 func (p *Player) TogglePause() {
 	p.mu.Lock()
@@ -179,6 +324,158 @@ func (p *Player) TogglePause() {
 	speaker.Unlock()
 }
 
+// Seek moves playback of the current track by offset, forwards or
+// backwards. The position is clamped to the track bounds.
+func (p *Player) Seek(offset time.Duration) error {
+	p.mu.Lock()
+	if p.streamer == nil {
+		p.mu.Unlock()
+		return fmt.Errorf("nothing is playing")
+	}
+	speaker.Lock()
+	position := p.format.SampleRate.D(p.streamer.Position())
+	speaker.Unlock()
+	p.mu.Unlock()
+
+	return p.SeekTo(position + offset)
+}
+
+// SeekTo moves playback of the current track to the given position.
+// The position is clamped to the track bounds.
+func (p *Player) SeekTo(position time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.streamer == nil {
+		return fmt.Errorf("nothing is playing")
+	}
+
+	sample := p.format.SampleRate.N(position)
+	if sample < 0 {
+		sample = 0
+	}
+	if sample >= p.streamer.Len() {
+		sample = p.streamer.Len() - 1
+	}
+
+	speaker.Lock()
+	err := p.streamer.Seek(sample)
+	speaker.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	return nil
+}
+
+// ToggleMute toggles muting of the audio output.
+func (p *Player) ToggleMute() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.muted = !p.muted
+	p.applyVolumeLocked()
+	return p.muted
+}
+
+// GetVolume returns the volume level, 0-100.
+func (p *Player) GetVolume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+// ChangeVolume raises or lowers the volume by delta, clamped to 0-100, and
+// unmutes. Returns the new level.
+func (p *Player) ChangeVolume(delta int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.volume = max(0, min(p.volume+delta, 100))
+	p.muted = false
+	p.applyVolumeLocked()
+	return p.volume
+}
+
+// SetVolume sets the volume level, clamped to 0-100.
+func (p *Player) SetVolume(level int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.volume = max(0, min(level, 100))
+	p.applyVolumeLocked()
+}
+
+// SetMuted mutes or unmutes the audio output.
+func (p *Player) SetMuted(muted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.muted = muted
+	p.applyVolumeLocked()
+}
+
+// SetRepeat selects the repeat mode.
+func (p *Player) SetRepeat(mode RepeatMode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.repeat = mode
+	p.invalidateNextLocked()
+}
+
+// CycleRepeat switches to the next repeat mode (off → all → track → off)
+// and returns it.
+func (p *Player) CycleRepeat() RepeatMode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.repeat = (p.repeat + 1) % (RepeatTrack + 1)
+	p.invalidateNextLocked()
+	return p.repeat
+}
+
+// autoAdvance moves on after a track played to the end: it plays the track
+// again, stops after the last track of the playlist, or continues with the
+// queue or the next track, wrapping around.
+func (p *Player) autoAdvance(finished string) {
+	p.mu.Lock()
+	repeat := p.repeat
+	atEnd := len(p.queue) == 0 && p.atPlaylistEndLocked()
+	p.mu.Unlock()
+
+	switch {
+	case repeat == RepeatTrack:
+		p.PlayFile(finished)
+	case repeat == RepeatOff && atEnd:
+		// Stop after the last track
+	default:
+		p.NextSong()
+	}
+}
+
+// atPlaylistEndLocked reports whether the current track is the last of
+// the playlist, or in shuffle mode the last unplayed one of the round.
+// Caller must hold p.mu.
+func (p *Player) atPlaylistEndLocked() bool {
+	if !p.shuffle {
+		return p.currentIndex >= len(p.playlist)-1
+	}
+	for _, f := range p.playlist {
+		if f.Path != p.currentFile && !p.shufflePlayed[f.Path] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyVolumeLocked pushes the volume level and mute state to the volume effect.
+// Caller must hold p.mu.
+func (p *Player) applyVolumeLocked() {
+	if p.volumeFx == nil {
+		return
+	}
+	speaker.Lock()
+	p.volumeFx.Silent = p.muted || p.volume <= 0
+	if p.volume > 0 {
+		p.volumeFx.Volume = math.Log2(float64(p.volume) / 100)
+	}
+	speaker.Unlock()
+}
+
 // Stop stops the current playback.
 func (p *Player) Stop() {
 	p.mu.Lock()
@@ -190,24 +487,37 @@ func (p *Player) Stop() {
 func (p *Player) stopInternal() {
 	if p.streamer != nil {
 		speaker.Clear()
-		p.streamer.Close()
+		p.source.close()
 		p.streamer = nil
 		p.ctrl = nil
+		p.volumeFx = nil
+		p.tap.reset()
+		p.eq.reset()
 	}
+	p.source = nil
+	p.invalidateNextLocked()
 	p.isPlaying = false
 	p.isPaused = false
 }
 
-// NextSong advances to the next song in the playlist.
+// NextSong advances to the next queued song, or the next song in the playlist.
 func (p *Player) NextSong() error {
 	p.mu.Lock()
+	if file, ok := p.popQueueLocked(); ok {
+		p.currentIndex = p.playlistIndexLocked(file.Path)
+		p.mu.Unlock()
+		return p.PlayFile(file.Path)
+	}
 	if len(p.playlist) == 0 {
 		p.mu.Unlock()
 		return fmt.Errorf("playlist is empty")
 	}
 
-	// Move to next song (wrap around)
+	// Move to next song (wrap around), or a random unplayed one in shuffle mode
 	nextIndex := (p.currentIndex + 1) % len(p.playlist)
+	if p.shuffle {
+		nextIndex, _ = p.nextShuffledLocked()
+	}
 	p.mu.Unlock()
 
 	return p.PlayIndex(nextIndex)
@@ -221,11 +531,16 @@ func (p *Player) PrevSong() error {
 		return fmt.Errorf("playlist is empty")
 	}
 
-	// Move to previous song (wrap around)
+	// Move to previous song (wrap around), or retrace the shuffled order
 	prevIndex := p.currentIndex - 1
 	if prevIndex < 0 {
 		prevIndex = len(p.playlist) - 1
 	}
+	if p.shuffle {
+		if i, ok := p.prevShuffledLocked(); ok {
+			prevIndex = i
+		}
+	}
 	p.mu.Unlock()
 
 	return p.PlayIndex(prevIndex)
@@ -243,6 +558,12 @@ func (p *Player) GetState() PlaybackState {
 		Duration:     p.duration,
 		CurrentIndex: p.currentIndex,
 		TotalTracks:  len(p.playlist),
+		QueueLength:  len(p.queue),
+		Volume:       p.volume,
+		Muted:        p.muted,
+		Shuffle:      p.shuffle,
+		Repeat:       p.repeat,
+		Loop:         p.loop,
 	}
 
 	// Get current position if playing
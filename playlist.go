@@ -0,0 +1,196 @@
+// Package main provides saved playlists for Personal Musician.
+// Playlists are stored as extended M3U files in the Playlists folder of the
+// data directory, one file per playlist, so other players can read them too.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PlaylistDir is the directory where playlists are stored.
+var PlaylistDir = dataPath("Playlists")
+
+// playlistExt is the file extension of saved playlists.
+const playlistExt = ".m3u"
+
+// Playlist is a named, ordered list of tracks.
+type Playlist struct {
+	Name   string
+	Tracks []MusicFile
+}
+
+// ListPlaylists returns the names of the saved playlists, sorted.
+func ListPlaylists() ([]string, error) {
+	entries, err := os.ReadDir(PlaylistDir)
+	if os.IsNotExist(err) {
+		return nil, nil // No playlists yet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playlists: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), playlistExt) {
+			names = append(names, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names, nil
+}
+
+// LoadPlaylist reads a saved playlist. A missing playlist yields an empty one.
+func LoadPlaylist(name string) (Playlist, error) {
+	pl := Playlist{Name: name}
+
+	tracks, err := readM3U(playlistPath(name), "")
+	if os.IsNotExist(err) {
+		return pl, nil
+	}
+	pl.Tracks = tracks
+	return pl, err
+}
+
+// LoadM3U reads an M3U file from anywhere on disk. Relative track paths are
+// resolved against the file's directory.
+func LoadM3U(path string) (Playlist, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	tracks, err := readM3U(path, filepath.Dir(path))
+	if os.IsNotExist(err) {
+		return Playlist{}, fmt.Errorf("failed to open playlist: %w", err)
+	}
+	return Playlist{Name: name, Tracks: tracks}, err
+}
+
+// readM3U reads the tracks of an M3U file, joining relative paths to base
+// when base is set. A missing file is reported with an os.IsNotExist error.
+func readM3U(path, base string) ([]MusicFile, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open playlist: %w", err)
+	}
+	defer f.Close()
+
+	var tracks []MusicFile
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue // Skip blank lines and M3U directives
+		}
+		if base != "" && !filepath.IsAbs(line) {
+			line = filepath.Join(base, line)
+		}
+		tracks = append(tracks, musicFileFromPath(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return tracks, fmt.Errorf("failed to read playlist: %w", err)
+	}
+	return tracks, nil
+}
+
+// SavePlaylist writes a playlist, replacing any previous version.
+func SavePlaylist(pl Playlist) error {
+	if err := os.MkdirAll(PlaylistDir, 0755); err != nil {
+		return fmt.Errorf("failed to create playlist directory: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, t := range pl.Tracks {
+		fmt.Fprintf(&b, "#EXTINF:-1,%s\n%s\n", t.Name, t.Path)
+	}
+
+	if err := os.WriteFile(playlistPath(pl.Name), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write playlist: %w", err)
+	}
+	return nil
+}
+
+// DeletePlaylist removes a saved playlist.
+func DeletePlaylist(name string) error {
+	if err := os.Remove(playlistPath(name)); err != nil {
+		return fmt.Errorf("failed to delete playlist %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddToPlaylist appends tracks to a playlist, creating it if needed.
+func AddToPlaylist(name string, files []MusicFile) error {
+	pl, err := LoadPlaylist(name)
+	if err != nil {
+		return err
+	}
+	pl.Tracks = append(pl.Tracks, files...)
+	return SavePlaylist(pl)
+}
+
+// RepointPlaylists replaces the tracks at the old paths in moved with their
+// new paths in every saved playlist. Returns the number of playlists changed.
+func RepointPlaylists(moved map[string]string) (int, error) {
+	names, err := ListPlaylists()
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, name := range names {
+		pl, err := LoadPlaylist(name)
+		if err != nil {
+			return changed, err
+		}
+		dirty := false
+		for i, t := range pl.Tracks {
+			if to, ok := moved[t.Path]; ok {
+				pl.Tracks[i] = musicFileFromPath(to)
+				dirty = true
+			}
+		}
+		if !dirty {
+			continue
+		}
+		if err := SavePlaylist(pl); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// ValidatePlaylistName checks that a name can be used as a playlist file name.
+func ValidatePlaylistName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:*?"<>|`) {
+		return fmt.Errorf("invalid playlist name %q", name)
+	}
+	return nil
+}
+
+// playlistPath returns the file path of a playlist.
+func playlistPath(name string) string {
+	return filepath.Join(PlaylistDir, name+playlistExt)
+}
+
+// musicFileFromPath builds a MusicFile for a path, using the file's
+// modification time when it exists.
+func musicFileFromPath(path string) MusicFile {
+	fileName := filepath.Base(path)
+	file := MusicFile{
+		Name:     strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		Path:     path,
+		FileName: fileName,
+	}
+	if info, err := os.Stat(path); err == nil {
+		file.ModTime = info.ModTime()
+		file.Size = info.Size()
+	}
+	return file
+}
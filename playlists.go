@@ -0,0 +1,489 @@
+// Package main provides the playlist views for Personal Musician.
+// The playlists view lists saved playlists, then smart playlists; the
+// editor reorders and removes entries, inserts tracks from a library picker
+// and saves. Smart playlists are edited as a line of rules in a dialog.
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// playlistsLoadedMsg carries the saved playlist names and smart playlists.
+type playlistsLoadedMsg struct {
+	names []string
+	smart []SmartPlaylist
+	err   error
+}
+
+// loadPlaylists reads the saved playlist names and smart playlists in the
+// background.
+func loadPlaylists() tea.Cmd {
+	return func() tea.Msg {
+		names, err := ListPlaylists()
+		if err != nil {
+			return playlistsLoadedMsg{err: err}
+		}
+		smart, err := ListSmartPlaylists()
+		return playlistsLoadedMsg{names: names, smart: smart, err: err}
+	}
+}
+
+// applyPlaylistsLoaded lists loaded playlists. Smart playlists follow the
+// saved ones in playlistNames, so the list is navigated as one.
+func (m Model) applyPlaylistsLoaded(msg playlistsLoadedMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	m.playlistNames = msg.names
+	m.smartPlaylists = msg.smart
+	for _, sp := range msg.smart {
+		m.playlistNames = append(m.playlistNames, sp.Name)
+	}
+	if m.playlistsCursor >= len(m.playlistNames) {
+		m.playlistsCursor = 0
+	}
+	return m, nil
+}
+
+// smartPlaylistAt returns the smart playlist at index i of playlistNames.
+func (m Model) smartPlaylistAt(i int) (SmartPlaylist, bool) {
+	first := len(m.playlistNames) - len(m.smartPlaylists)
+	if i < first || i >= len(m.playlistNames) {
+		return SmartPlaylist{}, false
+	}
+	return m.smartPlaylists[i-first], true
+}
+
+// openPlaylists switches to the playlists view and refreshes it.
+func (m Model) openPlaylists() (tea.Model, tea.Cmd) {
+	m.currentView = ViewPlaylists
+	return m, loadPlaylists()
+}
+
+// handlePlaylistsKeys handles keys in the playlists view.
+func (m Model) handlePlaylistsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if cursor, ok := m.navigateList(msg.String(), m.playlistsCursor, len(m.playlistNames)); ok {
+		m.playlistsCursor = cursor
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "n": // New playlist
+		return m.openDialog(NewPromptDialog(T("New playlist"), T("Name of the new playlist:"), "",
+			func(m Model, name string) (tea.Model, tea.Cmd) {
+				if err := ValidatePlaylistName(name); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				return m.editPlaylist(Playlist{Name: name}, true)
+			}))
+	case "S": // New smart playlist
+		return m.openDialog(NewPromptDialog(T("New smart playlist"), T("Name of the new smart playlist:"), "",
+			func(m Model, name string) (tea.Model, tea.Cmd) {
+				if err := ValidatePlaylistName(name); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				return m.editSmartPlaylist(SmartPlaylist{Name: name})
+			}))
+	}
+
+	if m.playlistsCursor >= len(m.playlistNames) {
+		return m, nil
+	}
+	name := m.playlistNames[m.playlistsCursor]
+	if sp, ok := m.smartPlaylistAt(m.playlistsCursor); ok {
+		return m.handleSmartPlaylistKeys(msg, sp)
+	}
+
+	switch msg.String() {
+	case "enter": // Open in the editor
+		pl, err := LoadPlaylist(name)
+		if err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		return m.editPlaylist(pl, false)
+	case "p": // Play
+		pl, err := LoadPlaylist(name)
+		if err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		return m.playPlaylist(pl)
+	case "x", "delete":
+		return m.openDialog(NewConfirmDialog(T("Delete playlist"),
+			Tf("Delete the playlist %q? The songs stay in the library.", name),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				if err := DeletePlaylist(name); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				if m.playlistsCursor > 0 {
+					m.playlistsCursor--
+				}
+				return m, tea.Batch(loadPlaylists(), func() tea.Msg { return statusMsg(Tf("Deleted playlist: %s", name)) })
+			}))
+	}
+	return m, nil
+}
+
+// handleSmartPlaylistKeys handles keys on a smart playlist in the
+// playlists view.
+func (m Model) handleSmartPlaylistKeys(msg tea.KeyMsg, sp SmartPlaylist) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter": // Edit the rules
+		if sp.fromConfig {
+			return m, func() tea.Msg { return statusMsg(Tf("%s is defined in the config file", sp.Name)) }
+		}
+		return m.editSmartPlaylist(sp)
+	case "p": // Play
+		return m.playSmartPlaylist(sp)
+	case "x", "delete":
+		if sp.fromConfig {
+			return m, func() tea.Msg { return statusMsg(Tf("%s is defined in the config file", sp.Name)) }
+		}
+		return m.openDialog(NewConfirmDialog(T("Delete smart playlist"),
+			Tf("Delete the smart playlist %q? The songs stay in the library.", sp.Name),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				if err := DeleteSmartPlaylist(sp.Name); err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				if m.playlistsCursor > 0 {
+					m.playlistsCursor--
+				}
+				return m, tea.Batch(loadPlaylists(), func() tea.Msg { return statusMsg(Tf("Deleted smart playlist: %s", sp.Name)) })
+			}))
+	}
+	return m, nil
+}
+
+// editSmartPlaylist asks for the rules of a smart playlist, separated by
+// semicolons, and saves it once they are valid.
+func (m Model) editSmartPlaylist(sp SmartPlaylist) (tea.Model, tea.Cmd) {
+	initial := sp.Summary()
+	if len(sp.Rules) == 0 {
+		initial = "added < 30d; plays > 5"
+	}
+	return m.openDialog(NewPromptDialog(Tf("Rules of %s", sp.Name),
+		T("Rules separated by ';', e.g. artist contains Queen; year < 1990. Start with 'match any;' to match any rule."), initial,
+		func(m Model, rules string) (tea.Model, tea.Cmd) {
+			edited, err := parseSmartSummary(sp.Name, rules)
+			if err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			if err := SaveSmartPlaylist(edited); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			tracks, _ := edited.Tracks(m.libraryFiles, m.stats)
+			return m, tea.Batch(loadPlaylists(), func() tea.Msg {
+				return statusMsg(Tf("Saved smart playlist: %s (%d songs)", edited.Name, len(tracks)))
+			})
+		}))
+}
+
+// playSmartPlaylist finds the tracks of a smart playlist in the library and
+// plays them like a saved playlist.
+func (m Model) playSmartPlaylist(sp SmartPlaylist) (tea.Model, tea.Cmd) {
+	tracks, err := sp.Tracks(m.libraryFiles, m.stats)
+	if err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	if len(tracks) == 0 {
+		return m, func() tea.Msg { return statusMsg(Tf("No songs match %s", sp.Name)) }
+	}
+	return m.playPlaylist(Playlist{Name: sp.Name, Tracks: tracks})
+}
+
+// editPlaylist opens a playlist in the editor.
+func (m Model) editPlaylist(pl Playlist, dirty bool) (tea.Model, tea.Cmd) {
+	m.editing = pl
+	m.editorCursor = 0
+	m.editorDirty = dirty
+	m.currentView = ViewPlaylistEditor
+	return m, nil
+}
+
+// closePlaylistEditor returns to the playlists view, asking before
+// discarding unsaved changes.
+func (m Model) closePlaylistEditor() (tea.Model, tea.Cmd) {
+	if !m.editorDirty {
+		return m.openPlaylists()
+	}
+	return m.openDialog(NewConfirmDialog(T("Unsaved changes"),
+		Tf("Discard the changes to %q?", m.editing.Name),
+		func(m Model, _ string) (tea.Model, tea.Cmd) {
+			m.editorDirty = false
+			return m.openPlaylists()
+		}))
+}
+
+// handlePlaylistEditorKeys handles keys in the playlist editor.
+func (m Model) handlePlaylistEditorKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tracks := m.editing.Tracks
+	if cursor, ok := m.navigateList(msg.String(), m.editorCursor, len(tracks)); ok {
+		m.editorCursor = cursor
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "a": // Insert from the library
+		return m.openPicker()
+	case "ctrl+s": // Save
+		if err := SavePlaylist(m.editing); err != nil {
+			return m, func() tea.Msg { return errorMsg(err.Error()) }
+		}
+		m.editorDirty = false
+		name := m.editing.Name
+		return m, func() tea.Msg { return statusMsg(Tf("Saved playlist: %s", name)) }
+	case "p": // Play the whole playlist
+		return m.playPlaylist(m.editing)
+	}
+
+	if m.editorCursor >= len(tracks) {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "shift+up", "K", "ctrl+up", "shift+down", "J", "ctrl+down": // Reorder, as in the queue
+		target := m.editorCursor - 1
+		if k := msg.String(); k == "shift+down" || k == "J" || k == "ctrl+down" {
+			target = m.editorCursor + 1
+		}
+		if target < 0 || target >= len(tracks) {
+			return m, nil
+		}
+		m.editing.Tracks = moveTrack(tracks, m.editorCursor, target)
+		m.editorCursor = target
+		m.editorDirty = true
+	case "x", "delete": // Remove entry
+		m.editing.Tracks = append(append([]MusicFile{}, tracks[:m.editorCursor]...), tracks[m.editorCursor+1:]...)
+		if m.editorCursor >= len(m.editing.Tracks) && m.editorCursor > 0 {
+			m.editorCursor--
+		}
+		m.editorDirty = true
+	case "enter": // Play from this entry on, in the order shown
+		return m.playPlaylistFrom(m.editing, m.editorCursor)
+	}
+	return m, nil
+}
+
+// moveTrack returns a copy of tracks with the entry at from moved to to.
+func moveTrack(tracks []MusicFile, from, to int) []MusicFile {
+	moved := append([]MusicFile{}, tracks...)
+	moved[from], moved[to] = moved[to], moved[from]
+	return moved
+}
+
+// playPlaylist plays the first track of a playlist and queues the rest,
+// replacing the current queue.
+func (m Model) playPlaylist(pl Playlist) (tea.Model, tea.Cmd) {
+	if len(pl.Tracks) == 0 {
+		return m, func() tea.Msg { return statusMsg(T("Playlist is empty")) }
+	}
+	return m.playPlaylistFrom(pl, 0)
+}
+
+// playPlaylistFrom plays the track at start and queues the ones after it,
+// replacing the current queue, so playback follows the playlist's order.
+func (m Model) playPlaylistFrom(pl Playlist, start int) (tea.Model, tea.Cmd) {
+	if err := m.player.PlayTrack(pl.Tracks[start]); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	m.player.ClearQueue()
+	for _, t := range pl.Tracks[start+1:] {
+		m.player.Enqueue(t)
+	}
+	if start > 0 {
+		return m, func() tea.Msg { return statusMsg(Tf("Playing playlist %s from %s", pl.Name, pl.Tracks[start].Name)) }
+	}
+	return m, func() tea.Msg { return statusMsg(Tf("Playing playlist: %s", pl.Name)) }
+}
+
+// promptAddToPlaylist asks for a playlist name and appends files to it.
+func (m Model) promptAddToPlaylist(files []MusicFile) (tea.Model, tea.Cmd) {
+	if len(files) == 0 {
+		return m, nil
+	}
+	message := Tf("Add %q to playlist:", files[0].Name)
+	if len(files) > 1 {
+		message = Tf("Add %d songs to playlist:", len(files))
+	}
+
+	return m.openDialog(NewPromptDialog(T("Add to playlist"), message, m.lastPlaylist,
+		func(m Model, name string) (tea.Model, tea.Cmd) {
+			if err := ValidatePlaylistName(name); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			if err := AddToPlaylist(name, files); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			m.lastPlaylist = name
+			m.librarySel.clear()
+			return m, func() tea.Msg { return statusMsg(Tf("Added %d songs to %s", len(files), name)) }
+		}))
+}
+
+// openPicker shows the library picker used to insert tracks into the playlist.
+func (m Model) openPicker() (tea.Model, tea.Cmd) {
+	m.pickerOpen = true
+	m.pickerCursor = 0
+	m.pickerInput.SetValue("")
+	m.pickerInput.Focus()
+	return m, textinput.Blink
+}
+
+// pickerMatches returns the library tracks matching the picker's query.
+func (m Model) pickerMatches() []MusicFile {
+	query := m.pickerInput.Value()
+
+	type scored struct {
+		file  MusicFile
+		score int
+	}
+	var matches []scored
+	for _, f := range m.libraryFiles {
+		if score, ok := fuzzyScore(query, f.Name); ok {
+			matches = append(matches, scored{file: f, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > finderMaxResults {
+		matches = matches[:finderMaxResults]
+	}
+
+	files := make([]MusicFile, len(matches))
+	for i, match := range matches {
+		files[i] = match.file
+	}
+	return files
+}
+
+// handlePickerKeys handles keys while the library picker is open.
+// Enter inserts the track below the editor cursor and keeps the picker open.
+func (m Model) handlePickerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.pickerOpen = false
+		m.pickerInput.Blur()
+		return m, nil
+	case "up", "ctrl+k":
+		if m.pickerCursor > 0 {
+			m.pickerCursor--
+		}
+		return m, nil
+	case "down", "ctrl+j":
+		if m.pickerCursor < len(m.pickerMatches())-1 {
+			m.pickerCursor++
+		}
+		return m, nil
+	case "enter":
+		matches := m.pickerMatches()
+		if m.pickerCursor >= len(matches) {
+			return m, nil
+		}
+		file := matches[m.pickerCursor]
+
+		at := m.editorCursor + 1
+		if len(m.editing.Tracks) == 0 {
+			at = 0
+		}
+		tracks := append([]MusicFile{}, m.editing.Tracks[:at]...)
+		tracks = append(tracks, file)
+		m.editing.Tracks = append(tracks, m.editing.Tracks[at:]...)
+		m.editorCursor = at
+		m.editorDirty = true
+		return m, func() tea.Msg { return statusMsg(Tf("Inserted: %s", file.Name)) }
+	}
+
+	var cmd tea.Cmd
+	m.pickerInput, cmd = m.pickerInput.Update(msg)
+	m.pickerCursor = 0
+	return m, cmd
+}
+
+// renderPlaylistsView renders the list of saved playlists.
+func (m Model) renderPlaylistsView() string {
+	var b strings.Builder
+
+	b.WriteString(headerStyle.Render(" 🎼 "+T("Playlists")+" ") + "\n\n")
+
+	if len(m.playlistNames) == 0 {
+		b.WriteString(mutedStyle.Render(T("No playlists yet") + "\n"))
+		b.WriteString(mutedStyle.Render(T("Press 'n' to create one, or 'p' in the library to add songs to one") + "\n"))
+		b.WriteString(mutedStyle.Render(T("Press 'S' for a smart playlist that picks songs by rules") + "\n"))
+		return b.String()
+	}
+
+	b.WriteString(m.playlistsScroll.render(len(m.playlistNames), 1, m.maxVisible(), func(i int) string {
+		entry := m.playlistNames[i]
+		rules := ""
+		if sp, ok := m.smartPlaylistAt(i); ok {
+			entry = "✦ " + entry
+			rules = mutedStyle.Render("  " + sp.Summary())
+		}
+		if i == m.playlistsCursor {
+			return selectedStyle.Render("> "+entry) + rules
+		}
+		return normalStyle.Render("  "+entry) + rules
+	}))
+
+	return b.String()
+}
+
+// renderPlaylistEditor renders the playlist editor.
+func (m Model) renderPlaylistEditor() string {
+	var b strings.Builder
+
+	title := " ✎ " + m.editing.Name + " "
+	if m.editorDirty {
+		title += T("(unsaved)") + " "
+	}
+	b.WriteString(headerStyle.Render(title) + "\n\n")
+
+	if len(m.editing.Tracks) == 0 {
+		b.WriteString(mutedStyle.Render(T("Playlist is empty") + "\n"))
+		b.WriteString(mutedStyle.Render(T("Press 'a' to insert songs from the library") + "\n"))
+		return b.String()
+	}
+
+	b.WriteString(m.editorScroll.render(len(m.editing.Tracks), 1, m.maxVisible(), func(i int) string {
+		entry := fmt.Sprintf("%2d. %s", i+1, m.editing.Tracks[i].Name)
+		if i == m.editorCursor {
+			return selectedStyle.Render("> " + entry)
+		}
+		return normalStyle.Render("  " + entry)
+	}))
+
+	return b.String()
+}
+
+// renderPicker renders the library picker overlay.
+func (m Model) renderPicker() string {
+	var b strings.Builder
+
+	b.WriteString(headerStyle.Render(" ➕ "+Tf("Insert into %s", m.editing.Name)+" ") + "\n\n")
+	b.WriteString(m.pickerInput.View() + "\n\n")
+
+	matches := m.pickerMatches()
+	if len(matches) == 0 {
+		b.WriteString(mutedStyle.Render(T("No matches")))
+	}
+	for i, file := range matches {
+		if i == m.pickerCursor {
+			b.WriteString(selectedStyle.Render("> "+file.Name) + "\n")
+		} else {
+			b.WriteString(normalStyle.Render("  "+file.Name) + "\n")
+		}
+	}
+
+	width := m.width * 2 / 3
+	if width < 40 {
+		width = 40
+	}
+	overlay := boxStyle.Width(width).Render(strings.TrimRight(b.String(), "\n"))
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, overlay)
+}
@@ -0,0 +1,372 @@
+// Package main provides Lua plugins for Personal Musician.
+// Every *.lua file in the plugins directory is loaded at startup. Scripts
+// register hooks on player and download events with pm.on and can queue
+// tracks, search, start downloads, show notifications and call web APIs,
+// so custom behavior such as scrobblers, tagging rules or lighting effects
+// needs no changes to the player. All scripts run on one goroutine, one
+// hook at a time, and a hook that runs too long is stopped.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// pluginHookTimeout bounds loading a script and each hook call.
+const pluginHookTimeout = 10 * time.Second
+
+// pluginCloseTimeout bounds the quit hooks at shutdown.
+const pluginCloseTimeout = 5 * time.Second
+
+// PluginDir holds the Lua plugins. The config file can change it.
+var PluginDir = configPath("plugins")
+
+// pluginEvents are the events scripts can hook with pm.on.
+var pluginEvents = []string{"start", "track", "state", "download", "quit"}
+
+// plugin is one loaded script.
+type plugin struct {
+	name  string // File name without extension
+	L     *lua.LState
+	hooks map[string][]*lua.LFunction
+}
+
+// PluginHost runs the plugins and feeds them events.
+type PluginHost struct {
+	paths      []string
+	plugins    []*plugin
+	player     *Player
+	downloader *Downloader
+	program    *tea.Program
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// pluginPaths returns the scripts in dir, sorted by name.
+func pluginPaths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".lua") {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// StartPlugins loads the scripts in dir and starts feeding them events. It
+// returns nil when there are none.
+func StartPlugins(dir string, player *Player, downloader *Downloader, program *tea.Program) (*PluginHost, error) {
+	paths, err := pluginPaths(dir)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	h := &PluginHost{
+		paths:      paths,
+		player:     player,
+		downloader: downloader,
+		program:    program,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+// Close runs the quit hooks and stops the plugins.
+func (h *PluginHost) Close() {
+	close(h.stop)
+	select {
+	case <-h.done:
+	case <-time.After(pluginCloseTimeout):
+		slog.Warn("plugins did not stop in time")
+	}
+}
+
+// run loads the scripts, then polls the player and the downloads and
+// passes what changed to the hooks.
+func (h *PluginHost) run() {
+	defer close(h.done)
+	for _, path := range h.paths {
+		if p, err := h.load(path); err != nil {
+			h.report(err)
+		} else {
+			h.plugins = append(h.plugins, p)
+		}
+	}
+	defer func() {
+		for _, p := range h.plugins {
+			p.L.Close()
+		}
+	}()
+	slog.Info("plugins loaded", "count", len(h.plugins))
+	h.fire("start")
+
+	ticker := time.NewTicker(eventInterval)
+	defer ticker.Stop()
+	last := newPlayerStatus(h.player.GetState())
+	downloads := make(map[int]string)
+	for _, item := range h.downloader.Downloads() {
+		downloads[item.ID] = item.State.String()
+	}
+
+	for {
+		select {
+		case <-h.stop:
+			h.fire("quit")
+			return
+		case <-ticker.C:
+		}
+
+		status := newPlayerStatus(h.player.GetState())
+		switch {
+		case status.Path != last.Path && status.Path != "":
+			h.fire("track", status)
+		case status.State != last.State:
+			h.fire("state", status)
+		}
+		last = status
+
+		// Report downloads as they are queued, start, finish or fail
+		for _, item := range h.downloader.Downloads() {
+			if state := item.State.String(); downloads[item.ID] != state {
+				downloads[item.ID] = state
+				h.fire("download", newDownloadEvent(item))
+			}
+		}
+	}
+}
+
+// load runs a script, which registers its hooks.
+func (h *PluginHost) load(path string) (*plugin, error) {
+	p := &plugin{
+		name:  strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		L:     lua.NewState(),
+		hooks: make(map[string][]*lua.LFunction),
+	}
+	p.L.SetGlobal("pm", h.api(p))
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginHookTimeout)
+	defer cancel()
+	p.L.SetContext(ctx)
+	defer p.L.RemoveContext()
+	if err := p.L.DoFile(path); err != nil {
+		p.L.Close()
+		return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	return p, nil
+}
+
+// fire calls every hook registered for event, passing data as a table.
+// A failing hook is reported and does not stop the others.
+func (h *PluginHost) fire(event string, data ...any) {
+	for _, p := range h.plugins {
+		for _, fn := range p.hooks[event] {
+			args := make([]lua.LValue, len(data))
+			for i, d := range data {
+				args[i] = luaJSON(p.L, d)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), pluginHookTimeout)
+			p.L.SetContext(ctx)
+			err := p.L.CallByParam(lua.P{Fn: fn, Protect: true}, args...)
+			p.L.RemoveContext()
+			cancel()
+			if err != nil {
+				h.report(fmt.Errorf("plugin %s: %s hook: %w", p.name, event, err))
+			}
+		}
+	}
+}
+
+// report logs a plugin error and shows it in the TUI.
+func (h *PluginHost) report(err error) {
+	slog.Error("plugin failed", "err", err)
+	appLog.Add("plugin", err.Error())
+	h.program.Send(errorMsg(err.Error()))
+}
+
+// control sends a request to the TUI as ctl does and returns its answer.
+func (h *PluginHost) control(command string, args ...string) instanceResponse {
+	reply := make(chan instanceResponse, 1)
+	h.program.Send(remoteMsg{req: instanceRequest{Command: command, Args: args}, reply: reply})
+	select {
+	case resp := <-reply:
+		return resp
+	case <-time.After(instanceTimeout):
+		return instanceResponse{Message: "the player did not answer"}
+	}
+}
+
+// api returns the pm table of functions scripts call.
+func (h *PluginHost) api(p *plugin) *lua.LTable {
+	// answer pushes true, or nil and the error message.
+	answer := func(L *lua.LState, resp instanceResponse) int {
+		if !resp.OK {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(resp.Message))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		return 1
+	}
+
+	pm := p.L.NewTable()
+	p.L.SetFuncs(pm, map[string]lua.LGFunction{
+		// pm.on(event, fn) registers a hook
+		"on": func(L *lua.LState) int {
+			event := L.CheckString(1)
+			fn := L.CheckFunction(2)
+			if !slices.Contains(pluginEvents, event) {
+				L.ArgError(1, fmt.Sprintf("unknown event %q (known: %s)", event, strings.Join(pluginEvents, ", ")))
+			}
+			p.hooks[event] = append(p.hooks[event], fn)
+			return 0
+		},
+		// pm.status() returns the player's state
+		"status": func(L *lua.LState) int {
+			L.Push(luaJSON(L, newPlayerStatus(h.player.GetState())))
+			return 1
+		},
+		// pm.queue(target) queues a file, folder, playlist or YouTube URL
+		"queue": func(L *lua.LState) int {
+			return answer(L, h.control("add", L.CheckString(1)))
+		},
+		"play_pause": func(L *lua.LState) int { return answer(L, h.control("play-pause")) },
+		"next":       func(L *lua.LState) int { return answer(L, h.control("next")) },
+		"prev":       func(L *lua.LState) int { return answer(L, h.control("prev")) },
+		// pm.search(query) returns a list of YouTube results
+		"search": func(L *lua.LState) int {
+			results, err := SearchYouTube(L.CheckString(1))
+			if err != nil {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(err.Error()))
+				return 2
+			}
+			list := L.NewTable()
+			for _, r := range results {
+				t := L.NewTable()
+				t.RawSetString("video_id", lua.LString(r.VideoID))
+				t.RawSetString("title", lua.LString(r.Title))
+				t.RawSetString("channel", lua.LString(r.Channel))
+				t.RawSetString("duration", lua.LString(r.Duration))
+				list.Append(t)
+			}
+			L.Push(list)
+			return 1
+		},
+		// pm.download(video_id, title) queues a download
+		"download": func(L *lua.LState) int {
+			h.program.Send(titleResolvedMsg{VideoID: L.CheckString(1), Title: L.OptString(2, L.CheckString(1))})
+			return 0
+		},
+		// pm.notify(text) shows text in the status bar
+		"notify": func(L *lua.LState) int {
+			h.program.Send(statusMsg(L.CheckString(1)))
+			return 0
+		},
+		// pm.log(text) adds a line to the in-app log
+		"log": func(L *lua.LState) int {
+			appLog.Add(p.name, L.CheckString(1))
+			return 0
+		},
+		// pm.http_get(url [, headers]) returns the body and status code
+		"http_get": func(L *lua.LState) int {
+			return h.httpRequest(L, http.MethodGet, L.CheckString(1), "", L.OptTable(2, nil))
+		},
+		// pm.http_post(url, body [, headers]) returns the body and status code
+		"http_post": func(L *lua.LState) int {
+			return h.httpRequest(L, http.MethodPost, L.CheckString(1), L.CheckString(2), L.OptTable(3, nil))
+		},
+	})
+	return pm
+}
+
+// httpRequest makes a request for a script. It pushes the body and status
+// code, or nil and the error message.
+func (h *PluginHost) httpRequest(L *lua.LState, method, url, body string, headers *lua.LTable) int {
+	fail := func(err error) int {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	req, err := http.NewRequestWithContext(L.Context(), method, url, strings.NewReader(body))
+	if err != nil {
+		return fail(fmt.Errorf("failed to create request: %w", err))
+	}
+	if headers != nil {
+		headers.ForEach(func(k, v lua.LValue) {
+			req.Header.Set(k.String(), v.String())
+		})
+	}
+	resp, err := httpDo("plugin", req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read response: %w", err))
+	}
+	L.Push(lua.LString(data))
+	L.Push(lua.LNumber(resp.StatusCode))
+	return 2
+}
+
+// luaJSON converts v to Lua through its JSON form, so tables use the same
+// field names as the event stream.
+func luaJSON(L *lua.LState, v any) lua.LValue {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return lua.LNil
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return lua.LNil
+	}
+	return luaValue(L, decoded)
+}
+
+// luaValue converts a decoded JSON value to Lua.
+func luaValue(L *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []any:
+		t := L.NewTable()
+		for _, e := range v {
+			t.Append(luaValue(L, e))
+		}
+		return t
+	case map[string]any:
+		t := L.NewTable()
+		for k, e := range v {
+			t.RawSetString(k, luaValue(L, e))
+		}
+		return t
+	}
+	return lua.LNil
+}
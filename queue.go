@@ -0,0 +1,136 @@
+// Package main provides the play queue for Personal Musician.
+// Queued tracks play before the playlist continues, in the order they were added.
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Enqueue appends a track to the end of the play queue.
+func (p *Player) Enqueue(file MusicFile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = append(p.queue, file)
+	p.queueVersion++
+	p.invalidateNextLocked()
+}
+
+// PlayNext puts a track at the front of the play queue.
+func (p *Player) PlayNext(file MusicFile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = append([]MusicFile{file}, p.queue...)
+	p.queueVersion++
+	p.invalidateNextLocked()
+}
+
+// GetQueue returns a copy of the upcoming tracks in the play queue.
+func (p *Player) GetQueue() []MusicFile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := make([]MusicFile, len(p.queue))
+	copy(queue, p.queue)
+	return queue
+}
+
+// QueueRange returns a copy of the queue entries from start up to end,
+// so views can show part of a long queue without copying all of it.
+func (p *Player) QueueRange(start, end int) []MusicFile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	end = min(end, len(p.queue))
+	start = min(max(start, 0), end)
+	return slices.Clone(p.queue[start:end])
+}
+
+// MoveQueueItem moves the queue entry at index by delta positions.
+// Returns the new index of the entry.
+func (p *Player) MoveQueueItem(index, delta int) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.queue) {
+		return index, fmt.Errorf("index out of range")
+	}
+
+	target := index + delta
+	if target < 0 || target >= len(p.queue) {
+		return index, nil
+	}
+
+	p.queue[index], p.queue[target] = p.queue[target], p.queue[index]
+	p.queueVersion++
+	p.invalidateNextLocked()
+	return target, nil
+}
+
+// RemoveFromQueue removes the queue entry at index.
+func (p *Player) RemoveFromQueue(index int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.queue) {
+		return fmt.Errorf("index out of range")
+	}
+	p.queue = append(p.queue[:index], p.queue[index+1:]...)
+	p.queueVersion++
+	p.invalidateNextLocked()
+	return nil
+}
+
+// ClearQueue removes all entries from the play queue.
+func (p *Player) ClearQueue() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = nil
+	p.queueVersion++
+	p.invalidateNextLocked()
+}
+
+// PlayQueueItem plays the queue entry at index, dropping it and everything before it.
+func (p *Player) PlayQueueItem(index int) error {
+	p.mu.Lock()
+	if index < 0 || index >= len(p.queue) {
+		p.mu.Unlock()
+		return fmt.Errorf("index out of range")
+	}
+	file := p.queue[index]
+	p.queue = p.queue[index+1:]
+	p.queueVersion++
+	p.currentIndex = p.playlistIndexLocked(file.Path)
+	p.mu.Unlock()
+
+	return p.PlayFile(file.Path)
+}
+
+// popQueueLocked removes and returns the next queued track.
+// Returns false if the queue is empty. Caller must hold p.mu.
+func (p *Player) popQueueLocked() (MusicFile, bool) {
+	if len(p.queue) == 0 {
+		return MusicFile{}, false
+	}
+	file := p.queue[0]
+	p.queue = p.queue[1:]
+	p.queueVersion++
+	return file, true
+}
+
+// QueueVersion returns a number that changes whenever the queue does, so
+// callers can tell when to save it.
+func (p *Player) QueueVersion() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queueVersion
+}
+
+// playlistIndexLocked returns the playlist index of a path, or the current index
+// if the path is not in the playlist. Caller must hold p.mu.
+func (p *Player) playlistIndexLocked(path string) int {
+	for i, f := range p.playlist {
+		if f.Path == path {
+			return i
+		}
+	}
+	return p.currentIndex
+}
@@ -0,0 +1,37 @@
+// Package main provides track ratings for Personal Musician.
+// Pressing 1 to 5 in the library gives the selected song that many stars
+// and 0 clears its rating. Ratings are kept with the other statistics in
+// stats.json, shown next to the song and in its details, and the library
+// can be sorted by them or limited to songs rated at least so many stars.
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxRating is the highest rating, in stars.
+const maxRating = 5
+
+// ratingStars returns a rating as filled stars, padded with empty ones to
+// maxRating when pad is set. An unrated track has no stars unless padded.
+func ratingStars(rating int, pad bool) string {
+	stars := strings.Repeat("★", rating)
+	if pad {
+		stars += strings.Repeat("☆", maxRating-rating)
+	}
+	return stars
+}
+
+// rateTrack sets the rating of a library track and reports it in the
+// status bar.
+func (m Model) rateTrack(file MusicFile, rating int) (tea.Model, tea.Cmd) {
+	if err := m.stats.SetRating(file.Path, rating); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	if rating == 0 {
+		return m, func() tea.Msg { return statusMsg(Tf("Cleared the rating of %s", file.Name)) }
+	}
+	return m, func() tea.Msg { return statusMsg(Tf("Rated %s: %s", file.Name, ratingStars(rating, true))) }
+}
@@ -0,0 +1,161 @@
+// Package main provides the recently added and recently played views for
+// Personal Musician. Both are the library limited to some of its songs:
+// those downloaded or copied in within the last days (by file modification
+// time, newest first) and those most recently played to the end (from the
+// listening history, newest first). Tab steps through the library, the two
+// views and on to the queue, and the sort/filter menu picks one directly.
+package main
+
+import (
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultRecentDays is how far back "recently added" reaches unless
+// recent_days under [library] says otherwise.
+const defaultRecentDays = 30
+
+// recentPlayedLimit is the most songs the recently played view lists.
+const recentPlayedLimit = 200
+
+// LibraryScope selects which songs the library view shows.
+type LibraryScope int
+
+const (
+	ScopeAll            LibraryScope = iota // The whole library
+	ScopeRecentlyAdded                      // Added within recentDays
+	ScopeRecentlyPlayed                     // Latest plays in the history
+	scopeCount
+)
+
+// String returns the display name of the scope.
+func (s LibraryScope) String() string {
+	switch s {
+	case ScopeRecentlyAdded:
+		return "Recently added"
+	case ScopeRecentlyPlayed:
+		return "Recently played"
+	default:
+		return "Library"
+	}
+}
+
+// recentDays returns how many days back the recently added view reaches.
+func recentDays() int {
+	if config.Library.RecentDays > 0 {
+		return config.Library.RecentDays
+	}
+	return defaultRecentDays
+}
+
+// recentPlaysMsg carries the paths of the latest plays, newest first.
+type recentPlaysMsg struct {
+	paths []string
+	stats int // StatsStore.Version when the history was read
+	err   error
+}
+
+// loadRecentPlays reads the listening history in the background and keeps
+// each track's latest play.
+func loadRecentPlays(statsVersion int) tea.Cmd {
+	return func() tea.Msg {
+		plays, err := ReadHistory(HistoryFile)
+		seen := make(map[string]bool)
+		var paths []string
+		for i := len(plays) - 1; i >= 0 && len(paths) < recentPlayedLimit; i-- {
+			if path := plays[i].Path; !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+		return recentPlaysMsg{paths: paths, stats: statsVersion, err: err}
+	}
+}
+
+// refreshRecentPlays reloads the recently played songs when that view is
+// showing and a play has been recorded since they were read.
+func (m Model) refreshRecentPlays() (Model, tea.Cmd) {
+	if m.libraryView.Scope != ScopeRecentlyPlayed {
+		return m, nil
+	}
+	version := m.stats.Version()
+	if m.recentLoading || (m.recentPlays != nil && m.recentStats == version) {
+		return m, nil
+	}
+	m.recentLoading = true
+	return m, loadRecentPlays(version)
+}
+
+// applyRecentPlays stores the loaded recently played songs.
+func (m Model) applyRecentPlays(msg recentPlaysMsg) (Model, tea.Cmd) {
+	m.recentLoading = false
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	m.recentPlays = msg.paths
+	if m.recentPlays == nil {
+		m.recentPlays = []string{} // Loaded, but nothing played yet
+	}
+	m.recentStats = msg.stats
+	m.libraryGen++
+	return m, nil
+}
+
+// scopedLibrary returns the library songs in the current scope, in the
+// scope's order.
+func (m Model) scopedLibrary() []MusicFile {
+	switch m.libraryView.Scope {
+	case ScopeRecentlyAdded:
+		since := time.Now().AddDate(0, 0, -recentDays())
+		var files []MusicFile
+		for _, f := range m.libraryFiles {
+			if f.ModTime.After(since) {
+				files = append(files, f)
+			}
+		}
+		slices.SortStableFunc(files, func(a, b MusicFile) int { return b.ModTime.Compare(a.ModTime) })
+		return files
+	case ScopeRecentlyPlayed:
+		byPath := make(map[string]MusicFile, len(m.libraryFiles))
+		for _, f := range m.libraryFiles {
+			byPath[f.Path] = f
+		}
+		var files []MusicFile
+		for _, path := range m.recentPlays {
+			if f, ok := byPath[path]; ok {
+				files = append(files, f)
+			}
+		}
+		return files
+	default:
+		return m.libraryFiles
+	}
+}
+
+// setLibraryScope switches the library view to another scope.
+func (m Model) setLibraryScope(scope LibraryScope) (Model, tea.Cmd) {
+	m.libraryView.Scope = scope
+	m.libraryCursor = 0
+	m.libraryGen++
+	return m.refreshRecentPlays()
+}
+
+// emptyScopeMessage explains why a scope has no songs.
+func (m Model) emptyScopeMessage() string {
+	if len(m.scopedLibrary()) > 0 {
+		return T("No songs match the filter")
+	}
+	switch m.libraryView.Scope {
+	case ScopeRecentlyAdded:
+		return Tf("No songs added in the last %d days", recentDays())
+	case ScopeRecentlyPlayed:
+		if m.recentPlays == nil {
+			return T("Loading...")
+		}
+		return T("Nothing played to the end yet")
+	default:
+		return T("No songs match the filter")
+	}
+}
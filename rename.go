@@ -0,0 +1,304 @@
+// Package main provides renaming and moving library tracks for Personal
+// Musician. R (or F2) in the library edits the selected song's path in
+// place, relative to its music folder, so it can be renamed or moved into
+// a subfolder; with songs marked, it asks for a folder to move them all
+// into. Playlists, the queue, play counts, ratings, episode progress and
+// the library index follow the files to their new paths, and the playing
+// track keeps playing.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// musicRoot returns the music directory path lies in, or MusicDir when it
+// lies in none.
+func musicRoot(path string) string {
+	for _, dir := range append([]string{MusicDir}, extraMusicDirs...) {
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return dir
+		}
+	}
+	return MusicDir
+}
+
+// movedTo returns the file as found at path after a move.
+func (f MusicFile) movedTo(path string) MusicFile {
+	f.Path = path
+	f.FileName = filepath.Base(path)
+	f.Name = strings.TrimSuffix(f.FileName, filepath.Ext(f.FileName))
+	return f
+}
+
+// renameTarget returns where a file goes when given name: a path relative
+// to its music directory, or an absolute one in any music directory. The
+// file keeps its extension when name leaves it out.
+func renameTarget(file MusicFile, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.HasSuffix(name, "/") || strings.HasSuffix(name, string(filepath.Separator)) {
+		return "", fmt.Errorf("a file name is needed")
+	}
+	if ext := filepath.Ext(file.Path); !strings.EqualFold(filepath.Ext(name), ext) {
+		name += ext
+	}
+	target := name
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(musicRoot(file.Path), target)
+	}
+	target = filepath.Clean(target)
+	if !inMusicDirs(target) {
+		return "", fmt.Errorf("%s is outside the music folders", target)
+	}
+	return target, nil
+}
+
+// RenameTracks points the playlist, the queue and the playing track at the
+// new paths of moved tracks, given by old path in moved.
+func (p *Player) RenameTracks(moved map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if to, ok := moved[p.currentFile]; ok {
+		p.currentFile = to
+	}
+	// The library shares the playlist's slice, so it is copied first
+	p.playlist = slices.Clone(p.playlist)
+	for i, f := range p.playlist {
+		if to, ok := moved[f.Path]; ok {
+			p.playlist[i] = f.movedTo(to)
+		}
+	}
+	for i, f := range p.queue {
+		if to, ok := moved[f.Path]; ok {
+			p.queue[i] = f.movedTo(to)
+			p.queueVersion++
+		}
+	}
+	for i, path := range p.shuffleHistory {
+		if to, ok := moved[path]; ok {
+			p.shuffleHistory[i] = to
+		}
+	}
+	for from, to := range moved {
+		if p.shufflePlayed[from] {
+			delete(p.shufflePlayed, from)
+			p.shufflePlayed[to] = true
+		}
+	}
+	p.invalidateNextLocked() // The next track may have been prepared from an old path
+}
+
+// moveTrack moves a track on disk. Where the system doesn't allow moving
+// an open file, the playing track is stopped for the move and resumed from
+// the same position at its new path.
+func (p *Player) moveTrack(from, to string) error {
+	err := MoveMusicFile(from, to)
+	state := p.GetState()
+	if err == nil || errors.Is(err, fs.ErrExist) || state.CurrentFile != from {
+		return err
+	}
+
+	p.Stop()
+	path := to
+	if err = MoveMusicFile(from, to); err != nil {
+		path = from // Carry on where it was
+	}
+	if playErr := p.PlayFile(path); playErr == nil {
+		p.SeekTo(state.Position)
+		if state.IsPaused {
+			p.TogglePause()
+		}
+	}
+	return err
+}
+
+// tracksMovedMsg reports the outcome of renaming or moving tracks.
+type tracksMovedMsg struct {
+	moved map[string]string // New path of each track moved, by old path
+	err   error             // Last failure, if any
+}
+
+// moveTracks returns a command that moves tracks to the paths given by old
+// path in moves and updates everything that refers to them.
+func (m Model) moveTracks(moves map[string]string) tea.Cmd {
+	player, stats, episodes, library := m.player, m.stats, m.episodes, m.library
+	return func() tea.Msg {
+		msg := tracksMovedMsg{moved: make(map[string]string)}
+		for from, to := range moves {
+			if err := player.moveTrack(from, to); err != nil {
+				msg.err = err
+				continue
+			}
+			msg.moved[from] = to
+		}
+		if len(msg.moved) == 0 {
+			return msg
+		}
+
+		player.RenameTracks(msg.moved)
+		libraryMetadata.move(msg.moved)
+		if err := stats.Move(msg.moved); err != nil {
+			msg.err = err
+		}
+		if episodes != nil {
+			if err := episodes.Move(msg.moved); err != nil {
+				msg.err = err
+			}
+		}
+		if _, err := RepointPlaylists(msg.moved); err != nil {
+			msg.err = fmt.Errorf("failed to update playlists: %w", err)
+		}
+		var paths []string
+		for from, to := range msg.moved {
+			paths = append(paths, from, to)
+		}
+		library.RefreshPaths(paths)
+		return msg
+	}
+}
+
+// applyTracksMoved shows moved tracks at their new paths straight away,
+// keeping the library cursor on the same song.
+func (m Model) applyTracksMoved(msg tracksMovedMsg) (Model, tea.Cmd) {
+	if len(msg.moved) > 0 {
+		var selected string
+		if files := m.visibleLibrary(); m.libraryCursor < len(files) {
+			selected = files[m.libraryCursor].Path
+		}
+		if to, ok := msg.moved[selected]; ok {
+			selected = to
+		}
+
+		m.libraryFiles = slices.Clone(m.libraryFiles) // The player holds the old slice
+		for i, f := range m.libraryFiles {
+			if to, ok := msg.moved[f.Path]; ok {
+				m.libraryFiles[i] = f.movedTo(to)
+			}
+		}
+		for i, f := range m.editing.Tracks {
+			if to, ok := msg.moved[f.Path]; ok {
+				m.editing.Tracks[i] = f.movedTo(to)
+			}
+		}
+		m = m.applyLibraryView()
+		if i := slices.IndexFunc(m.visibleLibrary(), func(f MusicFile) bool { return f.Path == selected }); i >= 0 {
+			m.libraryCursor = i
+		}
+	}
+
+	var status string
+	switch {
+	case len(msg.moved) == 1 && msg.err == nil:
+		for _, to := range msg.moved {
+			status = Tf("Renamed to %s", relToMusicDir(to))
+		}
+	case msg.err != nil && len(msg.moved) == 0:
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	case msg.err != nil:
+		status = Tf("Moved %d songs, error: %v", len(msg.moved), msg.err)
+	default:
+		status = Tf("Moved %d songs", len(msg.moved))
+	}
+	return m, func() tea.Msg { return statusMsg(status) }
+}
+
+// relToMusicDir returns path relative to its music directory.
+func relToMusicDir(path string) string {
+	if rel, err := filepath.Rel(musicRoot(path), path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// startRename edits the path of the selected library song in place, or,
+// with songs marked, asks for a folder to move them into.
+func (m Model) startRename() (tea.Model, tea.Cmd) {
+	if m.librarySel.count() > 0 {
+		return m.promptMoveTracks(m.markedLibrary())
+	}
+	files := m.visibleLibrary()
+	if m.libraryCursor >= len(files) {
+		return m, nil
+	}
+	file := files[m.libraryCursor]
+
+	input := textinput.New()
+	input.Prompt = ""
+	input.CharLimit = 255
+	input.Width = max(m.width-10, 20)
+	input.SetValue(relToMusicDir(file.Path))
+	input.Focus()
+	m.renameInput = input
+	m.renaming = &file
+	return m, textinput.Blink
+}
+
+// renameTrack asks for the new path of a track in a dialog, for views
+// without an inline editor.
+func (m Model) renameTrack(file MusicFile) (tea.Model, tea.Cmd) {
+	return m.openDialog(NewPromptDialog(T("Rename or move"), Tf("New name of %q, relative to its music folder:", file.FileName), relToMusicDir(file.Path),
+		func(m Model, name string) (tea.Model, tea.Cmd) {
+			return m.submitRename(file, name)
+		}))
+}
+
+// submitRename moves a track to the path typed for it.
+func (m Model) submitRename(file MusicFile, name string) (tea.Model, tea.Cmd) {
+	target, err := renameTarget(file, name)
+	if err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+	if target == file.Path {
+		return m, nil
+	}
+	return m, m.moveTracks(map[string]string{file.Path: target})
+}
+
+// promptMoveTracks asks for a folder, relative to the first track's music
+// directory, and moves the tracks into it.
+func (m Model) promptMoveTracks(files []MusicFile) (tea.Model, tea.Cmd) {
+	if len(files) == 0 {
+		return m, nil
+	}
+	return m.openDialog(NewPromptDialog(T("Move songs"), Tf("Move %d songs to the folder, relative to the music folder:", len(files)), "",
+		func(m Model, folder string) (tea.Model, tea.Cmd) {
+			folder = strings.TrimSpace(folder)
+			moves := make(map[string]string)
+			for _, file := range files {
+				target, err := renameTarget(file, filepath.Join(folder, file.FileName))
+				if err != nil {
+					return m, func() tea.Msg { return errorMsg(err.Error()) }
+				}
+				if target != file.Path {
+					moves[file.Path] = target
+				}
+			}
+			m.librarySel.clear()
+			return m, m.moveTracks(moves)
+		}))
+}
+
+// handleRenameKeys handles keys while a library song's path is edited in
+// place. Enter moves it, Esc cancels.
+func (m Model) handleRenameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		file := *m.renaming
+		m.renaming = nil
+		return m.submitRename(file, m.renameInput.Value())
+	case "esc":
+		m.renaming = nil
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.renameInput, cmd = m.renameInput.Update(msg)
+	return m, cmd
+}
@@ -0,0 +1,349 @@
+// Package main provides tag correction for Personal Musician.
+// "Look up tags" in a track's menu searches MusicBrainz for the track by its
+// tags or file name and offers to write the canonical title, artist, album,
+// year and track number to the file. "Identify by sound" does the same for
+// files with nothing to search by, from their audio fingerprint, and the
+// "Identify unknown songs" job tags every untitled song in the library that
+// way. "personal-musician tag" does either for many files at once, printing
+// what it found and writing it with --write.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultMinTagScore is the lowest MusicBrainz score the tag command
+// accepts unless --min-score says otherwise.
+const defaultMinTagScore = 90
+
+// tagLookupMsg carries the result of looking up a track's tags.
+type tagLookupMsg struct {
+	file  MusicFile
+	match *MusicBrainzMatch // nil when nothing was found
+	err   error
+}
+
+// tagsWrittenMsg reports that tags were written to a file.
+type tagsWrittenMsg struct {
+	file MusicFile
+	tags Tags
+	err  error
+}
+
+// lookupTags searches MusicBrainz for a track in the background.
+func (m Model) lookupTags(file MusicFile) tea.Cmd {
+	lookup := func() tea.Msg {
+		file.Tags, _ = ReadTags(file.Path) // The library's copy may not be read yet
+		title, artist := guessTitleArtist(file)
+		matches, err := SearchMusicBrainz(context.Background(), title, artist)
+		if err != nil || len(matches) == 0 {
+			return tagLookupMsg{file: file, err: err}
+		}
+		return tagLookupMsg{file: file, match: &matches[0]}
+	}
+	status := func() tea.Msg { return statusMsg(Tf("Looking up %s…", file.DisplayName())) }
+	return tea.Batch(status, lookup)
+}
+
+// identifyTrack identifies a track by its sound in the background. The
+// result is offered like a tag lookup's.
+func (m Model) identifyTrack(file MusicFile) tea.Cmd {
+	identify := func() tea.Msg {
+		file.Tags, _ = ReadTags(file.Path)
+		match, err := IdentifyTrack(context.Background(), file.Path)
+		return tagLookupMsg{file: file, match: match, err: err}
+	}
+	status := func() tea.Msg { return statusMsg(Tf("Identifying %s…", file.DisplayName())) }
+	return tea.Batch(status, identify)
+}
+
+// applyTagLookup offers to write the tags found for a track.
+func (m Model) applyTagLookup(msg tagLookupMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	if msg.match == nil {
+		return m, func() tea.Msg { return statusMsg(Tf("No match found for %s", msg.file.DisplayName())) }
+	}
+
+	file, tags := msg.file, mergeTags(msg.file.Tags, msg.match.Tags)
+	if tags == file.Tags {
+		return m, func() tea.Msg { return statusMsg(Tf("Tags are already correct: %s", file.DisplayName())) }
+	}
+	return m.openDialog(NewConfirmDialog(T("Look up tags"),
+		Tf("Write these tags to %s? (%d%% match)", file.FileName, msg.match.Score)+"\n\n"+describeTags(tags),
+		func(m Model, _ string) (tea.Model, tea.Cmd) {
+			return m, writeTagsCmd(file, tags)
+		}))
+}
+
+// writeTagsCmd writes tags to a file in the background.
+func writeTagsCmd(file MusicFile, tags Tags) tea.Cmd {
+	return func() tea.Msg {
+		return tagsWrittenMsg{file: file, tags: tags, err: WriteTags(file.Path, tags)}
+	}
+}
+
+// applyTagsWritten shows the new tags in the library.
+func (m Model) applyTagsWritten(msg tagsWrittenMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	}
+	m = m.updateLibraryTags(msg.file.Path, msg.tags)
+	msg.file.Tags = msg.tags
+	return m, func() tea.Msg { return statusMsg(Tf("Tagged: %s", msg.file.DisplayName())) }
+}
+
+// updateLibraryTags replaces the tags of a library file. The library is
+// copied first, as the player holds the old slice.
+func (m Model) updateLibraryTags(path string, tags Tags) Model {
+	i := slices.IndexFunc(m.libraryFiles, func(f MusicFile) bool { return f.Path == path })
+	if i < 0 {
+		return m
+	}
+	m.libraryFiles = slices.Clone(m.libraryFiles)
+	m.libraryFiles[i].Tags = tags
+	m.libraryGen++
+	libraryMetadata.put(m.libraryFiles[i]) // Read again by the next scan if its size changed
+	return m
+}
+
+// identifyJob identifies the library's untitled songs one at a time,
+// writing the tags of confident matches.
+type identifyJob struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	files  []MusicFile
+	done   int // Files looked at so far
+	tagged int // Files whose tags were written
+}
+
+// identifyStepMsg reports the outcome for one file of an identify job.
+type identifyStepMsg struct {
+	job  *identifyJob
+	file MusicFile
+	tags Tags // Written tags; empty when none were
+	err  error
+}
+
+// startIdentifyJob starts identifying the library's MP3 files that have
+// no title tag.
+func (m Model) startIdentifyJob() (tea.Model, tea.Cmd) {
+	if m.identify != nil {
+		return m, func() tea.Msg { return statusMsg(T("Already identifying songs")) }
+	}
+	if err := checkIdentifyTools(); err != nil {
+		return m, func() tea.Msg { return errorMsg(err.Error()) }
+	}
+
+	var files []MusicFile
+	for _, f := range m.libraryFiles {
+		if isMP3(f.Path) && f.Tags.Title == "" {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return m, func() tea.Msg { return statusMsg(T("Every song has a title")) }
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.identify = &identifyJob{ctx: ctx, cancel: cancel, files: files}
+	return m, tea.Batch(m.identify.next(), func() tea.Msg { return statusMsg(Tf("Identifying %d songs", len(files))) })
+}
+
+// next identifies the job's next file.
+func (j *identifyJob) next() tea.Cmd {
+	file := j.files[j.done]
+	return func() tea.Msg {
+		file.Tags, _ = ReadTags(file.Path)
+		if file.Tags.Title != "" {
+			return identifyStepMsg{job: j, file: file} // Tagged since the job started
+		}
+		match, err := IdentifyTrack(j.ctx, file.Path)
+		if err != nil || match == nil || match.Score < defaultMinTagScore {
+			return identifyStepMsg{job: j, file: file, err: err}
+		}
+		tags := mergeTags(file.Tags, match.Tags)
+		if err := WriteTags(file.Path, tags); err != nil {
+			return identifyStepMsg{job: j, file: file, err: err}
+		}
+		return identifyStepMsg{job: j, file: file, tags: tags}
+	}
+}
+
+// applyIdentifyStep records one file's outcome and moves on to the next.
+func (m Model) applyIdentifyStep(msg identifyStepMsg) (Model, tea.Cmd) {
+	job := m.identify
+	if job == nil || msg.job != job {
+		return m, nil // Cancelled
+	}
+	job.done++
+	if msg.err != nil {
+		slog.Warn("failed to identify song", "file", msg.file.Path, "err", msg.err)
+	}
+	if !msg.tags.IsEmpty() {
+		job.tagged++
+		m = m.updateLibraryTags(msg.file.Path, msg.tags)
+	}
+
+	if job.done < len(job.files) {
+		return m, job.next()
+	}
+	m.identify = nil
+	job.cancel()
+	return m, func() tea.Msg { return statusMsg(Tf("Identified %d of %d songs", job.tagged, len(job.files))) }
+}
+
+// cancelIdentifyJob stops identifying songs.
+func (m Model) cancelIdentifyJob() (tea.Model, tea.Cmd) {
+	if m.identify == nil {
+		return m, nil
+	}
+	m.identify.cancel()
+	tagged := m.identify.tagged
+	m.identify = nil
+	return m, func() tea.Msg { return statusMsg(Tf("Stopped identifying songs (%d tagged)", tagged)) }
+}
+
+// renderIdentifyProgress renders the header's indicator for an identify
+// job, e.g. "Identifying 3/40". It is empty when idle.
+func (m Model) renderIdentifyProgress() string {
+	if m.identify == nil {
+		return ""
+	}
+	return mutedStyle.Render(Tf("Identifying %d/%d", m.identify.done, len(m.identify.files)))
+}
+
+// describeTags returns tags on one line, e.g.
+// "Artist – Title · Album (1997) · track 3".
+func describeTags(t Tags) string {
+	parts := []string{MusicFile{Name: "?", Tags: t}.DisplayName()}
+	switch {
+	case t.Album != "" && t.Year != "":
+		parts = append(parts, t.Album+" ("+t.Year+")")
+	case t.Album != "":
+		parts = append(parts, t.Album)
+	case t.Year != "":
+		parts = append(parts, t.Year)
+	}
+	if n := t.TrackNumber(); n > 0 {
+		parts = append(parts, Tf("track %d", n))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// runTag looks up the tags of the given files and directories, or of the
+// whole library, on MusicBrainz and prints the matches; --write saves them.
+// With --identify files are looked up by their sound rather than their
+// names. Unless --all is given, files that already have a title, artist and
+// album are skipped.
+func runTag(args []string) error {
+	fs := flag.NewFlagSet("tag", flag.ContinueOnError)
+	write := fs.Bool("write", false, "write the tags found to the files")
+	all := fs.Bool("all", false, "also look up files that already have a title, artist and album")
+	identify := fs.Bool("identify", false, "identify files by their audio fingerprint (needs fpcalc and an AcoustID API key)")
+	minScore := fs.Int("min-score", defaultMinTagScore, "lowest match score (0-100) to accept")
+	targets, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *minScore < 0 || *minScore > 100 {
+		return fmt.Errorf("usage: personal-musician tag [--write] [--all] [--identify] [--min-score n] [file|dir...]")
+	}
+	if *identify {
+		if err := checkIdentifyTools(); err != nil {
+			return err
+		}
+	}
+
+	var files []MusicFile
+	if len(targets) == 0 {
+		if files, err = ScanMusicFiles(); err != nil {
+			return err
+		}
+	}
+	for _, target := range targets {
+		found, err := resolvePlayTarget(target)
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
+
+	matched, written := 0, 0
+	for _, file := range files {
+		if !isMP3(file.Path) {
+			continue
+		}
+		file.Tags, _ = ReadTags(file.Path)
+		if !*all && file.Tags.Title != "" && file.Tags.Artist != "" && file.Tags.Album != "" {
+			continue
+		}
+
+		match, err := findTags(ctx, file, *identify)
+		if ctx.Err() != nil {
+			fmt.Println("Stopped")
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, Glyphs("✗ "+file.Name+": "+err.Error()))
+			continue
+		}
+		if match == nil || match.Score < *minScore {
+			fmt.Println(Glyphs("? " + file.Name + ": no match"))
+			continue
+		}
+
+		matched++
+		tags := mergeTags(file.Tags, match.Tags)
+		fmt.Println(Glyphs(fmt.Sprintf("✓ %s → %s (%d%%)", file.Name, describeTags(tags), match.Score)))
+		if *write {
+			if err := WriteTags(file.Path, tags); err != nil {
+				fmt.Fprintln(os.Stderr, Glyphs("✗ "+file.Name+": "+err.Error()))
+				continue
+			}
+			written++
+		}
+	}
+
+	switch {
+	case *write:
+		fmt.Printf("Tagged %d files\n", written)
+	case matched > 0:
+		fmt.Printf("Found tags for %d files; run again with --write to save them\n", matched)
+	}
+	return nil
+}
+
+// findTags returns the best match for a file, found by its sound or by its
+// tags or name, or nil if there is none.
+func findTags(ctx context.Context, file MusicFile, bySound bool) (*MusicBrainzMatch, error) {
+	if bySound {
+		return IdentifyTrack(ctx, file.Path)
+	}
+	title, artist := guessTitleArtist(file)
+	matches, err := SearchMusicBrainz(ctx, title, artist)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	return &matches[0], nil
+}
+
+// isMP3 reports whether a file is an MP3 file, the only kind whose tags
+// can be written.
+func isMP3(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".mp3")
+}
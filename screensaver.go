@@ -0,0 +1,194 @@
+// Package main provides the idle screensaver for Personal Musician.
+// After a period without input the UI is replaced by the playing track in
+// large block letters above the spectrum visualizer. Any key or click returns
+// to the normal UI.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultScreensaverDelay is how long the UI may sit idle before the
+// screensaver starts.
+const defaultScreensaverDelay = 5 * time.Minute
+
+// marqueeStep is how often a title too wide for the screen scrolls by one letter.
+const marqueeStep = 400 * time.Millisecond
+
+// DetectScreensaverDelay reads PM_SCREENSAVER (a duration such as "10m";
+// "0" disables the screensaver), falling back to the screensaver setting of
+// the config file. The screensaver is off in accessible mode.
+func DetectScreensaverDelay() (time.Duration, error) {
+	if accessibleMode {
+		return 0, nil
+	}
+	v := os.Getenv("PM_SCREENSAVER")
+	if v == "" {
+		v = config.UI.Screensaver
+	}
+	if v == "" {
+		return defaultScreensaverDelay, nil
+	}
+	delay, err := time.ParseDuration(v)
+	if err != nil || delay < 0 {
+		return defaultScreensaverDelay, fmt.Errorf("invalid PM_SCREENSAVER %q, using %s", v, defaultScreensaverDelay)
+	}
+	return delay, nil
+}
+
+// noteInput records user activity and wakes the screensaver. It reports
+// whether the screensaver was showing, in which case the input is consumed.
+func (m Model) noteInput() (Model, bool) {
+	m.lastInput = time.Now()
+	if m.screensaver {
+		m.screensaver = false
+		return m, true
+	}
+	return m, false
+}
+
+// maybeStartScreensaver starts the screensaver once the UI has been idle long enough.
+func (m Model) maybeStartScreensaver() (Model, tea.Cmd) {
+	if m.screensaver || m.screensaverDelay <= 0 || m.dialog != nil {
+		return m, nil
+	}
+	if time.Since(m.lastInput) < m.screensaverDelay {
+		return m, nil
+	}
+	m.screensaver = true
+	m.screensaverStart = time.Now()
+	if m.currentView == ViewVisualizer {
+		return m, nil // The visualizer is already animating
+	}
+	return m, m.vizTickCmd()
+}
+
+// renderScreensaver renders the track in block letters above the spectrum.
+func (m Model) renderScreensaver() string {
+	state := m.player.GetState()
+
+	title := T("No song playing")
+	if state.CurrentFile != "" {
+		title = m.currentSongName(state)
+	}
+
+	// Scroll titles that do not fit, one letter at a time
+	fit := (m.width - 4) / (bigGlyphWidth + 1)
+	runes := []rune(strings.ToUpper(title))
+	if fit > 0 && len(runes) > fit {
+		loop := append(runes, []rune("   ")...)
+		offset := int(time.Since(m.screensaverStart)/marqueeStep) % len(loop)
+		runes = append(loop[offset:], loop[:offset]...)[:fit]
+	}
+
+	var sections []string
+	sections = append(sections, nowPlayingStyle.Render(bigText(string(runes))))
+
+	// The full title in normal text, for letters the block font lacks
+	info := "♪ " + title
+	if state.Duration > 0 {
+		info += fmt.Sprintf("  %s / %s", FormatDuration(state.Position), FormatDuration(state.Duration))
+	}
+	if state.IsPaused {
+		info = "⏸ " + info
+	}
+	sections = append(sections, mutedStyle.Render(info), "")
+
+	height := m.height - lipgloss.Height(strings.Join(sections, "\n")) - 4
+	if height < 4 {
+		height = 4
+	}
+	sections = append(sections, nowPlayingStyle.Render(m.spectrum.Render(height, 2)))
+
+	screen := strings.Join(sections, "\n")
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, screen)
+}
+
+// bigGlyphWidth and bigGlyphHeight are the size of a block letter.
+const (
+	bigGlyphWidth  = 3
+	bigGlyphHeight = 5
+)
+
+// bigFont holds 3x5 block letters. Characters without a glyph are drawn as a box.
+var bigFont = map[rune][bigGlyphHeight]string{
+	'A':  {"###", "# #", "###", "# #", "# #"},
+	'B':  {"## ", "# #", "## ", "# #", "## "},
+	'C':  {"###", "#  ", "#  ", "#  ", "###"},
+	'D':  {"## ", "# #", "# #", "# #", "## "},
+	'E':  {"###", "#  ", "## ", "#  ", "###"},
+	'F':  {"###", "#  ", "## ", "#  ", "#  "},
+	'G':  {"###", "#  ", "# #", "# #", "###"},
+	'H':  {"# #", "# #", "###", "# #", "# #"},
+	'I':  {"###", " # ", " # ", " # ", "###"},
+	'J':  {"  #", "  #", "  #", "# #", "###"},
+	'K':  {"# #", "# #", "## ", "# #", "# #"},
+	'L':  {"#  ", "#  ", "#  ", "#  ", "###"},
+	'M':  {"# #", "###", "###", "# #", "# #"},
+	'N':  {"## ", "# #", "# #", "# #", "# #"},
+	'O':  {"###", "# #", "# #", "# #", "###"},
+	'P':  {"###", "# #", "###", "#  ", "#  "},
+	'Q':  {"###", "# #", "# #", "###", "  #"},
+	'R':  {"## ", "# #", "## ", "# #", "# #"},
+	'S':  {"###", "#  ", "###", "  #", "###"},
+	'T':  {"###", " # ", " # ", " # ", " # "},
+	'U':  {"# #", "# #", "# #", "# #", "###"},
+	'V':  {"# #", "# #", "# #", "# #", " # "},
+	'W':  {"# #", "# #", "###", "###", "# #"},
+	'X':  {"# #", "# #", " # ", "# #", "# #"},
+	'Y':  {"# #", "# #", " # ", " # ", " # "},
+	'Z':  {"###", "  #", " # ", "#  ", "###"},
+	'0':  {"###", "# #", "# #", "# #", "###"},
+	'1':  {" # ", "## ", " # ", " # ", "###"},
+	'2':  {"###", "  #", "###", "#  ", "###"},
+	'3':  {"###", "  #", "###", "  #", "###"},
+	'4':  {"# #", "# #", "###", "  #", "  #"},
+	'5':  {"###", "#  ", "###", "  #", "###"},
+	'6':  {"###", "#  ", "###", "# #", "###"},
+	'7':  {"###", "  #", "  #", "  #", "  #"},
+	'8':  {"###", "# #", "###", "# #", "###"},
+	'9':  {"###", "# #", "###", "  #", "###"},
+	' ':  {"   ", "   ", "   ", "   ", "   "},
+	'-':  {"   ", "   ", "###", "   ", "   "},
+	'_':  {"   ", "   ", "   ", "   ", "###"},
+	'.':  {"   ", "   ", "   ", "   ", " # "},
+	',':  {"   ", "   ", "   ", " # ", "#  "},
+	':':  {"   ", " # ", "   ", " # ", "   "},
+	'\'': {" # ", " # ", "   ", "   ", "   "},
+	'!':  {" # ", " # ", " # ", "   ", " # "},
+	'?':  {"###", "  #", " ##", "   ", " # "},
+	'&':  {" # ", "# #", " # ", "# #", " ##"},
+	'(':  {" # ", "#  ", "#  ", "#  ", " # "},
+	')':  {" # ", "  #", "  #", "  #", " # "},
+	'[':  {"## ", "#  ", "#  ", "#  ", "## "},
+	']':  {" ##", "  #", "  #", "  #", " ##"},
+}
+
+// bigText renders s in block letters, one column apart.
+func bigText(s string) string {
+	var rows [bigGlyphHeight]strings.Builder
+	for i, r := range s {
+		glyph, ok := bigFont[r]
+		if !ok {
+			glyph = [bigGlyphHeight]string{"###", "# #", "# #", "# #", "###"}
+		}
+		for row := range rows {
+			if i > 0 {
+				rows[row].WriteByte(' ')
+			}
+			rows[row].WriteString(strings.ReplaceAll(glyph[row], "#", "█"))
+		}
+	}
+
+	lines := make([]string, bigGlyphHeight)
+	for row := range rows {
+		lines[row] = rows[row].String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,111 @@
+// Package main provides scrollable lists for the Personal Musician TUI.
+// Lists keep a window that follows the cursor, with a scrollbar drawn
+// alongside when the content does not fit. Only the rows inside the window
+// are rendered, so a list of 20,000 tracks draws as fast as one of 20.
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// scrollList is a virtualized list that keeps the cursor row visible.
+type scrollList struct {
+	offset int // First visible line
+}
+
+// newScrollList creates an empty scroll list.
+func newScrollList() scrollList {
+	return scrollList{}
+}
+
+// follow scrolls the list so the cursor row is visible.
+// rowHeight is the number of lines per row and height the visible lines.
+func (l *scrollList) follow(cursor, total, rowHeight, height int) {
+	visible := height / rowHeight
+	if visible < 1 {
+		visible = 1
+	}
+
+	first := l.offset / rowHeight
+	if cursor < first {
+		first = cursor
+	}
+	if cursor >= first+visible {
+		first = cursor - visible + 1
+	}
+	if first > total-visible {
+		first = total - visible
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	l.offset = first * rowHeight
+}
+
+// center scrolls the list so the cursor row is in the middle of the view.
+func (l *scrollList) center(cursor, total, rowHeight, height int) {
+	visible := height / rowHeight
+	if visible < 1 {
+		visible = 1
+	}
+
+	first := cursor - visible/2
+	if first > total-visible {
+		first = total - visible
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	l.offset = first * rowHeight
+}
+
+// firstRow returns the index of the first visible row.
+func (l scrollList) firstRow(rowHeight int) int {
+	return l.offset / rowHeight
+}
+
+// render draws the visible rows of a list of total rows, each rowHeight
+// lines high, with a scrollbar on the right when the rows do not fit. row
+// renders the row at an index and is only called for visible rows.
+func (l scrollList) render(total, rowHeight, height int, row func(i int) string) string {
+	first := l.firstRow(rowHeight)
+	end := min(first+max(height/rowHeight, 1), total)
+	rows := make([]string, 0, max(end-first, 0))
+	for i := first; i < end; i++ {
+		rows = append(rows, row(i))
+	}
+	view := strings.Join(rows, "\n")
+
+	lines := total * rowHeight
+	if lines <= height {
+		return view
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, view, " ", renderScrollbar(height, l.offset, lines))
+}
+
+// renderScrollbar draws a vertical scrollbar for a window of height lines
+// starting at offset within total lines.
+func renderScrollbar(height, offset, total int) string {
+	thumb := height * height / total
+	if thumb < 1 {
+		thumb = 1
+	}
+	top := 0
+	if total > height {
+		top = offset * (height - thumb) / (total - height)
+	}
+
+	lines := make([]string, height)
+	for i := range lines {
+		if i >= top && i < top+thumb {
+			lines[i] = selectedStyle.Render("┃")
+		} else {
+			lines[i] = mutedStyle.Render("│")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
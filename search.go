@@ -3,14 +3,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"html"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
-	"time"
 )
 
 // SearchResult represents a single YouTube search result.
@@ -22,42 +23,97 @@ type SearchResult struct {
 	Thumbnail string // Thumbnail URL
 }
 
-// SearchYouTube searches YouTube for videos matching the query.
-// Returns a slice of SearchResult with video information.
-func SearchYouTube(query string) ([]SearchResult, error) {
+// youtubeAPIKey is a YouTube Data API key from the config file. When set,
+// searches use the API instead of scraping the search page, unless the
+// providers are chosen explicitly.
+var youtubeAPIKey string
+
+// searchYouTubePage searches by scraping YouTube's search results page.
+func searchYouTubePage(ctx context.Context, query string) ([]SearchResult, error) {
 	// Use YouTube's search page and parse results
 	searchURL := fmt.Sprintf("https://www.youtube.com/results?search_query=%s",
 		url.QueryEscape(query+" audio"))
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 15 * time.Second,
-	}
+	// Request with browser-like headers through the shared client
+	header := http.Header{}
+	header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	header.Set("Accept-Language", "en-US,en;q=0.9")
 
-	// Create request with browser-like headers
-	req, err := http.NewRequest("GET", searchURL, nil)
+	body, err := httpGet(ctx, "youtube", searchURL, header)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to search: %w", err)
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Parse the response to extract video data
+	return parseYouTubeResults(string(body))
+}
 
-	// Make the request
-	resp, err := client.Do(req)
-	if err != nil {
+// apiSearchResponse is the part of a YouTube Data API search response we use.
+type apiSearchResponse struct {
+	Items []struct {
+		ID struct {
+			VideoID string `json:"videoId"`
+		} `json:"id"`
+		Snippet struct {
+			Title        string `json:"title"`
+			ChannelTitle string `json:"channelTitle"`
+			Thumbnails   struct {
+				Default struct {
+					URL string `json:"url"`
+				} `json:"default"`
+			} `json:"thumbnails"`
+		} `json:"snippet"`
+	} `json:"items"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// searchYouTubeAPI searches through the YouTube Data API. The API does not
+// report durations, so results have none.
+func searchYouTubeAPI(ctx context.Context, query, key string) ([]SearchResult, error) {
+	params := url.Values{
+		"part":       {"snippet"},
+		"type":       {"video"},
+		"maxResults": {"10"},
+		"q":          {query + " audio"},
+		"key":        {key},
+	}
+	body, err := httpGet(ctx, "youtube-api", "https://www.googleapis.com/youtube/v3/search?"+params.Encode(), nil)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		body = statusErr.Body // The API explains errors in the body
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to search: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	var data apiSearchResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		if statusErr != nil {
+			return nil, fmt.Errorf("YouTube API error: %s", statusErr.Status)
+		}
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+	if data.Error != nil {
+		return nil, fmt.Errorf("YouTube API error: %s", data.Error.Message)
+	}
+	if statusErr != nil {
+		return nil, fmt.Errorf("YouTube API error: %s", statusErr.Status)
 	}
 
-	// Parse the response to extract video data
-	return parseYouTubeResults(string(body))
+	results := make([]SearchResult, 0, len(data.Items))
+	for _, item := range data.Items {
+		if item.ID.VideoID == "" {
+			continue
+		}
+		results = append(results, SearchResult{
+			VideoID:   item.ID.VideoID,
+			Title:     html.UnescapeString(item.Snippet.Title),
+			Channel:   html.UnescapeString(item.Snippet.ChannelTitle),
+			Thumbnail: item.Snippet.Thumbnails.Default.URL,
+		})
+	}
+	return results, nil
 }
 
 // parseYouTubeResults extracts video information from YouTube HTML response.
@@ -186,6 +242,15 @@ func extractVideoInfo(renderer map[string]interface{}) SearchResult {
 		}
 	}
 
+	// Get the smallest thumbnail (listed first)
+	if thumbs, ok := navigateJSON(renderer, "thumbnail", "thumbnails").([]interface{}); ok && len(thumbs) > 0 {
+		if thumb, ok := thumbs[0].(map[string]interface{}); ok {
+			if thumbURL, ok := thumb["url"].(string); ok {
+				result.Thumbnail = thumbURL
+			}
+		}
+	}
+
 	return result
 }
 
@@ -207,6 +272,35 @@ func GetYouTubeURL(videoID string) string {
 	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
 }
 
+// videoIDPattern matches a YouTube video ID.
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// ParseVideoURL extracts the video ID from a YouTube URL such as
+// https://www.youtube.com/watch?v=ID, https://youtu.be/ID or
+// https://music.youtube.com/watch?v=ID.
+func ParseVideoURL(s string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+
+	var id string
+	switch host := strings.TrimPrefix(u.Hostname(), "www."); {
+	case host == "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case host == "youtube.com", host == "m.youtube.com", host == "music.youtube.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+		} else if rest, ok := strings.CutPrefix(u.Path, "/shorts/"); ok {
+			id = rest
+		}
+	}
+	if !videoIDPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
 // FormatSearchResult returns a formatted string representation of a SearchResult.
 func FormatSearchResult(r SearchResult) string {
 	return fmt.Sprintf("%s [%s] - %s", r.Title, r.Duration, r.Channel)
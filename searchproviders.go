@@ -0,0 +1,261 @@
+// Package main provides multi-provider search for Personal Musician.
+// The providers listed under [providers] search are all asked at once, with
+// one shared deadline. Results stream into the results view as each
+// provider answers, duplicates are dropped, and a provider that fails or
+// times out is reported without holding up the others.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchTimeout bounds a search across all providers.
+const searchTimeout = 20 * time.Second
+
+// searchFunc searches one provider.
+type searchFunc func(ctx context.Context, query string) ([]SearchResult, error)
+
+// searchProviderFuncs are the known search providers by name.
+var searchProviderFuncs = map[string]searchFunc{
+	"page": searchYouTubePage,
+	"api": func(ctx context.Context, query string) ([]SearchResult, error) {
+		if youtubeAPIKey == "" {
+			return nil, fmt.Errorf("no YouTube API key configured")
+		}
+		return searchYouTubeAPI(ctx, query, youtubeAPIKey)
+	},
+	"invidious": searchInvidious,
+	"piped":     searchPiped,
+}
+
+// searchProviderNames returns the known provider names, sorted.
+func searchProviderNames() []string {
+	names := make([]string, 0, len(searchProviderFuncs))
+	for name := range searchProviderFuncs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// searchProviders returns the enabled providers in order of preference: the
+// configured list, or else the API when a key is set, Invidious or Piped
+// when instances are listed, and the search page otherwise. See also
+// searchFallback.
+func searchProviders() []string {
+	switch {
+	case len(config.Providers.Search) > 0:
+		return config.Providers.Search
+	case youtubeAPIKey != "":
+		return []string{"api"}
+	case len(config.Providers.Invidious) > 0:
+		return []string{"invidious"}
+	case len(config.Providers.Piped) > 0:
+		return []string{"piped"}
+	}
+	return []string{"page"}
+}
+
+// searchFallback returns the provider asked when every provider chosen
+// automatically failed, such as when all Invidious instances are down: the
+// search page, unless it was chosen already or the providers are listed in
+// the config file. It returns "" when there is none.
+func searchFallback() string {
+	if len(config.Providers.Search) > 0 || slices.Contains(searchProviders(), "page") {
+		return ""
+	}
+	return "page"
+}
+
+// searchPartial is one provider's answer to a search.
+type searchPartial struct {
+	provider string
+	results  []SearchResult
+	err      error
+}
+
+// searchAll asks every enabled provider at once, and the fallback provider
+// once all of them failed. Answers arrive on the returned channel as they
+// come in; it is closed once all providers have answered or ctx has ended.
+func searchAll(ctx context.Context, query string) <-chan searchPartial {
+	providers := searchProviders()
+	answers := make(chan searchPartial, len(providers)+1)
+	ask := func(name string) searchPartial {
+		slog.Info("search", "query", query, "source", name)
+		start := time.Now()
+		results, err := searchProviderFuncs[name](ctx, query)
+		if err != nil {
+			slog.Error("search failed", "query", query, "source", name, "err", err)
+		} else {
+			slog.Debug("search finished", "query", query, "source", name, "results", len(results), "elapsed", time.Since(start))
+		}
+		return searchPartial{provider: name, results: results, err: err}
+	}
+
+	done := make(chan bool) // Whether the provider answered without error
+	for _, name := range providers {
+		go func() {
+			answer := ask(name)
+			answers <- answer
+			done <- answer.err == nil
+		}()
+	}
+	go func() {
+		succeeded := false
+		for range providers {
+			if <-done {
+				succeeded = true
+			}
+		}
+		if fallback := searchFallback(); fallback != "" && !succeeded && ctx.Err() == nil {
+			answers <- ask(fallback)
+		}
+		close(answers)
+	}()
+	return answers
+}
+
+// mergeResults appends the results not already present.
+func mergeResults(have, more []SearchResult) []SearchResult {
+	seen := make(map[string]bool, len(have))
+	for _, r := range have {
+		seen[r.VideoID] = true
+	}
+	for _, r := range more {
+		if !seen[r.VideoID] {
+			seen[r.VideoID] = true
+			have = append(have, r)
+		}
+	}
+	return have
+}
+
+// SearchYouTube searches every enabled provider and returns their results
+// combined in order of preference. It fails only if every provider failed.
+func SearchYouTube(query string) ([]SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+
+	answers := make(map[string]searchPartial)
+	for answer := range searchAll(ctx, query) {
+		answers[answer.provider] = answer
+	}
+
+	names := searchProviders()
+	if _, ok := answers[searchFallback()]; ok {
+		names = append(names, searchFallback())
+	}
+	var results []SearchResult
+	var errs []error
+	for _, name := range names {
+		answer, ok := answers[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: %w", name, ctx.Err()))
+		case answer.err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", name, answer.err))
+		default:
+			results = mergeResults(results, answer.results)
+		}
+	}
+	if len(errs) == len(names) {
+		return nil, errors.Join(errs...)
+	}
+	return results, nil
+}
+
+// searchStream is a search in progress in the TUI.
+type searchStream struct {
+	id      int
+	answers <-chan searchPartial
+	cancel  context.CancelFunc
+	failed  []string // Errors of the providers that failed so far
+}
+
+// searchPartialMsg delivers one provider's answer to the TUI.
+type searchPartialMsg struct {
+	id     int
+	answer searchPartial
+	done   bool // All providers have answered
+}
+
+// next returns a command that waits for the next provider's answer.
+func (s *searchStream) next() tea.Cmd {
+	return func() tea.Msg {
+		answer, ok := <-s.answers
+		return searchPartialMsg{id: s.id, answer: answer, done: !ok}
+	}
+}
+
+// startSearch asks every provider for query, replacing any search in
+// progress.
+func (m Model) startSearch(query string) (Model, tea.Cmd) {
+	if m.search != nil {
+		m.search.cancel()
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, searchTimeout)
+	m.searchID++
+	m.search = &searchStream{id: m.searchID, answers: searchAll(ctx, query), cancel: cancel}
+	m.searchQuery = query
+	m.isSearching = true
+	m.searchError = ""
+	m.youtubeResults = nil
+	return m, m.search.next()
+}
+
+// applySearchPartial shows one provider's results as soon as they arrive.
+func (m Model) applySearchPartial(msg searchPartialMsg) (Model, tea.Cmd) {
+	if m.search == nil || msg.id != m.search.id {
+		return m, nil // From a replaced search
+	}
+
+	if msg.done {
+		m.search.cancel()
+		failed := m.search.failed
+		m.search = nil
+		m.isSearching = false
+		if len(m.youtubeResults) > 0 {
+			return m, nil
+		}
+		if len(failed) > 0 {
+			m.searchError = strings.Join(failed, "; ")
+			if len(searchProviders()) == 1 {
+				for _, err := range failed {
+					appLog.Add("error", err) // Not reported as it happened
+				}
+			}
+			return m, nil
+		}
+		m.searchError = T("No results found")
+		return m, nil
+	}
+
+	answer := msg.answer
+	if answer.err != nil {
+		err := Tf("%s search failed: %v", answer.provider, answer.err)
+		m.search.failed = append(m.search.failed, err)
+		if len(searchProviders()) > 1 {
+			// Say so while the other providers carry on
+			return m, tea.Batch(m.search.next(), func() tea.Msg { return errorMsg(err) })
+		}
+		return m, m.search.next()
+	}
+
+	// Show the first results right away and add the rest below them
+	before := len(m.youtubeResults)
+	m.youtubeResults = mergeResults(m.youtubeResults, answer.results)
+	added := m.youtubeResults[before:]
+	if before == 0 && len(added) > 0 {
+		m.resultsCursor = 0
+		m.currentView = ViewResults
+	}
+	return m, tea.Batch(m.search.next(), m.loadThumbnails(added))
+}
@@ -0,0 +1,212 @@
+// Package main provides multi-select for the library and results lists.
+// In selection mode (V), space marks the row under the cursor and v marks the
+// range from the anchor to the cursor; bulk actions then apply to all marks.
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// selection tracks marked rows of a list by a stable key (file path or video ID).
+type selection struct {
+	active bool            // Whether selection mode is on
+	anchor int             // Row where selection mode started, for ranges
+	marked map[string]bool // Marked row keys
+}
+
+// toggle marks or unmarks a key.
+func (s *selection) toggle(key string) {
+	if s.marked == nil {
+		s.marked = make(map[string]bool)
+	}
+	if s.marked[key] {
+		delete(s.marked, key)
+	} else {
+		s.marked[key] = true
+	}
+}
+
+// markRange marks all keys between two rows, inclusive.
+func (s *selection) markRange(keys []string, from, to int) {
+	if s.marked == nil {
+		s.marked = make(map[string]bool)
+	}
+	if from > to {
+		from, to = to, from
+	}
+	for i := from; i <= to && i < len(keys); i++ {
+		if i >= 0 {
+			s.marked[keys[i]] = true
+		}
+	}
+}
+
+// clear leaves selection mode and drops all marks.
+func (s *selection) clear() {
+	s.active = false
+	s.marked = nil
+}
+
+// count returns the number of marked rows.
+func (s *selection) count() int {
+	return len(s.marked)
+}
+
+// markerFor returns the mark column for a row.
+func (s *selection) markerFor(key string) string {
+	if s.marked[key] {
+		return "●"
+	}
+	if s.active {
+		return "○"
+	}
+	return " "
+}
+
+// handleSelectionKeys handles selection-mode keys shared by the library and
+// results views. Returns handled=false for keys the view should process.
+func (m Model) handleSelectionKeys(msg tea.KeyMsg, sel *selection, keys []string, cursor int) (handled bool) {
+	switch msg.String() {
+	case "V": // Enter or leave selection mode
+		if sel.active {
+			sel.clear()
+		} else {
+			sel.active = true
+			sel.anchor = cursor
+		}
+		return true
+	}
+
+	if !sel.active {
+		return false
+	}
+
+	switch msg.String() {
+	case " ": // Mark row
+		if cursor < len(keys) {
+			sel.toggle(keys[cursor])
+		}
+		return true
+	case "v": // Mark range from anchor
+		sel.markRange(keys, sel.anchor, cursor)
+		sel.anchor = cursor
+		return true
+	case "esc":
+		sel.clear()
+		return true
+	}
+	return false
+}
+
+// markedLibrary returns the marked library files, or the file under the cursor
+// when nothing is marked.
+func (m Model) markedLibrary() []MusicFile {
+	files := m.visibleLibrary()
+	if m.librarySel.count() == 0 {
+		if m.libraryCursor < len(files) {
+			return []MusicFile{files[m.libraryCursor]}
+		}
+		return nil
+	}
+
+	var marked []MusicFile
+	for _, f := range m.libraryFiles {
+		if m.librarySel.marked[f.Path] {
+			marked = append(marked, f)
+		}
+	}
+	return marked
+}
+
+// markedResults returns the marked search results, or the result under the
+// cursor when nothing is marked.
+func (m Model) markedResults() []SearchResult {
+	if m.resultsSel.count() == 0 {
+		if m.resultsCursor < len(m.youtubeResults) {
+			return []SearchResult{m.youtubeResults[m.resultsCursor]}
+		}
+		return nil
+	}
+
+	var marked []SearchResult
+	for _, r := range m.youtubeResults {
+		if m.resultsSel.marked[r.VideoID] {
+			marked = append(marked, r)
+		}
+	}
+	return marked
+}
+
+// libraryKeys returns the selection keys of the visible library rows.
+func (m Model) libraryKeys() []string {
+	files := m.visibleLibrary()
+	keys := make([]string, len(files))
+	for i, f := range files {
+		keys[i] = f.Path
+	}
+	return keys
+}
+
+// resultKeys returns the selection keys of the search result rows.
+func (m Model) resultKeys() []string {
+	keys := make([]string, len(m.youtubeResults))
+	for i, r := range m.youtubeResults {
+		keys[i] = r.VideoID
+	}
+	return keys
+}
+
+// enqueueMarked adds the marked library files to the play queue.
+func (m Model) enqueueMarked() (tea.Model, tea.Cmd) {
+	files := m.markedLibrary()
+	for _, f := range files {
+		m.player.Enqueue(f)
+	}
+	m.librarySel.clear()
+
+	if len(files) == 1 {
+		return m, func() tea.Msg { return statusMsg(Tf("Queued: %s", files[0].Name)) }
+	}
+	return m, func() tea.Msg { return statusMsg(Tf("Queued %d songs", len(files))) }
+}
+
+// deleteMarked asks for confirmation and deletes the marked library files.
+func (m Model) deleteMarked() (tea.Model, tea.Cmd) {
+	files := m.markedLibrary()
+	if len(files) == 0 {
+		return m, nil
+	}
+
+	message := Tf("Move %q to the trash?", files[0].Name)
+	if len(files) > 1 {
+		message = Tf("Move %d songs to the trash?", len(files))
+	}
+
+	return m.openDialog(NewConfirmDialog(T("Delete"), message,
+		func(m Model, _ string) (tea.Model, tea.Cmd) {
+			current := m.player.GetState().CurrentFile
+			for _, f := range files {
+				if f.Path == current {
+					m.player.Stop()
+				}
+			}
+			m.librarySel.clear()
+			return m, m.library.Delete(files)
+		}))
+}
+
+// downloadMarked queues downloads for the marked search results.
+func (m Model) downloadMarked() (tea.Model, tea.Cmd) {
+	results := m.markedResults()
+	for _, r := range results {
+		if err := m.downloader.QueueDownload(m.ctx, r.VideoID, r.Title); err != nil {
+			return m, func() tea.Msg { return errorMsg(Tf("Download error: %v", err)) }
+		}
+	}
+	m.resultsSel.clear()
+
+	return m, tea.Batch(
+		m.downloadSpinner.Tick,
+		func() tea.Msg { return statusMsg(Tf("Queued %d downloads", len(results))) },
+	)
+}
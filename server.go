@@ -0,0 +1,259 @@
+// Package main provides the local HTTP server for Personal Musician.
+// When an address is configured ([server] listen, PM_LISTEN or --listen) the
+// player serves a WebSocket event stream at /events that broadcasts track
+// changes, playback state, position ticks and download progress, so external
+// dashboards can react in real time. With sync enabled it also leads
+// multi-room playback (see multiroom.go).
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// eventInterval is how often the player and downloads are checked for
+// changes, and how often position events are sent during playback.
+const eventInterval = time.Second
+
+// WebSocket connection limits.
+const (
+	eventWriteTimeout = 10 * time.Second
+	eventPingInterval = 30 * time.Second
+	eventClientBuffer = 64 // Events queued per client before it misses some
+)
+
+// Event is a message on the event stream.
+type Event struct {
+	Type string    `json:"type"` // "status", "track", "state", "position" or "download"
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// positionEvent is the data of a "position" event, in seconds.
+type positionEvent struct {
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+}
+
+// downloadEvent is the data of a "download" event.
+type downloadEvent struct {
+	ID       int     `json:"id"`
+	VideoID  string  `json:"video_id"`
+	Title    string  `json:"title"`
+	State    string  `json:"state"` // "queued", "active", "done", "failed" or "cancelled"
+	Progress float64 `json:"progress"`
+	Speed    string  `json:"speed,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	File     string  `json:"file,omitempty"`
+}
+
+// newDownloadEvent converts a download into its reported form.
+func newDownloadEvent(item DownloadItem) downloadEvent {
+	return downloadEvent{
+		ID:       item.ID,
+		VideoID:  item.VideoID,
+		Title:    item.Title,
+		State:    item.State.String(),
+		Progress: item.Progress,
+		Speed:    item.Speed,
+		Error:    item.Error,
+		File:     item.File,
+	}
+}
+
+// Server is the local HTTP server.
+type Server struct {
+	server     *http.Server
+	player     *Player
+	downloader *Downloader
+	stop       chan struct{}
+
+	mu        sync.Mutex
+	clients   map[chan []byte]struct{}
+	syncFiles map[string]string // Tracks served to followers, by token
+}
+
+// upgrader accepts WebSocket connections. The default origin check only
+// admits pages served from the same host.
+var upgrader = websocket.Upgrader{}
+
+// StartServer starts serving on addr in the background. With sync set,
+// other instances can join it to play in sync.
+func StartServer(addr string, player *Player, downloader *Downloader, sync bool) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s := &Server{
+		player:     player,
+		downloader: downloader,
+		stop:       make(chan struct{}),
+		clients:    make(map[chan []byte]struct{}),
+		syncFiles:  make(map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", s.handleEvents)
+	if sync {
+		mux.HandleFunc("GET /sync", s.handleSync)
+		mux.HandleFunc("GET /sync/media/{file}", s.handleSyncMedia)
+	}
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go s.server.Serve(listener)
+	go s.watch()
+	slog.Info("server listening", "addr", listener.Addr().String())
+	return s, nil
+}
+
+// Close stops the server and disconnects all clients.
+func (s *Server) Close() error {
+	close(s.stop)
+	return s.server.Close()
+}
+
+// subscribe registers a client for events.
+func (s *Server) subscribe() chan []byte {
+	ch := make(chan []byte, eventClientBuffer)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes a client.
+func (s *Server) unsubscribe(ch chan []byte) {
+	s.mu.Lock()
+	delete(s.clients, ch)
+	s.mu.Unlock()
+}
+
+// publish sends an event to every client. Clients that fall behind miss
+// events rather than holding up the others.
+func (s *Server) publish(eventType string, data any) {
+	msg, err := encodeEvent(eventType, data)
+	if err != nil {
+		slog.Error("failed to encode event", "type", eventType, "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// encodeEvent encodes an event as JSON.
+func encodeEvent(eventType string, data any) ([]byte, error) {
+	return json.Marshal(Event{Type: eventType, Time: time.Now(), Data: data})
+}
+
+// handleEvents streams events to a WebSocket client, starting with a
+// "status" snapshot.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has replied with an error
+	}
+	defer conn.Close()
+	slog.Debug("event client connected", "remote", r.RemoteAddr)
+
+	ch := s.subscribe()
+	defer s.unsubscribe(ch)
+
+	// Clients only send control frames; reading handles them and notices
+	// when the client goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	snapshot, err := encodeEvent("status", newPlayerStatus(s.player.GetState()))
+	if err != nil {
+		return
+	}
+	ping := time.NewTicker(eventPingInterval)
+	defer ping.Stop()
+	for msg := snapshot; ; {
+		if msg != nil {
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+			msg = nil
+		}
+		select {
+		case msg = <-ch:
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// watch polls the player and the downloads and publishes what changed.
+func (s *Server) watch() {
+	ticker := time.NewTicker(eventInterval)
+	defer ticker.Stop()
+
+	last := newPlayerStatus(s.player.GetState())
+	downloads := make(map[int]downloadEvent)
+	for _, item := range s.downloader.Downloads() {
+		downloads[item.ID] = newDownloadEvent(item)
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		status := newPlayerStatus(s.player.GetState())
+		switch {
+		case status.Path != last.Path:
+			s.publish("track", status)
+		case !sameState(status, last):
+			s.publish("state", status)
+		}
+		if status.State == "playing" {
+			s.publish("position", positionEvent{Position: status.Position, Duration: status.Duration})
+		}
+		last = status
+
+		for _, item := range s.downloader.Downloads() {
+			event := newDownloadEvent(item)
+			if prev, ok := downloads[item.ID]; !ok || prev != event {
+				s.publish("download", event)
+				downloads[item.ID] = event
+			}
+		}
+	}
+}
+
+// sameState reports whether two statuses differ only in position.
+func sameState(a, b playerStatus) bool {
+	a.Position, b.Position = 0, 0
+	return a == b
+}
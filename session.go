@@ -0,0 +1,41 @@
+// Package main provides the session clock for Personal Musician.
+// The header shows the current time and how long music has played this
+// session; the session length is saved to the statistics on exit.
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// trackListening adds the time since the last check to the session's
+// listening time when music is playing.
+func (m Model) trackListening() Model {
+	now := time.Now()
+	state := m.player.GetState()
+	if state.IsPlaying && !state.IsPaused && !m.lastListenCheck.IsZero() {
+		m.listened += now.Sub(m.lastListenCheck)
+	}
+	m.lastListenCheck = now
+	return m
+}
+
+// SaveSession records this session's length and listening time in the stats.
+func (m Model) SaveSession() error {
+	m = m.trackListening()
+	return m.stats.RecordSession(m.sessionStart, time.Now(), m.listened)
+}
+
+// renderSessionClock renders the listening time and the current time.
+func (m Model) renderSessionClock() string {
+	return mutedStyle.Render(fmt.Sprintf("🎧 %s  🕒 %s", formatListened(m.listened), time.Now().Format("15:04")))
+}
+
+// formatListened formats a listening time as e.g. "1h 05m" or "12m".
+func formatListened(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	if h := int(d.Hours()); h > 0 {
+		return fmt.Sprintf("%dh %02dm", h, int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
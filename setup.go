@@ -0,0 +1,454 @@
+// Package main provides the dependency setup command for Personal Musician.
+// "personal-musician setup" installs yt-dlp and ffmpeg, which downloads need,
+// with the system package manager (Homebrew, apt, dnf, pacman, zypper, winget,
+// scoop or Chocolatey). Without one, or with --standalone, it downloads
+// standalone builds into the data directory, where the downloader finds them.
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// packageManager is a system package manager setup can install tools with.
+type packageManager struct {
+	name     string            // Executable looked up in PATH
+	install  []string          // Install command; the package name is appended
+	packages map[string]string // Package names that differ from the tool name
+}
+
+// packageManagers are the supported package managers per OS, in order of preference.
+var packageManagers = map[string][]packageManager{
+	"darwin": {
+		{name: "brew", install: []string{"brew", "install"}},
+	},
+	"linux": {
+		{name: "apt-get", install: []string{"sudo", "apt-get", "install", "-y"}},
+		{name: "dnf", install: []string{"sudo", "dnf", "install", "-y"}},
+		{name: "pacman", install: []string{"sudo", "pacman", "-S", "--needed", "--noconfirm"}},
+		{name: "zypper", install: []string{"sudo", "zypper", "install", "-y"}},
+	},
+	"windows": {
+		{name: "winget", install: []string{"winget", "install", "--exact", "--id"},
+			packages: map[string]string{"yt-dlp": "yt-dlp.yt-dlp", "ffmpeg": "Gyan.FFmpeg"}},
+		{name: "scoop", install: []string{"scoop", "install"}},
+		{name: "choco", install: []string{"choco", "install", "-y"}},
+	},
+}
+
+// Release locations of the standalone builds.
+const (
+	ytDlpReleaseURL  = "https://github.com/yt-dlp/yt-dlp/releases/latest/download/"
+	ffmpegReleaseURL = "https://github.com/yt-dlp/FFmpeg-Builds/releases/download/latest/"
+
+	ytDlpChecksums  = "SHA2-256SUMS"     // Published next to the yt-dlp builds
+	ffmpegChecksums = "checksums.sha256" // Published next to the ffmpeg archives
+)
+
+// setupHosts are the hosts standalone builds may be downloaded from,
+// including those GitHub redirects release assets to.
+var setupHosts = []string{"github.com", "objects.githubusercontent.com", "release-assets.githubusercontent.com"}
+
+// setupClient downloads standalone builds, refusing redirects to other
+// hosts than setupHosts.
+var setupClient = &http.Client{
+	Transport: httpTransport,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if !slices.Contains(setupHosts, req.URL.Hostname()) || req.URL.Scheme != "https" {
+			return fmt.Errorf("refusing redirect to %s", req.URL.Redacted())
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		return nil
+	},
+}
+
+// ytDlpAssets are the standalone yt-dlp builds per platform.
+var ytDlpAssets = map[string]string{
+	"linux/amd64":   "yt-dlp_linux",
+	"linux/arm64":   "yt-dlp_linux_aarch64",
+	"darwin/amd64":  "yt-dlp_macos",
+	"darwin/arm64":  "yt-dlp_macos",
+	"windows/amd64": "yt-dlp.exe",
+	"windows/386":   "yt-dlp_x86.exe",
+}
+
+// ffmpegAssets are the standalone ffmpeg archives per platform. macOS builds
+// come as one zip per program from ffmpegMacURL instead.
+var ffmpegAssets = map[string]string{
+	"linux/amd64":   "ffmpeg-master-latest-linux64-gpl.tar.xz",
+	"linux/arm64":   "ffmpeg-master-latest-linuxarm64-gpl.tar.xz",
+	"windows/amd64": "ffmpeg-master-latest-win64-gpl.zip",
+}
+
+// ffmpegPrograms are the programs yt-dlp needs from an ffmpeg build.
+var ffmpegPrograms = []string{"ffmpeg", "ffprobe"}
+
+// ToolsDir returns the directory standalone tools are installed into.
+func ToolsDir() string {
+	return dataPath("bin")
+}
+
+// exeName adds the executable suffix of the OS to a program name.
+func exeName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// findTool looks up a program in PATH, then in the tools directory. Names
+// with a directory are only checked as given.
+func findTool(name string) (string, bool) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, true
+	}
+	if filepath.Base(name) != name {
+		return "", false
+	}
+	path := filepath.Join(ToolsDir(), exeName(name))
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path, true
+	}
+	return "", false
+}
+
+// runSetup installs yt-dlp and ffmpeg if they are missing.
+func runSetup(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	standalone := fs.Bool("standalone", false, "download standalone builds instead of using the package manager")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: personal-musician setup [--standalone]")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var pm *packageManager
+	if !*standalone {
+		pm = detectPackageManager()
+	}
+	if pm != nil {
+		fmt.Printf("Detected %s/%s with %s\n", runtime.GOOS, runtime.GOARCH, pm.name)
+	} else {
+		fmt.Printf("Detected %s/%s; installing standalone builds into %s\n", runtime.GOOS, runtime.GOARCH, ToolsDir())
+	}
+
+	ytDlp := config.Download.YtDlp
+	if ytDlp == "" {
+		ytDlp = "yt-dlp"
+	}
+	tools := []struct {
+		name       string
+		found      string // Name looked up to see whether the tool is installed
+		standalone func(ctx context.Context, dir string) error
+	}{
+		{"yt-dlp", ytDlp, installYtDlp},
+		{"ffmpeg", "ffmpeg", installFFmpeg},
+	}
+
+	var failed []string
+	for _, tool := range tools {
+		if path, ok := findTool(tool.found); ok {
+			fmt.Printf("%s: already installed (%s)\n", tool.name, path)
+			continue
+		}
+		if pm != nil {
+			err := pm.run(ctx, tool.name)
+			if err == nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "%s: %s failed (%v); trying a standalone build\n", tool.name, pm.name, err)
+		}
+		fmt.Printf("%s: downloading a standalone build...\n", tool.name)
+		if err := tool.standalone(ctx, ToolsDir()); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "%s: %v\n", tool.name, err)
+			failed = append(failed, tool.name)
+			continue
+		}
+		fmt.Printf("%s: installed into %s\n", tool.name, ToolsDir())
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not install %s; see the README for manual installation", strings.Join(failed, " and "))
+	}
+	fmt.Println("Setup complete.")
+	return nil
+}
+
+// detectPackageManager returns the first package manager of this OS found in
+// PATH, or nil.
+func detectPackageManager() *packageManager {
+	for _, pm := range packageManagers[runtime.GOOS] {
+		if _, err := exec.LookPath(pm.name); err == nil {
+			return &pm
+		}
+	}
+	return nil
+}
+
+// run installs a tool with the package manager, attached to the terminal so
+// sudo can ask for a password. sudo is dropped when already running as root
+// or when it is not installed.
+func (pm *packageManager) run(ctx context.Context, tool string) error {
+	name := tool
+	if pkg, ok := pm.packages[tool]; ok {
+		name = pkg
+	}
+	args := append(slices.Clone(pm.install), name)
+	if args[0] == "sudo" {
+		if _, err := exec.LookPath("sudo"); os.Geteuid() == 0 || err != nil {
+			args = args[1:]
+		}
+	}
+
+	fmt.Printf("%s: running %s\n", tool, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	if _, ok := findTool(tool); !ok {
+		return fmt.Errorf("%s is still not in PATH", tool)
+	}
+	return nil
+}
+
+// installYtDlp downloads the standalone yt-dlp build into dir.
+func installYtDlp(ctx context.Context, dir string) error {
+	asset, ok := ytDlpAssets[runtime.GOOS+"/"+runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("no standalone build for %s/%s; install it with: pip install yt-dlp", runtime.GOOS, runtime.GOARCH)
+	}
+	archive, err := downloadTemp(ctx, ytDlpReleaseURL+asset)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+	if err := verifyChecksum(ctx, archive, ytDlpReleaseURL+ytDlpChecksums, asset); err != nil {
+		return err
+	}
+	return installFile(archive, filepath.Join(dir, exeName("yt-dlp")))
+}
+
+// installFFmpeg downloads the standalone ffmpeg and ffprobe builds into dir.
+// There is none for macOS, as setup has no checksums to verify one against.
+func installFFmpeg(ctx context.Context, dir string) error {
+	if runtime.GOOS == "darwin" {
+		return fmt.Errorf("no verifiable standalone build for macOS; install it with: brew install ffmpeg")
+	}
+
+	asset, ok := ffmpegAssets[runtime.GOOS+"/"+runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("no standalone build for %s/%s; install ffmpeg with your package manager", runtime.GOOS, runtime.GOARCH)
+	}
+	archive, err := downloadTemp(ctx, ffmpegReleaseURL+asset)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+	if err := verifyChecksum(ctx, archive, ffmpegReleaseURL+ffmpegChecksums, asset); err != nil {
+		return err
+	}
+
+	programs := make([]string, len(ffmpegPrograms))
+	for i, p := range ffmpegPrograms {
+		programs[i] = exeName(p)
+	}
+	if strings.HasSuffix(asset, ".zip") {
+		return extractZip(archive, dir, programs)
+	}
+	return extractTarXz(ctx, archive, dir, programs)
+}
+
+// downloadTemp downloads url into a temporary file and returns its path.
+func downloadTemp(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := setupClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp("", "personal-musician-setup-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// verifyChecksum checks the SHA-256 of the downloaded file path against the
+// one listed for asset in the checksum file at sumsURL, in the format of
+// sha256sum.
+func verifyChecksum(ctx context.Context, path, sumsURL, asset string) error {
+	sumsFile, err := downloadTemp(ctx, sumsURL)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sumsFile)
+	sums, err := os.ReadFile(sumsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read checksums: %w", err)
+	}
+
+	want := ""
+	for _, line := range strings.Split(string(sums), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			want = strings.ToLower(fields[0])
+		}
+	}
+	if want == "" {
+		return fmt.Errorf("no checksum published for %s", asset)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", asset, err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", asset, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", asset, got, want)
+	}
+	return nil
+}
+
+// installFile copies src to the executable dst, replacing it atomically.
+func installFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open download: %w", err)
+	}
+	defer in.Close()
+	return writeExecutable(in, dst)
+}
+
+// writeExecutable writes r to the executable dst through a temporary file,
+// so a failed write never leaves a broken program behind.
+func writeExecutable(r io.Reader, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create tools directory: %w", err)
+	}
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(dst), err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(dst), err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(dst), err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to install %s: %w", filepath.Base(dst), err)
+	}
+	return nil
+}
+
+// extractZip installs the named programs from a zip archive into dir,
+// wherever they are in the archive.
+func extractZip(archive, dir string, programs []string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	found := 0
+	for _, f := range zr.File {
+		name := filepath.Base(f.Name)
+		if f.FileInfo().IsDir() || !slices.Contains(programs, name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from archive: %w", name, err)
+		}
+		err = writeExecutable(rc, filepath.Join(dir, name))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		found++
+	}
+	if found < len(programs) {
+		return fmt.Errorf("archive is missing %s", strings.Join(programs, " or "))
+	}
+	return nil
+}
+
+// extractTarXz installs the named programs from a .tar.xz archive into dir.
+// The standard library cannot read xz, so the system tar unpacks it.
+func extractTarXz(ctx context.Context, archive, dir string, programs []string) error {
+	tmp, err := os.MkdirTemp("", "personal-musician-setup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if out, err := exec.CommandContext(ctx, "tar", "-xJf", archive, "-C", tmp).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to unpack archive: %w: %s", err, lastLine(string(out), ""))
+	}
+
+	found := 0
+	err = filepath.WalkDir(tmp, func(path string, e os.DirEntry, err error) error {
+		if err != nil || e.IsDir() || !slices.Contains(programs, e.Name()) {
+			return err
+		}
+		found++
+		return installFile(path, filepath.Join(dir, e.Name()))
+	})
+	if err != nil {
+		return err
+	}
+	if found < len(programs) {
+		return fmt.Errorf("archive is missing %s", strings.Join(programs, " or "))
+	}
+	return nil
+}
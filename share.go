@@ -0,0 +1,355 @@
+// Package main provides library sharing for Personal Musician.
+// "personal-musician share" serves the library on the local network as a
+// small web page, with a QR code for the address, so a phone on the same
+// Wi-Fi can download tracks one by one, or a playlist or the whole library
+// as a ZIP file. A password can be required with --password.
+package main
+
+import (
+	"archive/zip"
+	"crypto/subtle"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// defaultShareAddr is the address the library is shared on.
+const defaultShareAddr = ":8770"
+
+// shareServer serves the library to browsers.
+type shareServer struct {
+	password string // Empty means no password
+
+	mu     sync.Mutex
+	tracks map[string]string // Paths of listed tracks, by token
+}
+
+// shareTrack is a track on a page.
+type shareTrack struct {
+	Name string
+	URL  string
+	Size string
+}
+
+// sharePage is the data of a page.
+type sharePage struct {
+	Title     string
+	Back      bool // Link back to the library
+	ZipURL    string
+	M3UURL    string
+	Playlists []string
+	Tracks    []shareTrack
+}
+
+// runShare serves the library until interrupted.
+func runShare(args []string) error {
+	fs := flag.NewFlagSet("share", flag.ContinueOnError)
+	addr := fs.String("addr", defaultShareAddr, "listen on `addr`")
+	password := fs.String("password", os.Getenv("PM_SHARE_PASSWORD"), "require `password` to download")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("usage: personal-musician share [--addr addr] [--password password]")
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *addr, err)
+	}
+	s := &shareServer{password: *password, tracks: make(map[string]string)}
+	server := &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// Show the address phones can reach
+	host := "localhost"
+	if ip, err := outboundIP(); err == nil {
+		host = ip.String()
+	}
+	address := fmt.Sprintf("http://%s/", net.JoinHostPort(host, fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)))
+	if qr, err := qrcode.New(address, qrcode.Medium); err == nil && !asciiMode {
+		fmt.Print(qr.ToSmallString(false))
+	}
+	fmt.Printf("Sharing %s at %s\n", MusicDir, address)
+	if *password != "" {
+		fmt.Println("A password is required (any user name)")
+	}
+	fmt.Println("Press Ctrl+C to stop")
+	slog.Info("sharing library", "addr", listener.Addr().String())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, shutdownSignals...)
+	defer signal.Stop(interrupt)
+	<-interrupt
+	fmt.Println("Stopped")
+	return nil
+}
+
+// routes returns the share's request handler.
+func (s *shareServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /library.zip", s.handleLibraryZip)
+	mux.HandleFunc("GET /playlist/{name}", s.handlePlaylist)
+	mux.HandleFunc("GET /playlist/{name}/zip", s.handlePlaylistZip)
+	mux.HandleFunc("GET /playlist/{name}/m3u", s.handlePlaylistM3U)
+	mux.HandleFunc("GET /track/{token}/{file}", s.handleTrack)
+	return s.authorize(mux)
+}
+
+// authorize requires the password, if set, with HTTP basic authentication.
+func (s *shareServer) authorize(next http.Handler) http.Handler {
+	if s.password == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Personal Musician", charset="UTF-8"`)
+			http.Error(w, "password required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// register makes tracks downloadable and returns them for a page.
+func (s *shareServer) register(files []MusicFile) []shareTrack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tracks := make([]shareTrack, 0, len(files))
+	for _, f := range files {
+		token := fileToken(f.Path)
+		s.tracks[token] = f.Path
+		track := shareTrack{
+			Name: f.Name,
+			URL:  "/track/" + token + "/" + url.PathEscape(filepath.Base(f.Path)),
+		}
+		if info, err := os.Stat(f.Path); err == nil {
+			track.Size = FormatSize(info.Size())
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+// handleIndex lists the playlists and every track in the library.
+func (s *shareServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	files, err := ScanMusicFiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	playlists, err := ListPlaylists()
+	if err != nil {
+		slog.Warn("failed to list playlists", "err", err)
+	}
+	s.render(w, sharePage{
+		Title:     "Personal Musician",
+		ZipURL:    "/library.zip",
+		Playlists: playlists,
+		Tracks:    s.register(files),
+	})
+}
+
+// handlePlaylist lists the tracks of a playlist.
+func (s *shareServer) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	pl, ok := s.playlist(w, r)
+	if !ok {
+		return
+	}
+	base := "/playlist/" + url.PathEscape(pl.Name)
+	s.render(w, sharePage{
+		Title:  pl.Name,
+		Back:   true,
+		ZipURL: base + "/zip",
+		M3UURL: base + "/m3u",
+		Tracks: s.register(pl.Tracks),
+	})
+}
+
+// handlePlaylistZip sends the tracks of a playlist as a ZIP file.
+func (s *shareServer) handlePlaylistZip(w http.ResponseWriter, r *http.Request) {
+	if pl, ok := s.playlist(w, r); ok {
+		writeShareZip(w, pl.Name, pl.Tracks)
+	}
+}
+
+// handlePlaylistM3U sends a playlist as M3U, naming the files as they are
+// named in its ZIP file, so it plays once both are in one folder.
+func (s *shareServer) handlePlaylistM3U(w http.ResponseWriter, r *http.Request) {
+	pl, ok := s.playlist(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	w.Header().Set("Content-Disposition", attachment(pl.Name+".m3u"))
+	fmt.Fprintln(w, "#EXTM3U")
+	for _, name := range zipNames(pl.Tracks) {
+		fmt.Fprintln(w, name)
+	}
+}
+
+// handleLibraryZip sends the whole library as a ZIP file.
+func (s *shareServer) handleLibraryZip(w http.ResponseWriter, r *http.Request) {
+	files, err := ScanMusicFiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeShareZip(w, "Personal Musician", files)
+}
+
+// handleTrack sends a track that has been listed.
+func (s *shareServer) handleTrack(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	path, ok := s.tracks[r.PathValue("token")]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", attachment(filepath.Base(path)))
+	http.ServeFile(w, r, path)
+}
+
+// playlist loads the playlist named in the request, answering with an
+// error if there is none.
+func (s *shareServer) playlist(w http.ResponseWriter, r *http.Request) (Playlist, bool) {
+	name := r.PathValue("name")
+	if ValidatePlaylistName(name) != nil {
+		http.NotFound(w, r)
+		return Playlist{}, false
+	}
+	if _, err := os.Stat(playlistPath(name)); err != nil {
+		http.NotFound(w, r)
+		return Playlist{}, false
+	}
+	pl, err := LoadPlaylist(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return Playlist{}, false
+	}
+	return pl, true
+}
+
+// render writes a page.
+func (s *shareServer) render(w http.ResponseWriter, page sharePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := sharePageTemplate.Execute(w, page); err != nil {
+		slog.Warn("failed to render share page", "err", err)
+	}
+}
+
+// writeShareZip streams tracks as a ZIP file. Audio barely compresses, so
+// the files are stored as they are.
+func writeShareZip(w http.ResponseWriter, name string, files []MusicFile) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", attachment(name+".zip"))
+
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+	for i, zipName := range zipNames(files) {
+		if err := addZipFile(zw, files[i].Path, name+"/"+zipName); err != nil {
+			slog.Warn("failed to add track to ZIP", "path", files[i].Path, "err", err)
+		}
+	}
+}
+
+// addZipFile copies a file into a ZIP file.
+func addZipFile(zw *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: info.ModTime()}
+	out, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, f)
+	return err
+}
+
+// zipNames returns the file names of tracks, numbered where they clash.
+func zipNames(files []MusicFile) []string {
+	names := make([]string, len(files))
+	used := make(map[string]bool)
+	for i, f := range files {
+		ext := filepath.Ext(f.Path)
+		base := strings.TrimSuffix(filepath.Base(f.Path), ext)
+		name := base + ext
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// attachment returns a Content-Disposition header that downloads a file.
+func attachment(filename string) string {
+	return fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(filename))
+}
+
+// sharePageTemplate is the page listing playlists and tracks, laid out for
+// phones.
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0 auto; max-width: 40em; padding: 1em; background: #1e1e2e; color: #cdd6f4; }
+a { color: #89b4fa; text-decoration: none; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; color: #a6adc8; }
+ul { list-style: none; padding: 0; }
+li { display: flex; justify-content: space-between; gap: 1em; padding: .6em 0; border-bottom: 1px solid #313244; }
+.size { color: #6c7086; white-space: nowrap; }
+.actions a { display: inline-block; margin: 0 1em .5em 0; padding: .5em 1em; border-radius: .4em; background: #313244; }
+</style>
+</head>
+<body>
+{{if .Back}}<p><a href="/">‹ Library</a></p>{{end}}
+<h1>🎵 {{.Title}}</h1>
+<p class="actions">
+{{if .Tracks}}<a href="{{.ZipURL}}">Download all (ZIP)</a>{{end}}
+{{if .M3UURL}}<a href="{{.M3UURL}}">Playlist file (M3U)</a>{{end}}
+</p>
+{{if .Playlists}}
+<h2>Playlists</h2>
+<ul>{{range .Playlists}}<li><a href="/playlist/{{.}}">{{.}}</a></li>{{end}}</ul>
+{{end}}
+<h2>Tracks</h2>
+{{if .Tracks}}
+<ul>{{range .Tracks}}<li><a href="{{.URL}}" download>{{.Name}}</a><span class="size">{{.Size}}</span></li>{{end}}</ul>
+{{else}}
+<p>No tracks.</p>
+{{end}}
+</body>
+</html>
+`))
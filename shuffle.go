@@ -0,0 +1,105 @@
+// Package main provides shuffle mode for Personal Musician.
+// With shuffle on, the next track is drawn at random from the playlist
+// tracks not yet played in this round, so nothing repeats until every
+// track has played; then a new round starts. The library keeps its order,
+// and going back retraces the tracks that were actually played.
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// maxShuffleHistory bounds the tracks remembered for going back.
+const maxShuffleHistory = 1000
+
+// ToggleShuffle turns shuffle mode on or off and returns the new state.
+func (p *Player) ToggleShuffle() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setShuffleLocked(!p.shuffle)
+	return p.shuffle
+}
+
+// SetShuffle turns shuffle mode on or off.
+func (p *Player) SetShuffle(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setShuffleLocked(on)
+}
+
+// setShuffleLocked switches shuffle mode, starting a fresh round. Caller
+// must hold p.mu.
+func (p *Player) setShuffleLocked(on bool) {
+	p.shuffle = on
+	p.invalidateNextLocked()
+	p.shufflePlayed = make(map[string]bool)
+	p.shuffleHistory = nil
+}
+
+// nextShuffledLocked picks a random playlist track not yet played in this
+// round and records the current track as played. When every track has
+// played, a new round starts. Returns false if the playlist is empty.
+// Caller must hold p.mu.
+func (p *Player) nextShuffledLocked() (int, bool) {
+	index, ok := p.pickShuffledLocked()
+	if ok {
+		p.recordShuffledLocked()
+	}
+	return index, ok
+}
+
+// pickShuffledLocked picks the track that follows the current one in
+// shuffle mode without recording anything, so the next track can be
+// prepared ahead of time. Returns false if the playlist is empty. Caller
+// must hold p.mu.
+func (p *Player) pickShuffledLocked() (int, bool) {
+	if len(p.playlist) == 0 {
+		return 0, false
+	}
+	var unplayed []int
+	for i, f := range p.playlist {
+		if f.Path != p.currentFile && !p.shufflePlayed[f.Path] {
+			unplayed = append(unplayed, i)
+		}
+	}
+	if len(unplayed) == 0 {
+		// New round; avoid playing the last track twice in a row
+		for i, f := range p.playlist {
+			if f.Path != p.currentFile || len(p.playlist) == 1 {
+				unplayed = append(unplayed, i)
+			}
+		}
+	}
+	return unplayed[rand.IntN(len(unplayed))], true
+}
+
+// recordShuffledLocked marks the current track as played before moving on
+// and starts a new round once every track has played. Caller must hold p.mu.
+func (p *Player) recordShuffledLocked() {
+	if p.currentFile == "" {
+		return
+	}
+	p.shufflePlayed[p.currentFile] = true
+	p.shuffleHistory = append(p.shuffleHistory, p.currentFile)
+	if len(p.shuffleHistory) > maxShuffleHistory {
+		p.shuffleHistory = p.shuffleHistory[1:]
+	}
+	if !slices.ContainsFunc(p.playlist, func(f MusicFile) bool { return !p.shufflePlayed[f.Path] }) {
+		clear(p.shufflePlayed)
+	}
+}
+
+// prevShuffledLocked returns the playlist index of the track played before
+// the current one, or false when there is none. Caller must hold p.mu.
+func (p *Player) prevShuffledLocked() (int, bool) {
+	for len(p.shuffleHistory) > 0 {
+		last := len(p.shuffleHistory) - 1
+		path := p.shuffleHistory[last]
+		p.shuffleHistory = p.shuffleHistory[:last]
+		if i := slices.IndexFunc(p.playlist, func(f MusicFile) bool { return f.Path == path }); i >= 0 {
+			return i, true
+		}
+	}
+	return 0, false
+}
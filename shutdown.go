@@ -0,0 +1,61 @@
+// Package main provides graceful shutdown on signals for Personal Musician.
+// SIGINT, SIGTERM and SIGHUP (the terminal closing) stop playback, cancel
+// running yt-dlp processes and quit the TUI the same way as pressing q, so
+// the terminal is restored and the statistics and UI state are saved. A
+// second signal kills the program at once.
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shutdownSignals are the signals that quit the TUI gracefully.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// shutdownMsg asks the TUI to shut down after a signal.
+type shutdownMsg struct {
+	signal os.Signal
+}
+
+// handleShutdownSignals forwards shutdown signals to the program until it
+// exits. It replaces Bubble Tea's own handler, which ignores SIGHUP.
+func handleShutdownSignals(program *tea.Program) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			program.Send(shutdownMsg{signal: sig})
+		case <-done:
+			return
+		}
+		select {
+		case sig := <-signals:
+			slog.Warn("killed", "signal", sig.String())
+			program.Kill()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// shutdown stops downloads and quits the TUI. Playback is left to stop on
+// the way out, like when quitting with q, so the playing track and position
+// are saved for the next start first.
+func (m Model) shutdown(msg shutdownMsg) (tea.Model, tea.Cmd) {
+	slog.Info("shutting down", "signal", msg.signal.String())
+	m.cancelFunc()
+	m.downloader.CancelAll()
+	return m, tea.Quit
+}
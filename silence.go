@@ -0,0 +1,114 @@
+// Package main provides silence trimming for Personal Musician.
+// Many rips begin or end with seconds of silence. With trim_silence on
+// under [playback], the first and last seconds of each track are scanned
+// when it is opened, and playback starts at the first sound and moves on
+// after the last one, so tracks start immediately. Short pauses are kept,
+// as is a moment of silence either side of the sound.
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+)
+
+// Silence trimming tuning.
+const (
+	silenceScan      = 30 * time.Second       // How far into either end silence is looked for
+	silenceMin       = 500 * time.Millisecond // Shorter silence is left alone
+	silenceMargin    = 100 * time.Millisecond // Kept before the first and after the last sound
+	silenceThreshold = 0.003                  // About -50 dBFS
+)
+
+// SetTrimSilence turns silence trimming on or off. It applies from the
+// next track opened.
+func (p *Player) SetTrimSilence(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.trimSilence = on
+}
+
+// findSilence sets where the sound of the track starts and ends, leaving
+// the streamer at the beginning. Reading errors leave the track untrimmed.
+func (t *decodedTrack) findSilence() {
+	rate := t.format.SampleRate
+	length := t.streamer.Len()
+	scan := rate.N(silenceScan)
+	buf := make([][2]float64, 4096)
+
+	// loud reports the index of the first or last sample above the
+	// threshold in samples, or -1.
+	loud := func(samples [][2]float64, last bool) int {
+		found := -1
+		for i, s := range samples {
+			if math.Abs(s[0]) > silenceThreshold || math.Abs(s[1]) > silenceThreshold {
+				if !last {
+					return i
+				}
+				found = i
+			}
+		}
+		return found
+	}
+
+	// Leading silence: read until the first sound
+	start := 0
+	for pos := 0; pos < min(scan, length); {
+		n, ok := t.streamer.Stream(buf)
+		if i := loud(buf[:n], false); i >= 0 {
+			start = pos + i
+			break
+		}
+		pos += n
+		start = pos
+		if !ok {
+			break
+		}
+	}
+
+	// Trailing silence: read the end of the track for the last sound
+	end := length
+	if tail := max(start, length-scan); tail < length {
+		if err := t.streamer.Seek(tail); err != nil {
+			slog.Warn("failed to scan for silence", "file", t.path, "err", err)
+			t.streamer.Seek(0)
+			return
+		}
+		end = tail
+		for pos := tail; ; {
+			n, ok := t.streamer.Stream(buf)
+			if i := loud(buf[:n], true); i >= 0 {
+				end = pos + i + 1
+			}
+			pos += n
+			if !ok || n == 0 {
+				break
+			}
+		}
+	}
+
+	if err := t.streamer.Seek(0); err != nil {
+		slog.Warn("failed to scan for silence", "file", t.path, "err", err)
+		return
+	}
+	if start >= end {
+		return // Silent throughout; play it as it is
+	}
+	if start >= rate.N(silenceMin) {
+		t.start = max(0, start-rate.N(silenceMargin))
+	}
+	if length-end >= rate.N(silenceMin) {
+		t.end = min(length, end+rate.N(silenceMargin))
+	}
+}
+
+// trimChunk limits samples to the part of the track before its trimmed
+// end. It returns nothing once the end is reached. Called on the audio
+// thread.
+func (s *gaplessSource) trimChunk(samples [][2]float64) [][2]float64 {
+	if s.track.end == 0 {
+		return samples
+	}
+	left := s.rate.N(s.track.format.SampleRate.D(s.track.end - s.track.streamer.Position()))
+	return samples[:max(0, min(len(samples), left))]
+}
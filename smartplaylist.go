@@ -0,0 +1,372 @@
+// Package main provides smart playlists for Personal Musician.
+// A smart playlist is a list of rules such as "added < 30d", "plays > 5" or
+// "artist contains Beatles" instead of a list of tracks. Its tracks are
+// found in the library each time it is played, so it keeps up with new
+// downloads and listening. Smart playlists are defined under
+// [[smart_playlists]] in the config file, or made in the playlists view
+// and kept as .smart files next to the saved playlists.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smartPlaylistExt is the file extension of smart playlists made in the UI.
+const smartPlaylistExt = ".smart"
+
+// smartMatchAny is the rule line that makes a playlist match tracks that
+// pass any rule rather than all of them.
+const smartMatchAny = "match any"
+
+// SmartPlaylist is a named set of rules that selects library tracks.
+type SmartPlaylist struct {
+	Name  string   `toml:"name"`
+	Match string   `toml:"match"` // "all" (the default) or "any"
+	Rules []string `toml:"rules"`
+
+	fromConfig bool // Defined in the config file, so not editable in the UI
+}
+
+// smartRule is a parsed rule: a track field compared with a value.
+type smartRule struct {
+	field string
+	op    string
+	text  string        // Lowercase value of text fields
+	num   float64       // Value of numeric fields
+	dur   time.Duration // Value of length, and of added and played as an age
+	flag  bool          // Value of liked
+}
+
+// smartFieldKinds maps the fields rules can test to their kind of value.
+var smartFieldKinds = map[string]string{
+	"title":  "text",
+	"artist": "text",
+	"album":  "text",
+	"genre":  "text",
+	"name":   "text",
+	"year":   "number",
+	"plays":  "number",
+	"rating": "number",
+	"length": "duration",
+	"added":  "age",
+	"played": "age",
+	"liked":  "bool",
+}
+
+// smartOps are the operators each kind of field accepts.
+var smartOps = map[string][]string{
+	"text":     {"=", "!=", "~", "!~"},
+	"number":   {"=", "!=", "<", ">", "<=", ">="},
+	"duration": {"<", ">", "<=", ">="},
+	"age":      {"<", ">", "<=", ">="},
+	"bool":     {"=", "!="},
+}
+
+// smartRulePattern splits a rule into field, operator and value. "contains"
+// is another way to write ~.
+var smartRulePattern = regexp.MustCompile(`^\s*(\w+)\s*(!=|<=|>=|!~|=|~|<|>|\s(?:not\s+)?contains\s)\s*(.*?)\s*$`)
+
+// parseSmartRule parses a rule such as "plays > 5".
+func parseSmartRule(rule string) (smartRule, error) {
+	parts := smartRulePattern.FindStringSubmatch(rule)
+	if parts == nil {
+		return smartRule{}, fmt.Errorf("invalid rule %q (want a field, an operator and a value, such as \"plays > 5\")", rule)
+	}
+	r := smartRule{field: strings.ToLower(parts[1]), op: strings.Join(strings.Fields(parts[2]), " ")}
+	switch r.op {
+	case "contains":
+		r.op = "~"
+	case "not contains":
+		r.op = "!~"
+	}
+	value := strings.Trim(parts[3], `"'`)
+
+	kind, ok := smartFieldKinds[r.field]
+	if !ok {
+		return smartRule{}, fmt.Errorf("rule %q: unknown field %q", rule, r.field)
+	}
+	if !slices.Contains(smartOps[kind], r.op) {
+		return smartRule{}, fmt.Errorf("rule %q: %s can't be compared with %s", rule, r.field, r.op)
+	}
+
+	var err error
+	switch kind {
+	case "text":
+		r.text = strings.ToLower(value)
+	case "number":
+		r.num, err = strconv.ParseFloat(value, 64)
+	case "duration":
+		r.dur, err = time.ParseDuration(value)
+	case "age":
+		r.dur, err = parseAge(value)
+	case "bool":
+		r.flag, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return smartRule{}, fmt.Errorf("rule %q: invalid value %q", rule, value)
+	}
+	return r, nil
+}
+
+// parseAge parses an age such as "30d", "2w" or "1y", or any Go duration.
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour}
+	if len(s) > 1 {
+		if unit, ok := units[s[len(s)-1:]]; ok {
+			n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// matches reports whether a track passes the rule.
+func (r smartRule) matches(file MusicFile, stats TrackStats, now time.Time) bool {
+	switch r.field {
+	case "title":
+		title := file.Tags.Title
+		if title == "" {
+			title = file.Name
+		}
+		return r.matchText(title)
+	case "artist":
+		return r.matchText(file.Tags.Artist)
+	case "album":
+		return r.matchText(file.Tags.Album)
+	case "genre":
+		return r.matchText(file.Tags.Genre)
+	case "name":
+		return r.matchText(file.Name)
+	case "year":
+		year, err := strconv.Atoi(strings.TrimSpace(file.Tags.Year))
+		return err == nil && compare(float64(year), r.op, r.num)
+	case "plays":
+		return compare(float64(stats.PlayCount), r.op, r.num)
+	case "rating":
+		return compare(float64(stats.Rating), r.op, r.num)
+	case "length":
+		return file.Duration > 0 && compare(float64(file.Duration), r.op, float64(r.dur))
+	case "added":
+		return compare(float64(now.Sub(file.ModTime)), r.op, float64(r.dur))
+	case "played":
+		if stats.LastPlayed.IsZero() {
+			return r.op == ">" || r.op == ">=" // Never played is longer ago than anything
+		}
+		return compare(float64(now.Sub(stats.LastPlayed)), r.op, float64(r.dur))
+	case "liked":
+		return (stats.Liked == r.flag) == (r.op == "=")
+	}
+	return false
+}
+
+// matchText compares a text field, ignoring case.
+func (r smartRule) matchText(value string) bool {
+	value = strings.ToLower(value)
+	switch r.op {
+	case "=":
+		return value == r.text
+	case "!=":
+		return value != r.text
+	case "~":
+		return strings.Contains(value, r.text)
+	default: // !~
+		return !strings.Contains(value, r.text)
+	}
+}
+
+// compare applies a numeric comparison.
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	case "<=":
+		return a <= b
+	default: // >=
+		return a >= b
+	}
+}
+
+// validate checks the name and every rule of the playlist.
+func (sp SmartPlaylist) validate() error {
+	if err := ValidatePlaylistName(sp.Name); err != nil {
+		return err
+	}
+	if sp.Match != "" && sp.Match != "all" && sp.Match != "any" {
+		return fmt.Errorf("invalid match %q (want all or any)", sp.Match)
+	}
+	if len(sp.Rules) == 0 {
+		return fmt.Errorf("smart playlist %q has no rules", sp.Name)
+	}
+	for _, rule := range sp.Rules {
+		if _, err := parseSmartRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tracks returns the library files that pass the playlist's rules, in
+// library order.
+func (sp SmartPlaylist) Tracks(files []MusicFile, stats *StatsStore) ([]MusicFile, error) {
+	rules := make([]smartRule, len(sp.Rules))
+	for i, rule := range sp.Rules {
+		r, err := parseSmartRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = r
+	}
+
+	now := time.Now()
+	var tracks []MusicFile
+	for _, f := range files {
+		trackStats := stats.Get(f.Path)
+		passed := 0
+		for _, r := range rules {
+			if r.matches(f, trackStats, now) {
+				passed++
+			}
+		}
+		if passed == len(rules) || (sp.Match == "any" && passed > 0) {
+			tracks = append(tracks, f)
+		}
+	}
+	return tracks, nil
+}
+
+// Summary returns the rules on one line.
+func (sp SmartPlaylist) Summary() string {
+	summary := strings.Join(sp.Rules, "; ")
+	if sp.Match == "any" {
+		summary = smartMatchAny + "; " + summary
+	}
+	return summary
+}
+
+// parseSmartSummary is the reverse of Summary: it reads rules separated by
+// semicolons, as typed in the smart playlist dialog.
+func parseSmartSummary(name, summary string) (SmartPlaylist, error) {
+	sp := SmartPlaylist{Name: name}
+	for _, rule := range strings.Split(summary, ";") {
+		sp.addRule(rule)
+	}
+	return sp, sp.validate()
+}
+
+// addRule adds a rule line, or applies it if it is "match any".
+func (sp *SmartPlaylist) addRule(line string) {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "":
+	case strings.EqualFold(line, smartMatchAny):
+		sp.Match = "any"
+	default:
+		sp.Rules = append(sp.Rules, line)
+	}
+}
+
+// ListSmartPlaylists returns the smart playlists of the config file followed
+// by the ones made in the UI, sorted. Unreadable files are skipped.
+func ListSmartPlaylists() ([]SmartPlaylist, error) {
+	var playlists []SmartPlaylist
+	for _, sp := range config.SmartPlaylists {
+		sp.fromConfig = true
+		playlists = append(playlists, sp)
+	}
+
+	entries, err := os.ReadDir(PlaylistDir)
+	if os.IsNotExist(err) {
+		return playlists, nil
+	}
+	if err != nil {
+		return playlists, fmt.Errorf("failed to read playlists: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), smartPlaylistExt) {
+			continue
+		}
+		sp, err := loadSmartPlaylist(strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		if err != nil {
+			continue
+		}
+		playlists = append(playlists, sp)
+	}
+	return playlists, nil
+}
+
+// loadSmartPlaylist reads a smart playlist made in the UI: one rule per
+// line, with # starting a comment.
+func loadSmartPlaylist(name string) (SmartPlaylist, error) {
+	f, err := os.Open(smartPlaylistPath(name))
+	if err != nil {
+		return SmartPlaylist{}, fmt.Errorf("failed to open smart playlist: %w", err)
+	}
+	defer f.Close()
+
+	sp := SmartPlaylist{Name: name}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); !strings.HasPrefix(line, "#") {
+			sp.addRule(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return SmartPlaylist{}, fmt.Errorf("failed to read smart playlist: %w", err)
+	}
+	return sp, nil
+}
+
+// SaveSmartPlaylist writes a smart playlist, replacing any previous version.
+func SaveSmartPlaylist(sp SmartPlaylist) error {
+	if err := os.MkdirAll(PlaylistDir, 0755); err != nil {
+		return fmt.Errorf("failed to create playlist directory: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Personal Musician smart playlist: one rule per line\n")
+	if sp.Match == "any" {
+		b.WriteString(smartMatchAny + "\n")
+	}
+	for _, rule := range sp.Rules {
+		b.WriteString(rule + "\n")
+	}
+
+	if err := os.WriteFile(smartPlaylistPath(sp.Name), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write smart playlist: %w", err)
+	}
+	return nil
+}
+
+// DeleteSmartPlaylist removes a smart playlist made in the UI.
+func DeleteSmartPlaylist(name string) error {
+	if err := os.Remove(smartPlaylistPath(name)); err != nil {
+		return fmt.Errorf("failed to delete smart playlist %s: %w", name, err)
+	}
+	return nil
+}
+
+// smartPlaylistPath returns the file path of a smart playlist.
+func smartPlaylistPath(name string) string {
+	return filepath.Join(PlaylistDir, name+smartPlaylistExt)
+}
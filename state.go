@@ -0,0 +1,229 @@
+// Package main provides UI state persistence for Personal Musician.
+// On exit the open view, library cursor and filters, play queue, playing
+// track and position, volume, playback modes, equalizer and visualizer mode
+// are saved to a small JSON file and restored at startup, so the player
+// reopens where it was left, offering to continue the track that was playing.
+// The file is also saved whenever the play queue changes, so a carefully
+// built queue survives a crash or power cut as well as quitting.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// StateFile is where the UI state is saved between runs.
+var StateFile = dataPath("state.json")
+
+// SessionState is the UI state saved between runs.
+type SessionState struct {
+	View         string      `json:"view"`
+	LibraryTrack string      `json:"library_track,omitempty"` // Path under the library cursor
+	Filter       string      `json:"filter,omitempty"`
+	LibraryView  LibraryView `json:"library_view"`
+	Queue        []string    `json:"queue,omitempty"` // Paths of the queued tracks
+	QueueCursor  int         `json:"queue_cursor,omitempty"`
+	Track        string      `json:"track,omitempty"`    // Path of the playing track
+	Position     float64     `json:"position,omitempty"` // Seconds into the playing track
+	Volume       int         `json:"volume"`
+	Muted        bool        `json:"muted,omitempty"`
+	Shuffle      bool        `json:"shuffle,omitempty"`
+	Repeat       RepeatMode  `json:"repeat,omitempty"`
+	Equalizer    []float64   `json:"equalizer,omitempty"`  // Band gains in dB
+	Visualizer   string      `json:"visualizer,omitempty"` // Visualizer mode
+}
+
+// resumeModes are the values of resume under [playback].
+var resumeModes = []string{"ask", "play", "never"}
+
+// restorableViews are the views that can be reopened without data from the
+// previous run. Others, such as search results, reopen as the library.
+var restorableViews = []View{ViewLibrary, ViewQueue, ViewVisualizer, ViewDownloads, ViewPlaylists, ViewLog}
+
+// LoadState reads the saved UI state. A missing file yields nil.
+func LoadState(path string) (*SessionState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var state SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return &state, nil
+}
+
+// stateSaves orders writes of the state file, so a background save that
+// finishes late can't replace a newer state, such as the one saved on exit.
+var stateSaves struct {
+	sync.Mutex
+	next    int // Number of the next save
+	written int // Number of the last save written
+}
+
+// nextStateSave numbers a state save in the order the states were captured.
+func nextStateSave() int {
+	stateSaves.Lock()
+	defer stateSaves.Unlock()
+	stateSaves.next++
+	return stateSaves.next
+}
+
+// SaveState writes the UI state to disk.
+func SaveState(path string, state SessionState) error {
+	return saveStateInOrder(path, state, nextStateSave())
+}
+
+// saveStateInOrder writes the UI state numbered seq by nextStateSave,
+// unless a later state has been written already.
+func saveStateInOrder(path string, state SessionState, seq int) error {
+	stateSaves.Lock()
+	defer stateSaves.Unlock()
+	if seq < stateSaves.written {
+		return nil
+	}
+	stateSaves.written = seq
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// captureState collects the UI state to save.
+func (m Model) captureState() SessionState {
+	playback := m.player.GetState()
+	state := SessionState{
+		View:        m.currentView.String(),
+		Filter:      m.filterInput.Value(),
+		LibraryView: m.libraryView,
+		Volume:      playback.Volume,
+		Muted:       playback.Muted,
+		Shuffle:     playback.Shuffle,
+		Repeat:      playback.Repeat,
+		Visualizer:  m.vizMode.String(),
+	}
+	if eqPresetIndex(m.player.EqualizerGains()) != 0 {
+		state.Equalizer = m.player.EqualizerGains()
+	}
+	if visible := m.visibleLibrary(); m.libraryCursor < len(visible) {
+		state.LibraryTrack = visible[m.libraryCursor].Path
+	}
+	for _, f := range m.player.GetQueue() {
+		state.Queue = append(state.Queue, f.Path)
+	}
+	state.QueueCursor = min(m.queueCursor, max(0, len(state.Queue)-1))
+	if playback.IsPlaying {
+		state.Track = playback.CurrentFile
+		state.Position = playback.Position.Seconds()
+	}
+	return state
+}
+
+// SaveState saves the UI state for the next run.
+func (m Model) SaveState(path string) error {
+	return SaveState(path, m.captureState())
+}
+
+// autoSaveQueue saves the UI state in the background when the play queue
+// has changed since it was last saved.
+func (m Model) autoSaveQueue() (Model, tea.Cmd) {
+	version := m.player.QueueVersion()
+	if version == m.savedQueue {
+		return m, nil
+	}
+	m.savedQueue = version
+	state, seq := m.captureState(), nextStateSave()
+	return m, func() tea.Msg {
+		if err := saveStateInOrder(StateFile, state, seq); err != nil {
+			slog.Warn("failed to save the play queue", "err", err)
+		}
+		return nil
+	}
+}
+
+// restoreState applies a saved UI state. The library cursor is placed once
+// the library has been scanned; queued tracks that no longer exist are dropped.
+// The playing track is loaded paused at its saved position, and the player
+// offers to continue it, plays it right away or leaves it out as resume
+// under [playback] says.
+func (m Model) restoreState(state *SessionState) Model {
+	if state == nil {
+		return m
+	}
+
+	for _, v := range restorableViews {
+		if v.String() == state.View {
+			m.currentView = v
+			m.paneLeftView = v
+		}
+	}
+	m.filterInput.SetValue(state.Filter)
+	m.libraryView = state.LibraryView
+	m.restoreTrack = state.LibraryTrack
+
+	m.player.SetVolume(state.Volume)
+	m.player.SetMuted(state.Muted)
+	m.player.SetShuffle(state.Shuffle)
+	m.player.SetRepeat(state.Repeat)
+	m.player.SetEqualizer(state.Equalizer)
+	if state.Visualizer == VisualizerWaveform.String() {
+		m.vizMode = VisualizerWaveform
+	}
+	for _, path := range state.Queue {
+		if _, err := os.Stat(path); err == nil {
+			m.player.Enqueue(musicFileFromPath(path))
+		}
+	}
+	m.queueCursor = min(state.QueueCursor, max(0, m.player.GetState().QueueLength-1))
+	m.savedQueue = m.player.QueueVersion() // Restored, not changed
+	if _, err := os.Stat(state.Track); state.Track != "" && err == nil && config.Playback.Resume != "never" {
+		position := time.Duration(state.Position * float64(time.Second))
+		track := musicFileFromPath(state.Track)
+		if err := m.player.CueTrack(track, position); err != nil {
+			appLog.Add("error", err.Error())
+			return m
+		}
+		if config.Playback.Resume == "play" {
+			m.player.TogglePause()
+			return m
+		}
+		m.dialog = NewConfirmDialog(T("Resume playback?"),
+			Tf("Continue %s from %s where you left off?", track.Name, FormatDuration(position)),
+			func(m Model, _ string) (tea.Model, tea.Cmd) {
+				m.player.TogglePause()
+				return m, nil
+			})
+	}
+	return m
+}
+
+// restoreLibraryCursor moves the library cursor to the track saved in the
+// state, once the library has been loaded.
+func (m Model) restoreLibraryCursor() Model {
+	if m.restoreTrack == "" {
+		return m
+	}
+	for i, f := range m.visibleLibrary() {
+		if f.Path == m.restoreTrack {
+			m.libraryCursor = i
+			break
+		}
+	}
+	m.restoreTrack = ""
+	return m
+}
@@ -0,0 +1,220 @@
+// Package main provides per-track statistics for Personal Musician.
+// This module stores liked flags, ratings and play counts in a small JSON
+// file so the library can be sorted and filtered by them, along with the
+// length of each listening session.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// StatsFile is where per-track statistics are stored.
+var StatsFile = dataPath("stats.json")
+
+// TrackStats holds the statistics of a single track.
+type TrackStats struct {
+	Liked      bool      `json:"liked,omitempty"`
+	Rating     int       `json:"rating,omitempty"` // 1 to 5 stars; 0 is unrated
+	PlayCount  int       `json:"play_count,omitempty"`
+	LastPlayed time.Time `json:"last_played,omitempty"`
+}
+
+// Session records one run of the application.
+type Session struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	ListenedSeconds int64     `json:"listened_seconds"` // Time spent playing music
+}
+
+// statsData is the on-disk layout of the statistics file.
+type statsData struct {
+	Tracks   map[string]TrackStats `json:"tracks"`
+	Sessions []Session             `json:"sessions,omitempty"`
+}
+
+// StatsStore keeps track statistics keyed by file path and persists them.
+type StatsStore struct {
+	mu       sync.Mutex
+	path     string
+	tracks   map[string]TrackStats
+	sessions []Session
+	version  int // Bumped whenever a track's stats change
+}
+
+// LoadStats reads the statistics file. A missing file yields an empty store.
+func LoadStats(path string) (*StatsStore, error) {
+	s := &StatsStore{path: path, tracks: make(map[string]TrackStats)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read stats: %w", err)
+	}
+
+	var stored statsData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return s, fmt.Errorf("failed to parse stats: %w", err)
+	}
+	if stored.Tracks == nil {
+		// Older files hold only the per-track map
+		if err := json.Unmarshal(data, &s.tracks); err != nil {
+			return s, fmt.Errorf("failed to parse stats: %w", err)
+		}
+		return s, nil
+	}
+	s.tracks = stored.Tracks
+	s.sessions = stored.Sessions
+	return s, nil
+}
+
+// Get returns the statistics of a track.
+func (s *StatsStore) Get(path string) TrackStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tracks[path]
+}
+
+// ToggleLiked flips the liked flag of a track and saves the store.
+// Returns the new liked state.
+func (s *StatsStore) ToggleLiked(path string) (bool, error) {
+	s.mu.Lock()
+	t := s.tracks[path]
+	t.Liked = !t.Liked
+	s.tracks[path] = t
+	s.version++
+	s.mu.Unlock()
+
+	return t.Liked, s.Save()
+}
+
+// SetRating sets the star rating of a track, 0 to 5 with 0 clearing it,
+// and saves the store.
+func (s *StatsStore) SetRating(path string, rating int) error {
+	if rating < 0 || rating > maxRating {
+		return fmt.Errorf("invalid rating %d (want 0 to %d)", rating, maxRating)
+	}
+	s.mu.Lock()
+	t := s.tracks[path]
+	t.Rating = rating
+	s.tracks[path] = t
+	s.version++
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Merge folds the statistics of the tracks at from into the track at into,
+// as when copies of a song are merged, and saves the store. Play counts add
+// up; the track is liked if any copy was and keeps the best rating and the
+// latest play.
+func (s *StatsStore) Merge(into string, from []string) error {
+	s.mu.Lock()
+	t := s.tracks[into]
+	for _, path := range from {
+		other, ok := s.tracks[path]
+		if !ok || path == into {
+			continue
+		}
+		t.Liked = t.Liked || other.Liked
+		t.Rating = max(t.Rating, other.Rating)
+		t.PlayCount += other.PlayCount
+		if other.LastPlayed.After(t.LastPlayed) {
+			t.LastPlayed = other.LastPlayed
+		}
+		delete(s.tracks, path)
+	}
+	if t != (TrackStats{}) {
+		s.tracks[into] = t
+	}
+	s.version++
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Move keeps the statistics of renamed or moved tracks under their new
+// paths, given by old path in moved, and saves the store.
+func (s *StatsStore) Move(moved map[string]string) error {
+	s.mu.Lock()
+	for from, to := range moved {
+		if t, ok := s.tracks[from]; ok {
+			delete(s.tracks, from)
+			s.tracks[to] = t
+		}
+	}
+	s.version++
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Version returns a number that changes whenever a track's stats change,
+// so cached views can tell when to refresh.
+func (s *StatsStore) Version() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// RecordPlay counts a completed playback of a track, adds it to the
+// listening history and saves the store.
+func (s *StatsStore) RecordPlay(path string, listened time.Duration) error {
+	now := time.Now()
+	s.mu.Lock()
+	t := s.tracks[path]
+	t.PlayCount++
+	t.LastPlayed = now
+	s.tracks[path] = t
+	s.version++
+	s.mu.Unlock()
+
+	historyErr := AppendHistory(HistoryFile, Play{Path: path, Time: now, Listened: listened.Seconds()})
+	if err := s.Save(); err != nil {
+		return err
+	}
+	return historyErr
+}
+
+// RecordSession adds a finished listening session and saves the store.
+func (s *StatsStore) RecordSession(start, end time.Time, listened time.Duration) error {
+	s.mu.Lock()
+	s.sessions = append(s.sessions, Session{
+		Start:           start,
+		End:             end,
+		ListenedSeconds: int64(listened / time.Second),
+	})
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Sessions returns the recorded listening sessions, oldest first.
+func (s *StatsStore) Sessions() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := make([]Session, len(s.sessions))
+	copy(sessions, s.sessions)
+	return sessions
+}
+
+// Save writes the store to disk.
+func (s *StatsStore) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(statsData{Tracks: s.tracks, Sessions: s.sessions}, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode stats: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package main provides the persistent status bar for Personal Musician.
+// The bar sits at the bottom of the screen and shows the active view,
+// download activity, library size, playback modes and pending errors.
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errorMsg reports an error that stays in the status bar until dismissed.
+type errorMsg string
+
+// String returns the display name of the view.
+func (v View) String() string {
+	switch v {
+	case ViewSearch:
+		return "Search"
+	case ViewResults:
+		return "Results"
+	case ViewQueue:
+		return "Queue"
+	case ViewVisualizer:
+		return "Visualizer"
+	case ViewDownloads:
+		return "Downloads"
+	case ViewPlaylists:
+		return "Playlists"
+	case ViewPlaylistEditor:
+		return "Playlist editor"
+	case ViewLog:
+		return "Log"
+	case ViewJellyfin:
+		return "Jellyfin"
+	case ViewDuplicates:
+		return "Duplicates"
+	case ViewBrowse:
+		return "Artists"
+	case ViewOrganize:
+		return "Organize library"
+	default:
+		return "Library"
+	}
+}
+
+// renderStatusBar renders the bottom status bar across the full width.
+func (m Model) renderStatusBar() string {
+	state := m.player.GetState()
+
+	segments := []string{
+		T(m.currentView.String()),
+		"♫ " + Tf("%d tracks", len(m.libraryFiles)),
+	}
+
+	// Downloads in flight (active plus queued)
+	downloads := m.downloader.PendingCount()
+	if m.downloader.IsDownloading() {
+		downloads++
+	}
+	if downloads > 0 {
+		segments = append(segments, fmt.Sprintf("⇩ %d", downloads))
+	}
+
+	if m.cast != nil {
+		segments = append(segments, "🎧 "+m.cast.session.Name())
+	}
+	if m.syncLeader != "" {
+		segments = append(segments, "⇄ "+m.syncLeader)
+	}
+
+	if modes := m.renderStatusModes(state); modes != "" {
+		segments = append(segments, modes)
+	}
+
+	left := strings.Join(segments, " │ ")
+
+	// Pending errors take precedence over informational messages
+	right := m.statusMessage
+	if n := len(m.errors); n > 0 {
+		right = fmt.Sprintf("⚠ %s", m.errors[n-1])
+		if n > 1 {
+			right = fmt.Sprintf("⚠ (%d) %s", n, m.errors[n-1])
+		}
+		right += "  " + T("[E: dismiss]")
+	}
+
+	// Truncate the message so the bar stays on one line
+	space := m.width - lipgloss.Width(left) - 4
+	if space < 0 {
+		space = 0
+	}
+	if runes := []rune(right); len(runes) > space {
+		right = string(runes[:space])
+	}
+
+	gap := m.width - lipgloss.Width(left) - lipgloss.Width(right) - 2
+	if gap < 1 {
+		gap = 1
+	}
+	line := left + strings.Repeat(" ", gap) + right
+
+	if len(m.errors) > 0 {
+		return statusBarErrorStyle.Width(m.width).Render(line)
+	}
+	return statusBarStyle.Width(m.width).Render(line)
+}
+
+// renderStatusModes renders the shuffle and repeat indicators, if enabled.
+func (m Model) renderStatusModes(state PlaybackState) string {
+	var modes []string
+	if state.Shuffle {
+		modes = append(modes, "🔀 "+T("shuffle"))
+	}
+	switch state.Repeat {
+	case RepeatAll:
+		modes = append(modes, "🔁 "+T("repeat"))
+	case RepeatTrack:
+		modes = append(modes, "🔂 "+T("repeat one"))
+	}
+	return strings.Join(modes, " ")
+}
@@ -0,0 +1,317 @@
+// Package main provides ID3 tag reading for Personal Musician.
+// This module parses the common text frames of ID3v2.2/2.3/2.4 tags,
+// falling back to an ID3v1 trailer when no ID3v2 tag is present, and
+// extracts embedded album art.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Tags holds the textual metadata of a music file.
+type Tags struct {
+	Title  string
+	Artist string
+	Album  string
+	Track  string
+	Genre  string
+	Year   string
+
+	// Comment is the comment without a description, such as the source URL
+	// of a download.
+	Comment string
+}
+
+// TrackNumber returns the track number, reading "3" and "3/12" alike, or 0
+// when the tag is missing or not a number.
+func (t Tags) TrackNumber() int {
+	number, _, _ := strings.Cut(strings.TrimSpace(t.Track), "/")
+	n, err := strconv.Atoi(strings.TrimSpace(number))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// IsEmpty reports whether no tag fields are set.
+func (t Tags) IsEmpty() bool {
+	return t == Tags{}
+}
+
+// ReadTags reads the ID3 tags of an MP3 file.
+// Files without tags yield empty Tags and no error.
+func ReadTags(path string) (Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tags{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	tags, err := readID3v2(f)
+	if err != nil {
+		return Tags{}, err
+	}
+	if tags.IsEmpty() {
+		return readID3v1(f)
+	}
+	return tags, nil
+}
+
+// readID3v2 parses an ID3v2 tag at the start of the file.
+func readID3v2(f io.ReadSeeker) (Tags, error) {
+	var tags Tags
+	err := forEachID3v2Frame(f, func(id string, data []byte) {
+		switch id {
+		case "TIT2", "TT2":
+			tags.Title = decodeText(data)
+		case "TPE1", "TP1":
+			tags.Artist = decodeText(data)
+		case "TALB", "TAL":
+			tags.Album = decodeText(data)
+		case "TRCK", "TRK":
+			tags.Track = decodeText(data)
+		case "TCON", "TCO":
+			tags.Genre = normalizeGenre(decodeText(data))
+		case "TYER", "TDRC", "TYE":
+			tags.Year = decodeText(data)
+		case "COMM", "COM":
+			if desc, text, ok := parseComment(data); ok && desc == "" {
+				tags.Comment = text
+			}
+		}
+	})
+	return tags, err
+}
+
+// parseComment splits a comment frame into its description and text.
+// Layout: encoding, 3-byte language, description (NUL terminated), text.
+func parseComment(data []byte) (desc, text string, ok bool) {
+	if len(data) < 4 {
+		return "", "", false
+	}
+	enc, rest := data[0], data[4:]
+	end, width := bytes.IndexByte(rest, 0), 1
+	if enc == 1 || enc == 2 {
+		// UTF-16 descriptions end with a two-byte NUL on an even offset
+		end, width = -1, 2
+		for i := 0; i+1 < len(rest); i += 2 {
+			if rest[i] == 0 && rest[i+1] == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return "", "", false
+	}
+	return decodeText(append([]byte{enc}, rest[:end]...)), decodeText(append([]byte{enc}, rest[end+width:]...)), true
+}
+
+// forEachID3v2Frame calls fn with the ID and body of every frame of the
+// ID3v2 tag at the start of the file. Files without a tag have no frames.
+func forEachID3v2Frame(f io.ReadSeeker, fn func(id string, data []byte)) error {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:3]) != "ID3" {
+		return nil // No ID3v2 tag
+	}
+	version := header[3]
+	flags := header[5]
+	size := syncsafe(header[6:10])
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(f, body); err != nil {
+		return fmt.Errorf("failed to read ID3 tag: %w", err)
+	}
+
+	// Skip the extended header, if any
+	if flags&0x40 != 0 && version >= 3 && len(body) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if version == 4 {
+			extSize = syncsafe(body[:4])
+		}
+		if extSize > len(body) {
+			return nil
+		}
+		body = body[extSize:]
+	}
+
+	// Frame layout differs between v2.2 and later versions
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		var frameSize int
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 4:
+			frameSize = syncsafe(body[4:8])
+		default:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		if frameSize <= 0 || headerLen+frameSize > len(body) {
+			break
+		}
+		fn(id, body[headerLen:headerLen+frameSize])
+		body = body[headerLen+frameSize:]
+	}
+
+	return nil
+}
+
+// ReadPicture returns the embedded album art of an MP3 file, preferring the
+// front cover. Files without a picture yield nil and no error.
+func ReadPicture(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	var picture []byte
+	err = forEachID3v2Frame(f, func(id string, data []byte) {
+		var picType byte
+		var image []byte
+		var ok bool
+		switch id {
+		case "APIC":
+			picType, image, ok = parseAPIC(data)
+		case "PIC":
+			picType, image, ok = parsePIC(data)
+		}
+		if ok && (picture == nil || picType == 3) { // 3 is the front cover
+			picture = image
+		}
+	})
+	return picture, err
+}
+
+// parseAPIC splits an ID3v2.3/2.4 APIC frame into picture type and image data.
+// Layout: encoding, MIME type (NUL terminated), picture type, description, data.
+func parseAPIC(data []byte) (byte, []byte, bool) {
+	if len(data) < 2 {
+		return 0, nil, false
+	}
+	enc := data[0]
+	mimeEnd := bytes.IndexByte(data[1:], 0)
+	if mimeEnd < 0 || 1+mimeEnd+2 > len(data) {
+		return 0, nil, false
+	}
+	rest := data[1+mimeEnd+1:]
+	return splitPicture(enc, rest[0], rest[1:])
+}
+
+// parsePIC splits an ID3v2.2 PIC frame into picture type and image data.
+// Layout: encoding, 3-byte image format, picture type, description, data.
+func parsePIC(data []byte) (byte, []byte, bool) {
+	if len(data) < 5 {
+		return 0, nil, false
+	}
+	return splitPicture(data[0], data[4], data[5:])
+}
+
+// splitPicture skips the description, terminated according to the text
+// encoding, and returns the image data that follows it.
+func splitPicture(enc, picType byte, rest []byte) (byte, []byte, bool) {
+	if enc == 1 || enc == 2 {
+		// UTF-16 descriptions end with a two-byte NUL on an even offset
+		for i := 0; i+1 < len(rest); i += 2 {
+			if rest[i] == 0 && rest[i+1] == 0 {
+				return picType, rest[i+2:], true
+			}
+		}
+		return 0, nil, false
+	}
+	end := bytes.IndexByte(rest, 0)
+	if end < 0 {
+		return 0, nil, false
+	}
+	return picType, rest[end+1:], true
+}
+
+// readID3v1 parses an ID3v1 trailer in the last 128 bytes of the file.
+func readID3v1(f io.ReadSeeker) (Tags, error) {
+	if _, err := f.Seek(-128, io.SeekEnd); err != nil {
+		return Tags{}, nil // File too short for a trailer
+	}
+	trailer := make([]byte, 128)
+	if _, err := io.ReadFull(f, trailer); err != nil || string(trailer[:3]) != "TAG" {
+		return Tags{}, nil
+	}
+
+	field := func(b []byte) string {
+		return strings.TrimSpace(string(bytes.TrimRight(b, "\x00")))
+	}
+	tags := Tags{
+		Title:  field(trailer[3:33]),
+		Artist: field(trailer[33:63]),
+		Album:  field(trailer[63:93]),
+		Year:   field(trailer[93:97]),
+		Genre:  id3v1Genre(int(trailer[127])),
+	}
+	// ID3v1.1 stores the track number in the last comment byte
+	if trailer[125] == 0 && trailer[126] != 0 {
+		tags.Track = fmt.Sprint(trailer[126])
+	}
+	return tags, nil
+}
+
+// syncsafe decodes a 4-byte ID3 syncsafe integer.
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// decodeText decodes an ID3v2 text frame according to its encoding byte.
+func decodeText(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	enc, text := data[0], data[1:]
+
+	var s string
+	switch enc {
+	case 1, 2: // UTF-16 with BOM, UTF-16BE
+		bigEndian := enc == 2
+		if len(text) >= 2 {
+			switch {
+			case text[0] == 0xff && text[1] == 0xfe:
+				bigEndian, text = false, text[2:]
+			case text[0] == 0xfe && text[1] == 0xff:
+				bigEndian, text = true, text[2:]
+			}
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			if bigEndian {
+				units = append(units, binary.BigEndian.Uint16(text[i:]))
+			} else {
+				units = append(units, binary.LittleEndian.Uint16(text[i:]))
+			}
+		}
+		s = string(utf16.Decode(units))
+	case 3: // UTF-8
+		s = string(text)
+	default: // ISO-8859-1
+		runes := make([]rune, len(text))
+		for i, c := range text {
+			runes[i] = rune(c)
+		}
+		s = string(runes)
+	}
+
+	// Multiple values are NUL separated; keep the first
+	if i := strings.IndexRune(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,273 @@
+// Package main provides ID3 tag writing for Personal Musician.
+// WriteTags replaces the text frames for title, artist, album, track, genre
+// and year in an MP3 file's ID3v2 tag, and the comment and cover art when
+// given, keeping every other frame as it was. The file is rewritten next to itself
+// and renamed into place, so a failure never leaves it half written, and
+// keeps its modification time, which the library uses as the date added.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3Padding is the free space left in a written tag, so later edits by
+// other taggers need not rewrite the whole file.
+const id3Padding = 1024
+
+// writtenFrames are the text frames WriteTags sets, keyed by ID3v2.3 and
+// ID3v2.4 frame ID. TYER is the year in v2.3, TDRC in v2.4.
+var writtenFrames = map[string]bool{
+	"TIT2": true, "TPE1": true, "TALB": true, "TRCK": true, "TCON": true, "TYER": true, "TDRC": true,
+}
+
+// WriteTags sets the tags of an MP3 file. Empty fields remove the frame,
+// except the comment, which is only replaced when set.
+func WriteTags(path string, tags Tags) error {
+	return writeID3(path, tags, nil)
+}
+
+// WriteTagsWithCover sets the tags of an MP3 file like WriteTags and
+// replaces its pictures with a JPEG front cover.
+func WriteTagsWithCover(path string, tags Tags, cover []byte) error {
+	return writeID3(path, tags, cover)
+}
+
+// writeID3 rewrites the ID3v2 tag of an MP3 file with tags and, unless
+// nil, a cover.
+func writeID3(path string, tags Tags, cover []byte) error {
+	if !isMP3(path) {
+		return fmt.Errorf("writing tags is only supported for MP3 files: %s", filepath.Base(path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Frames being replaced are left out of the old tag
+	replaced := func(id string, body []byte) bool {
+		switch {
+		case writtenFrames[id]:
+			return true
+		case id == "COMM" && tags.Comment != "":
+			desc, _, ok := parseComment(body)
+			return ok && desc == ""
+		case id == "APIC":
+			return cover != nil
+		}
+		return false
+	}
+	version, frames, audio, err := splitID3v2(data, replaced)
+	if err != nil {
+		return err
+	}
+	year := "TYER"
+	if version == 4 {
+		year = "TDRC"
+	}
+	for _, field := range []struct{ id, value string }{
+		{"TIT2", tags.Title},
+		{"TPE1", tags.Artist},
+		{"TALB", tags.Album},
+		{"TRCK", tags.Track},
+		{"TCON", tags.Genre},
+		{year, tags.Year},
+	} {
+		if value := strings.TrimSpace(field.value); value != "" {
+			frames = append(frames, encodeTextFrame(version, field.id, value))
+		}
+	}
+	if tags.Comment != "" {
+		frames = append(frames, encodeFrame(version, "COMM", encodeCommentBody(version, tags.Comment)))
+	}
+	if cover != nil {
+		// Encoding, MIME type, picture type (3 is the front cover), empty description
+		body := append([]byte("\x00image/jpeg\x00\x03\x00"), cover...)
+		frames = append(frames, encodeFrame(version, "APIC", body))
+	}
+
+	// Header, frames, padding, then the audio untouched
+	body := bytes.Join(frames, nil)
+	size := len(body) + id3Padding
+	var out bytes.Buffer
+	out.Grow(10 + size + len(audio))
+	out.Write([]byte{'I', 'D', '3', version, 0, 0})
+	out.Write(syncsafeBytes(size))
+	out.Write(body)
+	out.Write(make([]byte, id3Padding))
+	out.Write(audio)
+
+	if err := replaceFile(path, out.Bytes(), info); err != nil {
+		return err
+	}
+	libraryMetadata.forget(path) // The file keeps its modification time
+	return nil
+}
+
+// splitID3v2 splits a file into the version of its ID3v2 tag, the frames
+// to keep from it (with their headers), those not replaced, and the audio
+// after it. Files without a tag get version 3. Tags in the old v2.2 format
+// or stored unsynchronised are replaced whole, as their frames can't be
+// copied.
+func splitID3v2(data []byte, replaced func(id string, body []byte) bool) (version byte, frames [][]byte, audio []byte, err error) {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return 3, nil, data, nil
+	}
+	version, flags := data[3], data[5]
+	tagEnd := 10 + syncsafe(data[6:10])
+	end := tagEnd
+	if flags&0x10 != 0 {
+		end += 10 // Footer
+	}
+	if end > len(data) {
+		return 0, nil, nil, fmt.Errorf("failed to read ID3 tag: tag is longer than the file")
+	}
+	audio = data[end:]
+	if version < 3 || version > 4 || flags&0x80 != 0 {
+		return 3, nil, audio, nil
+	}
+
+	body := data[10:tagEnd]
+	if flags&0x40 != 0 && len(body) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if version == 4 {
+			extSize = syncsafe(body[:4])
+		}
+		if extSize > len(body) {
+			return version, nil, audio, nil
+		}
+		body = body[extSize:]
+	}
+
+	for len(body) >= 10 && body[0] != 0 {
+		frameSize := int(binary.BigEndian.Uint32(body[4:8]))
+		if version == 4 {
+			frameSize = syncsafe(body[4:8])
+		}
+		if frameSize <= 0 || 10+frameSize > len(body) {
+			break
+		}
+		if !replaced(string(body[:4]), body[10:10+frameSize]) {
+			frames = append(frames, body[:10+frameSize])
+		}
+		body = body[10+frameSize:]
+	}
+	return version, frames, audio, nil
+}
+
+// encodeTextFrame builds a text frame with its header.
+func encodeTextFrame(version byte, id, value string) []byte {
+	enc := textEncoding(version, value)
+	return encodeFrame(version, id, append([]byte{enc}, encodeString(enc, value)...))
+}
+
+// encodeCommentBody builds the body of a comment frame in English with no
+// description.
+func encodeCommentBody(version byte, value string) []byte {
+	enc := textEncoding(version, value)
+	body := append([]byte{enc}, "eng"...)
+	body = append(body, encodeString(enc, "")...)
+	if enc == 1 {
+		body = append(body, 0, 0)
+	} else {
+		body = append(body, 0)
+	}
+	return append(body, encodeString(enc, value)...)
+}
+
+// encodeFrame adds a frame header to a body.
+func encodeFrame(version byte, id string, body []byte) []byte {
+	frame := []byte(id)
+	if version == 4 {
+		frame = append(frame, syncsafeBytes(len(body))...)
+	} else {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(body)))
+	}
+	frame = append(frame, 0, 0) // Flags
+	return append(frame, body...)
+}
+
+// textEncoding picks the encoding byte for a text: UTF-8 in ID3v2.4, and in
+// v2.3 ISO-8859-1 when it can and UTF-16 otherwise.
+func textEncoding(version byte, value string) byte {
+	switch {
+	case version == 4:
+		return 3
+	case isLatin1(value):
+		return 0
+	default:
+		return 1
+	}
+}
+
+// encodeString encodes text in an ID3 text encoding.
+func encodeString(enc byte, value string) []byte {
+	switch enc {
+	case 3:
+		return []byte(value)
+	case 1:
+		text := []byte{0xff, 0xfe} // UTF-16 with a little-endian BOM
+		for _, u := range utf16.Encode([]rune(value)) {
+			text = append(text, byte(u), byte(u>>8))
+		}
+		return text
+	default:
+		text := make([]byte, 0, len(value))
+		for _, r := range value {
+			text = append(text, byte(r))
+		}
+		return text
+	}
+}
+
+// isLatin1 reports whether s can be written as ISO-8859-1.
+func isLatin1(s string) bool {
+	for _, r := range s {
+		if r > 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// syncsafeBytes encodes a 4-byte ID3 syncsafe integer.
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n>>21) & 0x7f, byte(n>>14) & 0x7f, byte(n>>7) & 0x7f, byte(n) & 0x7f}
+}
+
+// replaceFile writes data to a temporary file next to path and renames it
+// over path, keeping the permissions and modification time of info.
+func replaceFile(path string, data []byte, info os.FileInfo) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pm-tags-*")
+	if err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	if err := os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Package main provides the audio tap for Personal Musician.
+// The tap sits in the playback chain and keeps the most recent samples
+// so visualizers can read what is currently playing.
+package main
+
+import (
+	"sync"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// tapSize is the number of mono samples kept by the audio tap.
+const tapSize = 4096
+
+// audioTap is a pass-through streamer that records recent samples.
+type audioTap struct {
+	Streamer beep.Streamer
+
+	mu  sync.Mutex
+	buf []float64 // Ring buffer of mono samples
+	pos int       // Next write position in buf
+}
+
+// newAudioTap creates an audio tap with an empty buffer.
+func newAudioTap() *audioTap {
+	return &audioTap{buf: make([]float64, tapSize)}
+}
+
+// Stream streams from the wrapped streamer and records the samples.
+func (t *audioTap) Stream(samples [][2]float64) (n int, ok bool) {
+	if t.Streamer == nil {
+		return 0, false
+	}
+	n, ok = t.Streamer.Stream(samples)
+
+	t.mu.Lock()
+	for _, s := range samples[:n] {
+		t.buf[t.pos] = (s[0] + s[1]) / 2
+		t.pos = (t.pos + 1) % len(t.buf)
+	}
+	t.mu.Unlock()
+
+	return n, ok
+}
+
+// Err propagates the wrapped streamer's error.
+func (t *audioTap) Err() error {
+	if t.Streamer == nil {
+		return nil
+	}
+	return t.Streamer.Err()
+}
+
+// Snapshot returns the most recent n samples, oldest first.
+func (t *audioTap) Snapshot(n int) []float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n > len(t.buf) {
+		n = len(t.buf)
+	}
+	out := make([]float64, n)
+	start := (t.pos - n + len(t.buf)) % len(t.buf)
+	for i := range out {
+		out[i] = t.buf[(start+i)%len(t.buf)]
+	}
+	return out
+}
+
+// reset clears the recorded samples.
+func (t *audioTap) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.buf {
+		t.buf[i] = 0
+	}
+	t.pos = 0
+}
+
+// TapSamples returns the most recent n mono samples flowing to the speaker,
+// along with the sample rate they are played at.
+// Returns silence while paused or stopped.
+func (p *Player) TapSamples(n int) ([]float64, beep.SampleRate) {
+	p.mu.Lock()
+	playing := p.isPlaying && !p.isPaused
+	rate := p.sampleRate
+	p.mu.Unlock()
+
+	if !playing {
+		return make([]float64, n), rate
+	}
+	return p.tap.Snapshot(n), rate
+}
@@ -0,0 +1,220 @@
+// Package main provides the color theme system for Personal Musician.
+// This module defines named color palettes and rebuilds the TUI styles from them.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ThemesFile is the optional JSON file holding user-defined themes.
+var ThemesFile = configPath("themes.json")
+
+// ThemeColor is a single palette entry.
+// Hex is used on true-color and 256-color terminals, ANSI (0-15) on 16-color terminals.
+type ThemeColor struct {
+	Hex  string `json:"hex"`
+	ANSI string `json:"ansi,omitempty"`
+}
+
+// Color returns the lipgloss color for this entry.
+// Without an explicit ANSI fallback, lipgloss degrades the hex color automatically.
+func (c ThemeColor) Color() lipgloss.TerminalColor {
+	if c.ANSI == "" {
+		return lipgloss.Color(c.Hex)
+	}
+	return lipgloss.CompleteColor{TrueColor: c.Hex, ANSI256: c.Hex, ANSI: c.ANSI}
+}
+
+// Theme is a named color palette for the TUI.
+type Theme struct {
+	Name      string     `json:"name"`
+	Primary   ThemeColor `json:"primary"`   // Headers, borders, selection
+	Secondary ThemeColor `json:"secondary"` // Status messages
+	Accent    ThemeColor `json:"accent"`    // Now playing
+	Text      ThemeColor `json:"text"`      // Normal text
+	Muted     ThemeColor `json:"muted"`     // Secondary info and help
+}
+
+// themes holds the built-in themes followed by any user-defined ones.
+var themes = []Theme{
+	{
+		Name:      "dark",
+		Primary:   ThemeColor{"#7C3AED", "5"},
+		Secondary: ThemeColor{"#10B981", "2"},
+		Accent:    ThemeColor{"#F59E0B", "3"},
+		Text:      ThemeColor{"#E5E7EB", "7"},
+		Muted:     ThemeColor{"#6B7280", "8"},
+	},
+	{
+		Name:      "light",
+		Primary:   ThemeColor{"#6D28D9", "5"},
+		Secondary: ThemeColor{"#047857", "2"},
+		Accent:    ThemeColor{"#B45309", "3"},
+		Text:      ThemeColor{"#1F2937", "0"},
+		Muted:     ThemeColor{"#9CA3AF", "8"},
+	},
+	{
+		Name:      "gruvbox",
+		Primary:   ThemeColor{"#D3869B", "5"},
+		Secondary: ThemeColor{"#B8BB26", "2"},
+		Accent:    ThemeColor{"#FABD2F", "11"},
+		Text:      ThemeColor{"#EBDBB2", "15"},
+		Muted:     ThemeColor{"#928374", "8"},
+	},
+	{
+		Name:      "high-contrast",
+		Primary:   ThemeColor{"#FFFF00", "11"},
+		Secondary: ThemeColor{"#00FF00", "10"},
+		Accent:    ThemeColor{"#00FFFF", "14"},
+		Text:      ThemeColor{"#FFFFFF", "15"},
+		Muted:     ThemeColor{"#C0C0C0", "7"},
+	},
+}
+
+// currentTheme is the index of the active theme in themes.
+var currentTheme int
+
+// albumColors, when set, override the theme's primary and accent colors.
+var albumColors *AlbumColors
+
+func init() {
+	applyTheme(themes[0])
+}
+
+// LoadThemes reads user-defined themes from a JSON file and registers them.
+// A theme with the same name as an existing one replaces it.
+// A missing file is not an error.
+func LoadThemes(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read themes: %w", err)
+	}
+
+	var custom []Theme
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return fmt.Errorf("failed to parse themes: %w", err)
+	}
+
+	for _, t := range custom {
+		if t.Name == "" {
+			return fmt.Errorf("theme without a name in %s", path)
+		}
+		RegisterTheme(t)
+	}
+	return nil
+}
+
+// RegisterTheme adds a theme, replacing any existing theme with the same name.
+func RegisterTheme(t Theme) {
+	for i := range themes {
+		if strings.EqualFold(themes[i].Name, t.Name) {
+			themes[i] = t
+			if i == currentTheme {
+				applyTheme(t)
+			}
+			return
+		}
+	}
+	themes = append(themes, t)
+}
+
+// SetTheme activates the theme with the given name.
+func SetTheme(name string) error {
+	for i, t := range themes {
+		if strings.EqualFold(t.Name, name) {
+			currentTheme = i
+			applyTheme(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown theme %q", name)
+}
+
+// NextTheme activates the next theme in order and returns it.
+func NextTheme() Theme {
+	currentTheme = (currentTheme + 1) % len(themes)
+	applyTheme(themes[currentTheme])
+	return themes[currentTheme]
+}
+
+// CurrentTheme returns the active theme.
+func CurrentTheme() Theme {
+	return themes[currentTheme]
+}
+
+// SetAlbumColors overrides the active theme's primary and accent colors with
+// colors taken from album art. Nil restores the theme's own colors.
+func SetAlbumColors(c *AlbumColors) {
+	albumColors = c
+	applyTheme(themes[currentTheme])
+}
+
+// applyTheme rebuilds the color palette and all TUI styles from a theme,
+// with album colors layered on top when set.
+func applyTheme(t Theme) {
+	if albumColors != nil {
+		t.Primary = ThemeColor{Hex: albumColors.Primary}
+		t.Accent = ThemeColor{Hex: albumColors.Accent}
+	}
+
+	primaryColor = t.Primary.Color()
+	secondaryColor = t.Secondary.Color()
+	accentColor = t.Accent.Color()
+	textColor = t.Text.Color()
+	mutedColor = t.Muted.Color()
+
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1)
+
+	headerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(textColor).
+		Background(primaryColor).
+		Padding(0, 1)
+
+	selectedStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor)
+
+	normalStyle = lipgloss.NewStyle().
+		Foreground(textColor)
+
+	mutedStyle = lipgloss.NewStyle().
+		Foreground(mutedColor)
+
+	statusStyle = lipgloss.NewStyle().
+		Foreground(secondaryColor).
+		Bold(true)
+
+	nowPlayingStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(accentColor)
+
+	helpStyle = lipgloss.NewStyle().
+		Foreground(mutedColor).
+		MarginTop(1)
+
+	boxStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Padding(0, 1)
+
+	statusBarStyle = lipgloss.NewStyle().
+		Foreground(textColor).
+		Background(secondaryColor).
+		Padding(0, 1)
+
+	statusBarErrorStyle = statusBarStyle.
+		Bold(true).
+		Background(accentColor)
+}
@@ -0,0 +1,164 @@
+// Package main provides search result thumbnails for Personal Musician.
+// On true-color terminals each result gets a small preview of its video
+// thumbnail, drawn with half-block characters so that one text cell shows
+// two pixels. Previews help tell official uploads from covers.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // YouTube thumbnails are JPEG
+	_ "image/png"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Size of a thumbnail preview in text cells. Each cell holds two pixel rows.
+const (
+	thumbnailCols = 8
+	thumbnailRows = 2
+)
+
+// thumbnailsEnabled reports whether result thumbnails are shown.
+var thumbnailsEnabled bool
+
+// DetectThumbnails decides whether to show thumbnails. PM_THUMBNAILS=1/0 or
+// the thumbnails setting of the config file forces the choice; otherwise
+// they are shown on terminals announcing true color. They are never shown in
+// ASCII or accessible mode.
+func DetectThumbnails() bool {
+	if asciiMode || accessibleMode {
+		return false
+	}
+	if v := os.Getenv("PM_THUMBNAILS"); v != "" {
+		if on, err := strconv.ParseBool(v); err == nil {
+			return on
+		}
+	}
+	if config.UI.Thumbnails != nil {
+		return *config.UI.Thumbnails
+	}
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	return colorTerm == "truecolor" || colorTerm == "24bit"
+}
+
+// SetThumbnails turns result thumbnails on or off.
+func SetThumbnails(on bool) {
+	thumbnailsEnabled = on
+}
+
+// thumbnailMsg carries a rendered thumbnail for a search result.
+type thumbnailMsg struct {
+	videoID string
+	art     string
+}
+
+// standardThumbnail returns the URL of YouTube's standard JPEG thumbnail for
+// a video, used when the search page gave none or it could not be decoded.
+func standardThumbnail(videoID string) string {
+	return fmt.Sprintf("https://i.ytimg.com/vi/%s/mqdefault.jpg", videoID)
+}
+
+// loadThumbnails returns commands fetching the thumbnails of results that
+// are not cached yet.
+func (m Model) loadThumbnails(results []SearchResult) tea.Cmd {
+	if !thumbnailsEnabled {
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	for _, r := range results {
+		if _, ok := m.thumbnails[r.VideoID]; ok {
+			continue
+		}
+		cmds = append(cmds, func() tea.Msg {
+			art, err := FetchThumbnail(r.Thumbnail)
+			if err != nil {
+				art, err = FetchThumbnail(standardThumbnail(r.VideoID))
+			}
+			if err != nil {
+				appLog.Add("thumbnail", err.Error())
+				return nil
+			}
+			return thumbnailMsg{videoID: r.VideoID, art: art}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// FetchThumbnail downloads an image and renders it as a thumbnail preview.
+func FetchThumbnail(url string) (string, error) {
+	if url == "" {
+		return "", fmt.Errorf("no thumbnail URL")
+	}
+	data, err := httpGet(context.Background(), "thumbnail", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch thumbnail: %w", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	return renderThumbnail(img, thumbnailCols, thumbnailRows), nil
+}
+
+// renderThumbnail scales img down to cols x rows cells using upper half
+// blocks, coloring the top pixel as foreground and the bottom as background.
+func renderThumbnail(img image.Image, cols, rows int) string {
+	lines := make([]string, rows)
+	for row := 0; row < rows; row++ {
+		var b strings.Builder
+		for col := 0; col < cols; col++ {
+			top := averageColor(img, col, row*2, cols, rows*2)
+			bottom := averageColor(img, col, row*2+1, cols, rows*2)
+			b.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color(top)).
+				Background(lipgloss.Color(bottom)).
+				Render("▀"))
+		}
+		lines[row] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// averageColor returns the mean color, as "#rrggbb", of the image area that
+// maps to pixel (x, y) of a w x h downscaled grid.
+func averageColor(img image.Image, x, y, w, h int) string {
+	bounds := img.Bounds()
+	x0 := bounds.Min.X + x*bounds.Dx()/w
+	x1 := bounds.Min.X + (x+1)*bounds.Dx()/w
+	y0 := bounds.Min.Y + y*bounds.Dy()/h
+	y1 := bounds.Min.Y + (y+1)*bounds.Dy()/h
+
+	var r, g, b, n uint64
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			cr, cg, cb, _ := img.At(px, py).RGBA()
+			r += uint64(cr >> 8)
+			g += uint64(cg >> 8)
+			b += uint64(cb >> 8)
+			n++
+		}
+	}
+	if n == 0 {
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r/n, g/n, b/n)
+}
+
+// resultThumbnail returns the preview for a result, or blank space of the
+// same size while it is loading.
+func (m Model) resultThumbnail(videoID string) string {
+	if art, ok := m.thumbnails[videoID]; ok {
+		return art
+	}
+	blank := strings.Repeat(" ", thumbnailCols)
+	return strings.TrimSuffix(strings.Repeat(blank+"\n", thumbnailRows), "\n")
+}
@@ -0,0 +1,127 @@
+// Package main provides the refresh ticker for Personal Musician.
+// The UI refreshes at the configured rate while music plays or downloads
+// run, and slows down (or stops, in battery-saver mode) when idle.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	defaultTickInterval = 500 * time.Millisecond // Refresh rate while busy
+	idleTickInterval    = 5 * time.Second        // Refresh rate while idle
+	statusDuration      = 5 * time.Second        // How long status messages show
+	minTickInterval     = 50 * time.Millisecond  // Fastest allowed refresh rate
+)
+
+// TickSettings controls how often the UI refreshes.
+type TickSettings struct {
+	Interval     time.Duration // Refresh rate while playing or downloading
+	BatterySaver bool          // Stop refreshing entirely while idle
+}
+
+// DetectTickSettings reads PM_TICK (a duration such as "250ms") and
+// PM_BATTERY_SAVER (a boolean) from the environment, falling back to the
+// refresh and battery_saver settings of the config file.
+func DetectTickSettings() (TickSettings, error) {
+	settings := TickSettings{Interval: defaultTickInterval}
+
+	// The config file was validated when loaded
+	if config.UI.Refresh != "" {
+		settings.Interval, _ = time.ParseDuration(config.UI.Refresh)
+	}
+	if config.UI.BatterySaver != nil {
+		settings.BatterySaver = *config.UI.BatterySaver
+	}
+
+	if v := os.Getenv("PM_TICK"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < minTickInterval {
+			return settings, fmt.Errorf("invalid PM_TICK %q (want a duration of at least %s)", v, minTickInterval)
+		}
+		settings.Interval = interval
+	}
+
+	if v := os.Getenv("PM_BATTERY_SAVER"); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return settings, fmt.Errorf("invalid PM_BATTERY_SAVER %q (want true or false)", v)
+		}
+		settings.BatterySaver = on
+	}
+
+	return settings, nil
+}
+
+// tickMsg is sent periodically to update the UI. gen identifies the ticker
+// that sent it, so ticks from a replaced ticker can be dropped.
+type tickMsg struct {
+	gen int
+}
+
+// isBusy reports whether something is playing, searching, downloading or
+// backing up.
+func (m Model) isBusy() bool {
+	state := m.player.GetState()
+	if state.IsPlaying && !state.IsPaused {
+		return true
+	}
+	if m.backup != nil && m.backup.Running() {
+		return true
+	}
+	return m.isSearching || m.downloader.IsDownloading() || m.downloader.PendingCount() > 0
+}
+
+// wantedTickInterval returns the refresh rate for the current activity.
+// Zero means no ticking.
+func (m Model) wantedTickInterval() time.Duration {
+	switch {
+	case m.isBusy():
+		return m.ticks.Interval
+	case m.ticks.BatterySaver:
+		return 0
+	default:
+		return idleTickInterval
+	}
+}
+
+// retick replaces the running ticker when the wanted refresh rate changed.
+func (m Model) retick() (Model, tea.Cmd) {
+	interval := m.wantedTickInterval()
+	if interval == m.tickInterval {
+		return m, nil
+	}
+	m.tickInterval = interval
+	m.tickGen++
+	if interval == 0 {
+		return m, nil
+	}
+	// Tick right away so a sped-up ticker takes effect immediately
+	gen := m.tickGen
+	return m, func() tea.Msg { return tickMsg{gen: gen} }
+}
+
+// tickCmd schedules the next tick of the current ticker.
+func (m Model) tickCmd() tea.Cmd {
+	if m.tickInterval == 0 {
+		return nil
+	}
+	gen := m.tickGen
+	return tea.Tick(m.tickInterval, func(time.Time) tea.Msg {
+		return tickMsg{gen: gen}
+	})
+}
+
+// expireStatus clears the status message once it has been shown long enough.
+func (m Model) expireStatus() Model {
+	if !m.statusUntil.IsZero() && time.Now().After(m.statusUntil) {
+		m.statusMessage = ""
+		m.statusUntil = time.Time{}
+	}
+	return m
+}
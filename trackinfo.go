@@ -0,0 +1,126 @@
+// Package main provides the track detail panel for Personal Musician.
+// Pressing 'i' on a track shows its path, tags, audio properties and
+// listening statistics in a popup.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TrackInfo holds the details shown in the track detail panel.
+type TrackInfo struct {
+	File     MusicFile
+	Tags     Tags
+	Duration time.Duration
+	Bitrate  int   // Average bitrate in kbit/s
+	Size     int64 // File size in bytes
+	Stats    TrackStats
+}
+
+// trackInfoMsg carries loaded track details to the UI.
+type trackInfoMsg struct {
+	info TrackInfo
+	err  error
+}
+
+// LoadTrackInfo reads the tags and audio properties of a music file.
+func LoadTrackInfo(file MusicFile) (TrackInfo, error) {
+	info := TrackInfo{File: file}
+
+	fi, err := os.Stat(file.Path)
+	if err != nil {
+		return info, fmt.Errorf("failed to stat file: %w", err)
+	}
+	info.Size = fi.Size()
+
+	tags, err := ReadTags(file.Path)
+	if err != nil {
+		return info, err
+	}
+	info.Tags = tags
+
+	// Decode the stream headers to measure the duration
+	streamer, format, err := decodeAudioFile(file.Path)
+	if err != nil {
+		return info, err
+	}
+	defer streamer.Close()
+
+	info.Duration = format.SampleRate.D(streamer.Len())
+	if secs := info.Duration.Seconds(); secs > 0 {
+		info.Bitrate = int(float64(info.Size*8) / secs / 1000)
+	}
+	return info, nil
+}
+
+// showTrackInfo loads the details of a track in the background.
+func (m Model) showTrackInfo(file MusicFile) tea.Cmd {
+	return func() tea.Msg {
+		info, err := LoadTrackInfo(file)
+		if err == nil {
+			info.Stats = m.stats.Get(file.Path)
+		}
+		return trackInfoMsg{info: info, err: err}
+	}
+}
+
+// handleTrackInfoKeys handles keys while the detail panel is open.
+func (m Model) handleTrackInfoKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "i", "enter", "q":
+		m.trackInfo = nil
+	}
+	return m, nil
+}
+
+// renderTrackInfo renders the track detail panel.
+func (m Model) renderTrackInfo() string {
+	info := m.trackInfo
+
+	orDash := func(s string) string {
+		if s == "" {
+			return "—"
+		}
+		return s
+	}
+	when := func(t time.Time) string {
+		if t.IsZero() {
+			return T("never")
+		}
+		return t.Format("2006-01-02 15:04")
+	}
+
+	rows := [][2]string{
+		{"Path", info.File.Path},
+		{"Title", orDash(info.Tags.Title)},
+		{"Artist", orDash(info.Tags.Artist)},
+		{"Album", orDash(info.Tags.Album)},
+		{"Track", orDash(info.Tags.Track)},
+		{"Genre", orDash(info.Tags.Genre)},
+		{"Year", orDash(info.Tags.Year)},
+		{"Comment", orDash(info.Tags.Comment)},
+		{"Duration", FormatDuration(info.Duration)},
+		{"Bitrate", fmt.Sprintf("%d kbit/s", info.Bitrate)},
+		{"Size", FormatSize(info.Size)},
+		{"Rating", ratingStars(info.Stats.Rating, true)},
+		{"Play count", fmt.Sprint(info.Stats.PlayCount)},
+		{"Last played", when(info.Stats.LastPlayed)},
+		{"Date added", when(info.File.ModTime)},
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(" ℹ "+truncate(info.File.Name, 50)+" ") + "\n\n")
+	for _, row := range rows {
+		b.WriteString(mutedStyle.Render(padLabel(row[0], 12)) + normalStyle.Render(row[1]) + "\n")
+	}
+	b.WriteString("\n" + mutedStyle.Render(T("esc: close")))
+
+	box := boxStyle.Render(b.String())
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, box)
+}
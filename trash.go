@@ -0,0 +1,229 @@
+// Package main provides the trash of Personal Musician.
+// Deleting songs moves them into a .trash folder inside their music
+// directory instead of removing them, so an accidental delete can be
+// undone: u in the library (or Undo delete in the finder) puts back the
+// songs deleted last, one delete at a time. Trashed songs are listed in
+// trash.json in the data directory with where they came from, and stay
+// until Empty trash in the finder removes them for good. The .trash folders
+// are left out of library scans.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// trashDirName is the folder of each music directory that holds its
+// trashed songs.
+const trashDirName = ".trash"
+
+// TrashFile is where the trashed songs are listed.
+var TrashFile = dataPath("trash.json")
+
+// trashMu serializes changes to the trash.
+var trashMu sync.Mutex
+
+// trashEntry is a song in the trash.
+type trashEntry struct {
+	Path    string    `json:"path"`    // Where it was
+	Trashed string    `json:"trashed"` // Where it is now
+	Deleted time.Time `json:"deleted"` // When; songs deleted together share it
+}
+
+// inTrash reports whether path lies in a trash folder.
+func inTrash(path string) bool {
+	return strings.Contains(path+string(filepath.Separator), string(filepath.Separator)+trashDirName+string(filepath.Separator))
+}
+
+// loadTrash reads the list of trashed songs. A missing list is empty.
+func loadTrash() ([]trashEntry, error) {
+	data, err := os.ReadFile(TrashFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+	var entries []trashEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse trash: %w", err)
+	}
+	return entries, nil
+}
+
+// saveTrash writes the list of trashed songs.
+func saveTrash(entries []trashEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trash: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(TrashFile), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(TrashFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write trash: %w", err)
+	}
+	return nil
+}
+
+// trashPath returns a free place in the trash of path's music directory,
+// keeping its folders so songs of the same name don't clash.
+func trashPath(path string) string {
+	root := musicRoot(path)
+	to := filepath.Join(root, trashDirName, relToMusicDir(path))
+	base := strings.TrimSuffix(to, filepath.Ext(to))
+	for n := 2; fileExists(to); n++ {
+		to = fmt.Sprintf("%s (%d)%s", base, n, filepath.Ext(path))
+	}
+	return to
+}
+
+// TrashMusicFiles moves music files to the trash as one delete, which
+// undoing puts back together. It returns the paths trashed and the last
+// failure, if any.
+func TrashMusicFiles(paths []string) ([]string, error) {
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	entries, err := loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var trashed []string
+	for _, path := range paths {
+		to := trashPath(path)
+		if moveErr := MoveMusicFile(path, to); moveErr != nil {
+			err = fmt.Errorf("failed to delete %s: %w", filepath.Base(path), moveErr)
+			continue
+		}
+		libraryMetadata.forget(path)
+		entries = append(entries, trashEntry{Path: path, Trashed: to, Deleted: now})
+		trashed = append(trashed, path)
+	}
+	if len(trashed) > 0 {
+		if saveErr := saveTrash(entries); saveErr != nil {
+			err = saveErr
+		}
+	}
+	return trashed, err
+}
+
+// RestoreTrash puts back the songs deleted last. Songs whose place has been
+// taken since stay in the trash. It returns the paths restored and the
+// last failure, if any.
+func RestoreTrash() ([]string, error) {
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	entries, err := loadTrash()
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	last := entries[len(entries)-1].Deleted
+	var kept []trashEntry
+	var restored []string
+	for _, entry := range entries {
+		if !entry.Deleted.Equal(last) {
+			kept = append(kept, entry)
+			continue
+		}
+		if moveErr := MoveMusicFile(entry.Trashed, entry.Path); moveErr != nil {
+			if errors.Is(moveErr, os.ErrNotExist) {
+				continue // Gone from the trash by other means
+			}
+			err = fmt.Errorf("failed to restore %s: %w", filepath.Base(entry.Path), moveErr)
+			kept = append(kept, entry)
+			continue
+		}
+		restored = append(restored, entry.Path)
+	}
+	removeEmptyDirs(trashFolders(entries))
+	if saveErr := saveTrash(kept); saveErr != nil {
+		err = saveErr
+	}
+	return restored, err
+}
+
+// EmptyTrash removes the trashed songs for good, along with everything else
+// in the trash folders. It returns the number of songs removed.
+func EmptyTrash() (int, error) {
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	entries, err := loadTrash()
+	if err != nil {
+		return 0, err
+	}
+	for _, dir := range append([]string{MusicDir}, extraMusicDirs...) {
+		if rmErr := os.RemoveAll(filepath.Join(dir, trashDirName)); rmErr != nil {
+			err = fmt.Errorf("failed to empty trash: %w", rmErr)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), saveTrash(nil)
+}
+
+// trashFolders returns the folders that held the trashed songs of entries.
+func trashFolders(entries []trashEntry) []string {
+	var dirs []string
+	for _, entry := range entries {
+		dirs = append(dirs, filepath.Dir(entry.Trashed))
+	}
+	return dirs
+}
+
+// trashRestoredMsg reports the outcome of undoing a delete.
+type trashRestoredMsg struct {
+	restored []string // Paths put back
+	err      error    // Last failure, if any
+}
+
+// undoDelete returns a command that puts back the songs deleted last.
+func (m Model) undoDelete() tea.Cmd {
+	library := m.library
+	return func() tea.Msg {
+		restored, err := RestoreTrash()
+		library.RefreshPaths(restored)
+		return trashRestoredMsg{restored: restored, err: err}
+	}
+}
+
+// applyTrashRestored reports an undone delete in the status line.
+func (m Model) applyTrashRestored(msg trashRestoredMsg) (Model, tea.Cmd) {
+	switch {
+	case msg.err != nil && len(msg.restored) == 0:
+		return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+	case msg.err != nil:
+		return m, func() tea.Msg { return statusMsg(Tf("Restored %d songs, error: %v", len(msg.restored), msg.err)) }
+	case len(msg.restored) == 0:
+		return m, func() tea.Msg { return statusMsg(T("Nothing to undo")) }
+	case len(msg.restored) == 1:
+		return m, func() tea.Msg { return statusMsg(Tf("Restored: %s", filepath.Base(msg.restored[0]))) }
+	}
+	return m, func() tea.Msg { return statusMsg(Tf("Restored %d songs", len(msg.restored))) }
+}
+
+// confirmEmptyTrash asks before removing the trashed songs for good.
+func (m Model) confirmEmptyTrash() (tea.Model, tea.Cmd) {
+	return m.openDialog(NewConfirmDialog(T("Empty trash"), T("Permanently delete the songs in the trash?"),
+		func(m Model, _ string) (tea.Model, tea.Cmd) {
+			return m, func() tea.Msg {
+				n, err := EmptyTrash()
+				if err != nil {
+					return errorMsg(err.Error())
+				}
+				return statusMsg(Tf("Emptied the trash: %d songs deleted", n))
+			}
+		}))
+}
@@ -8,77 +8,61 @@ import (
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// progressBarWidth is the width of the now-playing progress bar in cells.
+const progressBarWidth = 20
+
+// seekStep is how far Shift+←/→ jump within the playing track.
+const seekStep = 10 * time.Second
+
+// volumeStep is how much +/- change the volume, in percent.
+const volumeStep = 5
+
 // View represents the current active view in the TUI.
 type View int
 
 const (
-	ViewLibrary View = iota // Default view - show local music files
-	ViewSearch              // Search input view
-	ViewResults             // Search results view
+	ViewLibrary        View = iota // Default view - show local music files
+	ViewSearch                     // Search input view
+	ViewResults                    // Search results view
+	ViewQueue                      // Upcoming tracks in the play queue
+	ViewVisualizer                 // Real-time spectrum visualizer
+	ViewDownloads                  // Queued, active and finished downloads
+	ViewPlaylists                  // Saved playlists
+	ViewPlaylistEditor             // Entries of the playlist being edited
+	ViewLog                        // Errors and subprocess output
+	ViewJellyfin                   // Browser for a Jellyfin server
+	ViewDuplicates                 // Copies of the same song in the library
+	ViewBrowse                     // Library grouped by artist and album
+	ViewOrganize                   // Planned moves of the library organizer
 )
 
-// Styles for the TUI
+// Styles for the TUI, rebuilt by applyTheme whenever the theme changes.
 var (
 	// Color palette
-	primaryColor   = lipgloss.Color("#7C3AED") // Purple
-	secondaryColor = lipgloss.Color("#10B981") // Green
-	accentColor    = lipgloss.Color("#F59E0B") // Amber
-	textColor      = lipgloss.Color("#E5E7EB") // Light gray
-	mutedColor     = lipgloss.Color("#6B7280") // Muted gray
-
-	// Title style
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(primaryColor).
-			MarginBottom(1)
-
-	// Header style
-	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(textColor).
-			Background(primaryColor).
-			Padding(0, 1)
-
-	// Selected item style
-	selectedStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(primaryColor)
-
-	// Normal item style
-	normalStyle = lipgloss.NewStyle().
-			Foreground(textColor)
-
-	// Muted style for secondary info
-	mutedStyle = lipgloss.NewStyle().
-			Foreground(mutedColor)
-
-	// Status bar style
-	statusStyle = lipgloss.NewStyle().
-			Foreground(secondaryColor).
-			Bold(true)
-
-	// Now playing style
-	nowPlayingStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(accentColor)
-
-	// Help style
-	helpStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			MarginTop(1)
-
-	// Box style
-	boxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
-			Padding(0, 1)
+	primaryColor   lipgloss.TerminalColor
+	secondaryColor lipgloss.TerminalColor
+	accentColor    lipgloss.TerminalColor
+	textColor      lipgloss.TerminalColor
+	mutedColor     lipgloss.TerminalColor
+
+	titleStyle      lipgloss.Style // Title style
+	headerStyle     lipgloss.Style // Header style
+	selectedStyle   lipgloss.Style // Selected item style
+	normalStyle     lipgloss.Style // Normal item style
+	mutedStyle      lipgloss.Style // Muted style for secondary info
+	statusStyle     lipgloss.Style // Status bar style
+	nowPlayingStyle lipgloss.Style // Now playing style
+	helpStyle       lipgloss.Style // Help style
+	boxStyle        lipgloss.Style // Box style
+
+	statusBarStyle      lipgloss.Style // Bottom status bar
+	statusBarErrorStyle lipgloss.Style // Bottom status bar with pending errors
 )
 
 // Model represents the application state for Bubble Tea.
@@ -86,50 +70,204 @@ type Model struct {
 	// Dependencies
 	player     *Player
 	downloader *Downloader
+	stats      *StatsStore
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 
 	// View state
-	currentView View
-	width       int
-	height      int
+	currentView  View
+	paneLeftView View // View in the left pane while the queue pane has focus
+	miniMode     bool // Compact footer-only UI
+	panes        *PaneSizes
+	width        int
+	height       int
+
+	// Accessible mode announcements
+	announcedTrack     string
+	announcedDownloads map[int]bool // Download IDs already announced
+
+	// Keybindings
+	keyPreset  KeyPreset
+	pendingKey string // First key of a pending vim sequence
 
 	// Library view state
+	library       *Library // Scans the music directories in the background
 	libraryFiles  []MusicFile
 	libraryCursor int
+	filterInput   textinput.Model
+	filtering     bool // Whether the filter input has focus
+	renameInput   textinput.Model
+	renaming      *MusicFile // Song whose path is being edited in place (see rename.go)
+	librarySel    selection
+	libraryScroll scrollList
+	libraryView   LibraryView         // Sort and filter settings
+	restoreTrack  string              // Track to put the cursor on once the library loads
+	libraryGen    int                 // Bumped whenever libraryFiles changes
+	libraryCache  *libraryFilterCache // Last visibleLibrary result, shared by copies of the model
+
+	// Recently played view (see recent.go)
+	recentPlays   []string // Paths, newest first; nil until loaded
+	recentStats   int      // StatsStore.Version when recentPlays was read
+	recentLoading bool
+
+	// Sort/filter menu state
+	libraryMenuOpen   bool
+	libraryMenuCursor int
+
+	// Equalizer panel state
+	eqOpen   bool
+	eqCursor int
+
+	// Popups for the item under the cursor (nil when closed)
+	contextMenu *ContextMenu // Actions menu
+	trackInfo   *TrackInfo   // Track detail panel
+
+	// Queue view state
+	queueCursor int
+	savedQueue  int // Player.QueueVersion when the state was last saved
+	queueScroll scrollList
+
+	// Playlists view state
+	playlistNames   []string        // Saved playlists, then smart playlists
+	smartPlaylists  []SmartPlaylist // The smart playlists at the end of playlistNames
+	playlistsCursor int
+	playlistsScroll scrollList
+	lastPlaylist    string // Playlist used by the last "add to playlist"
+
+	// Playlist editor state
+	editing      Playlist
+	editorCursor int
+	editorDirty  bool // Unsaved changes
+	editorScroll scrollList
+	pickerOpen   bool // Library picker for inserting tracks
+	pickerInput  textinput.Model
+	pickerCursor int
+
+	// Visualizer state
+	spectrum *Spectrum
+	vizMode  VisualizerMode
+	scope    []float64 // Latest tap samples for the waveform
+
+	// Waveform of the playing track (nil until computed)
+	waveform     *Waveform
+	waveformPath string // Track the waveform was requested for
+
+	// Track the album colors were requested for
+	albumColorsPath string
+
+	// Jellyfin browser state
+	jellyfin       *JellyfinClient // nil when no server is configured
+	jellyfinPages  []jellyfinPage  // Navigation stack, the root menu first
+	jellyfinScroll scrollList
+
+	// Cast session mirroring the player (nil when not casting)
+	cast *castMirror
+
+	// Multi-room following state
+	syncLeader   string        // Address of the leader, empty when not following
+	syncState    syncMessage   // Latest state from the leader
+	syncOffset   time.Duration // Leader clock minus local clock
+	syncToken    string        // Leader track being played
+	syncFetching string        // Leader track being fetched
+
+	// Position and played status of long tracks
+	episodes *EpisodeStore
+
+	// Library backup (nil when no remote is configured)
+	backup *Backup
+
+	// Progress of walking the music directories (nil when idle)
+	libraryScan *libraryScanMsg
+
+	// Background reading of tags and durations (nil when idle)
+	metadataScan   *metadataScan
+	metadataScanID int
+
+	// Songs being identified by their sound (see retag.go); nil when idle
+	identify *identifyJob
+
+	// Search across providers in progress (nil when idle)
+	search   *searchStream
+	searchID int
+
+	// Modal dialog (nil when closed)
+	dialog *Dialog
+
+	// Fuzzy-finder overlay state
+	finderOpen   bool
+	finderInput  textinput.Model
+	finderCursor int
 
 	// Search state
-	searchInput  textinput.Model
-	searchQuery  string
-	isSearching  bool
-	searchError  string
+	searchInput textinput.Model
+	searchQuery string
+	isSearching bool
+	searchError string
 
 	// Search results state (YouTube results)
 	youtubeResults []SearchResult
+	thumbnails     map[string]string // Rendered thumbnails by video ID
 	resultsCursor  int
+	resultsSel     selection
+	resultsScroll  scrollList
+
+	// Session clock
+	sessionStart    time.Time
+	listened        time.Duration // Time spent playing music this session
+	lastListenCheck time.Time
+
+	// Screensaver state
+	screensaver      bool
+	screensaverDelay time.Duration // Idle time before the screensaver starts; 0 disables it
+	screensaverStart time.Time
+	lastInput        time.Time
+
+	// Log view state
+	logCursor int
+	logScroll scrollList
+
+	// Duplicates view state
+	duplicates       []duplicateRow
+	duplicatesCursor int
+	duplicatesScroll scrollList
+
+	// Organize view state
+	organize       []organizeMove
+	organizeCursor int
+	organizeScroll scrollList
+
+	// Artists view state
+	browse       []browseArtist
+	browseGen    int    // libraryGen the artists were grouped from
+	browseLevel  int    // browseArtists, browseAlbums or browseTracks
+	browseCursor [3]int // Cursor at each level
+	browseScroll scrollList
 
 	// Download state
-	downloadProgress progress.Model
-	downloadSpinner  spinner.Model
+	downloadSpinner    spinner.Model
+	downloadsCursor    int
+	downloadsCompleted int // Completed downloads already reflected in the library
+	downloadsScroll    scrollList
 
 	// Status message
 	statusMessage string
-	statusTimer   int
+	statusUntil   time.Time // When the status message expires
+	errors        []string  // Pending errors shown until dismissed
 
 	// Playback refresh ticker
-	tickCount int
+	ticks        TickSettings
+	tickInterval time.Duration // Rate of the running ticker (0 when stopped)
+	tickGen      int           // Generation of the running ticker
+
+	// Mouse state for double-click detection
+	lastClickRow  int
+	lastClickTime time.Time
 }
 
 // Messages for Bubble Tea
 type (
-	// tickMsg is sent periodically to update the UI.
-	tickMsg time.Time
-
-	// youtubeSearchCompleteMsg is sent when a YouTube search completes.
-	youtubeSearchCompleteMsg struct {
-		results []SearchResult
-		err     error
-	}
+	// vizTickMsg is sent at animation rate while the visualizer is visible.
+	vizTickMsg time.Time
 
 	// libraryRefreshMsg is sent when the library needs refreshing.
 	libraryRefreshMsg []MusicFile
@@ -142,16 +280,31 @@ type (
 )
 
 // NewModel creates a new TUI model with all dependencies.
-func NewModel(player *Player, downloader *Downloader) Model {
+func NewModel(player *Player, downloader *Downloader, stats *StatsStore, panes *PaneSizes, keyPreset KeyPreset, ticks TickSettings, screensaverDelay time.Duration) Model {
 	// Initialize text input for search
 	ti := textinput.New()
-	ti.Placeholder = "Search for music on YouTube..."
+	ti.Placeholder = T("Search for music on YouTube...")
 	ti.CharLimit = 100
 	ti.Width = 50
 
-	// Initialize progress bar
-	prog := progress.New(progress.WithDefaultGradient())
-	prog.Width = 30
+	// Initialize text input for the library filter
+	fi := textinput.New()
+	fi.Prompt = "/"
+	fi.Placeholder = T("filter")
+	fi.CharLimit = 100
+	fi.Width = 30
+
+	// Initialize text input for the fuzzy finder
+	fz := textinput.New()
+	fz.Placeholder = T("Type to find tracks, playlists and commands...")
+	fz.CharLimit = 100
+	fz.Width = 50
+
+	// Initialize text input for the playlist editor's library picker
+	pk := textinput.New()
+	pk.Placeholder = T("Type to find a track...")
+	pk.CharLimit = 100
+	pk.Width = 50
 
 	// Initialize spinner
 	sp := spinner.New()
@@ -162,14 +315,40 @@ func NewModel(player *Player, downloader *Downloader) Model {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return Model{
-		player:           player,
-		downloader:       downloader,
-		ctx:              ctx,
-		cancelFunc:       cancel,
-		currentView:      ViewLibrary,
-		searchInput:      ti,
-		downloadProgress: prog,
-		downloadSpinner:  sp,
+		player:             player,
+		downloader:         downloader,
+		stats:              stats,
+		panes:              panes,
+		announcedDownloads: make(map[int]bool),
+		thumbnails:         make(map[string]string),
+		ctx:                ctx,
+		cancelFunc:         cancel,
+		currentView:        ViewLibrary,
+		paneLeftView:       ViewLibrary,
+		keyPreset:          keyPreset,
+		ticks:              ticks,
+		tickInterval:       ticks.Interval,
+		screensaverDelay:   screensaverDelay,
+		lastInput:          time.Now(),
+		sessionStart:       time.Now(),
+		searchInput:        ti,
+		filterInput:        fi,
+		finderInput:        fz,
+		pickerInput:        pk,
+		downloadSpinner:    sp,
+		spectrum:           &Spectrum{},
+		library:            NewLibrary(),
+		libraryCache:       &libraryFilterCache{},
+		libraryScroll:      newScrollList(),
+		queueScroll:        newScrollList(),
+		resultsScroll:      newScrollList(),
+		downloadsScroll:    newScrollList(),
+		playlistsScroll:    newScrollList(),
+		editorScroll:       newScrollList(),
+		logScroll:          newScrollList(),
+		duplicatesScroll:   newScrollList(),
+		browseScroll:       newScrollList(),
+		organizeScroll:     newScrollList(),
 	}
 }
 
@@ -177,70 +356,250 @@ func NewModel(player *Player, downloader *Downloader) Model {
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.refreshLibrary(),
+		m.library.next(),
+		m.library.nextProgress(),
+		m.watchLibrary(),
+		loadPlaylists(),
 		m.tickCmd(),
 	)
 }
 
 // Update handles incoming messages and updates the model.
+// After every message the list viewports are scrolled to follow their cursors
+// and the refresh ticker is adjusted to the current activity.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	if mm, ok := model.(Model); ok {
+		if mm.currentView != ViewQueue {
+			mm.paneLeftView = mm.currentView
+		}
+		mm, castCmd := mm.syncCast()
+		mm, tickCmd := mm.followCursors().retick()
+		return mm, tea.Batch(cmd, castCmd, tickCmd)
+	}
+	return model, cmd
+}
+
+// update handles incoming messages and updates the model.
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		var woke bool
+		if m, woke = m.noteInput(); woke {
+			return m, nil // Any key only dismisses the screensaver
+		}
 		return m.handleKeyPress(msg)
 
+	case tea.MouseMsg:
+		var woke bool
+		if m, woke = m.noteInput(); woke {
+			return m, nil
+		}
+		return m.handleMouse(msg)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.downloadProgress.Width = msg.Width - 20
 
 	case tickMsg:
-		m.tickCount++
-		// Decrement status timer
-		if m.statusTimer > 0 {
-			m.statusTimer--
-			if m.statusTimer == 0 {
-				m.statusMessage = ""
-			}
+		if msg.gen != m.tickGen {
+			return m, nil // Tick from a replaced ticker
 		}
-		
-		// Check if download completed and refresh library
-		if !m.downloader.IsDownloading() {
-			dp := m.downloader.GetProgress()
-			if dp.Progress >= 100 && len(dp.Files) > 0 {
-				return m, tea.Batch(m.tickCmd(), m.refreshLibrary())
-			}
+		m = m.expireStatus().trackListening()
+
+		// Announce state changes for screen readers
+		var announceCmd tea.Cmd
+		m, announceCmd = m.announceChanges()
+
+		// Start the screensaver after a while without input
+		var saverCmd tea.Cmd
+		m, saverCmd = m.maybeStartScreensaver()
+
+		// Compute the waveform and album colors when a new track starts
+		var waveformCmd, colorsCmd tea.Cmd
+		m, waveformCmd = m.updateWaveform()
+		m, colorsCmd = m.updateAlbumColors()
+
+		// Keep the saved queue and the recently played view up to date
+		var queueCmd, recentCmd tea.Cmd
+		m, queueCmd = m.autoSaveQueue()
+		m, recentCmd = m.refreshRecentPlays()
+
+		// Refresh the library when new downloads have completed, unless the
+		// watcher picks them up
+		if completed := m.downloader.CompletedCount(); completed != m.downloadsCompleted && !m.library.Watching() {
+			m.downloadsCompleted = completed
+			return m, tea.Batch(m.tickCmd(), m.refreshLibrary(), waveformCmd, colorsCmd, announceCmd, saverCmd, queueCmd, recentCmd)
 		}
-		
-		return m, m.tickCmd()
 
-	case youtubeSearchCompleteMsg:
-		m.isSearching = false
-		if msg.err != nil {
-			m.searchError = msg.err.Error()
-			m.youtubeResults = nil
-		} else if len(msg.results) == 0 {
-			m.searchError = "No results found"
-			m.youtubeResults = nil
-		} else {
-			m.youtubeResults = msg.results
-			m.resultsCursor = 0
-			m.currentView = ViewResults
-			m.searchError = ""
+		return m, tea.Batch(m.tickCmd(), waveformCmd, colorsCmd, announceCmd, saverCmd, queueCmd, recentCmd)
+
+	case vizTickMsg:
+		if m.currentView != ViewVisualizer && !m.screensaver {
+			return m, nil // Stop animating when the visualizer is hidden
 		}
+		samples, rate := m.player.TapSamples(fftSize)
+		m.spectrum.Update(samples, rate, m.visualizerBands())
+		m.scope = samples
+		return m, m.vizTickCmd()
+
+	case searchPartialMsg:
+		var searchCmd tea.Cmd
+		m, searchCmd = m.applySearchPartial(msg)
+		cmds = append(cmds, searchCmd)
+
+	case recentPlaysMsg:
+		var recentCmd tea.Cmd
+		m, recentCmd = m.applyRecentPlays(msg)
+		cmds = append(cmds, recentCmd)
 
 	case libraryRefreshMsg:
+		cmds = append(cmds, m.library.next()) // Wait for the next scan
+		if sameLibrary(m.libraryFiles, msg) {
+			break // Nothing changed on disk; keep the sorted, tagged list
+		}
 		m.libraryFiles = msg
-		m.player.SetPlaylist(msg)
-		if m.libraryCursor >= len(msg) && len(msg) > 0 {
-			m.libraryCursor = len(msg) - 1
+		m.libraryGen++
+
+		// Fill in the known tags and durations before sorting, which may go
+		// by them, and read the rest in the background
+		var scanCmd tea.Cmd
+		m, scanCmd = m.scanLibraryMetadata()
+		cmds = append(cmds, scanCmd)
+		sortLibrary(m.libraryFiles, m.libraryView, m.stats)
+		m.player.SetPlaylist(m.libraryFiles)
+		if visible := m.visibleLibrary(); m.libraryCursor >= len(visible) && len(visible) > 0 {
+			m.libraryCursor = len(visible) - 1
+		}
+		m = m.restoreLibraryCursor()
+		if m.currentView == ViewDuplicates {
+			m = m.refreshDuplicates()
+		}
+		if m.currentView == ViewBrowse {
+			m = m.refreshBrowse()
+		}
+
+	case libraryScanMsg:
+		cmds = append(cmds, m.library.nextProgress())
+		m.libraryScan = &msg
+		if msg.done {
+			m.libraryScan = nil
+		}
+
+	case filesDeletedMsg:
+		var deletedCmd tea.Cmd
+		m, deletedCmd = m.applyFilesDeleted(msg)
+		cmds = append(cmds, deletedCmd)
+
+	case trashRestoredMsg:
+		var restoredCmd tea.Cmd
+		m, restoredCmd = m.applyTrashRestored(msg)
+		cmds = append(cmds, restoredCmd)
+
+	case metadataBatchMsg:
+		var scanCmd tea.Cmd
+		m, scanCmd = m.applyMetadataBatch(msg)
+		cmds = append(cmds, scanCmd)
+		if m.currentView == ViewDuplicates {
+			m = m.refreshDuplicates()
+		}
+		if m.currentView == ViewBrowse {
+			m = m.refreshBrowse()
 		}
 
+	case duplicatesMergedMsg:
+		var mergedCmd tea.Cmd
+		m, mergedCmd = m.applyDuplicatesMerged(msg)
+		cmds = append(cmds, mergedCmd)
+
 	case statusMsg:
 		m.statusMessage = string(msg)
-		m.statusTimer = 10 // Show for ~5 seconds (10 ticks at 500ms)
+		m.statusUntil = time.Now().Add(statusDuration)
+		return m, announce(m.statusMessage)
+
+	case errorMsg:
+		m.errors = append(m.errors, string(msg))
+		appLog.Add("error", string(msg))
+		return m, announce(Tf("Error: %s", string(msg)))
+
+	case shutdownMsg:
+		return m.shutdown(msg)
+
+	case remoteMsg:
+		return m.handleRemote(msg)
+
+	case titleResolvedMsg:
+		return m.startDownload(SearchResult(msg))
+
+	case jellyfinItemsMsg:
+		return m.showJellyfinItems(msg)
+
+	case jellyfinFetchedMsg:
+		return m.jellyfinFetched(msg)
+
+	case syncStateMsg:
+		return m.followSync(msg)
+
+	case syncFetchedMsg:
+		return m.syncFetched(msg)
+
+	case castDevicesMsg:
+		return m.showCastDevices(msg)
+
+	case castStartedMsg:
+		return m.castStarted(msg)
+
+	case playlistsLoadedMsg:
+		var loadCmd tea.Cmd
+		m, loadCmd = m.applyPlaylistsLoaded(msg)
+		cmds = append(cmds, loadCmd)
+
+	case albumColorsMsg:
+		m = m.applyAlbumColors(msg)
+
+	case thumbnailMsg:
+		m.thumbnails[msg.videoID] = msg.art
+
+	case waveformMsg:
+		if msg.err == nil && msg.waveform.Path == m.waveformPath {
+			m.waveform = msg.waveform
+		}
+
+	case tagLookupMsg:
+		return m.applyTagLookup(msg)
+
+	case identifyStepMsg:
+		var identifyCmd tea.Cmd
+		m, identifyCmd = m.applyIdentifyStep(msg)
+		cmds = append(cmds, identifyCmd)
+
+	case tagsWrittenMsg:
+		var tagCmd tea.Cmd
+		m, tagCmd = m.applyTagsWritten(msg)
+		cmds = append(cmds, tagCmd)
+
+	case tracksMovedMsg:
+		var movedCmd tea.Cmd
+		m, movedCmd = m.applyTracksMoved(msg)
+		cmds = append(cmds, movedCmd)
+
+	case genreSetMsg:
+		var genreCmd tea.Cmd
+		m, genreCmd = m.applyGenreSet(msg)
+		cmds = append(cmds, genreCmd)
+
+	case trackInfoMsg:
+		if msg.err != nil {
+			return m, func() tea.Msg { return errorMsg(msg.err.Error()) }
+		}
+		m.trackInfo = &msg.info
 
 	case spinner.TickMsg:
+		if !m.isBusy() {
+			return m, nil // Let the spinner stop while nothing is running
+		}
 		var cmd tea.Cmd
 		m.downloadSpinner, cmd = m.downloadSpinner.Update(msg)
 		cmds = append(cmds, cmd)
@@ -258,12 +617,85 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKeyPress processes keyboard input.
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// Global keys (work in all views)
-	switch msg.String() {
-	case "ctrl+c":
+	if msg.String() == "ctrl+c" {
 		m.cancelFunc()
 		return m, tea.Quit
+	}
+
+	// An open dialog captures all keys
+	if m.dialog != nil {
+		return m.handleDialogKeys(msg)
+	}
+
+	// The sort/filter menu captures all keys while open
+	if m.libraryMenuOpen {
+		return m.handleLibraryMenuKeys(msg)
+	}
+
+	// The equalizer panel captures all keys while open
+	if m.eqOpen {
+		return m.handleEqualizerKeys(msg)
+	}
+
+	// The context menu captures all keys while open
+	if m.contextMenu != nil {
+		return m.handleContextMenuKeys(msg)
+	}
+
+	// The playlist editor's library picker captures all keys while open
+	if m.pickerOpen {
+		return m.handlePickerKeys(msg)
+	}
+
+	// The track detail panel captures all keys while open
+	if m.trackInfo != nil {
+		return m.handleTrackInfoKeys(msg)
+	}
+
+	// The finder overlay captures all keys while open
+	if m.finderOpen {
+		return m.handleFinderKeys(msg)
+	}
+	if msg.String() == "ctrl+p" {
+		return m.openFinder()
+	}
 
+	// The library filter input captures all keys while focused
+	if m.filtering {
+		return m.handleFilterKeys(msg)
+	}
+	if m.renaming != nil {
+		return m.handleRenameKeys(msg)
+	}
+
+	// Apply extra bindings from the config file outside text input
+	if m.currentView != ViewSearch {
+		msg = remapKey(msg)
+	}
+
+	// Translate vim sequences (gg, G, dd) into default keys
+	if m.keyPreset == KeyPresetVim && m.currentView != ViewSearch {
+		translated, pending := m.translateVimKey(msg)
+		if pending {
+			return m, nil
+		}
+		msg = translated
+	}
+
+	// Selection mode keys take precedence over global keys like space and v
+	switch m.currentView {
+	case ViewLibrary:
+		if m.handleSelectionKeys(msg, &m.librarySel, m.libraryKeys(), m.libraryCursor) {
+			return m, nil
+		}
+	case ViewResults:
+		if m.handleSelectionKeys(msg, &m.resultsSel, m.resultKeys(), m.resultsCursor) {
+			return m, nil
+		}
+	}
+
+	// Global keys (work in all views)
+	switch msg.String() {
 	case "q": // Quit (only when not in search view)
 		if m.currentView != ViewSearch {
 			m.cancelFunc()
@@ -292,6 +724,50 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+	case "r": // Cycle repeat mode (retries downloads in the downloads view)
+		if m.currentView != ViewSearch && m.currentView != ViewDownloads {
+			var status string
+			switch m.player.CycleRepeat() {
+			case RepeatAll:
+				status = T("Repeat all")
+			case RepeatTrack:
+				status = T("Repeat one")
+			default:
+				status = T("Repeat off")
+			}
+			return m, func() tea.Msg { return statusMsg(status) }
+		}
+
+	case "z": // Toggle shuffle
+		if m.currentView != ViewSearch {
+			if m.player.ToggleShuffle() {
+				return m, func() tea.Msg { return statusMsg(T("Shuffle on")) }
+			}
+			return m, func() tea.Msg { return statusMsg(T("Shuffle off")) }
+		}
+
+	case "+", "=", "-": // Volume up/down; = is + without shift
+		if m.currentView != ViewSearch {
+			delta := volumeStep
+			if msg.String() == "-" {
+				delta = -volumeStep
+			}
+			m.player.ChangeVolume(delta)
+			return m, nil
+		}
+
+	case "shift+left", "shift+right": // Seek within the track
+		if m.currentView != ViewSearch {
+			offset := seekStep
+			if msg.String() == "shift+left" {
+				offset = -seekStep
+			}
+			if err := m.player.Seek(offset); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m, nil
+		}
+
 	case "s": // Open search
 		if m.currentView != ViewSearch {
 			m.currentView = ViewSearch
@@ -300,20 +776,160 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, textinput.Blink
 		}
 
-	case "tab": // Switch views
-		if m.currentView == ViewSearch {
+	case "t": // Cycle color theme
+		if m.currentView != ViewSearch {
+			theme := NextTheme()
+			m.downloadSpinner.Style = lipgloss.NewStyle().Foreground(primaryColor)
+			return m, func() tea.Msg { return statusMsg(Tf("Theme: %s", theme.Name)) }
+		}
+
+	case "X": // Cancel the running download (all downloads in the downloads view)
+		if m.currentView == ViewDownloads && (m.downloader.IsDownloading() || m.downloader.PendingCount() > 0) {
+			return m.openDialog(NewConfirmDialog(T("Cancel all downloads"),
+				T("Stop the active download and drop all queued ones?"),
+				func(m Model, _ string) (tea.Model, tea.Cmd) {
+					m.downloader.CancelAll()
+					return m, func() tea.Msg { return statusMsg(T("All downloads cancelled")) }
+				}))
+		}
+		if m.currentView != ViewSearch && m.downloader.IsDownloading() {
+			return m.openDialog(NewConfirmDialog(T("Cancel download"),
+				T("Stop the download in progress?"),
+				func(m Model, _ string) (tea.Model, tea.Cmd) {
+					m.downloader.CancelDownload()
+					return m, func() tea.Msg { return statusMsg(T("Download cancelled")) }
+				}))
+		}
+
+	case "b": // Toggle mini mode
+		if m.currentView != ViewSearch {
+			m.miniMode = !m.miniMode
+			return m, nil
+		}
+
+	case "[", "]": // Resize panes
+		if m.currentView != ViewSearch && m.isWide() {
+			delta := paneRatioStep
+			if msg.String() == "[" {
+				delta = -paneRatioStep
+			}
+			if _, err := m.panes.Resize(m.width, delta); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m, nil
+		}
+
+	case "ctrl+w": // Cycle pane focus
+		if m.isWide() {
+			return m.cycleFocus(), nil
+		}
+
+	case "E": // Dismiss pending errors
+		if m.currentView != ViewSearch && len(m.errors) > 0 {
+			m.errors = nil
+			return m, nil
+		}
+
+	case "A": // Mark the start of the A–B loop (queues a page in the Jellyfin view)
+		if m.currentView != ViewSearch && m.currentView != ViewJellyfin {
+			position, err := m.player.MarkLoopA()
+			if err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m, func() tea.Msg { return statusMsg(Tf("Loop start: %s", FormatDuration(position))) }
+		}
+
+	case "B": // Mark the end of the A–B loop, or clear the loop
+		if m.currentView != ViewSearch {
+			if m.player.GetState().Loop.Active {
+				m.player.ClearLoop()
+				return m, func() tea.Msg { return statusMsg(T("Loop cleared")) }
+			}
+			loop, err := m.player.MarkLoopB()
+			if err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m, func() tea.Msg {
+				return statusMsg(Tf("Looping %s–%s", FormatDuration(loop.A), FormatDuration(loop.B)))
+			}
+		}
+
+	case "e": // Equalizer
+		if m.currentView != ViewSearch {
+			m.eqOpen = true
+			return m, nil
+		}
+
+	case "M": // Toggle mute
+		if m.currentView != ViewSearch {
+			if m.player.ToggleMute() {
+				return m, func() tea.Msg { return statusMsg(T("Muted")) }
+			}
+			return m, func() tea.Msg { return statusMsg(T("Unmuted")) }
+		}
+
+	case "v": // Toggle visualizer
+		if m.currentView == ViewVisualizer {
+			m.currentView = ViewLibrary
+			return m, nil
+		}
+		if m.currentView != ViewSearch {
+			m.currentView = ViewVisualizer
+			return m, m.vizTickCmd()
+		}
+
+	case "P": // Playlists
+		if m.currentView != ViewSearch {
+			return m.openPlaylists()
+		}
+
+	case "L": // Log
+		if m.currentView != ViewSearch {
+			return m.openLog()
+		}
+
+	case "J": // Jellyfin (shift+down in the queue and playlist editor moves entries)
+		if m.currentView != ViewSearch && m.currentView != ViewQueue && m.currentView != ViewPlaylistEditor {
+			return m.openJellyfin()
+		}
+
+	case ".": // Jump to the playing track
+		if m.currentView != ViewSearch {
+			return m.jumpToPlaying()
+		}
+
+	case "tab": // Switch views: Library → Recently added → Recently played → Artists → Queue → Playlists → Downloads → Results → Library
+		switch m.currentView {
+		case ViewSearch:
 			m.currentView = ViewLibrary
 			m.searchInput.Blur()
-		} else if len(m.youtubeResults) > 0 {
-			if m.currentView == ViewLibrary {
+		case ViewLibrary:
+			if m.libraryView.Scope+1 < scopeCount {
+				return m.setLibraryScope(m.libraryView.Scope + 1)
+			}
+			m, _ = m.setLibraryScope(ScopeAll)
+			return m.openBrowse()
+		case ViewBrowse:
+			m.currentView = ViewQueue
+		case ViewQueue:
+			return m.openPlaylists()
+		case ViewPlaylists, ViewPlaylistEditor:
+			m.currentView = ViewDownloads
+		case ViewDownloads:
+			if len(m.youtubeResults) > 0 {
 				m.currentView = ViewResults
 			} else {
 				m.currentView = ViewLibrary
 			}
+		default:
+			m.currentView = ViewLibrary
 		}
 		return m, nil
 
-	case "esc": // Back to library
+	case "esc": // Back to library (the editor goes back to the playlists)
+		if m.currentView == ViewPlaylistEditor {
+			return m.closePlaylistEditor()
+		}
 		if m.currentView != ViewLibrary {
 			m.currentView = ViewLibrary
 			m.searchInput.Blur()
@@ -329,6 +945,26 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleLibraryKeys(msg)
 	case ViewResults:
 		return m.handleResultsKeys(msg)
+	case ViewQueue:
+		return m.handleQueueKeys(msg)
+	case ViewDownloads:
+		return m.handleDownloadsKeys(msg)
+	case ViewPlaylists:
+		return m.handlePlaylistsKeys(msg)
+	case ViewPlaylistEditor:
+		return m.handlePlaylistEditorKeys(msg)
+	case ViewLog:
+		return m.handleLogKeys(msg)
+	case ViewJellyfin:
+		return m.handleJellyfinKeys(msg)
+	case ViewDuplicates:
+		return m.handleDuplicatesKeys(msg)
+	case ViewBrowse:
+		return m.handleBrowseKeys(msg)
+	case ViewOrganize:
+		return m.handleOrganizeKeys(msg)
+	case ViewVisualizer:
+		return m.handleVisualizerKeys(msg)
 	}
 
 	return m, nil
@@ -340,10 +976,8 @@ func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter":
 		query := strings.TrimSpace(m.searchInput.Value())
 		if query != "" {
-			m.searchQuery = query
-			m.isSearching = true
-			m.searchError = ""
-			return m, m.performYouTubeSearch(query)
+			m, cmd := m.startSearch(query)
+			return m, tea.Batch(cmd, m.downloadSpinner.Tick)
 		}
 	}
 
@@ -355,111 +989,449 @@ func (m Model) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleLibraryKeys handles keys in the library view.
 func (m Model) handleLibraryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	files := m.visibleLibrary()
+	if cursor, ok := m.navigateList(msg.String(), m.libraryCursor, len(files)); ok {
+		m.libraryCursor = cursor
+		return m, nil
+	}
+
 	switch msg.String() {
-	case "up", "k":
-		if m.libraryCursor > 0 {
-			m.libraryCursor--
+	case "enter":
+		if m.libraryCursor < len(files) {
+			file := files[m.libraryCursor]
+			if err := m.player.PlayTrack(file); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			return m, func() tea.Msg { return statusMsg(Tf("Now playing: %s", file.Name)) }
+		}
+	case "a": // Add to queue (marked songs or the selected one)
+		return m.enqueueMarked()
+	case "p": // Add to playlist (marked songs or the selected one)
+		return m.promptAddToPlaylist(m.markedLibrary())
+	case "x", "delete": // Move to the trash (marked songs or the selected one)
+		return m.deleteMarked()
+	case "u": // Undo the last delete
+		return m, m.undoDelete()
+	case "o": // Sort/filter menu
+		m.libraryMenuOpen = true
+		return m, nil
+	case "O": // Next sort key; o is taken by the sort/filter menu
+		return m.cycleLibrarySort()
+	case "R", "f2": // Rename or move (marked songs or the selected one)
+		return m.startRename()
+	case "m": // Context menu
+		return m.openContextMenu()
+	case "i": // Track details
+		if m.libraryCursor < len(files) {
+			return m, m.showTrackInfo(files[m.libraryCursor])
+		}
+	case "0", "1", "2", "3", "4", "5": // Rate, or clear the rating with 0
+		if m.libraryCursor < len(files) {
+			return m.rateTrack(files[m.libraryCursor], int(msg.String()[0]-'0'))
 		}
-	case "down", "j":
-		if m.libraryCursor < len(m.libraryFiles)-1 {
-			m.libraryCursor++
+	case "f": // Like / unlike
+		if m.libraryCursor < len(files) {
+			file := files[m.libraryCursor]
+			liked, err := m.stats.ToggleLiked(file.Path)
+			if err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			if liked {
+				return m, func() tea.Msg { return statusMsg(Tf("Liked: %s", file.Name)) }
+			}
+			return m, func() tea.Msg { return statusMsg(Tf("Unliked: %s", file.Name)) }
 		}
+	case "/": // Filter library
+		m.filtering = true
+		m.filterInput.Focus()
+		return m, textinput.Blink
+	}
+	return m, nil
+}
+
+// handleFilterKeys handles keys while the library filter input is focused.
+// Enter keeps the filter, Esc clears it.
+func (m Model) handleFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
 	case "enter":
-		if len(m.libraryFiles) > 0 && m.libraryCursor < len(m.libraryFiles) {
-			if err := m.player.PlayIndex(m.libraryCursor); err != nil {
-				return m, func() tea.Msg { return statusMsg("Error: " + err.Error()) }
+		m.filtering = false
+		m.filterInput.Blur()
+		return m, nil
+	case "esc":
+		m.filtering = false
+		m.filterInput.Blur()
+		m.filterInput.SetValue("")
+		m.libraryCursor = 0
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.libraryCursor = 0
+	return m, cmd
+}
+
+// jumpToPlaying selects the playing track and scrolls it to the middle of the
+// list: in the playlist editor when the playlist contains it, otherwise in the
+// library, clearing any filter that hides it.
+func (m Model) jumpToPlaying() (tea.Model, tea.Cmd) {
+	current := m.player.GetState().CurrentFile
+	if current == "" {
+		return m, func() tea.Msg { return statusMsg(T("No song playing")) }
+	}
+
+	if m.currentView == ViewPlaylistEditor {
+		for i, f := range m.editing.Tracks {
+			if f.Path == current {
+				m.editorCursor = i
+				m.editorScroll.center(i, len(m.editing.Tracks), 1, m.maxVisible())
+				return m, nil
 			}
-			return m, func() tea.Msg { return statusMsg("Now playing: " + m.libraryFiles[m.libraryCursor].Name) }
 		}
 	}
+
+	index := -1
+	for i, f := range m.visibleLibrary() {
+		if f.Path == current {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		// Hidden by a filter
+		m.filterInput.SetValue("")
+		m.libraryView.clearFilters()
+		for i, f := range m.visibleLibrary() {
+			if f.Path == current {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		return m, func() tea.Msg { return statusMsg(T("The playing track is not in the library")) }
+	}
+
+	m.currentView = ViewLibrary
+	m.libraryCursor = index
+	m.libraryScroll.center(index, len(m.visibleLibrary()), 1, m.maxVisible())
 	return m, nil
 }
 
-// handleResultsKeys handles keys in the search results view (YouTube).
-func (m Model) handleResultsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// libraryFilterCache remembers the last filtered library, as the views,
+// cursor handling and mouse all ask for it several times per update.
+type libraryFilterCache struct {
+	key   libraryFilterKey
+	files []MusicFile
+}
+
+// libraryFilterKey identifies everything a filtered library depends on.
+type libraryFilterKey struct {
+	gen   int
+	query string
+	view  LibraryView
+	stats int
+}
+
+// visibleLibrary returns the library files matching the current filters.
+func (m Model) visibleLibrary() []MusicFile {
+	query := strings.ToLower(strings.TrimSpace(m.filterInput.Value()))
+	if query == "" && !m.libraryView.filtered() {
+		return m.libraryFiles
+	}
+
+	key := libraryFilterKey{gen: m.libraryGen, query: query, view: m.libraryView, stats: m.stats.Version()}
+	if m.libraryCache != nil && m.libraryCache.key == key {
+		return m.libraryCache.files
+	}
+
+	var files []MusicFile
+	for _, f := range m.scopedLibrary() {
+		if query != "" && !strings.Contains(strings.ToLower(f.Name), query) && !strings.Contains(strings.ToLower(f.DisplayName()), query) {
+			continue
+		}
+		if !m.libraryView.matchesFilters(f, m.stats) {
+			continue
+		}
+		files = append(files, f)
+	}
+	if m.libraryCache != nil {
+		m.libraryCache.key, m.libraryCache.files = key, files
+	}
+	return files
+}
+
+// handleQueueKeys handles keys in the queue view.
+func (m Model) handleQueueKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	queue := m.player.GetQueue()
+	if cursor, ok := m.navigateList(msg.String(), m.queueCursor, len(queue)); ok {
+		m.queueCursor = cursor
+		return m, nil
+	}
+
 	switch msg.String() {
-	case "up", "k":
-		if m.resultsCursor > 0 {
-			m.resultsCursor--
+	case "shift+up", "K": // Move entry up
+		if idx, err := m.player.MoveQueueItem(m.queueCursor, -1); err == nil {
+			m.queueCursor = idx
 		}
-	case "down", "j":
-		if m.resultsCursor < len(m.youtubeResults)-1 {
-			m.resultsCursor++
+	case "shift+down", "J": // Move entry down
+		if idx, err := m.player.MoveQueueItem(m.queueCursor, 1); err == nil {
+			m.queueCursor = idx
 		}
+	case "d", "delete": // Remove entry
+		if err := m.player.RemoveFromQueue(m.queueCursor); err == nil {
+			if m.queueCursor >= len(queue)-1 && m.queueCursor > 0 {
+				m.queueCursor--
+			}
+		}
+	case "c": // Clear queue
+		if len(queue) > 0 {
+			return m.openDialog(NewConfirmDialog(T("Clear queue"),
+				Tf("Remove all %d tracks from the queue?", len(queue)),
+				func(m Model, _ string) (tea.Model, tea.Cmd) {
+					m.player.ClearQueue()
+					m.queueCursor = 0
+					return m, func() tea.Msg { return statusMsg(T("Queue cleared")) }
+				}))
+		}
+	case "i": // Track details
+		if m.queueCursor < len(queue) {
+			return m, m.showTrackInfo(queue[m.queueCursor])
+		}
+	case "enter":
+		if m.queueCursor < len(queue) {
+			name := queue[m.queueCursor].Name
+			if err := m.player.PlayQueueItem(m.queueCursor); err != nil {
+				return m, func() tea.Msg { return errorMsg(err.Error()) }
+			}
+			m.queueCursor = 0
+			return m, func() tea.Msg { return statusMsg(Tf("Now playing: %s", name)) }
+		}
+	}
+	return m, nil
+}
+
+// handleResultsKeys handles keys in the search results view (YouTube).
+func (m Model) handleResultsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if cursor, ok := m.navigateList(msg.String(), m.resultsCursor, len(m.youtubeResults)); ok {
+		m.resultsCursor = cursor
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "m": // Context menu
+		return m.openContextMenu()
 	case "enter":
+		if m.resultsSel.count() > 0 {
+			return m.downloadMarked()
+		}
 		if len(m.youtubeResults) > 0 && m.resultsCursor < len(m.youtubeResults) {
 			result := m.youtubeResults[m.resultsCursor]
-			if err := m.downloader.DownloadFromYouTube(m.ctx, result.VideoID, result.Title); err != nil {
-				return m, func() tea.Msg { return statusMsg("Download error: " + err.Error()) }
+			if m.library.Contains(sanitizeFilename(result.Title)) {
+				return m.openDialog(NewConfirmDialog(T("Already downloaded"),
+					Tf("%q is already in the library. Download again and overwrite it?", result.Title),
+					func(m Model, _ string) (tea.Model, tea.Cmd) {
+						return m.startDownload(result)
+					}))
 			}
-			return m, tea.Batch(
-				m.downloadSpinner.Tick,
-				func() tea.Msg { return statusMsg("Downloading: " + result.Title) },
-			)
+			return m.startDownload(result)
 		}
 	}
 	return m, nil
 }
 
-// View renders the TUI.
-func (m Model) View() string {
-	if m.width == 0 {
-		return "Loading..."
+// startDownload starts downloading a search result, queueing it behind running downloads.
+func (m Model) startDownload(result SearchResult) (tea.Model, tea.Cmd) {
+	if err := m.downloader.QueueDownload(m.ctx, result.VideoID, result.Title); err != nil {
+		return m, func() tea.Msg { return errorMsg(Tf("Download error: %v", err)) }
 	}
+	return m, tea.Batch(
+		m.downloadSpinner.Tick,
+		func() tea.Msg { return statusMsg(Tf("Downloading: %s", result.Title)) },
+	)
+}
 
-	var sections []string
+// View renders the TUI, substituting ASCII glyphs when ASCII mode is on.
+func (m Model) View() string {
+	return Glyphs(m.render())
+}
 
-	// Title
-	title := titleStyle.Render("🎵 Personal Musician")
-	sections = append(sections, title)
+// render renders the TUI.
+func (m Model) render() string {
+	if m.width == 0 {
+		return T("Loading...")
+	}
 
-	// Now playing bar
-	sections = append(sections, m.renderNowPlaying())
+	if accessibleMode {
+		return m.renderAccessible()
+	}
 
-	// Main content based on current view
-	switch m.currentView {
-	case ViewSearch:
-		sections = append(sections, m.renderSearchView())
-	case ViewLibrary:
-		sections = append(sections, m.renderLibraryView())
-	case ViewResults:
-		sections = append(sections, m.renderResultsView())
+	if m.screensaver {
+		return m.renderScreensaver()
 	}
 
-	// Download progress (if downloading)
-	if m.downloader.IsDownloading() {
-		sections = append(sections, m.renderDownloadProgress())
+	if m.miniMode && m.dialog == nil {
+		return m.renderMini()
 	}
 
-	// Status message
-	if m.statusMessage != "" {
-		sections = append(sections, statusStyle.Render(m.statusMessage))
+	var sections []string
+
+	// Title with the download summary beside it
+	sections = append(sections, m.renderTitle())
+
+	if m.dialog != nil {
+		// Dialogs replace the main content until answered
+		sections = append(sections, m.renderDialog())
+	} else if m.libraryMenuOpen {
+		sections = append(sections, m.renderLibraryMenu())
+	} else if m.eqOpen {
+		sections = append(sections, m.renderEqualizer())
+	} else if m.contextMenu != nil {
+		sections = append(sections, m.renderContextMenu())
+	} else if m.pickerOpen {
+		sections = append(sections, m.renderPicker())
+	} else if m.trackInfo != nil {
+		sections = append(sections, m.renderTrackInfo())
+	} else if m.finderOpen {
+		// Fuzzy finder replaces the main content
+		sections = append(sections, m.renderFinder())
+	} else if m.isWide() {
+		// Main content and Now Playing side by side
+		sections = append(sections, m.renderPanes())
+	} else {
+		// Now playing bar above the main content
+		sections = append(sections, m.renderNowPlaying())
+		sections = append(sections, m.renderMainView(m.currentView))
 	}
 
 	// Help bar
 	sections = append(sections, m.renderHelp())
 
+	// Status bar
+	sections = append(sections, m.renderStatusBar())
+
 	return strings.Join(sections, "\n")
 }
 
+// appTitle is the title shown in the header.
+const appTitle = "🎵 Personal Musician"
+
+// renderTitle renders the header: the title with the download summary,
+// metadata scan and identify progress, if any, beside it and the session
+// clock on the right.
+func (m Model) renderTitle() string {
+	title := titleStyle.Render(appTitle)
+	if summary := m.renderDownloadSummary(); summary != "" {
+		title = lipgloss.JoinHorizontal(lipgloss.Top, title, "   ", summary)
+	}
+	if scan := m.renderScanProgress(); scan != "" {
+		title = lipgloss.JoinHorizontal(lipgloss.Top, title, "   ", scan)
+	}
+	if identify := m.renderIdentifyProgress(); identify != "" {
+		title = lipgloss.JoinHorizontal(lipgloss.Top, title, "   ", identify)
+	}
+
+	clock := m.renderSessionClock()
+	gap := m.width - lipgloss.Width(title) - lipgloss.Width(clock)
+	if gap < 2 {
+		return title // No room for the clock
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, title, strings.Repeat(" ", gap), clock)
+}
+
+// downloadSummaryX returns the column where the header's download summary starts.
+func downloadSummaryX() int {
+	return lipgloss.Width(appTitle) + 3
+}
+
+// renderMainView renders the main content for a view.
+func (m Model) renderMainView(view View) string {
+	switch view {
+	case ViewSearch:
+		return m.renderSearchView()
+	case ViewResults:
+		return m.renderResultsView()
+	case ViewQueue:
+		return m.renderQueueView()
+	case ViewVisualizer:
+		return m.renderVisualizerView()
+	case ViewDownloads:
+		return m.renderDownloadsView()
+	case ViewPlaylists:
+		return m.renderPlaylistsView()
+	case ViewPlaylistEditor:
+		return m.renderPlaylistEditor()
+	case ViewLog:
+		return m.renderLogView()
+	case ViewJellyfin:
+		return m.renderJellyfinView()
+	case ViewDuplicates:
+		return m.renderDuplicatesView()
+	case ViewBrowse:
+		return m.renderBrowseView()
+	case ViewOrganize:
+		return m.renderOrganizeView()
+	default:
+		return m.renderLibraryView()
+	}
+}
+
 // renderNowPlaying renders the now playing section.
 func (m Model) renderNowPlaying() string {
 	state := m.player.GetState()
 
 	if !state.IsPlaying && state.CurrentFile == "" {
-		return mutedStyle.Render("♪ No song playing")
+		return mutedStyle.Render("♪ " + T("No song playing"))
+	}
+
+	songName := m.currentSongName(state)
+
+	// Format time
+	posStr := FormatDuration(state.Position)
+	durStr := FormatDuration(state.Duration)
+
+	playing := fmt.Sprintf("%s%s  %s/%s  [%d/%d]",
+		nowPlayingHead(state, songName),
+		m.renderProgressBar(state),
+		posStr,
+		durStr,
+		state.CurrentIndex+1,
+		state.TotalTracks,
+	)
+
+	if state.QueueLength > 0 {
+		playing += mutedStyle.Render("  " + Tf("+%d queued", state.QueueLength))
 	}
 
-	// Get current file info
+	playing += "  " + renderModeIndicators(state)
+
+	return boxStyle.Render(playing)
+}
+
+// currentSongName returns the display name of the current track. The
+// library is asked first, as the player's copy may predate its tags.
+func (m Model) currentSongName(state PlaybackState) string {
+	if i := state.CurrentIndex; i >= 0 && i < len(m.libraryFiles) && m.libraryFiles[i].Path == state.CurrentFile {
+		return m.libraryFiles[i].DisplayName()
+	}
 	files := m.player.GetPlaylist()
-	var songName string
 	if state.CurrentIndex >= 0 && state.CurrentIndex < len(files) {
-		songName = files[state.CurrentIndex].Name
-	} else {
-		songName = state.CurrentFile
+		return files[state.CurrentIndex].DisplayName()
 	}
+	return musicFileFromPath(state.CurrentFile).Name // Not in the library, e.g. from Jellyfin
+}
 
-	// Status icon
+// truncate shortens s to at most max runes, marking the cut with an ellipsis.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// nowPlayingHead renders the status icon and song name that precede the progress bar.
+func nowPlayingHead(state PlaybackState, songName string) string {
 	var icon string
 	if state.IsPaused {
 		icon = "⏸"
@@ -468,46 +1440,53 @@ func (m Model) renderNowPlaying() string {
 	} else {
 		icon = "♪"
 	}
+	return fmt.Sprintf("%s %s  ", icon, nowPlayingStyle.Render(songName))
+}
 
-	// Format time
-	posStr := FormatDuration(state.Position)
-	durStr := FormatDuration(state.Duration)
+// renderModeIndicators renders compact volume, mute, shuffle and repeat icons.
+// Inactive modes are dimmed so the layout does not shift when they change.
+func renderModeIndicators(state PlaybackState) string {
+	var volume string
+	switch {
+	case state.Muted || state.Volume == 0:
+		volume = "🔇 " + T("mute")
+	case state.Volume < 50:
+		volume = fmt.Sprintf("🔉 %d%%", state.Volume)
+	default:
+		volume = fmt.Sprintf("🔊 %d%%", state.Volume)
+	}
 
-	// Progress bar (simple)
-	var progressBar string
-	if state.Duration > 0 {
-		pct := float64(state.Position) / float64(state.Duration)
-		barWidth := 20
-		filled := int(pct * float64(barWidth))
-		progressBar = strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+	shuffle := mutedStyle.Render("🔀")
+	if state.Shuffle {
+		shuffle = statusStyle.Render("🔀")
 	}
 
-	playing := fmt.Sprintf("%s %s  %s  %s/%s  [%d/%d]",
-		icon,
-		nowPlayingStyle.Render(songName),
-		progressBar,
-		posStr,
-		durStr,
-		state.CurrentIndex+1,
-		state.TotalTracks,
-	)
+	var repeat string
+	switch state.Repeat {
+	case RepeatTrack:
+		repeat = statusStyle.Render("🔂")
+	case RepeatAll:
+		repeat = statusStyle.Render("🔁")
+	default:
+		repeat = mutedStyle.Render("🔁")
+	}
 
-	return boxStyle.Render(playing)
+	return fmt.Sprintf("%s %s %s", normalStyle.Render(volume), shuffle, repeat)
 }
 
 // renderSearchView renders the search input view.
 func (m Model) renderSearchView() string {
 	var b strings.Builder
 
-	b.WriteString(headerStyle.Render(" 🔍 YouTube Search ") + "\n\n")
+	b.WriteString(headerStyle.Render(" 🔍 "+T("YouTube Search")+" ") + "\n\n")
 	b.WriteString(m.searchInput.View() + "\n")
 
 	if m.isSearching {
-		b.WriteString(m.downloadSpinner.View() + " Searching YouTube...\n")
+		b.WriteString(m.downloadSpinner.View() + " " + T("Searching YouTube...") + "\n")
 	}
 
 	if m.searchError != "" {
-		b.WriteString(mutedStyle.Render("⚠ " + m.searchError) + "\n")
+		b.WriteString(mutedStyle.Render("⚠ "+m.searchError) + "\n")
 	}
 
 	return b.String()
@@ -517,40 +1496,34 @@ func (m Model) renderSearchView() string {
 func (m Model) renderLibraryView() string {
 	var b strings.Builder
 
-	b.WriteString(headerStyle.Render(" 📚 Library ") + "\n\n")
+	header := headerStyle.Render(" 📚 " + T(m.libraryView.Scope.String()) + " ")
+	if m.filtering || m.filterInput.Value() != "" {
+		header += "  " + m.filterInput.View()
+	}
+	b.WriteString(header + "\n\n")
 
 	if len(m.libraryFiles) == 0 {
-		b.WriteString(mutedStyle.Render("No music files found in ./Music\n"))
-		b.WriteString(mutedStyle.Render("Press 's' to search and download music\n"))
+		b.WriteString(mutedStyle.Render(Tf("No music files found in %s", MusicDir) + "\n"))
+		b.WriteString(mutedStyle.Render(T("Press 's' to search and download music") + "\n"))
 		return b.String()
 	}
 
-	// Calculate visible range for scrolling
-	maxVisible := m.height - 15 // Leave room for other UI elements
-	if maxVisible < 5 {
-		maxVisible = 5
-	}
-
-	start := 0
-	if m.libraryCursor >= maxVisible {
-		start = m.libraryCursor - maxVisible + 1
-	}
-
-	end := start + maxVisible
-	if end > len(m.libraryFiles) {
-		end = len(m.libraryFiles)
+	files := m.visibleLibrary()
+	if len(files) == 0 {
+		b.WriteString(mutedStyle.Render(m.emptyScopeMessage() + "\n"))
+		return b.String()
 	}
 
 	// Get current playing index
 	state := m.player.GetState()
 
-	for i := start; i < end; i++ {
-		file := m.libraryFiles[i]
+	b.WriteString(m.libraryScroll.render(len(files), 1, m.maxVisible(), func(i int) string {
+		file := files[i]
 		var line string
 
 		// Playing indicator
 		var prefix string
-		if i == state.CurrentIndex && state.IsPlaying {
+		if file.Path == state.CurrentFile && state.IsPlaying {
 			if state.IsPaused {
 				prefix = "⏸ "
 			} else {
@@ -560,121 +1533,285 @@ func (m Model) renderLibraryView() string {
 			prefix = "  "
 		}
 
+		name := file.DisplayName()
+		stats := m.stats.Get(file.Path)
+		if stats.Liked {
+			name += " ♥"
+		}
+		if stats.Rating > 0 {
+			name += " " + ratingStars(stats.Rating, false)
+		}
+		if m.librarySel.active || m.librarySel.count() > 0 {
+			name = m.librarySel.markerFor(file.Path) + " " + name
+		}
+
+		if i == m.libraryCursor && m.renaming != nil {
+			return selectedStyle.Render(prefix+"> ") + m.renameInput.View()
+		}
 		if i == m.libraryCursor {
-			line = selectedStyle.Render(fmt.Sprintf("%s> %s", prefix, file.Name))
+			line = selectedStyle.Render(fmt.Sprintf("%s> %s", prefix, name))
 		} else {
-			line = normalStyle.Render(fmt.Sprintf("%s  %s", prefix, file.Name))
+			line = normalStyle.Render(fmt.Sprintf("%s  %s", prefix, name))
 		}
+		if file.Duration > 0 {
+			line += "  " + mutedStyle.Render(FormatDuration(file.Duration))
+		}
+		if stats.PlayCount > 0 {
+			line += "  " + mutedStyle.Render(fmt.Sprintf("%d×", stats.PlayCount)) // Play count column
+		}
+		line += m.renderEpisodeProgress(file)
+		return line
+	}))
+
+	return b.String()
+}
+
+// maxVisible returns how many list rows fit on screen.
+func (m Model) maxVisible() int {
+	maxVisible := m.height - 15 // Leave room for other UI elements
+	if maxVisible < 5 {
+		maxVisible = 5
+	}
+	return maxVisible
+}
+
+// followCursors scrolls every list viewport so its cursor row is visible.
+func (m Model) followCursors() Model {
+	height := m.maxVisible()
+	m.libraryScroll.follow(m.libraryCursor, len(m.visibleLibrary()), 1, height)
+	m.queueScroll.follow(m.queueCursor, m.player.GetState().QueueLength, 1, height)
+	m.resultsScroll.follow(m.resultsCursor, len(m.youtubeResults), 2, height*2)
+	m.downloadsScroll.follow(m.downloadsCursor, len(m.downloader.Downloads()), 1, height)
+	m.playlistsScroll.follow(m.playlistsCursor, len(m.playlistNames), 1, height)
+	m.editorScroll.follow(m.editorCursor, len(m.editing.Tracks), 1, height)
+	m.logScroll.follow(m.logCursor, len(appLog.Entries()), 1, height)
+	m.duplicatesScroll.follow(m.duplicatesCursor, len(m.duplicates), 1, height)
+	m.browseScroll.follow(m.browseCursor[m.browseLevel], m.browseCount(m.browseLevel), 1, height)
+	m.organizeScroll.follow(m.organizeCursor, len(m.organize), 2, height*2)
+	if page := m.jellyfinPage(); page != nil {
+		m.jellyfinScroll.follow(page.cursor, len(page.items), 1, height)
+	}
+	return m
+}
+
+// renderQueueView renders the upcoming tracks in the play queue.
+func (m Model) renderQueueView() string {
+	var b strings.Builder
+
+	b.WriteString(headerStyle.Render(" 📋 "+T("Queue")+" ") + "\n\n")
 
-		b.WriteString(line + "\n")
+	total := m.player.GetState().QueueLength
+	if total == 0 {
+		b.WriteString(mutedStyle.Render(T("Queue is empty") + "\n"))
+		b.WriteString(mutedStyle.Render(T("Press 'a' in the library to queue a song") + "\n"))
+		return b.String()
 	}
 
-	// Scroll indicator
-	if len(m.libraryFiles) > maxVisible {
-		b.WriteString(mutedStyle.Render(fmt.Sprintf("\n(%d/%d)", m.libraryCursor+1, len(m.libraryFiles))))
+	cursor := m.queueCursor
+	if cursor >= total {
+		cursor = total - 1
 	}
 
+	// Copy only the visible part of the queue
+	first := m.queueScroll.firstRow(1)
+	window := m.player.QueueRange(first, first+m.maxVisible())
+	b.WriteString(m.queueScroll.render(total, 1, m.maxVisible(), func(i int) string {
+		if i-first >= len(window) {
+			return "" // The queue shrank meanwhile
+		}
+		entry := fmt.Sprintf("%2d. %s", i+1, window[i-first].DisplayName())
+		if i == cursor {
+			return selectedStyle.Render("> " + entry)
+		}
+		return normalStyle.Render("  " + entry)
+	}))
+
 	return b.String()
 }
 
-// renderResultsView renders the YouTube search results.
-func (m Model) renderResultsView() string {
+// renderVisualizerView renders the spectrum or waveform visualizer.
+func (m Model) renderVisualizerView() string {
 	var b strings.Builder
 
-	b.WriteString(headerStyle.Render(fmt.Sprintf(" 🎬 Results for '%s' ", m.searchQuery)) + "\n\n")
+	b.WriteString(headerStyle.Render(" 📊 "+T("Visualizer")+" · "+T(m.vizMode.String())+" ") + "\n\n")
 
-	if len(m.youtubeResults) == 0 {
-		b.WriteString(mutedStyle.Render("No results\n"))
-		return b.String()
+	height := m.height - 14
+	if height < 4 {
+		height = 4
+	}
+	if m.vizMode == VisualizerWaveform {
+		b.WriteString(nowPlayingStyle.Render(RenderWaveform(m.scope, max(8, m.width-4), height)) + "\n")
+	} else {
+		b.WriteString(nowPlayingStyle.Render(m.spectrum.Render(height, 2)) + "\n")
 	}
 
-	// Calculate visible range
-	maxVisible := m.height - 15
-	if maxVisible < 5 {
-		maxVisible = 5
+	return b.String()
+}
+
+// handleVisualizerKeys handles keys in the visualizer view.
+func (m Model) handleVisualizerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "w" { // Switch between spectrum and waveform
+		if m.vizMode == VisualizerWaveform {
+			m.vizMode = VisualizerSpectrum
+		} else {
+			m.vizMode = VisualizerWaveform
+		}
 	}
+	return m, nil
+}
 
-	start := 0
-	if m.resultsCursor >= maxVisible {
-		start = m.resultsCursor - maxVisible + 1
+// visualizerBands returns how many spectrum bands fit the terminal width.
+func (m Model) visualizerBands() int {
+	bands := (m.width - 4) / 3 // Two columns per bar plus a gap
+	if bands < 8 {
+		bands = 8
 	}
+	if bands > 64 {
+		bands = 64
+	}
+	return bands
+}
+
+// renderResultsView renders the YouTube search results.
+func (m Model) renderResultsView() string {
+	var b strings.Builder
 
-	end := start + maxVisible
-	if end > len(m.youtubeResults) {
-		end = len(m.youtubeResults)
+	b.WriteString(headerStyle.Render(" 🎬 "+Tf("Results for '%s'", m.searchQuery)+" ") + "\n\n")
+
+	if len(m.youtubeResults) == 0 {
+		b.WriteString(mutedStyle.Render(T("No results") + "\n"))
+		return b.String()
 	}
 
-	for i := start; i < end; i++ {
+	b.WriteString(m.resultsScroll.render(len(m.youtubeResults), 2, m.maxVisible()*2, func(i int) string {
 		result := m.youtubeResults[i]
 		info := fmt.Sprintf("[%s] %s", result.Duration, result.Channel)
 
+		title := result.Title
+		if m.resultsSel.active || m.resultsSel.count() > 0 {
+			title = m.resultsSel.markerFor(result.VideoID) + " " + title
+		}
+
 		var line string
 		if i == m.resultsCursor {
-			line = selectedStyle.Render("> " + result.Title)
+			line = selectedStyle.Render("> " + title)
 			line += "\n  " + mutedStyle.Render(info)
 		} else {
-			line = normalStyle.Render("  " + result.Title)
+			line = normalStyle.Render("  " + title)
 			line += "\n  " + mutedStyle.Render(info)
 		}
 
-		b.WriteString(line + "\n")
-	}
-
-	return b.String()
-}
+		// Preview beside the two lines of the result
+		if thumbnailsEnabled {
+			line = lipgloss.JoinHorizontal(lipgloss.Top, m.resultThumbnail(result.VideoID), " ", line)
+		}
+		return line
+	}))
 
-// renderDownloadProgress renders the download progress bar.
-func (m Model) renderDownloadProgress() string {
-	dp := m.downloader.GetProgress()
-	
-	var b strings.Builder
-	b.WriteString("\n" + m.downloadSpinner.View())
-	b.WriteString(fmt.Sprintf(" %s\n", dp.Status))
-	b.WriteString(m.downloadProgress.ViewAs(dp.Progress / 100))
-	
 	return b.String()
 }
 
 // renderHelp renders the help bar.
 func (m Model) renderHelp() string {
+	if m.dialog != nil || m.contextMenu != nil || m.trackInfo != nil {
+		return ""
+	}
+	if m.finderOpen {
+		return helpStyle.Render(T("↑/↓: navigate • enter: go • esc: close"))
+	}
+	if m.pickerOpen {
+		return helpStyle.Render(T("↑/↓: navigate • enter: insert • esc: done"))
+	}
+	if m.renaming != nil {
+		return helpStyle.Render(T("enter: rename or move • esc: cancel"))
+	}
+
 	var keys []string
 
 	switch m.currentView {
 	case ViewSearch:
 		keys = []string{"enter: search", "esc: cancel", "tab: library"}
 	case ViewLibrary:
-		keys = []string{"↑/↓: navigate", "enter: play", "s: search", "space: pause"}
+		keys = []string{"↑/↓: navigate", "enter: play", ".: playing", "a: queue", "p: playlist", "m: menu", "i: info", "f: like", "1-5: rate", "o: sort", "O: next sort", "R: rename", "x: delete", "u: undo", "/: filter", "s: search", "space: pause", "t: theme"}
 	case ViewResults:
-		keys = []string{"↑/↓: navigate", "enter: download", "tab: library", "esc: back"}
+		keys = []string{"↑/↓: navigate", "enter: download", "m: menu", "tab: library", "esc: back"}
+	case ViewQueue:
+		keys = []string{"↑/↓: navigate", "enter: play", "shift+↑/↓: move", "d: remove", "c: clear"}
+	case ViewVisualizer:
+		keys = []string{"v: close", "w: spectrum/waveform", "space: pause", "tab: library"}
+	case ViewDownloads:
+		keys = []string{"↑/↓: navigate", "x: cancel", "X: cancel all", "r: retry", "C: clear finished"}
+	case ViewPlaylists:
+		keys = []string{"↑/↓: navigate", "enter: edit", "p: play", "n: new", "S: new smart", "x: delete"}
+	case ViewPlaylistEditor:
+		keys = []string{"↑/↓: navigate", "shift+↑/↓: move", "a: insert", "x: remove", "ctrl+s: save", "p: play", "esc: back"}
+	case ViewLog:
+		keys = []string{"↑/↓: navigate", "y: copy line", "c: copy all", "esc: back"}
+	case ViewJellyfin:
+		keys = []string{"↑/↓: navigate", "enter: open", "a: queue", "A: queue all", "backspace: up", "esc: back"}
+	case ViewDuplicates:
+		keys = []string{"↑/↓: navigate", "enter: play", "x: delete copy", "K: keep this copy", "D: merge all", "esc: back"}
+	case ViewBrowse:
+		keys = []string{"↑/↓: navigate", "enter: open", "p: play", "a: queue", "backspace: up", "esc: back"}
+	case ViewOrganize:
+		keys = []string{"↑/↓: navigate", "x: leave out", "enter: organize", "esc: back"}
+	}
+
+	// Selection mode replaces the view's hints
+	if (m.currentView == ViewLibrary && m.librarySel.active) || (m.currentView == ViewResults && m.resultsSel.active) {
+		if m.currentView == ViewResults {
+			keys = []string{"space: mark", "v: mark range", "enter: download marked", "esc: done"}
+		} else {
+			keys = []string{"space: mark", "v: mark range", "a: queue marked", "p: add marked to playlist", "x: delete marked", "esc: done"}
+		}
+	}
+
+	// Pane controls only apply to the wide layout
+	if m.isWide() && m.currentView != ViewSearch {
+		keys = append(keys, "ctrl+w: focus", "[/]: resize")
 	}
 
 	// Add playback controls
-	keys = append(keys, "←/→: prev/next", "q: quit")
+	keys = append(keys, "←/→: prev/next", "+/-: volume", "q: quit")
+	for i, key := range keys {
+		keys[i] = T(key)
+	}
 
 	return helpStyle.Render(strings.Join(keys, " • "))
 }
 
 // Command functions
 
-// tickCmd returns a command that sends a tick message periodically.
-func (m Model) tickCmd() tea.Cmd {
-	return tea.Tick(500*time.Millisecond, func(t time.Time) tea.Msg {
-		return tickMsg(t)
+// vizTickCmd returns a command that drives the visualizer animation.
+func (m Model) vizTickCmd() tea.Cmd {
+	return tea.Tick(50*time.Millisecond, func(t time.Time) tea.Msg {
+		return vizTickMsg(t)
 	})
 }
 
-// performYouTubeSearch returns a command that performs a YouTube search.
-func (m Model) performYouTubeSearch(query string) tea.Cmd {
-	return func() tea.Msg {
-		results, err := SearchYouTube(query)
-		return youtubeSearchCompleteMsg{results: results, err: err}
+// sameLibrary reports whether two scans found the same files with the same
+// modification times and sizes, in any order.
+func sameLibrary(old, scanned []MusicFile) bool {
+	if len(old) != len(scanned) {
+		return false
+	}
+	known := make(map[string]MusicFile, len(old))
+	for _, f := range old {
+		known[f.Path] = f
+	}
+	for _, f := range scanned {
+		if k, ok := known[f.Path]; !ok || !k.ModTime.Equal(f.ModTime) || k.Size != f.Size {
+			return false
+		}
 	}
+	return true
 }
 
-// refreshLibrary returns a command that refreshes the music library.
+// refreshLibrary returns a command that asks the library service for a
+// rescan. The result arrives as a libraryRefreshMsg.
 func (m Model) refreshLibrary() tea.Cmd {
 	return func() tea.Msg {
-		files, _ := ScanMusicFiles()
-		return libraryRefreshMsg(files)
+		m.library.Refresh()
+		return nil
 	}
 }
@@ -0,0 +1,237 @@
+// Package main provides the visualizer for Personal Musician.
+// This module turns samples from the player's audio tap into spectrum bar
+// heights or a live waveform and renders them with block characters.
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"strings"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// fftSize is the number of samples analyzed per visualizer frame (power of two).
+const fftSize = 2048
+
+// barLevels are the block characters used for partial bar heights.
+var barLevels = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// VisualizerMode selects what the visualizer view draws.
+type VisualizerMode int
+
+const (
+	VisualizerSpectrum VisualizerMode = iota // Frequency bars (FFT)
+	VisualizerWaveform                       // The waveform of the last few milliseconds
+)
+
+// String returns the display name of the mode.
+func (v VisualizerMode) String() string {
+	if v == VisualizerWaveform {
+		return "Waveform"
+	}
+	return "Spectrum"
+}
+
+// Spectrum holds smoothed band levels between frames.
+type Spectrum struct {
+	levels []float64 // Per-band level 0-1
+}
+
+// Update analyzes the samples and updates the band levels.
+// Bands are spaced logarithmically between 40 Hz and 16 kHz.
+// Levels rise immediately and fall off gradually for smoother motion.
+func (s *Spectrum) Update(samples []float64, rate beep.SampleRate, bands int) {
+	if bands < 1 {
+		return
+	}
+	if len(s.levels) != bands {
+		s.levels = make([]float64, bands)
+	}
+
+	magnitudes := fftMagnitudes(samples)
+	if rate <= 0 {
+		rate = 44100
+	}
+	binHz := float64(rate) / float64(fftSize)
+
+	const (
+		minHz = 40.0
+		maxHz = 16000.0
+		floor = -60.0 // dB mapped to an empty bar
+	)
+
+	for b := 0; b < bands; b++ {
+		lo := minHz * math.Pow(maxHz/minHz, float64(b)/float64(bands))
+		hi := minHz * math.Pow(maxHz/minHz, float64(b+1)/float64(bands))
+		loBin := int(lo / binHz)
+		hiBin := int(hi / binHz)
+		if hiBin <= loBin {
+			hiBin = loBin + 1
+		}
+		if hiBin > len(magnitudes) {
+			hiBin = len(magnitudes)
+		}
+
+		var peak float64
+		for i := loBin; i < hiBin; i++ {
+			if magnitudes[i] > peak {
+				peak = magnitudes[i]
+			}
+		}
+
+		level := 0.0
+		if peak > 0 {
+			db := 20 * math.Log10(peak)
+			level = (db - floor) / -floor
+		}
+		level = math.Max(0, math.Min(1, level))
+
+		// Fall off gradually instead of dropping instantly
+		if level < s.levels[b] {
+			level = math.Max(level, s.levels[b]-0.08)
+		}
+		s.levels[b] = level
+	}
+}
+
+// Render draws the spectrum as vertical bars of the given height.
+// Each band is drawn barWidth characters wide with a one-column gap.
+func (s *Spectrum) Render(height, barWidth int) string {
+	if height < 1 || len(s.levels) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	steps := len(barLevels) - 1
+	for row := height - 1; row >= 0; row-- {
+		for _, level := range s.levels {
+			// Number of eighths filled within this row
+			fill := int(level*float64(height*steps)) - row*steps
+			if fill < 0 {
+				fill = 0
+			}
+			if fill > steps {
+				fill = steps
+			}
+			b.WriteString(strings.Repeat(string(barLevels[fill]), barWidth))
+			b.WriteByte(' ')
+		}
+		if row > 0 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// fftMagnitudes returns the normalized magnitudes of the positive frequency bins
+// of the samples after applying a Hann window.
+func fftMagnitudes(samples []float64) []float64 {
+	buf := make([]complex128, fftSize)
+	offset := len(samples) - fftSize
+	for i := range buf {
+		if j := offset + i; j >= 0 && j < len(samples) {
+			window := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(fftSize-1)))
+			buf[i] = complex(samples[j]*window, 0)
+		}
+	}
+
+	fft(buf)
+
+	mags := make([]float64, fftSize/2)
+	for i := range mags {
+		// Scale so a full-scale sine wave reaches roughly 1.0
+		mags[i] = cmplx.Abs(buf[i]) * 4 / fftSize
+	}
+	return mags
+}
+
+// fft computes an in-place iterative radix-2 FFT. len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := x[start+k]
+				v := x[start+k+size/2] * w
+				x[start+k] = u + v
+				x[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
+
+// RenderWaveform draws samples as an oscilloscope trace of the given width
+// and height, with each column covering the range of its samples. The trace
+// starts at a rising zero crossing so a steady tone stands still.
+func RenderWaveform(samples []float64, width, height int) string {
+	if width < 1 || height < 1 || len(samples) < 2 {
+		return ""
+	}
+
+	// Trigger on the first rising zero crossing in the first half
+	window := samples[len(samples)/2:]
+	for i := 1; i < len(samples)/2; i++ {
+		if samples[i-1] < 0 && samples[i] >= 0 {
+			window = samples[i : i+len(samples)/2]
+			break
+		}
+	}
+
+	// Each row holds two half-cell levels; level 0 is the top
+	levels := height * 2
+	level := func(v float64) int {
+		v = max(-1, min(v, 1))
+		return max(0, min(int((1-v)/2*float64(levels)), levels-1))
+	}
+	top := make([]int, width)
+	bottom := make([]int, width)
+	for col := range width {
+		from := col * len(window) / width
+		to := max(from+1, (col+1)*len(window)/width)
+		lo, hi := window[from], window[from]
+		for _, v := range window[max(0, from-1):to] { // Join up with the previous column
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		top[col], bottom[col] = level(hi), level(lo)
+	}
+
+	var b strings.Builder
+	for row := range height {
+		upper, lower := row*2, row*2+1
+		for col := range width {
+			hasUpper := top[col] <= upper && upper <= bottom[col]
+			hasLower := top[col] <= lower && lower <= bottom[col]
+			switch {
+			case hasUpper && hasLower:
+				b.WriteRune('█')
+			case hasUpper:
+				b.WriteRune('▀')
+			case hasLower:
+				b.WriteRune('▄')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		if row < height-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,202 @@
+// Package main provides the music directory watcher of Personal Musician.
+// The music directories and every folder below them are watched with
+// fsnotify, so songs added, removed, renamed or rewritten by other programs,
+// and finished downloads, show up in the library without restarting.
+// Changes are collected until the directories have been quiet for a moment
+// and then trigger one library refresh of just the paths that changed,
+// whose result reaches the TUI as a libraryRefreshMsg like any other scan.
+// Where watching is not possible, such as on some network drives, the
+// library is refreshed when downloads complete instead.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the music directories must be quiet before a
+// change is scanned, so copying an album or finishing a download rescans
+// once.
+const watchDebounce = time.Second
+
+// DetectWatch reads PM_WATCH=1/0, falling back to the watch setting of the
+// config file; watching is on by default.
+func DetectWatch() bool {
+	if on, err := strconv.ParseBool(os.Getenv("PM_WATCH")); err == nil {
+		return on
+	}
+	if config.Library.Watch != nil {
+		return *config.Library.Watch
+	}
+	return true
+}
+
+// watchLibrary returns a command that starts watching the music
+// directories, unless turned off.
+func (m Model) watchLibrary() tea.Cmd {
+	if !DetectWatch() {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := m.library.Watch(m.ctx); err != nil {
+			slog.Warn("failed to watch music directories", "err", err)
+			appLog.Add("error", err.Error())
+		}
+		return nil
+	}
+}
+
+// musicWatcher watches the music directories and remembers which folders
+// it watches, as fsnotify forgets a folder as soon as it is moved away.
+type musicWatcher struct {
+	*fsnotify.Watcher
+	dirs map[string]bool // Folders being watched
+}
+
+// Watch refreshes the library whenever something changes in the music
+// directories, until ctx is done. It returns an error when the directories
+// cannot be watched.
+func (l *Library) Watch(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch music directories: %w", err)
+	}
+	w := &musicWatcher{Watcher: fw, dirs: make(map[string]bool)}
+	for _, dir := range append([]string{MusicDir}, extraMusicDirs...) {
+		if err := w.addTree(dir); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	l.watching = true
+	l.mu.Unlock()
+	go l.watch(ctx, w)
+	return nil
+}
+
+// Watching reports whether the music directories are being watched.
+func (l *Library) Watching() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.watching
+}
+
+// watch waits for changes and refreshes the changed paths once they
+// settle, or the whole library when events may have been lost.
+func (l *Library) watch(ctx context.Context, w *musicWatcher) {
+	defer func() {
+		w.Close()
+		l.mu.Lock()
+		l.watching = false
+		l.mu.Unlock()
+	}()
+
+	settled := time.NewTimer(watchDebounce)
+	settled.Stop()
+	changed := make(map[string]bool) // Paths changed since the last refresh
+	lost := false                    // Events were dropped since the last refresh
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if w.changed(event) {
+				changed[event.Name] = true
+				settled.Reset(watchDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been dropped, so scan to be sure
+			slog.Warn("failed to watch music directories", "err", err)
+			lost = true
+			settled.Reset(watchDebounce)
+		case <-settled.C:
+			if lost {
+				l.Refresh()
+			} else {
+				l.RefreshPaths(slices.Collect(maps.Keys(changed)))
+			}
+			changed, lost = make(map[string]bool), false
+		}
+	}
+}
+
+// changed reports whether an event changes the library. Folders created
+// or moved into the music directories are watched from then on.
+func (w *musicWatcher) changed(event fsnotify.Event) bool {
+	switch {
+	case inTrash(event.Name):
+		return false // Deleting and undoing are scanned by the library itself
+	case event.Has(fsnotify.Create):
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := w.addTree(event.Name); err != nil {
+				slog.Warn("failed to watch new folder", "dir", event.Name, "err", err)
+			}
+			return true // It may have arrived with songs in it
+		}
+		return isAudioFile(event.Name)
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		if w.dirs[event.Name] { // A folder moved away takes its songs with it
+			for dir := range w.dirs {
+				if dir == event.Name || strings.HasPrefix(dir, event.Name+string(filepath.Separator)) {
+					delete(w.dirs, dir)
+				}
+			}
+			return true
+		}
+		return isAudioFile(event.Name)
+	case event.Has(fsnotify.Write):
+		return isAudioFile(event.Name)
+	}
+	return false
+}
+
+// addTree watches dir and every folder below it. A missing directory is
+// skipped, as are folders that can't be read.
+func (w *musicWatcher) addTree(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == trashDirName {
+			return filepath.SkipDir
+		}
+		if err := w.Add(path); err != nil {
+			if path == dir {
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			slog.Warn("failed to watch folder", "dir", path, "err", err)
+			return nil
+		}
+		w.dirs[path] = true
+		return nil
+	})
+}
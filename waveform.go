@@ -0,0 +1,153 @@
+// Package main provides the waveform progress bar for Personal Musician.
+// When a track starts, its peak envelope is computed in the background and
+// drawn as the progress bar, with the played part and playhead highlighted.
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// waveformResolution is the number of peaks computed per track.
+const waveformResolution = 256
+
+// waveformLevels are the glyphs used for increasing peak levels.
+var waveformLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Waveform is the peak envelope of a track.
+type Waveform struct {
+	Path  string
+	Peaks []float64 // Normalized to 0..1
+}
+
+// waveformMsg carries a computed waveform to the UI.
+type waveformMsg struct {
+	waveform *Waveform
+	err      error
+}
+
+// ComputeWaveform decodes a track and returns its peak envelope.
+func ComputeWaveform(path string, resolution int) (*Waveform, error) {
+	streamer, _, err := decodeAudioFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer streamer.Close()
+
+	total := streamer.Len()
+	if total <= 0 {
+		return nil, fmt.Errorf("failed to compute waveform: empty stream")
+	}
+
+	peaks := make([]float64, resolution)
+	buf := make([][2]float64, 4096)
+	pos := 0
+	for {
+		n, ok := streamer.Stream(buf)
+		for i := 0; i < n; i++ {
+			bucket := (pos + i) * resolution / total
+			if bucket >= resolution {
+				bucket = resolution - 1
+			}
+			level := math.Max(math.Abs(buf[i][0]), math.Abs(buf[i][1]))
+			if level > peaks[bucket] {
+				peaks[bucket] = level
+			}
+		}
+		pos += n
+		if !ok {
+			break
+		}
+	}
+	if err := streamer.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %w", err)
+	}
+
+	// Normalize so quiet masters still use the full height
+	max := 0.0
+	for _, p := range peaks {
+		max = math.Max(max, p)
+	}
+	if max > 0 {
+		for i := range peaks {
+			peaks[i] /= max
+		}
+	}
+
+	return &Waveform{Path: path, Peaks: peaks}, nil
+}
+
+// loadWaveform computes the waveform of a track in the background.
+func loadWaveform(path string) tea.Cmd {
+	return func() tea.Msg {
+		w, err := ComputeWaveform(path, waveformResolution)
+		return waveformMsg{waveform: w, err: err}
+	}
+}
+
+// updateWaveform starts computing the waveform when the playing track changes.
+func (m Model) updateWaveform() (Model, tea.Cmd) {
+	current := m.player.GetState().CurrentFile
+	if current == "" || current == m.waveformPath {
+		return m, nil
+	}
+	m.waveformPath = current
+	m.waveform = nil
+	return m, loadWaveform(current)
+}
+
+// renderProgressBar renders the playback progress bar: the track's waveform
+// once computed, otherwise a plain bar.
+func (m Model) renderProgressBar(state PlaybackState) string {
+	if state.Duration <= 0 {
+		return ""
+	}
+	pct := float64(state.Position) / float64(state.Duration)
+	filled := int(pct * float64(progressBarWidth))
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+
+	cells := make([]string, progressBarWidth)
+	if m.waveform == nil || m.waveform.Path != state.CurrentFile {
+		for i := range cells {
+			cells[i] = "░"
+			if i < filled {
+				cells[i] = "█"
+			}
+		}
+		markLoop(cells, state)
+		return strings.Join(cells, "")
+	}
+
+	played := lipgloss.NewStyle().Foreground(primaryColor)
+	playhead := lipgloss.NewStyle().Foreground(accentColor).Bold(true)
+
+	for i, level := range m.waveform.columns(progressBarWidth) {
+		glyph := string(waveformLevels[int(level*float64(len(waveformLevels)-1))])
+		switch {
+		case i < filled:
+			cells[i] = played.Render(glyph)
+		case i == filled:
+			cells[i] = playhead.Render(glyph)
+		default:
+			cells[i] = mutedStyle.Render(glyph)
+		}
+	}
+	markLoop(cells, state)
+	return strings.Join(cells, "")
+}
+
+// columns downsamples the peaks to width columns, keeping the maximum of each.
+func (w *Waveform) columns(width int) []float64 {
+	cols := make([]float64, width)
+	for i, p := range w.Peaks {
+		col := i * width / len(w.Peaks)
+		cols[col] = math.Max(cols[col], p)
+	}
+	return cols
+}